@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"aicg/internal/config"
+	"aicg/internal/database"
+	"aicg/internal/seed"
+)
+
+const usage = `Usage: api [flags] [command]
+
+Commands:
+  api [serve]                 Start the HTTP server
+  api migrate up              Apply all pending migrations
+  api migrate down [n]        Roll back the last n migrations (default 1)
+  api migrate status          List migrations and whether they are applied
+  api migrate new <name>      Create empty up/down files for a new migration, for every driver
+  api seed [--demo]           Load the fixtures, and with --demo synthetic users and results
+  api config print [--redact] Print the effective configuration as YAML, optionally hiding secrets
+
+Flags:
+  -config <file>              Load settings from a YAML file (or set CONFIG_FILE)
+  -<section>.<name> <value>   Override any setting, e.g. -db.driver sqlite; see api -h
+
+Settings are taken from the defaults, the config file, the environment and the flags,
+each overriding the ones before.`
+
+// runCommand runs a subcommand given on the command line
+func runCommand(cfg *config.Config, name string, args []string) error {
+	switch name {
+	case "migrate":
+		return runMigrate(cfg, args)
+	case "seed":
+		return runSeed(cfg, args)
+	case "help", "-h", "--help":
+		fmt.Println(usage)
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q\n%s", name, usage)
+	}
+}
+
+// runConfig prints the effective configuration, then reports whether it is valid
+func runConfig(cfg *config.Config, args []string) error {
+	if len(args) == 0 || args[0] != "print" {
+		return fmt.Errorf("usage: config print [--redact]")
+	}
+	flags := flag.NewFlagSet("config print", flag.ContinueOnError)
+	redact := flags.Bool("redact", false, "replace secrets with REDACTED")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	out, err := cfg.YAML(*redact)
+	if err != nil {
+		return err
+	}
+	fmt.Print(string(out))
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return nil
+}
+
+// runMigrate applies, rolls back, lists or creates schema migrations
+func runMigrate(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	dir := flags.String("dir", database.MigrationsDir, "directory new migrations are written to")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	args = flags.Args()
+	if len(args) == 0 {
+		return fmt.Errorf("missing migrate action\n%s", usage)
+	}
+
+	// Creating a migration only touches files. Every driver gets its own
+	// copy, to be filled in with that database's DDL.
+	if args[0] == "new" {
+		if len(args) != 2 {
+			return fmt.Errorf("usage: migrate new <name>")
+		}
+		for _, driver := range database.Drivers {
+			up, down, err := database.CreateMigration(filepath.Join(*dir, driver), args[1])
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Created %s\nCreated %s\n", up, down)
+		}
+		return nil
+	}
+
+	db, err := database.InitDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeDB(db)
+
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			fmt.Printf("Applied %06d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("Schema is up to date")
+		}
+		return nil
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of migrations to roll back: %s", args[1])
+			}
+		}
+		rolledBack, err := migrator.Down(ctx, steps)
+		for _, m := range rolledBack {
+			fmt.Printf("Rolled back %06d_%s\n", m.Version, m.Name)
+		}
+		return err
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range statuses {
+			appliedAt := "pending"
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05 MST")
+			}
+			fmt.Fprintf(w, "%06d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+		}
+		return w.Flush()
+
+	default:
+		return fmt.Errorf("unknown migrate action %q\n%s", args[0], usage)
+	}
+}
+
+// runSeed loads the fixture files and optionally generates demo data. Both
+// steps are idempotent, so it is safe to run after every deploy.
+func runSeed(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("seed", flag.ContinueOnError)
+	dir := flags.String("dir", "fixtures", "directory containing the YAML/JSON fixture files")
+	demo := flags.Bool("demo", false, "also generate demo users with a quiz history")
+	users := flags.Int("users", 50, "number of demo users")
+	days := flags.Int("days", 90, "days of quiz history per demo user")
+	randSeed := flags.Int64("seed", 1, "random seed for the demo data")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	fixtures, err := seed.LoadFixtures(os.DirFS(*dir))
+	if err != nil {
+		return fmt.Errorf("loading fixtures: %w", err)
+	}
+
+	db, err := database.InitDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeDB(db)
+
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		return err
+	}
+	if err := migrator.CheckSchema(context.Background()); err != nil {
+		return fmt.Errorf("%w; run \"go run ./cmd/api migrate up\" first", err)
+	}
+
+	seeder := seed.NewSeeder(db)
+	report, err := seeder.Seed(fixtures, os.Getenv("SEED_ADMIN_PASSWORD"))
+	if err != nil {
+		return err
+	}
+	fmt.Println(report)
+	if report.GeneratedAdminPassword != "" {
+		fmt.Printf("Created admin %s with password %s (shown only once)\n", fixtures.Admin.Email, report.GeneratedAdminPassword)
+	}
+
+	if !*demo {
+		return nil
+	}
+	report, err = seeder.SeedDemo(seed.DemoOptions{
+		Users: *users,
+		Days:  *days,
+		Seed:  *randSeed,
+		Now:   time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Println(report)
+	fmt.Printf("Demo users sign in with password %q\n", seed.DemoPassword)
+	return nil
+}
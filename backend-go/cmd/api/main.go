@@ -1,119 +1,189 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"aicg/internal/config"
 	"aicg/internal/database"
-	"aicg/internal/handlers"
-	"aicg/internal/middleware"
+	"aicg/internal/logging"
+	"aicg/internal/ratelimit"
 	"aicg/internal/routes"
-	"aicg/internal/services"
+	"aicg/internal/storage"
+	"aicg/internal/tracing"
+	"aicg/internal/version"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	"gorm.io/gorm"
 )
 
 func main() {
 	// Load environment variables
-	if err := godotenv.Load(); err != nil {
-		log.Printf("Warning: Error loading .env file: %v", err)
+	envErr := godotenv.Load()
+
+	// Load configuration from the config file, environment and flags
+	cfg, args, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		fmt.Println(usage)
+		return
+	}
+	if err != nil {
+		fatal("Error loading configuration", err)
+	}
+
+	// Log structured records from here on; the standard logger goes through slog too
+	slog.SetDefault(logging.New(os.Stdout, cfg.Log))
+	if envErr != nil {
+		slog.Debug("No .env file loaded", "error", envErr)
+	}
+
+	// Printing the configuration works even when it is invalid, to help fix it
+	if len(args) > 0 && args[0] == "config" {
+		if err := runConfig(cfg, args[1:]); err != nil {
+			fatal("Command failed", err, "command", "config")
+		}
+		return
+	}
+	if err := cfg.Validate(); err != nil {
+		fatal("Invalid configuration", err)
+	}
+
+	// Subcommands that manage the database instead of serving requests
+	if len(args) > 0 && args[0] != "serve" {
+		if err := runCommand(cfg, args[0], args[1:]); err != nil {
+			fatal("Command failed", err, "command", args[0])
+		}
+		return
 	}
 
-	// Load configuration
-	cfg, err := config.LoadConfig()
+	// Export traces, before anything starts spans
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
 	if err != nil {
-		log.Fatalf("Error loading configuration: %v", err)
+		fatal("Failed to initialize tracing", err)
 	}
+	defer flushTraces(shutdownTracing)
 
 	// Initialize database
-	db, err := database.InitDB()
+	db, err := database.InitDB(cfg)
+	if err != nil {
+		fatal("Failed to initialize database", err)
+	}
+	defer closeDB(db)
+
+	// Refuse to run against a schema that is missing migrations
+	migrator, err := database.NewMigrator(db)
 	if err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
+		fatal("Failed to load migrations", err)
 	}
-	defer func() {
-		sqlDB, err := db.DB()
-		if err != nil {
-			log.Printf("Error getting database instance: %v", err)
-			return
+	if err := migrator.CheckSchema(context.Background()); err != nil {
+		fatal("Database schema is not up to date; run \"go run ./cmd/api migrate up\" first", err)
+	}
+
+	// Initialize blob storage for uploaded images
+	blobStore, err := storage.NewFromConfig(cfg)
+	if err != nil {
+		fatal("Failed to initialize storage", err)
+	}
+
+	// Rate limit buckets, shared by every instance when kept in Redis
+	var rateLimits ratelimit.Store
+	if cfg.RateLimit.Enabled {
+		if rateLimits, err = ratelimit.NewFromConfig(cfg.RateLimit); err != nil {
+			fatal("Failed to initialize rate limiting", err)
 		}
-		sqlDB.Close()
-	}()
+	}
 
 	// Set Gin mode
-	if os.Getenv("GIN_MODE") == "release" {
-		gin.SetMode(gin.ReleaseMode)
-	}
-
-	// Initialize services
-	authService := services.NewAuthService(db, cfg)
-	quizService := services.NewQuizService(db)
-
-	// Initialize handlers
-	authHandler := handlers.NewAuthHandler(authService)
-	quizHandler := handlers.NewQuizHandler(quizService)
-
-	// Initialize middleware
-	authMiddleware := middleware.NewAuthMiddleware(cfg)
-
-	// Initialize Gin router
-	r := gin.Default()
-
-	// Middleware
-	r.Use(middleware.CORS())
-	r.Use(gin.Recovery())
-
-	// Public routes
-	public := r.Group("/api")
-	{
-		// Auth routes
-		auth := public.Group("/auth")
-		{
-			auth.POST("/register", authHandler.Register)
-			auth.POST("/login", authHandler.Login)
-			auth.POST("/refresh", authHandler.RefreshToken)
-			auth.GET("/sso/:provider/config", authHandler.GetSSOConfig)
-			auth.POST("/sso/:provider/callback", authHandler.HandleSSO)
-		}
+	gin.SetMode(cfg.Server.Mode)
+
+	// Initialize services and routes
+	svc := routes.NewServices(db, blobStore, cfg)
+	svc.RateLimits = rateLimits
+	if cfg.Features.DataExports {
+		svc.Export.Start()
+	}
+	srv := &http.Server{
+		Addr:              fmt.Sprintf(":%d", cfg.Server.Port),
+		Handler:           routes.SetupRouter(cfg, svc),
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
 	}
 
-	// Protected routes
-	protected := r.Group("/api")
-	protected.Use(authMiddleware.AuthRequired())
-	{
-		// Quiz routes
-		quiz := protected.Group("/quiz")
-		{
-			quiz.GET("/", quizHandler.GetQuizzes)
-			quiz.GET("/:id", quizHandler.GetQuiz)
-			quiz.POST("/:id/submit", quizHandler.SubmitQuiz)
-		}
+	// Serve until SIGINT or SIGTERM
+	slog.Info("AICG API listening",
+		"addr", srv.Addr,
+		"version", version.Get().String(),
+		"mode", cfg.Server.Mode,
+		"driver", cfg.DB.Driver,
+	)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := serve(ctx, srv, svc, cfg.Server.ShutdownTimeout); err != nil {
+		closeDB(db)
+		flushTraces(shutdownTracing)
+		fatal("Server failed", err)
+	}
+}
 
-		// Results routes
-		results := protected.Group("/results")
-		{
-			results.GET("/", quizHandler.GetResults)
-			results.GET("/:id", quizHandler.GetResult)
-		}
+// serve runs srv until ctx is done, then stops taking new requests and waits
+// for in-flight requests and background exports to finish, up to timeout
+func serve(ctx context.Context, srv *http.Server, svc routes.Services, timeout time.Duration) error {
+	errs := make(chan error, 1)
+	go func() {
+		errs <- srv.ListenAndServe()
+	}()
 
-		// Admin routes
-		admin := protected.Group("/admin")
-		admin.Use(authMiddleware.RequireSuperAdmin())
-		{
-			admin.POST("/quiz", quizHandler.CreateQuiz)
-			// Add more admin routes here
-		}
+	select {
+	case err := <-errs:
+		return fmt.Errorf("failed to start server: %w", err)
+	case <-ctx.Done():
+	}
+
+	// Fail readiness first, so load balancers stop routing new requests here
+	slog.Info("Shutting down, draining requests and background jobs", "timeout", timeout)
+	svc.Health.ShutDown()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to drain requests: %w", err)
+	}
+	if err := svc.Export.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to finish data exports: %w", err)
 	}
+	slog.Info("Server stopped")
+	return nil
+}
 
-	// Setup routes
-	routes.SetupRoutes(r, quizService)
+// closeDB closes the database connection pools
+func closeDB(db *gorm.DB) {
+	if err := database.Close(db); err != nil {
+		slog.Error("Error closing database", "error", err)
+	}
+}
 
-	// Start server
-	addr := fmt.Sprintf(":%s", cfg.ServerPort)
-	log.Printf("Server starting on %s", addr)
-	if err := r.Run(addr); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+// flushTraces exports the spans still buffered, waiting a few seconds at most
+func flushTraces(shutdown func(context.Context) error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdown(ctx); err != nil {
+		slog.Error("Error flushing traces", "error", err)
 	}
 }
+
+// fatal logs err and exits
+func fatal(msg string, err error, args ...interface{}) {
+	slog.Error(msg, append([]interface{}{"error", err}, args...)...)
+	os.Exit(1)
+}
@@ -1,60 +1,326 @@
+// Package config holds the application's settings. They are loaded from
+// defaults, an optional YAML file, environment variables and command line
+// flags, in increasing order of precedence; see Load.
+//
+// Every setting is described once, by its struct tags:
+//   - yaml: the key in the config file; the dotted path is also the flag name
+//   - env: the environment variable, prefixed by the env tags of enclosing structs
+//   - secret: the value is hidden by `config print --redact`
 package config
 
 import (
-	"os"
-	"strconv"
 	"time"
 )
 
+// Config is the complete application configuration
 type Config struct {
-	DBHost     string
-	DBUser     string
-	DBPassword string
-	DBName     string
-	DBPort     string
-	ServerPort string
-	JWTSecret  string
-	JWTExpiry  time.Duration
-}
-
-func LoadConfig() (*Config, error) {
-	config := &Config{
-		DBHost:     getEnv("DB_HOST", "localhost"),
-		DBUser:     getEnv("DB_USER", "postgres"),
-		DBPassword: getEnv("DB_PASSWORD", ""),
-		DBName:     getEnv("DB_NAME", "aicg"),
-		DBPort:     getEnv("DB_PORT", "5432"),
-		ServerPort: getEnv("PORT", "8080"),
-		JWTSecret:  getEnv("JWT_SECRET", "your-secret-key"),
-	}
+	Server    ServerConfig    `yaml:"server"`
+	Log       LogConfig       `yaml:"log"`
+	Metrics   MetricsConfig   `yaml:"metrics"`
+	Tracing   TracingConfig   `yaml:"tracing"`
+	DB        DBConfig        `yaml:"db"`
+	JWT       JWTConfig       `yaml:"jwt"`
+	CORS      CORSConfig      `yaml:"cors"`
+	SSO       SSOConfig       `yaml:"sso"`
+	Mail      MailConfig      `yaml:"mail"`
+	Storage   StorageConfig   `yaml:"storage"`
+	Exports   ExportsConfig   `yaml:"exports"`
+	RateLimit RateLimitConfig `yaml:"rate_limit"`
+	Features  FeatureFlags    `yaml:"features"`
+}
 
-	// Parse JWT expiration
-	expiryStr := getEnv("JWT_EXPIRATION", "24h")
-	expiry, err := time.ParseDuration(expiryStr)
-	if err != nil {
-		return nil, err
-	}
-	config.JWTExpiry = expiry
+// ServerConfig configures the HTTP server
+type ServerConfig struct {
+	Port int    `yaml:"port" env:"PORT"`
+	Mode string `yaml:"mode" env:"GIN_MODE"` // "debug", "release" or "test"
 
-	return config, nil
+	// Timeouts guarding against slow clients. WriteTimeout also bounds handlers.
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" env:"SERVER_READ_HEADER_TIMEOUT"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" env:"SERVER_READ_TIMEOUT"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" env:"SERVER_WRITE_TIMEOUT"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" env:"SERVER_IDLE_TIMEOUT"`
+	// How long shutdown waits for in-flight requests and background jobs
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SERVER_SHUTDOWN_TIMEOUT"`
+	// Addresses or CIDRs of the proxies whose X-Forwarded-For is believed.
+	// Empty trusts none, so clients are identified by their own address.
+	TrustedProxies []string `yaml:"trusted_proxies" env:"SERVER_TRUSTED_PROXIES"`
 }
 
-func getEnv(key, defaultValue string) string {
-	value := os.Getenv(key)
-	if value == "" {
-		return defaultValue
-	}
-	return value
+// LogConfig configures the application logs, which are written to stdout
+type LogConfig struct {
+	Level  string `yaml:"level" env:"LOG_LEVEL"`   // "debug", "info", "warn" or "error"; SQL statements are logged at debug
+	Format string `yaml:"format" env:"LOG_FORMAT"` // "json" or "text"
+
+	// Queries taking longer are logged as warnings
+	SlowQuery time.Duration `yaml:"slow_query" env:"LOG_SLOW_QUERY"`
+}
+
+// MetricsConfig configures the Prometheus metrics endpoint
+type MetricsConfig struct {
+	Enabled bool   `yaml:"enabled" env:"METRICS_ENABLED"`
+	Path    string `yaml:"path" env:"METRICS_PATH"` // Where Prometheus scrapes the metrics
+}
+
+// TracingConfig configures OpenTelemetry tracing of requests, service calls and queries
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter" env:"TRACING_EXPORTER"`         // "none", "otlp" or "stdout"
+	Endpoint    string  `yaml:"endpoint" env:"TRACING_ENDPOINT"`         // OTLP/HTTP collector URL, e.g. http://localhost:4318
+	SampleRatio float64 `yaml:"sample_ratio" env:"TRACING_SAMPLE_RATIO"` // Share of new traces recorded, from 0 to 1
+	ServiceName string  `yaml:"service_name" env:"TRACING_SERVICE_NAME"`
+}
+
+// DBConfig configures the database, its connection pools and read replicas
+type DBConfig struct {
+	Driver      string `yaml:"driver" env:"DB_DRIVER"` // "postgres" or "sqlite"
+	Path        string `yaml:"path" env:"DB_PATH"`     // Database file used by the sqlite driver
+	Host        string `yaml:"host" env:"DB_HOST"`
+	Port        int    `yaml:"port" env:"DB_PORT"`
+	User        string `yaml:"user" env:"DB_USER"`
+	Password    string `yaml:"password" env:"DB_PASSWORD" secret:"true"`
+	Name        string `yaml:"name" env:"DB_NAME"`
+	SSLMode     string `yaml:"sslmode" env:"DB_SSLMODE"`
+	SSLRootCert string `yaml:"sslrootcert" env:"DB_SSLROOTCERT"` // CA certificate used to verify the server (optional)
+
+	// Connection pool settings, shared by the primary and the replicas
+	MaxOpenConns    int           `yaml:"max_open_conns" env:"DB_MAX_OPEN_CONNS"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME"`
+
+	// Read replicas serving quiz listings, leaderboards and benchmarks
+	ReplicaDSNs          []string      `yaml:"replica_dsns" env:"DB_REPLICA_DSNS" secret:"true"` // Connection strings, or file paths for sqlite
+	ReplicaCheckInterval time.Duration `yaml:"replica_check_interval" env:"DB_REPLICA_CHECK_INTERVAL"`
+}
+
+// JWTConfig configures the access and refresh tokens
+type JWTConfig struct {
+	Secret        string        `yaml:"secret" env:"JWT_SECRET" secret:"true"`
+	Expiry        time.Duration `yaml:"expiry" env:"JWT_EXPIRATION"`
+	RefreshExpiry time.Duration `yaml:"refresh_expiry" env:"JWT_REFRESH_EXPIRATION"`
+}
+
+// CORSConfig configures which browser origins may call the API. Origins are
+// exact, like "https://app.example.com", match any subdomain, like
+// "https://*.example.com", or are "*" for any origin.
+type CORSConfig struct {
+	AllowedOrigins   []string      `yaml:"allowed_origins" env:"CORS_ALLOWED_ORIGINS"`
+	AllowedMethods   []string      `yaml:"allowed_methods" env:"CORS_ALLOWED_METHODS"`
+	AllowedHeaders   []string      `yaml:"allowed_headers" env:"CORS_ALLOWED_HEADERS"`
+	ExposedHeaders   []string      `yaml:"exposed_headers" env:"CORS_EXPOSED_HEADERS"` // Response headers scripts may read
+	AllowCredentials bool          `yaml:"allow_credentials" env:"CORS_ALLOW_CREDENTIALS"`
+	MaxAge           time.Duration `yaml:"max_age" env:"CORS_MAX_AGE"` // How long browsers may cache preflight responses
+	Routes           []CORSRoute   `yaml:"routes" env:"-"`             // Overrides for some paths, set in the config file only
+}
+
+// CORSRoute overrides the CORS policy for the paths starting with Path. Unset
+// fields keep the value of the main policy.
+type CORSRoute struct {
+	Path             string   `yaml:"path"`
+	AllowedOrigins   []string `yaml:"allowed_origins,omitempty"`
+	AllowedMethods   []string `yaml:"allowed_methods,omitempty"`
+	AllowedHeaders   []string `yaml:"allowed_headers,omitempty"`
+	AllowCredentials *bool    `yaml:"allow_credentials,omitempty"`
+}
+
+// SSOConfig holds SSO credentials. A provider configured here is enabled and
+// takes precedence over its row in the sso_configs table.
+type SSOConfig struct {
+	Google    SSOProviderConfig `yaml:"google" env:"SSO_GOOGLE_"`
+	Facebook  SSOProviderConfig `yaml:"facebook" env:"SSO_FACEBOOK_"`
+	Instagram SSOProviderConfig `yaml:"instagram" env:"SSO_INSTAGRAM_"`
+}
+
+// SSOProviderConfig holds one provider's OAuth client
+type SSOProviderConfig struct {
+	ClientID     string   `yaml:"client_id" env:"CLIENT_ID"`
+	ClientSecret string   `yaml:"client_secret" env:"CLIENT_SECRET" secret:"true"`
+	RedirectURL  string   `yaml:"redirect_url" env:"REDIRECT_URL"`
+	Scopes       []string `yaml:"scopes" env:"SCOPES"`
 }
 
-func getEnvInt(key string, defaultValue int) int {
-	value := os.Getenv(key)
-	if value == "" {
-		return defaultValue
+// Configured reports whether the provider has credentials
+func (p SSOProviderConfig) Configured() bool {
+	return p.ClientID != ""
+}
+
+// Provider returns the settings of the named provider, if it is one
+func (c SSOConfig) Provider(name string) (SSOProviderConfig, bool) {
+	switch name {
+	case "google":
+		return c.Google, true
+	case "facebook":
+		return c.Facebook, true
+	case "instagram":
+		return c.Instagram, true
+	default:
+		return SSOProviderConfig{}, false
 	}
-	intValue, err := strconv.Atoi(value)
-	if err != nil {
-		return defaultValue
+}
+
+// MailConfig configures the SMTP server outgoing mail is sent through.
+// Mail is disabled while Host is empty.
+type MailConfig struct {
+	Host     string `yaml:"host" env:"SMTP_HOST"`
+	Port     int    `yaml:"port" env:"SMTP_PORT"`
+	Username string `yaml:"username" env:"SMTP_USERNAME"`
+	Password string `yaml:"password" env:"SMTP_PASSWORD" secret:"true"`
+	From     string `yaml:"from" env:"MAIL_FROM"` // Sender address, e.g. "AICG <no-reply@example.com>"
+}
+
+// StorageConfig configures blob storage for uploaded images and data exports
+type StorageConfig struct {
+	Driver    string        `yaml:"driver" env:"STORAGE_DRIVER"`         // "local" or "s3"
+	LocalDir  string        `yaml:"local_dir" env:"STORAGE_LOCAL_DIR"`   // Directory used by the local driver
+	PublicURL string        `yaml:"public_url" env:"STORAGE_PUBLIC_URL"` // Base URL blobs are publicly served under (required for s3)
+	URLExpiry time.Duration `yaml:"url_expiry" env:"STORAGE_URL_EXPIRY"` // Lifetime of signed URLs
+	S3        S3Config      `yaml:"s3" env:"S3_"`
+}
+
+// ExportsConfig configures personal data exports
+type ExportsConfig struct {
+	SigningKey    string        `yaml:"signing_key" env:"EXPORT_SIGNING_KEY" secret:"true"` // Signs download links, and nothing else
+	SweepInterval time.Duration `yaml:"sweep_interval" env:"EXPORT_SWEEP_INTERVAL"`         // How often expired and abandoned exports are swept
+}
+
+// S3Config configures the s3 storage driver
+type S3Config struct {
+	Endpoint     string `yaml:"endpoint" env:"ENDPOINT"`
+	Region       string `yaml:"region" env:"REGION"`
+	Bucket       string `yaml:"bucket" env:"BUCKET"`
+	AccessKey    string `yaml:"access_key" env:"ACCESS_KEY" secret:"true"`
+	SecretKey    string `yaml:"secret_key" env:"SECRET_KEY" secret:"true"`
+	UsePathStyle bool   `yaml:"use_path_style" env:"USE_PATH_STYLE"`
+}
+
+// RateLimitConfig configures request rate limiting. Requests are limited per
+// user once logged in and per client IP before. Every API request counts
+// against the main limit, and those of some route groups against their own
+// policy as well.
+type RateLimitConfig struct {
+	Enabled           bool   `yaml:"enabled" env:"RATE_LIMIT_ENABLED"`
+	Store             string `yaml:"store" env:"RATE_LIMIT_STORE"`                       // "memory" or "redis"
+	RedisURL          string `yaml:"redis_url" env:"RATE_LIMIT_REDIS_URL" secret:"true"` // Used by the redis store
+	RequestsPerMinute int    `yaml:"requests_per_minute" env:"RATE_LIMIT_REQUESTS_PER_MINUTE"`
+	Burst             int    `yaml:"burst" env:"RATE_LIMIT_BURST"`
+
+	Auth   RateLimitPolicy `yaml:"auth" env:"RATE_LIMIT_AUTH_"`     // Sign-up, login, token refresh and SSO
+	Submit RateLimitPolicy `yaml:"submit" env:"RATE_LIMIT_SUBMIT_"` // Quiz submissions
+	Admin  RateLimitPolicy `yaml:"admin" env:"RATE_LIMIT_ADMIN_"`   // Admin endpoints
+}
+
+// RateLimitPolicy is a token bucket: Burst requests at once, refilled at
+// RequestsPerMinute
+type RateLimitPolicy struct {
+	RequestsPerMinute int `yaml:"requests_per_minute" env:"REQUESTS_PER_MINUTE"`
+	Burst             int `yaml:"burst" env:"BURST"`
+}
+
+// Policy returns the main limit as a policy
+func (c RateLimitConfig) Policy() RateLimitPolicy {
+	return RateLimitPolicy{RequestsPerMinute: c.RequestsPerMinute, Burst: c.Burst}
+}
+
+// FeatureFlags switch optional parts of the API on and off
+type FeatureFlags struct {
+	Registration bool `yaml:"registration" env:"FEATURE_REGISTRATION"` // Email sign-up
+	SSO          bool `yaml:"sso" env:"FEATURE_SSO"`                   // Sign-in with Google, Facebook and Instagram
+	DataExports  bool `yaml:"data_exports" env:"FEATURE_DATA_EXPORTS"` // Personal data exports
+	APIDocs      bool `yaml:"api_docs" env:"FEATURE_API_DOCS"`         // The OpenAPI document and Swagger UI
+}
+
+// Default returns the configuration used for anything not set elsewhere
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:              8080,
+			Mode:              "debug",
+			ReadHeaderTimeout: 5 * time.Second,
+			ReadTimeout:       30 * time.Second,
+			WriteTimeout:      60 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			ShutdownTimeout:   30 * time.Second,
+		},
+		Log: LogConfig{
+			Level:     "info",
+			Format:    "json",
+			SlowQuery: 200 * time.Millisecond,
+		},
+		Metrics: MetricsConfig{
+			Enabled: true,
+			Path:    "/metrics",
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			Endpoint:    "http://localhost:4318",
+			SampleRatio: 1,
+			ServiceName: "aicg-api",
+		},
+		DB: DBConfig{
+			Driver:               "postgres",
+			Path:                 "aicg.db",
+			Host:                 "localhost",
+			Port:                 5432,
+			User:                 "postgres",
+			Name:                 "aicg",
+			SSLMode:              "disable",
+			MaxOpenConns:         100,
+			MaxIdleConns:         10,
+			ConnMaxLifetime:      time.Hour,
+			ConnMaxIdleTime:      10 * time.Minute,
+			ReplicaCheckInterval: 10 * time.Second,
+		},
+		JWT: JWTConfig{
+			Secret:        DefaultJWTSecret,
+			Expiry:        24 * time.Hour,
+			RefreshExpiry: 7 * 24 * time.Hour,
+		},
+		CORS: CORSConfig{
+			AllowedOrigins: []string{
+				"http://localhost:3000", // Frontend development
+				"http://localhost:8080", // Backend development
+			},
+			AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+			AllowedHeaders:   []string{"Content-Type", "Authorization", "X-Request-ID", "Idempotency-Key"},
+			ExposedHeaders:   []string{"X-Request-ID", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Retry-After", "Idempotent-Replayed"},
+			AllowCredentials: true,
+			MaxAge:           12 * time.Hour,
+		},
+		Mail: MailConfig{
+			Port: 587,
+		},
+		Storage: StorageConfig{
+			Driver:    "local",
+			LocalDir:  "./uploads",
+			URLExpiry: time.Hour,
+			S3: S3Config{
+				Region:       "us-east-1",
+				UsePathStyle: true,
+			},
+		},
+		Exports: ExportsConfig{
+			SigningKey:    DefaultExportSigningKey,
+			SweepInterval: 15 * time.Minute,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:           true,
+			Store:             "memory",
+			RequestsPerMinute: 120,
+			Burst:             30,
+			Auth:              RateLimitPolicy{RequestsPerMinute: 10, Burst: 10},
+			Submit:            RateLimitPolicy{RequestsPerMinute: 10, Burst: 5},
+			Admin:             RateLimitPolicy{RequestsPerMinute: 60, Burst: 20},
+		},
+		Features: FeatureFlags{
+			Registration: true,
+			SSO:          true,
+			DataExports:  true,
+			APIDocs:      true,
+		},
 	}
-	return intValue
 }
+
+// DefaultJWTSecret is the development signing key. It is rejected in release mode.
+const DefaultJWTSecret = "your-secret-key"
+
+// DefaultExportSigningKey is the development key for download links. It is rejected in release mode.
+const DefaultExportSigningKey = "your-export-signing-key"
@@ -0,0 +1,193 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// writeFile writes a config file into a temporary directory
+func writeFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	// Test case 1: Nothing set gives the defaults
+	cfg, args, err := Load(nil)
+	require.NoError(t, err)
+	assert.Equal(t, Default(), cfg)
+	assert.Empty(t, args)
+
+	// Test case 2: The file overrides the defaults, the environment the file,
+	// and the flags the environment
+	path := writeFile(t, `
+server:
+  port: 9000
+db:
+  driver: sqlite
+  path: file.db
+  max_open_conns: 5
+jwt:
+  expiry: 2h
+cors:
+  allowed_origins: [https://file.example.com]
+  routes:
+    - path: /api/exports/
+      allowed_origins: ["*"]
+      allow_credentials: false
+storage:
+  s3:
+    bucket: from-file
+`)
+	t.Setenv(FileEnv, path)
+	t.Setenv("DB_PATH", "env.db")
+	t.Setenv("JWT_EXPIRATION", "3h")
+	t.Setenv("S3_BUCKET", "from-env")
+	t.Setenv("SSO_GOOGLE_CLIENT_ID", "google-id")
+	t.Setenv("FEATURE_SSO", "false")
+	t.Setenv("TRACING_SAMPLE_RATIO", "0.25")
+	t.Setenv("RATE_LIMIT_AUTH_BURST", "3")
+
+	cfg, args, err = Load([]string{"-db.path", "flag.db", "-features.registration=false", "migrate", "up"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"migrate", "up"}, args)
+	assert.Equal(t, 9000, cfg.Server.Port)
+	assert.Equal(t, "sqlite", cfg.DB.Driver)
+	assert.Equal(t, 5, cfg.DB.MaxOpenConns)
+	assert.Equal(t, "flag.db", cfg.DB.Path)
+	assert.Equal(t, 3*time.Hour, cfg.JWT.Expiry)
+	assert.Equal(t, []string{"https://file.example.com"}, cfg.CORS.AllowedOrigins)
+	require.Len(t, cfg.CORS.Routes, 1)
+	assert.Equal(t, "/api/exports/", cfg.CORS.Routes[0].Path)
+	assert.False(t, *cfg.CORS.Routes[0].AllowCredentials)
+	assert.Equal(t, "from-env", cfg.Storage.S3.Bucket)
+	assert.Equal(t, "google-id", cfg.SSO.Google.ClientID)
+	assert.False(t, cfg.Features.SSO)
+	assert.False(t, cfg.Features.Registration)
+	assert.True(t, cfg.Features.DataExports)
+	assert.Equal(t, 0.25, cfg.Tracing.SampleRatio)
+	assert.Equal(t, 3, cfg.RateLimit.Auth.Burst)
+	assert.Equal(t, 10, cfg.RateLimit.Auth.RequestsPerMinute)
+
+	// Test case 3: The -config flag wins over CONFIG_FILE
+	other := writeFile(t, "server:\n  port: 9100\n")
+	cfg, _, err = Load([]string{"-config", other})
+	require.NoError(t, err)
+	assert.Equal(t, 9100, cfg.Server.Port)
+}
+
+func TestLoadErrors(t *testing.T) {
+	// Test case 1: Unknown keys in the file are rejected, to catch typos
+	_, _, err := Load([]string{"-config", writeFile(t, "db:\n  hots: example.com\n")})
+	assert.ErrorContains(t, err, "hots")
+
+	// Test case 2: A missing file
+	_, _, err = Load([]string{"-config", filepath.Join(t.TempDir(), "missing.yaml")})
+	assert.ErrorContains(t, err, "reading config file")
+
+	// Test case 3: Malformed values name where they came from
+	_, _, err = Load([]string{"-jwt.expiry", "soon"})
+	assert.EqualError(t, err, `-jwt.expiry: invalid duration "soon"`)
+
+	t.Setenv("DB_MAX_OPEN_CONNS", "many")
+	_, _, err = Load(nil)
+	assert.EqualError(t, err, `DB_MAX_OPEN_CONNS: invalid integer "many"`)
+
+	// Test case 4: Unknown flags
+	_, _, err = Load([]string{"-db.hots", "example.com"})
+	assert.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	// Test case 1: The defaults are valid, and so are wildcard subdomains
+	require.NoError(t, Default().Validate())
+	wildcard := Default()
+	wildcard.CORS.AllowedOrigins = []string{"https://*.example.com", "http://localhost:3000"}
+	require.NoError(t, wildcard.Validate())
+
+	tests := []struct {
+		name    string
+		change  func(c *Config)
+		problem string
+	}{
+		{"port out of range", func(c *Config) { c.Server.Port = 70000 }, "server.port: must be a port between 1 and 65535, got 70000"},
+		{"unknown mode", func(c *Config) { c.Server.Mode = "prod" }, `server.mode: must be one of debug, release, test, got "prod"`},
+		{"unknown log level", func(c *Config) { c.Log.Level = "trace" }, `log.level: must be one of debug, info, warn, error, got "trace"`},
+		{"sample ratio above 1", func(c *Config) { c.Tracing.SampleRatio = 1.5 }, "tracing.sample_ratio: must be between 0 and 1, got 1.5"},
+		{"unknown driver", func(c *Config) { c.DB.Driver = "oracle" }, `db.driver: must be one of postgres, sqlite, got "oracle"`},
+		{"sqlite without a path", func(c *Config) { c.DB.Driver = "sqlite"; c.DB.Path = "" }, "db.path: is required"},
+		{"unknown sslmode", func(c *Config) { c.DB.SSLMode = "on" }, "db.sslmode: must be one of"},
+		{"root cert without verification", func(c *Config) { c.DB.SSLRootCert = "ca.pem" }, "db.sslrootcert: is only used with sslmode verify-ca or verify-full"},
+		{"more idle than open", func(c *Config) { c.DB.MaxOpenConns = 5 }, "db.max_idle_conns: must not exceed db.max_open_conns (5), got 10"},
+		{"default secret in release", func(c *Config) { c.Server.Mode = "release" }, "jwt.secret: must be changed from the development default in release mode"},
+		{"no token lifetime", func(c *Config) { c.JWT.Expiry = 0 }, "jwt.expiry: must be a positive duration, got 0s"},
+		{"any origin with credentials", func(c *Config) { c.CORS.AllowedOrigins = []string{"*"} }, `cors.allowed_origins: "*" can't be combined with cors.allow_credentials`},
+		{"relative origin", func(c *Config) { c.CORS.AllowedOrigins = []string{"localhost:3000"} }, "cors.allowed_origins: must be an absolute URL"},
+		{"wildcard top-level domain", func(c *Config) { c.CORS.AllowedOrigins = []string{"https://*.com"} }, `cors.allowed_origins: must be an origin like https://*.example.com, got "https://*.com"`},
+		{"relative route path", func(c *Config) { c.CORS.Routes = []CORSRoute{{Path: "api/exports"}} }, `cors.routes[0].path: must start with /, got "api/exports"`},
+		{"route allowing any origin with credentials", func(c *Config) { c.CORS.Routes = []CORSRoute{{Path: "/api", AllowedOrigins: []string{"*"}}} }, `cors.routes[0].allowed_origins: "*" can't be combined`},
+		{"sso without secret", func(c *Config) { c.SSO.Google.ClientID = "id" }, "sso.google.client_secret: is required"},
+		{"mail without sender", func(c *Config) { c.Mail.Host = "smtp.example.com" }, "mail.from: is required"},
+		{"s3 without bucket", func(c *Config) { c.Storage.Driver = "s3" }, "storage.s3.bucket: is required"},
+		{"s3 without public url", func(c *Config) { c.Storage.Driver = "s3" }, "storage.public_url: is required"},
+		{"default export key in release", func(c *Config) { c.Server.Mode = "release" }, "exports.signing_key: must be changed from the development default in release mode"},
+		{"export key shared with tokens", func(c *Config) { c.Exports.SigningKey = c.JWT.Secret }, "exports.signing_key: must differ from jwt.secret"},
+		{"no export sweeps", func(c *Config) { c.Exports.SweepInterval = 0 }, "exports.sweep_interval: must be a positive duration, got 0s"},
+		{"redis without url", func(c *Config) { c.RateLimit.Store = "redis" }, "rate_limit.redis_url: is required"},
+		{"no submissions allowed", func(c *Config) { c.RateLimit.Submit.Burst = 0 }, "rate_limit.submit.burst: must be at least 1, got 0"},
+		{"proxy hostname", func(c *Config) { c.Server.TrustedProxies = []string{"lb.internal"} }, `server.trusted_proxies: must be IP addresses or CIDRs, got "lb.internal"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			tt.change(cfg)
+			assert.ErrorContains(t, cfg.Validate(), tt.problem)
+		})
+	}
+
+	// Test case 2: Every problem is reported, not just the first
+	cfg := Default()
+	cfg.Server.Port = 0
+	cfg.Storage.Driver = "ftp"
+	err := cfg.Validate()
+	assert.ErrorContains(t, err, "server.port")
+	assert.ErrorContains(t, err, "storage.driver")
+}
+
+func TestYAML(t *testing.T) {
+	cfg := Default()
+	cfg.DB.Password = "db-password"
+	cfg.Storage.S3.SecretKey = "s3-secret"
+
+	// Test case 1: Redacted output hides the secrets that are set
+	out, err := cfg.YAML(true)
+	require.NoError(t, err)
+	assert.NotContains(t, string(out), "db-password")
+	assert.NotContains(t, string(out), "s3-secret")
+	assert.NotContains(t, string(out), DefaultJWTSecret)
+	assert.Contains(t, string(out), "password: REDACTED")
+
+	// Test case 2: Unredacted output loads back as a config file
+	out, err = cfg.YAML(false)
+	require.NoError(t, err)
+	assert.Contains(t, string(out), "conn_max_lifetime: 1h0m0s")
+	loaded, _, err := Load([]string{"-config", writeFile(t, string(out))})
+	require.NoError(t, err)
+	again, err := loaded.YAML(false)
+	require.NoError(t, err)
+	assert.Equal(t, string(out), string(again))
+
+	// Sections come out in declaration order
+	var doc yaml.Node
+	require.NoError(t, yaml.Unmarshal(out, &doc))
+	assert.Equal(t, "server", doc.Content[0].Content[0].Value)
+	assert.Equal(t, "log", doc.Content[0].Content[2].Value)
+	assert.Equal(t, "db", doc.Content[0].Content[8].Value)
+}
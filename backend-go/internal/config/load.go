@@ -0,0 +1,256 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileEnv names the environment variable holding the config file path.
+// The -config flag takes precedence over it.
+const FileEnv = "CONFIG_FILE"
+
+// redacted replaces secrets in redacted output
+const redacted = "REDACTED"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setting is one leaf of the Config tree
+type setting struct {
+	path     string // Dotted YAML path, e.g. "db.max_open_conns"
+	env      string
+	secret   bool
+	fileOnly bool // Tagged env:"-": too structured for a variable or flag
+	value    reflect.Value
+	flag     *string // Value given on the command line, if any
+}
+
+// settings lists every leaf of cfg in declaration order
+func settings(cfg *Config) []*setting {
+	var all []*setting
+	var walk func(v reflect.Value, path, env string)
+	walk = func(v reflect.Value, path, env string) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name := strings.Split(f.Tag.Get("yaml"), ",")[0]
+			if path != "" {
+				name = path + "." + name
+			}
+			if f.Type.Kind() == reflect.Struct && f.Type != durationType {
+				walk(v.Field(i), name, env+f.Tag.Get("env"))
+				continue
+			}
+			if f.Tag.Get("env") == "-" {
+				all = append(all, &setting{path: name, fileOnly: true, value: v.Field(i)})
+				continue
+			}
+			all = append(all, &setting{
+				path:   name,
+				env:    env + f.Tag.Get("env"),
+				secret: f.Tag.Get("secret") == "true",
+				value:  v.Field(i),
+			})
+		}
+	}
+	walk(reflect.ValueOf(cfg).Elem(), "", "")
+	return all
+}
+
+// set parses raw into the setting according to its type
+func (s *setting) set(raw string) error {
+	switch {
+	case s.value.Type() == durationType:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", raw)
+		}
+		s.value.SetInt(int64(d))
+	case s.value.Kind() == reflect.String:
+		s.value.SetString(raw)
+	case s.value.Kind() == reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		s.value.SetInt(int64(n))
+	case s.value.Kind() == reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		s.value.SetFloat(f)
+	case s.value.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		s.value.SetBool(b)
+	case s.value.Kind() == reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		s.value.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported setting type %s", s.value.Type())
+	}
+	return nil
+}
+
+// String formats the current value the way set parses it
+func (s *setting) String() string {
+	switch {
+	case s.value.Type() == durationType:
+		return time.Duration(s.value.Int()).String()
+	case s.value.Kind() == reflect.Slice:
+		return strings.Join(s.value.Interface().([]string), ",")
+	default:
+		return fmt.Sprint(s.value.Interface())
+	}
+}
+
+// flagValue adapts a setting to flag.Value. Flags are recorded while parsing
+// and applied after the file and environment, so they win over both.
+type flagValue struct{ s *setting }
+
+func (f flagValue) String() string {
+	if f.s == nil {
+		return ""
+	}
+	return f.s.String()
+}
+
+func (f flagValue) Set(raw string) error {
+	f.s.flag = &raw
+	return nil
+}
+
+func (f flagValue) IsBoolFlag() bool {
+	return f.s != nil && f.s.value.Kind() == reflect.Bool
+}
+
+// Load builds the configuration from the defaults, the YAML config file, the
+// environment and the command line flags, each overriding the ones before.
+// Every setting has a flag named after its YAML path, e.g. -db.host; the file
+// is named by -config or CONFIG_FILE. Load returns the arguments left after
+// the flags. It doesn't validate the result; see Validate.
+func Load(args []string) (*Config, []string, error) {
+	cfg := Default()
+	all := settings(cfg)
+
+	flags := flag.NewFlagSet("api", flag.ContinueOnError)
+	file := flags.String("config", os.Getenv(FileEnv), "YAML config file (env "+FileEnv+")")
+	for _, s := range all {
+		if !s.fileOnly {
+			flags.Var(flagValue{s}, s.path, "env "+s.env)
+		}
+	}
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Configuration flags, which override the config file and environment:")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	if *file != "" {
+		if err := loadFile(cfg, *file); err != nil {
+			return nil, nil, err
+		}
+	}
+	for _, s := range all {
+		if s.fileOnly {
+			continue
+		}
+		if raw := os.Getenv(s.env); raw != "" {
+			if err := s.set(raw); err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", s.env, err)
+			}
+		}
+	}
+	for _, s := range all {
+		if s.flag != nil {
+			if err := s.set(*s.flag); err != nil {
+				return nil, nil, fmt.Errorf("-%s: %w", s.path, err)
+			}
+		}
+	}
+	return cfg, flags.Args(), nil
+}
+
+// loadFile overlays the settings in a YAML file onto cfg
+func loadFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// YAML renders the configuration in the config file format. With redact,
+// secrets that are set are replaced by "REDACTED".
+func (c *Config) YAML(redact bool) ([]byte, error) {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	for _, s := range settings(c) {
+		// Find or create the mapping of each section along the path
+		parent := root
+		keys := strings.Split(s.path, ".")
+		for _, key := range keys[:len(keys)-1] {
+			parent = childMapping(parent, key)
+		}
+
+		value := &yaml.Node{}
+		switch {
+		case redact && s.secret && !s.value.IsZero():
+			value.SetString(redacted)
+		case s.value.Type() == durationType:
+			value.SetString(s.String())
+		default:
+			if err := value.Encode(s.value.Interface()); err != nil {
+				return nil, err
+			}
+		}
+		key := &yaml.Node{}
+		key.SetString(keys[len(keys)-1])
+		parent.Content = append(parent.Content, key, value)
+	}
+
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(root); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), encoder.Close()
+}
+
+// childMapping returns the mapping stored under key in parent, adding it if needed
+func childMapping(parent *yaml.Node, key string) *yaml.Node {
+	for i := 0; i < len(parent.Content); i += 2 {
+		if parent.Content[i].Value == key {
+			return parent.Content[i+1]
+		}
+	}
+	keyNode := &yaml.Node{}
+	keyNode.SetString(key)
+	child := &yaml.Node{Kind: yaml.MappingNode}
+	parent.Content = append(parent.Content, keyNode, child)
+	return child
+}
@@ -0,0 +1,233 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// validator collects the problems found in a configuration
+type validator struct {
+	problems []error
+}
+
+func (v *validator) addf(path, format string, args ...interface{}) {
+	v.problems = append(v.problems, fmt.Errorf("%s: %s", path, fmt.Sprintf(format, args...)))
+}
+
+func (v *validator) oneOf(path, value string, allowed ...string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	v.addf(path, "must be one of %s, got %q", strings.Join(allowed, ", "), value)
+}
+
+func (v *validator) required(path, value string) {
+	if value == "" {
+		v.addf(path, "is required")
+	}
+}
+
+func (v *validator) port(path string, value int) {
+	if value < 1 || value > 65535 {
+		v.addf(path, "must be a port between 1 and 65535, got %d", value)
+	}
+}
+
+func (v *validator) positive(path string, value time.Duration) {
+	if value <= 0 {
+		v.addf(path, "must be a positive duration, got %s", value)
+	}
+}
+
+func (v *validator) nonNegative(path string, value int) {
+	if value < 0 {
+		v.addf(path, "must not be negative, got %d", value)
+	}
+}
+
+func (v *validator) rateLimit(path string, policy RateLimitPolicy) {
+	if policy.RequestsPerMinute < 1 {
+		v.addf(path+".requests_per_minute", "must be at least 1, got %d", policy.RequestsPerMinute)
+	}
+	if policy.Burst < 1 {
+		v.addf(path+".burst", "must be at least 1, got %d", policy.Burst)
+	}
+}
+
+// origins checks a CORS allow-list: absolute origins, optionally with a
+// "*." wildcard for the subdomains, or "*" alone without credentials
+func (v *validator) origins(path string, origins []string, credentials bool) {
+	for _, origin := range origins {
+		if origin == "*" {
+			if credentials {
+				v.addf(path, `"*" can't be combined with cors.allow_credentials`)
+			}
+			continue
+		}
+		if scheme, host, ok := strings.Cut(origin, "://*."); ok {
+			if strings.Contains(host, "*") || !strings.Contains(host, ".") {
+				v.addf(path, "must be an origin like https://*.example.com, got %q", origin)
+				continue
+			}
+			origin = scheme + "://" + host
+		}
+		v.url(path, origin)
+	}
+}
+
+func (v *validator) url(path, value string) {
+	if value == "" {
+		return
+	}
+	if u, err := url.Parse(value); err != nil || u.Scheme == "" || u.Host == "" {
+		v.addf(path, "must be an absolute URL, got %q", value)
+	}
+}
+
+// Validate checks the configuration for missing and inconsistent settings.
+// The returned error lists every problem, one per line, by setting path.
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	v.port("server.port", c.Server.Port)
+	v.oneOf("server.mode", c.Server.Mode, "debug", "release", "test")
+	v.positive("server.read_header_timeout", c.Server.ReadHeaderTimeout)
+	v.positive("server.read_timeout", c.Server.ReadTimeout)
+	v.positive("server.write_timeout", c.Server.WriteTimeout)
+	v.positive("server.idle_timeout", c.Server.IdleTimeout)
+	v.positive("server.shutdown_timeout", c.Server.ShutdownTimeout)
+	for _, proxy := range c.Server.TrustedProxies {
+		if _, err := netip.ParsePrefix(proxy); err != nil {
+			if _, err := netip.ParseAddr(proxy); err != nil {
+				v.addf("server.trusted_proxies", "must be IP addresses or CIDRs, got %q", proxy)
+			}
+		}
+	}
+
+	v.oneOf("log.level", c.Log.Level, "debug", "info", "warn", "error")
+	v.oneOf("log.format", c.Log.Format, "json", "text")
+	v.positive("log.slow_query", c.Log.SlowQuery)
+
+	if c.Metrics.Enabled && !strings.HasPrefix(c.Metrics.Path, "/") {
+		v.addf("metrics.path", "must start with /, got %q", c.Metrics.Path)
+	}
+
+	v.oneOf("tracing.exporter", c.Tracing.Exporter, "none", "otlp", "stdout")
+	if c.Tracing.Exporter == "otlp" {
+		v.required("tracing.endpoint", c.Tracing.Endpoint)
+		v.url("tracing.endpoint", c.Tracing.Endpoint)
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		v.addf("tracing.sample_ratio", "must be between 0 and 1, got %g", c.Tracing.SampleRatio)
+	}
+	v.required("tracing.service_name", c.Tracing.ServiceName)
+
+	v.oneOf("db.driver", c.DB.Driver, "postgres", "sqlite")
+	switch c.DB.Driver {
+	case "postgres":
+		v.required("db.host", c.DB.Host)
+		v.port("db.port", c.DB.Port)
+		v.required("db.user", c.DB.User)
+		v.required("db.name", c.DB.Name)
+		v.oneOf("db.sslmode", c.DB.SSLMode, "disable", "allow", "prefer", "require", "verify-ca", "verify-full")
+		if c.DB.SSLRootCert != "" && c.DB.SSLMode != "verify-ca" && c.DB.SSLMode != "verify-full" {
+			v.addf("db.sslrootcert", "is only used with sslmode verify-ca or verify-full")
+		}
+	case "sqlite":
+		v.required("db.path", c.DB.Path)
+	}
+	v.nonNegative("db.max_open_conns", c.DB.MaxOpenConns)
+	v.nonNegative("db.max_idle_conns", c.DB.MaxIdleConns)
+	if c.DB.MaxOpenConns > 0 && c.DB.MaxIdleConns > c.DB.MaxOpenConns {
+		v.addf("db.max_idle_conns", "must not exceed db.max_open_conns (%d), got %d", c.DB.MaxOpenConns, c.DB.MaxIdleConns)
+	}
+	if len(c.DB.ReplicaDSNs) > 0 {
+		v.positive("db.replica_check_interval", c.DB.ReplicaCheckInterval)
+	}
+
+	v.required("jwt.secret", c.JWT.Secret)
+	if c.Server.Mode == "release" && c.JWT.Secret == DefaultJWTSecret {
+		v.addf("jwt.secret", "must be changed from the development default in release mode")
+	}
+	v.positive("jwt.expiry", c.JWT.Expiry)
+	v.positive("jwt.refresh_expiry", c.JWT.RefreshExpiry)
+
+	v.origins("cors.allowed_origins", c.CORS.AllowedOrigins, c.CORS.AllowCredentials)
+	for i, route := range c.CORS.Routes {
+		path := fmt.Sprintf("cors.routes[%d]", i)
+		if !strings.HasPrefix(route.Path, "/") {
+			v.addf(path+".path", "must start with /, got %q", route.Path)
+		}
+		origins, credentials := c.CORS.AllowedOrigins, c.CORS.AllowCredentials
+		if route.AllowedOrigins != nil {
+			origins = route.AllowedOrigins
+		}
+		if route.AllowCredentials != nil {
+			credentials = *route.AllowCredentials
+		}
+		v.origins(path+".allowed_origins", origins, credentials)
+	}
+
+	for _, name := range []string{"google", "facebook", "instagram"} {
+		provider, _ := c.SSO.Provider(name)
+		if !provider.Configured() {
+			continue
+		}
+		v.required("sso."+name+".client_secret", provider.ClientSecret)
+		v.required("sso."+name+".redirect_url", provider.RedirectURL)
+		v.url("sso."+name+".redirect_url", provider.RedirectURL)
+	}
+
+	if c.Mail.Host != "" {
+		v.port("mail.port", c.Mail.Port)
+		v.required("mail.from", c.Mail.From)
+		if _, err := mail.ParseAddress(c.Mail.From); c.Mail.From != "" && err != nil {
+			v.addf("mail.from", "must be an email address, got %q", c.Mail.From)
+		}
+	}
+
+	v.oneOf("storage.driver", c.Storage.Driver, "local", "s3")
+	switch c.Storage.Driver {
+	case "local":
+		v.required("storage.local_dir", c.Storage.LocalDir)
+	case "s3":
+		v.required("storage.s3.bucket", c.Storage.S3.Bucket)
+		v.required("storage.s3.region", c.Storage.S3.Region)
+		v.url("storage.s3.endpoint", c.Storage.S3.Endpoint)
+		// Image URLs are stored with the users and achievements, so they can't be presigned ones that expire
+		v.required("storage.public_url", c.Storage.PublicURL)
+	}
+	v.url("storage.public_url", c.Storage.PublicURL)
+	v.positive("storage.url_expiry", c.Storage.URLExpiry)
+
+	if c.Features.DataExports {
+		v.required("exports.signing_key", c.Exports.SigningKey)
+		if c.Server.Mode == "release" && c.Exports.SigningKey == DefaultExportSigningKey {
+			v.addf("exports.signing_key", "must be changed from the development default in release mode")
+		}
+		if c.Exports.SigningKey != "" && c.Exports.SigningKey == c.JWT.Secret {
+			v.addf("exports.signing_key", "must differ from jwt.secret")
+		}
+		v.positive("exports.sweep_interval", c.Exports.SweepInterval)
+	}
+
+	if c.RateLimit.Enabled {
+		v.oneOf("rate_limit.store", c.RateLimit.Store, "memory", "redis")
+		if c.RateLimit.Store == "redis" {
+			v.required("rate_limit.redis_url", c.RateLimit.RedisURL)
+		}
+		v.rateLimit("rate_limit", c.RateLimit.Policy())
+		v.rateLimit("rate_limit.auth", c.RateLimit.Auth)
+		v.rateLimit("rate_limit.submit", c.RateLimit.Submit)
+		v.rateLimit("rate_limit.admin", c.RateLimit.Admin)
+	}
+
+	return errors.Join(v.problems...)
+}
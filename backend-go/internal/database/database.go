@@ -1,39 +1,104 @@
 package database
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
-	"log"
-	"os"
+	"log/slog"
 
+	"aicg/internal/config"
+
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
+	gormtracing "gorm.io/plugin/opentelemetry/tracing"
+)
+
+// Supported values of DB_DRIVER. They match the dialector names GORM reports.
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
 )
 
-var DB *gorm.DB
+// Drivers lists the supported database drivers
+var Drivers = []string{DriverPostgres, DriverSQLite}
+
+// ErrUnknownDriver is returned for a DB_DRIVER we don't support
+var ErrUnknownDriver = errors.New("unknown database driver")
+
+func isDriver(driver string) bool {
+	for _, d := range Drivers {
+		if d == driver {
+			return true
+		}
+	}
+	return false
+}
 
-func InitDB() (*gorm.DB, error) {
-	// Get database configuration from environment variables
-	dbHost := os.Getenv("DB_HOST")
-	dbUser := os.Getenv("DB_USER")
-	dbPassword := os.Getenv("DB_PASSWORD")
-	dbName := os.Getenv("DB_NAME")
-	dbPort := os.Getenv("DB_PORT")
-	if dbPort == "" {
-		dbPort = "5432"
+// DSN returns the connection string for the configured database
+func DSN(cfg *config.Config) string {
+	if cfg.DB.Driver == DriverSQLite {
+		return sqliteDSN(cfg.DB.Path)
 	}
+	dsn := fmt.Sprintf(
+		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s TimeZone=UTC",
+		cfg.DB.Host, cfg.DB.Port, cfg.DB.User, cfg.DB.Password, cfg.DB.Name, cfg.DB.SSLMode,
+	)
+	if cfg.DB.SSLRootCert != "" {
+		dsn += " sslrootcert=" + cfg.DB.SSLRootCert
+	}
+	return dsn
+}
 
-	// Create DSN string
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable TimeZone=UTC",
-		dbHost, dbUser, dbPassword, dbName, dbPort)
+// sqliteDSN adds the connection pragmas to a SQLite file path
+func sqliteDSN(path string) string {
+	// Enforce foreign keys like Postgres does, and wait for locks instead of failing
+	return path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+}
 
-	// Configure GORM logger
-	gormConfig := &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+// dialector returns the GORM dialector for the configured driver
+func dialector(cfg *config.Config) (gorm.Dialector, error) {
+	switch cfg.DB.Driver {
+	case DriverPostgres:
+		return postgres.Open(DSN(cfg)), nil
+	case DriverSQLite:
+		return sqlite.Open(DSN(cfg)), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownDriver, cfg.DB.Driver)
 	}
+}
 
+// configurePool applies the configured pool sizes and lifetimes. Zero keeps
+// the database/sql default, like it does for the lifetimes.
+func configurePool(sqlDB *sql.DB, cfg *config.Config) {
+	if cfg.DB.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(cfg.DB.MaxIdleConns)
+	}
+	sqlDB.SetMaxOpenConns(cfg.DB.MaxOpenConns)
+	sqlDB.SetConnMaxLifetime(cfg.DB.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.DB.ConnMaxIdleTime)
+	if cfg.DB.Driver == DriverSQLite {
+		// SQLite has a single writer; sharing one connection avoids "database is locked" errors
+		sqlDB.SetMaxOpenConns(1)
+	}
+}
+
+// InitDB opens the database connection and configures the pool
+func InitDB(cfg *config.Config) (*gorm.DB, error) {
 	// Open database connection
-	db, err := gorm.Open(postgres.Open(dsn), gormConfig)
+	dialect, err := dialector(cfg)
+	if err != nil {
+		return nil, err
+	}
+	db, err := gorm.Open(dialect, &gorm.Config{
+		Logger: NewLogger(slog.Default(), cfg.Log.SlowQuery),
+		// We ping below. Replicas inherit this config and may be down at startup,
+		// which the health checks handle instead of failing the boot.
+		DisableAutomaticPing: true,
+		// Report constraint violations as gorm.ErrDuplicatedKey and
+		// gorm.ErrForeignKeyViolated whatever the driver
+		TranslateError: true,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
@@ -48,11 +113,39 @@ func InitDB() (*gorm.DB, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	// Set connection pool settings
-	sqlDB.SetMaxIdleConns(10)
-	sqlDB.SetMaxOpenConns(100)
+	// Configure connection pool
+	configurePool(sqlDB, cfg)
+
+	// Trace every query, without its values, under the span of the request that ran it
+	if err := db.Use(gormtracing.NewPlugin(
+		gormtracing.WithDBSystem(cfg.DB.Driver),
+		gormtracing.WithoutQueryVariables(),
+		gormtracing.WithoutMetrics(),
+	)); err != nil {
+		sqlDB.Close()
+		return nil, fmt.Errorf("failed to enable query tracing: %w", err)
+	}
+
+	// Route reads of the read-heavy tables to the replicas
+	if len(cfg.DB.ReplicaDSNs) > 0 {
+		if err := useReplicas(db, sqlDB, cfg); err != nil {
+			sqlDB.Close()
+			return nil, fmt.Errorf("failed to configure read replicas: %w", err)
+		}
+	}
 
-	DB = db
-	log.Println("Database connection established successfully")
+	slog.Info("Database connection established", "driver", cfg.DB.Driver)
 	return db, nil
 }
+
+// Close stops the replica health checks and closes every connection pool
+func Close(db *gorm.DB) error {
+	if set := replicasOf(db); set != nil {
+		set.close()
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
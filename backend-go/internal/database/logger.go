@@ -0,0 +1,89 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// slogLogger sends GORM's logs to slog. Statements are logged at debug level,
+// slow ones as warnings and failed ones as errors, always with placeholders
+// instead of the bound values, which include password hashes and tokens.
+// The request ID comes along in the statement's context.
+type slogLogger struct {
+	log       *slog.Logger
+	level     logger.LogLevel
+	slowQuery time.Duration
+}
+
+// NewLogger creates a GORM logger writing to log
+func NewLogger(log *slog.Logger, slowQuery time.Duration) logger.Interface {
+	return &slogLogger{log: log, level: logger.Info, slowQuery: slowQuery}
+}
+
+// LogMode returns a copy logging at the given GORM level
+func (l *slogLogger) LogMode(level logger.LogLevel) logger.Interface {
+	clone := *l
+	clone.level = level
+	return &clone
+}
+
+func (l *slogLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Info {
+		l.log.InfoContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *slogLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Warn {
+		l.log.WarnContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *slogLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Error {
+		l.log.ErrorContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+// Trace logs a statement once it has run
+func (l *slogLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.level <= logger.Silent {
+		return
+	}
+	elapsed := time.Since(begin)
+
+	level, msg := slog.LevelDebug, "SQL statement"
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && l.level >= logger.Error:
+		level, msg = slog.LevelError, "SQL statement failed"
+	case l.slowQuery > 0 && elapsed > l.slowQuery && l.level >= logger.Warn:
+		level, msg = slog.LevelWarn, "Slow SQL statement"
+	case l.level < logger.Info:
+		return
+	}
+	if !l.log.Enabled(ctx, level) {
+		return
+	}
+
+	sql, rows := fc()
+	attrs := []slog.Attr{
+		slog.String("sql", sql),
+		slog.Int64("rows", rows),
+		slog.Duration("duration", elapsed),
+	}
+	if level == slog.LevelError {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	l.log.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// ParamsFilter drops the bound values, so statements are logged with placeholders
+func (l *slogLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	return sql, nil
+}
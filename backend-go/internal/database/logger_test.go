@@ -0,0 +1,55 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"aicg/internal/config"
+	"aicg/internal/logging"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/logger"
+)
+
+func TestLogger(t *testing.T) {
+	db := migratedSQLite(t, filepath.Join(t.TempDir(), "aicg.db"))
+	var buf bytes.Buffer
+	db.Logger = NewLogger(logging.New(&buf, config.LogConfig{Level: "debug", Format: "json"}), 0)
+	ctx := logging.WithRequestID(context.Background(), "req-42")
+
+	// Test case 1: Statements are logged at debug level, with the request ID and without their values
+	user := newUser("ada@example.com")
+	user.PasswordHash = "$2a$10$secret-hash"
+	user.RefreshToken = "secret-refresh-token"
+	require.NoError(t, db.WithContext(ctx).Create(user).Error)
+	out := buf.String()
+	assert.Contains(t, out, `"level":"DEBUG"`)
+	assert.Contains(t, out, "INSERT INTO")
+	assert.Contains(t, out, `"request_id":"req-42"`)
+	assert.NotContains(t, out, "secret-hash")
+	assert.NotContains(t, out, "secret-refresh-token")
+	assert.NotContains(t, out, "ada@example.com")
+
+	// Test case 2: Failed statements are logged as errors
+	buf.Reset()
+	assert.Error(t, db.WithContext(ctx).Exec("SELECT * FROM no_such_table").Error)
+	assert.Contains(t, buf.String(), `"level":"ERROR"`)
+	assert.Contains(t, buf.String(), "no such table")
+
+	// Test case 3: Records that aren't found are not errors
+	buf.Reset()
+	assert.Error(t, db.WithContext(ctx).First(newUser(""), 999).Error)
+	assert.NotContains(t, buf.String(), `"level":"ERROR"`)
+
+	// Test case 4: Statements are dropped below debug level, and silencing GORM drops everything
+	db.Logger = NewLogger(logging.New(&buf, config.LogConfig{Level: "info", Format: "json"}), 0)
+	buf.Reset()
+	require.NoError(t, db.Create(newUser("grace@example.com")).Error)
+	assert.Empty(t, buf.String())
+	db.Logger = db.Logger.LogMode(logger.Silent)
+	assert.Error(t, db.Exec("SELECT * FROM no_such_table").Error)
+	assert.Empty(t, buf.String())
+}
@@ -0,0 +1,379 @@
+package database
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed migrations
+var migrationFiles embed.FS
+
+const (
+	// MigrationsDir is where migration files live, relative to the backend
+	// root. Each supported driver has its own subdirectory with the same
+	// versions, since DDL isn't portable between them.
+	MigrationsDir = "internal/database/migrations"
+
+	// lockStaleAfter is how long a migration lock is honoured before another
+	// instance may take it over, in case its holder crashed
+	lockStaleAfter = 10 * time.Minute
+	// lockRetryInterval is how often we retry a lock held by another instance
+	lockRetryInterval = time.Second
+)
+
+var (
+	// ErrSchemaOutOfDate is returned when migrations are pending
+	ErrSchemaOutOfDate = errors.New("database schema is out of date")
+	// ErrMigrationLocked is returned when another instance holds the migration lock for too long
+	ErrMigrationLocked = errors.New("migrations are locked by another instance")
+	// ErrInvalidMigrationName is returned for migration names that can't be used in a file name
+	ErrInvalidMigrationName = errors.New("migration name may only contain letters, digits and underscores")
+)
+
+// migrationFileName matches files like 000001_create_users.up.sql
+var migrationFileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+var migrationName = regexp.MustCompile(`^\w+$`)
+
+// Migration is one versioned schema change
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus tells whether a migration has been applied
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time // nil while pending
+}
+
+// schemaMigration is a row of the schema_migrations table
+type schemaMigration struct {
+	Version   int64     `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"size:255;not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// schemaMigrationLock is the single row that serializes migrations across instances
+type schemaMigrationLock struct {
+	ID       int  `gorm:"primaryKey;autoIncrement:false"`
+	Locked   bool `gorm:"not null;default:false"`
+	LockedAt *time.Time
+	LockedBy string `gorm:"size:255"`
+}
+
+func (schemaMigrationLock) TableName() string {
+	return "schema_migrations_lock"
+}
+
+// LoadMigrations reads and pairs up the migration files in fsys
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected file in migrations: %s", entry.Name())
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %w", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, m.Name, match[2])
+		}
+
+		body, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		if match[3] == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if strings.TrimSpace(m.Up) == "" || strings.TrimSpace(m.Down) == "" {
+			return nil, fmt.Errorf("migration %06d_%s needs both an up and a down file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Migrations returns the migrations compiled into the binary for a driver
+func Migrations(driver string) ([]Migration, error) {
+	if !isDriver(driver) {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownDriver, driver)
+	}
+	sub, err := fs.Sub(migrationFiles, "migrations/"+driver)
+	if err != nil {
+		return nil, err
+	}
+	return LoadMigrations(sub)
+}
+
+// Migrator applies and rolls back migrations, recording them in schema_migrations
+type Migrator struct {
+	db          *gorm.DB
+	migrations  []Migration
+	owner       string        // Identifies this process in the lock table
+	LockTimeout time.Duration // How long to wait for another instance's lock
+}
+
+// NewMigrator creates a migrator for the migrations compiled into the
+// binary, picking the set written for the database's driver
+func NewMigrator(db *gorm.DB) (*Migrator, error) {
+	migrations, err := Migrations(db.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+	return NewMigratorWith(db, migrations), nil
+}
+
+// NewMigratorWith creates a migrator for the given migrations
+func NewMigratorWith(db *gorm.DB, migrations []Migration) *Migrator {
+	host, _ := os.Hostname()
+	return &Migrator{
+		db:          db,
+		migrations:  migrations,
+		owner:       fmt.Sprintf("%s:%d", host, os.Getpid()),
+		LockTimeout: time.Minute,
+	}
+}
+
+// Up applies all pending migrations in order and returns the ones it applied
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := m.withLock(ctx, func(db *gorm.DB) error {
+		done, err := m.appliedVersions(db)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+			if err := db.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(migration.Up).Error; err != nil {
+					return err
+				}
+				return tx.Create(&schemaMigration{
+					Version:   migration.Version,
+					Name:      migration.Name,
+					AppliedAt: time.Now().UTC(),
+				}).Error
+			}); err != nil {
+				return fmt.Errorf("migration %06d_%s: %w", migration.Version, migration.Name, err)
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down rolls back the given number of most recently applied migrations
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var rolledBack []Migration
+	err := m.withLock(ctx, func(db *gorm.DB) error {
+		done, err := m.appliedVersions(db)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && len(rolledBack) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := done[migration.Version]; !ok {
+				continue
+			}
+			if err := db.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(migration.Down).Error; err != nil {
+					return err
+				}
+				return tx.Delete(&schemaMigration{}, migration.Version).Error
+			}); err != nil {
+				return fmt.Errorf("migration %06d_%s: %w", migration.Version, migration.Name, err)
+			}
+			rolledBack = append(rolledBack, migration)
+		}
+		return nil
+	})
+	return rolledBack, err
+}
+
+// Status lists every known migration and when it was applied
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	db := m.db.WithContext(ctx)
+	if err := m.ensureTables(db); err != nil {
+		return nil, err
+	}
+	done, err := m.appliedVersions(db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if row, ok := done[migration.Version]; ok {
+			appliedAt := row.AppliedAt
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// CheckSchema returns ErrSchemaOutOfDate if any migration is still pending.
+// Readiness probes run it every few seconds, possibly as a read-only role, so
+// unlike Status it only reads: a database without schema_migrations is out
+// of date rather than given the bookkeeping tables.
+func (m *Migrator) CheckSchema(ctx context.Context) error {
+	db := m.db.WithContext(ctx)
+	if !db.Migrator().HasTable(&schemaMigration{}) {
+		return fmt.Errorf("%w: no migrations applied", ErrSchemaOutOfDate)
+	}
+	done, err := m.appliedVersions(db)
+	if err != nil {
+		return err
+	}
+
+	var pending []string
+	for _, migration := range m.migrations {
+		if _, ok := done[migration.Version]; !ok {
+			pending = append(pending, fmt.Sprintf("%06d_%s", migration.Version, migration.Name))
+		}
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: %d pending migration(s): %s", ErrSchemaOutOfDate, len(pending), strings.Join(pending, ", "))
+	}
+	return nil
+}
+
+// ensureTables creates the bookkeeping tables and the lock row if needed
+func (m *Migrator) ensureTables(db *gorm.DB) error {
+	if err := db.AutoMigrate(&schemaMigration{}, &schemaMigrationLock{}); err != nil {
+		return err
+	}
+	// Several instances may get here at once, so ignore the row if it exists
+	return db.Exec("INSERT INTO schema_migrations_lock (id, locked) VALUES (1, false) ON CONFLICT DO NOTHING").Error
+}
+
+// appliedVersions returns the applied migrations keyed by version
+func (m *Migrator) appliedVersions(db *gorm.DB) (map[int64]schemaMigration, error) {
+	var rows []schemaMigration
+	if err := db.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	done := make(map[int64]schemaMigration, len(rows))
+	for _, row := range rows {
+		done[row.Version] = row
+	}
+	return done, nil
+}
+
+// withLock runs fn while holding the migration lock, so concurrent
+// instances starting up together don't apply the same migration twice
+func (m *Migrator) withLock(ctx context.Context, fn func(db *gorm.DB) error) error {
+	db := m.db.WithContext(ctx)
+	if err := m.ensureTables(db); err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(m.LockTimeout)
+	for {
+		now := time.Now().UTC()
+		result := db.Model(&schemaMigrationLock{}).
+			Where("id = 1 AND (locked = ? OR locked_at < ?)", false, now.Add(-lockStaleAfter)).
+			Updates(map[string]interface{}{"locked": true, "locked_at": now, "locked_by": m.owner})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			return ErrMigrationLocked
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(lockRetryInterval):
+		}
+	}
+
+	defer func() {
+		// Use a fresh context so the lock is released even if ctx was cancelled
+		m.db.Model(&schemaMigrationLock{}).
+			Where("id = 1 AND locked_by = ?", m.owner).
+			Updates(map[string]interface{}{"locked": false, "locked_at": nil, "locked_by": ""})
+	}()
+
+	return fn(db)
+}
+
+// CreateMigration writes empty up and down files for a new migration in dir
+// and returns their paths
+func CreateMigration(dir, name string) (string, string, error) {
+	if !migrationName.MatchString(name) {
+		return "", "", ErrInvalidMigrationName
+	}
+
+	migrations, err := LoadMigrations(os.DirFS(dir))
+	if err != nil {
+		return "", "", err
+	}
+	var version int64 = 1
+	if len(migrations) > 0 {
+		version = migrations[len(migrations)-1].Version + 1
+	}
+
+	base := filepath.Join(dir, fmt.Sprintf("%06d_%s", version, strings.ToLower(name)))
+	up, down := base+".up.sql", base+".down.sql"
+	if err := os.WriteFile(up, []byte("-- Write the schema change here\n"), 0o644); err != nil {
+		return "", "", err
+	}
+	if err := os.WriteFile(down, []byte("-- Undo the schema change here\n"), 0o644); err != nil {
+		os.Remove(up)
+		return "", "", err
+	}
+	return up, down, nil
+}
@@ -0,0 +1,220 @@
+package database
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"aicg/internal/config"
+	"aicg/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+func TestMigrations(t *testing.T) {
+	postgres, err := Migrations(DriverPostgres)
+	require.NoError(t, err)
+	require.NotEmpty(t, postgres)
+
+	// Versions start at 1 and have no gaps
+	for i, m := range postgres {
+		assert.Equal(t, int64(i+1), m.Version, m.Name)
+	}
+
+	// Every driver has the same migrations
+	for _, driver := range Drivers {
+		migrations, err := Migrations(driver)
+		require.NoError(t, err, driver)
+		require.Len(t, migrations, len(postgres), driver)
+		for i, m := range migrations {
+			assert.Equal(t, postgres[i].Version, m.Version, driver)
+			assert.Equal(t, postgres[i].Name, m.Name, driver)
+		}
+	}
+
+	_, err = Migrations("oracle")
+	assert.ErrorIs(t, err, ErrUnknownDriver)
+}
+
+var (
+	createTable = regexp.MustCompile(`(?s)CREATE TABLE (\w+) \((.*?)\n\);`)
+	addColumn   = regexp.MustCompile(`ALTER TABLE (\w+) ADD COLUMN (\w+)`)
+)
+
+// TestMigrationsMatchModels makes sure every model has a table with exactly
+// the columns GORM expects, for every driver
+func TestMigrationsMatchModels(t *testing.T) {
+	for _, driver := range Drivers {
+		migrations, err := Migrations(driver)
+		require.NoError(t, err)
+
+		tables := make(map[string][]string)
+		for _, m := range migrations {
+			for _, match := range createTable.FindAllStringSubmatch(m.Up, -1) {
+				var columns []string
+				for _, line := range strings.Split(match[2], "\n") {
+					line = strings.TrimSpace(line)
+					if line == "" || strings.HasPrefix(line, "--") {
+						continue
+					}
+					columns = append(columns, strings.Trim(strings.Fields(line)[0], `"`))
+				}
+				tables[match[1]] = columns
+			}
+			for _, match := range addColumn.FindAllStringSubmatch(m.Up, -1) {
+				tables[match[1]] = append(tables[match[1]], match[2])
+			}
+		}
+
+		for _, model := range models.All() {
+			s, err := schema.Parse(model, &sync.Map{}, schema.NamingStrategy{})
+			require.NoError(t, err)
+
+			columns, ok := tables[s.Table]
+			if !assert.True(t, ok, "no %s migration creates table %s", driver, s.Table) {
+				continue
+			}
+			assert.ElementsMatch(t, s.DBNames, columns, "columns of %s in %s", s.Table, driver)
+		}
+	}
+}
+
+// TestMigrateSQLite applies and rolls back the SQLite migrations on a real database
+func TestMigrateSQLite(t *testing.T) {
+	db, err := InitDB(&config.Config{DB: config.DBConfig{Driver: DriverSQLite, Path: filepath.Join(t.TempDir(), "test.db")}})
+	require.NoError(t, err)
+	db.Logger = logger.Discard
+	migrator, err := NewMigrator(db)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	// Test case 1: A new database is out of date, and checking it writes nothing
+	assert.ErrorIs(t, migrator.CheckSchema(ctx), ErrSchemaOutOfDate)
+	assert.False(t, db.Migrator().HasTable(&schemaMigration{}))
+	assert.False(t, db.Migrator().HasTable(&schemaMigrationLock{}))
+
+	// Test case 2: Applying everything brings it up to date
+	applied, err := migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.Len(t, applied, len(migrator.migrations))
+	assert.NoError(t, migrator.CheckSchema(ctx))
+	for _, model := range models.All() {
+		assert.True(t, db.Migrator().HasTable(model), "%T", model)
+	}
+
+	// Test case 3: Running it again is a no-op
+	applied, err = migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+
+	// Test case 4: Everything can be rolled back, and the schema is out of date again
+	rolledBack, err := migrator.Down(ctx, len(migrator.migrations))
+	require.NoError(t, err)
+	assert.Len(t, rolledBack, len(migrator.migrations))
+	assert.ErrorIs(t, migrator.CheckSchema(ctx), ErrSchemaOutOfDate)
+	for _, model := range models.All() {
+		assert.False(t, db.Migrator().HasTable(model), "%T", model)
+	}
+}
+
+// TestMergeDuplicateProgress checks that adding the unique progress index
+// first merges the rows concurrent submissions duplicated
+func TestMergeDuplicateProgress(t *testing.T) {
+	db, err := InitDB(&config.Config{DB: config.DBConfig{Driver: DriverSQLite, Path: filepath.Join(t.TempDir(), "test.db")}})
+	require.NoError(t, err)
+	db.Logger = logger.Discard
+	migrations, err := Migrations(DriverSQLite)
+	require.NoError(t, err)
+	ctx := context.Background()
+	var unique int
+	for unique < len(migrations) && migrations[unique].Name != "add_user_progress_version" {
+		unique++
+	}
+	_, err = NewMigratorWith(db, migrations[:unique]).Up(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, db.Exec("PRAGMA foreign_keys = OFF").Error)
+	for _, score := range []float64{40, 80, 90} {
+		require.NoError(t, db.Exec("INSERT INTO results (user_id, quiz_id, score, total_questions, correct_answers, time_taken) VALUES (1, 1, ?, 1, 0, 10)", score).Error)
+	}
+	for range 2 {
+		require.NoError(t, db.Exec("INSERT INTO user_progress (user_id, quiz_id, category, total_attempts, average_score) VALUES (1, 1, 'math', 1, 40)").Error)
+	}
+	require.NoError(t, db.Exec("INSERT INTO user_progress (user_id, quiz_id, category, total_attempts, average_score) VALUES (2, 1, 'math', 4, 55)").Error)
+
+	_, err = NewMigratorWith(db, migrations).Up(ctx)
+	require.NoError(t, err)
+
+	var progress []models.UserProgress
+	require.NoError(t, db.Order("id").Find(&progress).Error)
+	require.Len(t, progress, 2)
+
+	// Test case 1: The duplicates are merged, counting every result
+	assert.Equal(t, 3, progress[0].TotalAttempts)
+	assert.Equal(t, 90.0, progress[0].BestScore)
+	assert.Equal(t, 70.0, progress[0].AverageScore)
+	assert.Equal(t, 30, progress[0].TotalTimeSpent)
+	assert.Equal(t, 3, progress[0].MasteryLevel)
+
+	// Test case 2: Progress without results is kept as it was
+	assert.Equal(t, 4, progress[1].TotalAttempts)
+	assert.Equal(t, 55.0, progress[1].AverageScore)
+
+	// Test case 3: A second progress on the quiz is refused
+	assert.Error(t, db.Exec("INSERT INTO user_progress (user_id, quiz_id, category) VALUES (1, 1, 'math')").Error)
+}
+
+func TestLoadMigrations(t *testing.T) {
+	// Test case 1: Files are paired up and sorted by version
+	migrations, err := LoadMigrations(fstest.MapFS{
+		"000002_add_b.up.sql":   {Data: []byte("CREATE TABLE b (id INT);")},
+		"000002_add_b.down.sql": {Data: []byte("DROP TABLE b;")},
+		"000001_add_a.up.sql":   {Data: []byte("CREATE TABLE a (id INT);")},
+		"000001_add_a.down.sql": {Data: []byte("DROP TABLE a;")},
+	})
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+	assert.Equal(t, Migration{Version: 1, Name: "add_a", Up: "CREATE TABLE a (id INT);", Down: "DROP TABLE a;"}, migrations[0])
+	assert.Equal(t, "add_b", migrations[1].Name)
+
+	// Test case 2: Missing down file
+	_, err = LoadMigrations(fstest.MapFS{
+		"000001_add_a.up.sql": {Data: []byte("CREATE TABLE a (id INT);")},
+	})
+	assert.Error(t, err)
+
+	// Test case 3: Unexpected file
+	_, err = LoadMigrations(fstest.MapFS{
+		"notes.txt": {Data: []byte("hello")},
+	})
+	assert.Error(t, err)
+}
+
+func TestCreateMigration(t *testing.T) {
+	dir := t.TempDir()
+
+	// Test case 1: First migration
+	up, down, err := CreateMigration(dir, "create_things")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "000001_create_things.up.sql"), up)
+	assert.Equal(t, filepath.Join(dir, "000001_create_things.down.sql"), down)
+
+	// Test case 2: Versions keep counting up
+	up, _, err = CreateMigration(dir, "Add_Index")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "000002_add_index.up.sql"), up)
+	_, err = os.Stat(up)
+	assert.NoError(t, err)
+
+	// Test case 3: Names end up in file names, so they are restricted
+	_, _, err = CreateMigration(dir, "../escape")
+	assert.ErrorIs(t, err, ErrInvalidMigrationName)
+}
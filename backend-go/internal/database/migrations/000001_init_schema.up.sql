@@ -1,104 +0,0 @@
--- Enable UUID extension
-CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
-
--- Create users table
-CREATE TABLE users (
-    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
-    email VARCHAR(255) UNIQUE NOT NULL,
-    password_hash VARCHAR(255),
-    first_name VARCHAR(100) NOT NULL,
-    last_name VARCHAR(100) NOT NULL,
-    role VARCHAR(20) NOT NULL DEFAULT 'maveric',
-    auth_provider VARCHAR(20) NOT NULL DEFAULT 'email',
-    provider_id VARCHAR(255),
-    last_login_at TIMESTAMP WITH TIME ZONE,
-    is_active BOOLEAN DEFAULT true,
-    profile_image VARCHAR(255),
-    refresh_token TEXT,
-    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
-);
-
--- Create quizzes table
-CREATE TABLE quizzes (
-    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
-    title VARCHAR(255) NOT NULL,
-    description TEXT NOT NULL,
-    category VARCHAR(50) NOT NULL,
-    difficulty VARCHAR(20) NOT NULL,
-    time_limit INTEGER NOT NULL,
-    created_by UUID REFERENCES users(id),
-    is_published BOOLEAN DEFAULT false,
-    passing_score DECIMAL(5,2) NOT NULL CHECK (passing_score >= 0 AND passing_score <= 100),
-    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
-);
-
--- Create questions table
-CREATE TABLE questions (
-    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
-    quiz_id UUID REFERENCES quizzes(id) ON DELETE CASCADE,
-    text TEXT NOT NULL,
-    question_type VARCHAR(20) NOT NULL,
-    correct_answer TEXT NOT NULL,
-    points INTEGER DEFAULT 1,
-    explanation TEXT,
-    time_to_answer INTEGER DEFAULT 30,
-    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
-);
-
--- Create answers table
-CREATE TABLE answers (
-    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
-    question_id UUID REFERENCES questions(id) ON DELETE CASCADE,
-    text TEXT NOT NULL,
-    is_correct BOOLEAN DEFAULT false,
-    display_order INTEGER NOT NULL,
-    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
-);
-
--- Create quiz_results table
-CREATE TABLE quiz_results (
-    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
-    quiz_id UUID REFERENCES quizzes(id),
-    user_id UUID REFERENCES users(id),
-    score DECIMAL(5,2) NOT NULL CHECK (score >= 0 AND score <= 100),
-    total_questions INTEGER NOT NULL,
-    correct_answers INTEGER NOT NULL,
-    time_taken INTEGER NOT NULL, -- in seconds
-    answers JSONB NOT NULL, -- Store user answers
-    feedback TEXT,
-    is_passed BOOLEAN NOT NULL,
-    passing_score DECIMAL(5,2) NOT NULL,
-    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
-);
-
--- Create user_progress table
-CREATE TABLE user_progress (
-    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
-    user_id UUID REFERENCES users(id),
-    quiz_id UUID REFERENCES quizzes(id),
-    category VARCHAR(50) NOT NULL,
-    total_attempts INTEGER DEFAULT 0,
-    best_score DECIMAL(5,2) DEFAULT 0,
-    average_score DECIMAL(5,2) DEFAULT 0,
-    total_time_spent INTEGER DEFAULT 0, -- in seconds
-    last_attempted_at TIMESTAMP WITH TIME ZONE,
-    mastery_level INTEGER DEFAULT 1 CHECK (mastery_level BETWEEN 1 AND 5),
-    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
-);
-
--- Create indexes for better query performance
-CREATE INDEX idx_users_email ON users(email);
-CREATE INDEX idx_users_provider ON users(auth_provider, provider_id);
-CREATE INDEX idx_quizzes_category ON quizzes(category);
-CREATE INDEX idx_quizzes_difficulty ON quizzes(difficulty);
-CREATE INDEX idx_questions_quiz ON questions(quiz_id);
-CREATE INDEX idx_answers_question ON answers(question_id);
-CREATE INDEX idx_results_user ON quiz_results(user_id);
-CREATE INDEX idx_results_quiz ON quiz_results(quiz_id);
-CREATE INDEX idx_progress_user ON user_progress(user_id);
-CREATE INDEX idx_progress_category ON user_progress(category); 
\ No newline at end of file
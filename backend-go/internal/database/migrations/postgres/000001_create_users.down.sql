@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS sso_configs;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,42 @@
+-- Create users table
+CREATE TABLE users (
+    id BIGSERIAL PRIMARY KEY,
+    email VARCHAR(255) NOT NULL,
+    password_hash VARCHAR(255), -- Only for email/password auth
+    first_name VARCHAR(100),
+    last_name VARCHAR(100),
+    role VARCHAR(20) NOT NULL DEFAULT 'maveric',
+    auth_provider VARCHAR(20) NOT NULL DEFAULT 'email',
+    provider_id VARCHAR(255), -- ID from SSO provider
+    last_login_at TIMESTAMP WITH TIME ZONE,
+    is_active BOOLEAN DEFAULT true,
+    profile_image VARCHAR(255),
+    locale VARCHAR(35),
+    time_zone VARCHAR(64),
+    refresh_token TEXT,
+    anonymized_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Create sso_configs table
+CREATE TABLE sso_configs (
+    id BIGSERIAL PRIMARY KEY,
+    provider VARCHAR(20),
+    client_id VARCHAR(255) NOT NULL,
+    client_secret VARCHAR(255) NOT NULL,
+    redirect_url VARCHAR(255) NOT NULL,
+    scopes VARCHAR(255), -- Comma-separated scopes
+    is_enabled BOOLEAN DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Create indexes
+CREATE UNIQUE INDEX idx_users_email ON users(email);
+CREATE INDEX idx_users_provider ON users(auth_provider, provider_id);
+CREATE INDEX idx_users_deleted_at ON users(deleted_at);
+CREATE UNIQUE INDEX idx_sso_configs_provider ON sso_configs(provider);
+CREATE INDEX idx_sso_configs_deleted_at ON sso_configs(deleted_at);
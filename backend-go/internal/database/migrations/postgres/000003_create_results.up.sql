@@ -0,0 +1,43 @@
+-- Create results table
+CREATE TABLE results (
+    id BIGSERIAL PRIMARY KEY,
+    quiz_id BIGINT NOT NULL REFERENCES quizzes(id),
+    user_id BIGINT NOT NULL REFERENCES users(id),
+    score DECIMAL(5,2) NOT NULL CHECK (score >= 0 AND score <= 100),
+    total_questions INTEGER NOT NULL,
+    correct_answers INTEGER NOT NULL,
+    time_taken INTEGER NOT NULL, -- in seconds
+    answers JSONB, -- Store user answers
+    feedback TEXT,
+    is_passed BOOLEAN DEFAULT false,
+    passing_score DECIMAL(5,2),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Create user_progress table
+CREATE TABLE user_progress (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id),
+    quiz_id BIGINT NOT NULL REFERENCES quizzes(id),
+    category VARCHAR(50) NOT NULL,
+    total_attempts INTEGER DEFAULT 0,
+    best_score DECIMAL(5,2) DEFAULT 0,
+    average_score DECIMAL(5,2) DEFAULT 0,
+    total_time_spent INTEGER DEFAULT 0, -- in seconds
+    last_attempted_at TIMESTAMP WITH TIME ZONE,
+    mastery_level INTEGER DEFAULT 1 CHECK (mastery_level BETWEEN 1 AND 5),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Create indexes
+CREATE INDEX idx_results_user_id ON results(user_id);
+CREATE INDEX idx_results_quiz_id ON results(quiz_id);
+CREATE INDEX idx_results_deleted_at ON results(deleted_at);
+CREATE INDEX idx_user_progress_user_id ON user_progress(user_id);
+CREATE INDEX idx_user_progress_quiz_id ON user_progress(quiz_id);
+CREATE INDEX idx_user_progress_category ON user_progress(category);
+CREATE INDEX idx_user_progress_deleted_at ON user_progress(deleted_at);
@@ -0,0 +1,57 @@
+-- Create global_rankings table to track overall user performance
+CREATE TABLE global_rankings (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id),
+    total_score DECIMAL(10,2) DEFAULT 0,
+    quizzes_completed INTEGER DEFAULT 0,
+    average_score DECIMAL(5,2) DEFAULT 0,
+    total_time_spent INTEGER DEFAULT 0, -- in seconds
+    rank INTEGER,
+    percentile DECIMAL(5,2),
+    ranking_period VARCHAR(20) NOT NULL, -- 'weekly', 'monthly', 'all_time'
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Create category_rankings table to track performance by category
+CREATE TABLE category_rankings (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id),
+    category VARCHAR(50) NOT NULL,
+    total_score DECIMAL(10,2) DEFAULT 0,
+    quizzes_completed INTEGER DEFAULT 0,
+    average_score DECIMAL(5,2) DEFAULT 0,
+    rank INTEGER,
+    percentile DECIMAL(5,2),
+    ranking_period VARCHAR(20) NOT NULL, -- 'weekly', 'monthly', 'all_time'
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Create benchmarks table for category/difficulty combinations
+CREATE TABLE benchmarks (
+    id BIGSERIAL PRIMARY KEY,
+    category VARCHAR(50) NOT NULL,
+    difficulty VARCHAR(20) NOT NULL,
+    average_score DECIMAL(5,2) DEFAULT 0,
+    median_score DECIMAL(5,2) DEFAULT 0,
+    percentile75 DECIMAL(5,2) DEFAULT 0,
+    percentile90 DECIMAL(5,2) DEFAULT 0,
+    total_attempts INTEGER DEFAULT 0,
+    average_completion_time INTEGER DEFAULT 0, -- in seconds
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Create indexes
+CREATE UNIQUE INDEX idx_user_period ON global_rankings(user_id, ranking_period);
+CREATE INDEX idx_global_rankings_period ON global_rankings(ranking_period, total_score DESC);
+CREATE INDEX idx_global_rankings_deleted_at ON global_rankings(deleted_at);
+CREATE UNIQUE INDEX idx_user_category_period ON category_rankings(user_id, category, ranking_period);
+CREATE INDEX idx_category_rankings_period ON category_rankings(ranking_period, category, total_score DESC);
+CREATE INDEX idx_category_rankings_deleted_at ON category_rankings(deleted_at);
+CREATE UNIQUE INDEX idx_category_difficulty ON benchmarks(category, difficulty);
+CREATE INDEX idx_benchmarks_deleted_at ON benchmarks(deleted_at);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_category_rankings_rank;
+DROP INDEX IF EXISTS idx_global_rankings_rank;
+DROP INDEX IF EXISTS idx_users_created_at;
+DROP INDEX IF EXISTS idx_results_user_created_at;
+DROP INDEX IF EXISTS idx_quizzes_created_at;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_questions_search;
+DROP INDEX IF EXISTS idx_quizzes_search;
@@ -0,0 +1,3 @@
+-- Index the text searched by GET /api/search; the expressions must match the ones in services/search.go
+CREATE INDEX idx_quizzes_search ON quizzes USING GIN (to_tsvector('english', title || ' ' || description));
+CREATE INDEX idx_questions_search ON questions USING GIN (to_tsvector('english', text));
@@ -0,0 +1,17 @@
+-- Create idempotency_keys table to replay the responses of retried requests
+CREATE TABLE idempotency_keys (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id),
+    idempotency_key VARCHAR(255) NOT NULL,
+    fingerprint VARCHAR(64) NOT NULL, -- SHA-256 of the request
+    status_code INTEGER NOT NULL DEFAULT 0, -- 0 while the request is running
+    content_type VARCHAR(255),
+    body BYTEA,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create indexes
+CREATE UNIQUE INDEX idx_idempotency_keys_user_key ON idempotency_keys(user_id, idempotency_key);
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
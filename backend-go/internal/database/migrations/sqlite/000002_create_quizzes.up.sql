@@ -0,0 +1,51 @@
+-- Create quizzes table
+CREATE TABLE quizzes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    title VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL,
+    category VARCHAR(50) NOT NULL,
+    difficulty VARCHAR(20) NOT NULL,
+    time_limit INTEGER NOT NULL, -- in minutes
+    created_by BIGINT NOT NULL REFERENCES users(id),
+    is_published BOOLEAN DEFAULT false,
+    passing_score REAL NOT NULL CHECK (passing_score >= 0 AND passing_score <= 100),
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    deleted_at DATETIME
+);
+
+-- Create questions table
+CREATE TABLE questions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    quiz_id BIGINT NOT NULL REFERENCES quizzes(id) ON DELETE CASCADE,
+    text TEXT NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    correct_answer TEXT NOT NULL,
+    points INTEGER DEFAULT 1 CHECK (points >= 1),
+    explanation TEXT,
+    time_to_answer INTEGER DEFAULT 30 CHECK (time_to_answer >= 0), -- in seconds
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    deleted_at DATETIME
+);
+
+-- Create answers table
+CREATE TABLE answers (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    question_id BIGINT NOT NULL REFERENCES questions(id) ON DELETE CASCADE,
+    text TEXT NOT NULL,
+    is_correct BOOLEAN DEFAULT false,
+    "order" INTEGER DEFAULT 0, -- Display order
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    deleted_at DATETIME
+);
+
+-- Create indexes
+CREATE INDEX idx_quizzes_category ON quizzes(category);
+CREATE INDEX idx_quizzes_difficulty ON quizzes(difficulty);
+CREATE INDEX idx_quizzes_deleted_at ON quizzes(deleted_at);
+CREATE INDEX idx_questions_quiz_id ON questions(quiz_id);
+CREATE INDEX idx_questions_deleted_at ON questions(deleted_at);
+CREATE INDEX idx_answers_question_id ON answers(question_id);
+CREATE INDEX idx_answers_deleted_at ON answers(deleted_at);
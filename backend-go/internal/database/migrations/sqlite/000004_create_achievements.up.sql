@@ -0,0 +1,29 @@
+-- Create achievements table
+CREATE TABLE achievements (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(100) NOT NULL,
+    description TEXT NOT NULL,
+    category VARCHAR(50),
+    criteria JSON NOT NULL, -- Store achievement criteria
+    icon_url VARCHAR(255),
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    deleted_at DATETIME
+);
+
+-- Create user_achievements table to track earned achievements
+CREATE TABLE user_achievements (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id BIGINT NOT NULL REFERENCES users(id),
+    achievement_id BIGINT NOT NULL REFERENCES achievements(id),
+    earned_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    progress JSON, -- Store progress towards achievement
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    deleted_at DATETIME
+);
+
+-- Create indexes
+CREATE INDEX idx_achievements_deleted_at ON achievements(deleted_at);
+CREATE UNIQUE INDEX idx_user_achievement ON user_achievements(user_id, achievement_id);
+CREATE INDEX idx_user_achievements_deleted_at ON user_achievements(deleted_at);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS benchmarks;
+DROP TABLE IF EXISTS category_rankings;
+DROP TABLE IF EXISTS global_rankings;
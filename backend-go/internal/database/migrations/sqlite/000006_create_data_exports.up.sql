@@ -0,0 +1,19 @@
+-- Create data_exports table to track GDPR data subject access requests
+CREATE TABLE data_exports (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id BIGINT NOT NULL REFERENCES users(id),
+    requested_by BIGINT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- 'pending', 'ready', 'failed', 'expired'
+    blob_key VARCHAR(255),
+    size BIGINT DEFAULT 0,
+    error TEXT,
+    completed_at DATETIME,
+    expires_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    deleted_at DATETIME
+);
+
+-- Create indexes
+CREATE INDEX idx_data_exports_user_id ON data_exports(user_id);
+CREATE INDEX idx_data_exports_deleted_at ON data_exports(deleted_at);
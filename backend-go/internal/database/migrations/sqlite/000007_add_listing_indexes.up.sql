@@ -0,0 +1,6 @@
+-- Support the cursor-paginated listings, which order by these columns and an ID
+CREATE INDEX idx_quizzes_created_at ON quizzes(created_at, id);
+CREATE INDEX idx_results_user_created_at ON results(user_id, created_at, id);
+CREATE INDEX idx_users_created_at ON users(created_at, id);
+CREATE INDEX idx_global_rankings_rank ON global_rankings(ranking_period, rank, user_id);
+CREATE INDEX idx_category_rankings_rank ON category_rankings(ranking_period, category, rank, user_id);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_user_progress_user_quiz;
+ALTER TABLE user_progress DROP COLUMN version;
@@ -0,0 +1,26 @@
+-- Concurrent submissions could create a second progress row for a quiz, or
+-- lose an attempt. Keep the first row of each user and quiz, and count its
+-- attempts again from the results.
+DELETE FROM user_progress
+WHERE id NOT IN (SELECT MIN(id) FROM user_progress GROUP BY user_id, quiz_id);
+
+UPDATE user_progress SET
+    total_attempts = (SELECT COUNT(*) FROM results r WHERE r.user_id = user_progress.user_id AND r.quiz_id = user_progress.quiz_id AND r.deleted_at IS NULL),
+    best_score = (SELECT MAX(r.score) FROM results r WHERE r.user_id = user_progress.user_id AND r.quiz_id = user_progress.quiz_id AND r.deleted_at IS NULL),
+    average_score = (SELECT AVG(r.score) FROM results r WHERE r.user_id = user_progress.user_id AND r.quiz_id = user_progress.quiz_id AND r.deleted_at IS NULL),
+    total_time_spent = (SELECT SUM(r.time_taken) FROM results r WHERE r.user_id = user_progress.user_id AND r.quiz_id = user_progress.quiz_id AND r.deleted_at IS NULL),
+    last_attempted_at = (SELECT MAX(r.created_at) FROM results r WHERE r.user_id = user_progress.user_id AND r.quiz_id = user_progress.quiz_id AND r.deleted_at IS NULL)
+WHERE EXISTS (SELECT 1 FROM results r WHERE r.user_id = user_progress.user_id AND r.quiz_id = user_progress.quiz_id AND r.deleted_at IS NULL);
+
+UPDATE user_progress SET mastery_level = CASE
+    WHEN average_score >= 90 THEN 5
+    WHEN average_score >= 80 THEN 4
+    WHEN average_score >= 70 THEN 3
+    WHEN average_score >= 60 THEN 2
+    ELSE 1
+END;
+
+-- Updates only apply to the version they read, and bump it (optimistic locking)
+ALTER TABLE user_progress ADD COLUMN version INTEGER NOT NULL DEFAULT 0;
+
+CREATE UNIQUE INDEX idx_user_progress_user_quiz ON user_progress(user_id, quiz_id);
@@ -0,0 +1,217 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"aicg/internal/config"
+	"aicg/internal/models"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+// replicaModels are the read-heavy tables whose reads go to the replicas.
+// Everything else, and all writes and transactions, stay on the primary.
+var replicaModels = []interface{}{
+	&models.Quiz{}, &models.Question{}, &models.Answer{},
+	&models.GlobalRanking{}, &models.CategoryRanking{}, &models.Benchmark{},
+}
+
+// replicaCheckTimeout bounds a single health check
+const replicaCheckTimeout = 2 * time.Second
+
+// replica is one read replica and whether it answered its last health check
+type replica struct {
+	name    string
+	db      *sql.DB
+	healthy atomic.Bool
+}
+
+// replicaSet is the dbresolver policy that spreads reads over the healthy
+// replicas and falls back to the primary when none are healthy. It is also
+// registered as a GORM plugin so Stats and Close can find it from the *gorm.DB.
+type replicaSet struct {
+	primary  *sql.DB
+	replicas []*replica
+	next     atomic.Uint64
+	stop     chan struct{}
+	done     sync.WaitGroup
+}
+
+// useReplicas opens the configured replicas and routes reads of replicaModels to them
+func useReplicas(db *gorm.DB, primary *sql.DB, cfg *config.Config) error {
+	driverName := map[string]string{DriverPostgres: "pgx", DriverSQLite: sqlite.DriverName}[cfg.DB.Driver]
+	set := &replicaSet{primary: primary, stop: make(chan struct{})}
+
+	// The primary is listed last, after the replicas, as the fallback. This
+	// also makes dbresolver consult the policy when there is a single replica.
+	var dialectors []gorm.Dialector
+	for i, dsn := range cfg.DB.ReplicaDSNs {
+		if cfg.DB.Driver == DriverSQLite {
+			dsn = sqliteDSN(dsn)
+		}
+		sqlDB, err := sql.Open(driverName, dsn)
+		if err != nil {
+			set.close()
+			return err
+		}
+		configurePool(sqlDB, cfg)
+		set.replicas = append(set.replicas, &replica{name: fmt.Sprintf("replica-%d", i+1), db: sqlDB})
+		dialectors = append(dialectors, connDialector(cfg.DB.Driver, sqlDB))
+	}
+	dialectors = append(dialectors, connDialector(cfg.DB.Driver, primary))
+
+	set.check(context.Background())
+	if err := db.Use(set); err != nil {
+		set.close()
+		return err
+	}
+	resolver := dbresolver.Register(dbresolver.Config{Replicas: dialectors, Policy: set}, replicaModels...)
+	if err := db.Use(resolver); err != nil {
+		set.close()
+		return err
+	}
+
+	if cfg.DB.ReplicaCheckInterval > 0 {
+		set.done.Add(1)
+		go set.run(cfg.DB.ReplicaCheckInterval)
+	}
+	return nil
+}
+
+// connDialector wraps an open connection pool in a dialector for the driver
+func connDialector(driver string, conn *sql.DB) gorm.Dialector {
+	if driver == DriverSQLite {
+		return &sqlite.Dialector{Conn: conn}
+	}
+	return postgres.New(postgres.Config{Conn: conn})
+}
+
+// replicasOf returns the replica set registered on db, or nil without replicas
+func replicasOf(db *gorm.DB) *replicaSet {
+	plugin, ok := db.Config.Plugins[(*replicaSet)(nil).Name()]
+	if !ok {
+		return nil
+	}
+	return plugin.(*replicaSet)
+}
+
+// Name implements gorm.Plugin
+func (s *replicaSet) Name() string {
+	return "aicg:replicas"
+}
+
+// Initialize implements gorm.Plugin
+func (s *replicaSet) Initialize(*gorm.DB) error {
+	return nil
+}
+
+// Resolve implements dbresolver.Policy. It ignores the pools dbresolver passes
+// in, which are the replicas' and the primary's own pools in the same order.
+func (s *replicaSet) Resolve([]gorm.ConnPool) gorm.ConnPool {
+	n := uint64(len(s.replicas))
+	start := s.next.Add(1)
+	for i := uint64(0); i < n; i++ {
+		if r := s.replicas[(start+i)%n]; r.healthy.Load() {
+			return r.db
+		}
+	}
+	return s.primary
+}
+
+// check pings every replica and records which ones answered
+func (s *replicaSet) check(ctx context.Context) {
+	for _, r := range s.replicas {
+		pingCtx, cancel := context.WithTimeout(ctx, replicaCheckTimeout)
+		err := r.db.PingContext(pingCtx)
+		cancel()
+
+		wasHealthy := r.healthy.Swap(err == nil)
+		switch {
+		case err != nil:
+			slog.WarnContext(ctx, "Database is unhealthy, its reads go elsewhere", "database", r.name, "error", err)
+		case !wasHealthy:
+			slog.InfoContext(ctx, "Database is healthy", "database", r.name)
+		}
+	}
+}
+
+// run checks the replicas every interval until the set is closed
+func (s *replicaSet) run(interval time.Duration) {
+	defer s.done.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.check(context.Background())
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// close stops the health checks and closes the replicas' pools
+func (s *replicaSet) close() {
+	close(s.stop)
+	s.done.Wait()
+	for _, r := range s.replicas {
+		r.db.Close()
+	}
+}
+
+// PoolStats describes one connection pool for monitoring
+type PoolStats struct {
+	Name               string `json:"name"` // "primary", or "replica-N" in DB_REPLICA_DSNS order
+	Healthy            bool   `json:"healthy"`
+	MaxOpenConnections int    `json:"max_open_connections"`
+	OpenConnections    int    `json:"open_connections"`
+	InUse              int    `json:"in_use"`
+	Idle               int    `json:"idle"`
+	WaitCount          int64  `json:"wait_count"`
+	WaitDurationMS     int64  `json:"wait_duration_ms"`
+	MaxIdleClosed      int64  `json:"max_idle_closed"`
+	MaxIdleTimeClosed  int64  `json:"max_idle_time_closed"`
+	MaxLifetimeClosed  int64  `json:"max_lifetime_closed"`
+}
+
+func newPoolStats(name string, healthy bool, stats sql.DBStats) PoolStats {
+	return PoolStats{
+		Name:               name,
+		Healthy:            healthy,
+		MaxOpenConnections: stats.MaxOpenConnections,
+		OpenConnections:    stats.OpenConnections,
+		InUse:              stats.InUse,
+		Idle:               stats.Idle,
+		WaitCount:          stats.WaitCount,
+		WaitDurationMS:     stats.WaitDuration.Milliseconds(),
+		MaxIdleClosed:      stats.MaxIdleClosed,
+		MaxIdleTimeClosed:  stats.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  stats.MaxLifetimeClosed,
+	}
+}
+
+// Stats returns the statistics of the primary's pool followed by each replica's
+func Stats(db *gorm.DB) ([]PoolStats, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), replicaCheckTimeout)
+	defer cancel()
+	stats := []PoolStats{newPoolStats("primary", sqlDB.PingContext(ctx) == nil, sqlDB.Stats())}
+	if set := replicasOf(db); set != nil {
+		for _, r := range set.replicas {
+			stats = append(stats, newPoolStats(r.name, r.healthy.Load(), r.db.Stats()))
+		}
+	}
+	return stats, nil
+}
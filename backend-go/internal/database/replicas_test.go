@@ -0,0 +1,113 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"aicg/internal/config"
+	"aicg/internal/models"
+	"aicg/internal/models/enums"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// migratedSQLite creates a migrated SQLite database at path
+func migratedSQLite(t *testing.T, path string) *gorm.DB {
+	db, err := InitDB(&config.Config{DB: config.DBConfig{Driver: DriverSQLite, Path: path}})
+	require.NoError(t, err)
+	db.Logger = logger.Discard
+	migrator, err := NewMigrator(db)
+	require.NoError(t, err)
+	_, err = migrator.Up(context.Background())
+	require.NoError(t, err)
+	t.Cleanup(func() { Close(db) })
+	return db
+}
+
+func newUser(email string) *models.User {
+	return &models.User{Email: email, Role: enums.RoleMaveric, AuthProvider: enums.ProviderEmail, IsActive: true}
+}
+
+func newQuiz(title string) *models.Quiz {
+	return &models.Quiz{
+		Title: title, Description: "Routing test", Category: enums.CategoryMath,
+		Difficulty: enums.DifficultyEasy, TimeLimit: 5, CreatedBy: 1, PassingScore: 60,
+	}
+}
+
+func TestReplicas(t *testing.T) {
+	dir := t.TempDir()
+	primaryPath, replicaPath := filepath.Join(dir, "primary.db"), filepath.Join(dir, "replica.db")
+	migratedSQLite(t, primaryPath)
+	replica := migratedSQLite(t, replicaPath)
+
+	// Only the replica has this quiz, so reads show which database served them
+	require.NoError(t, replica.Create(newUser("replica@example.com")).Error)
+	require.NoError(t, replica.Create(newQuiz("Replica")).Error)
+
+	db, err := InitDB(&config.Config{DB: config.DBConfig{
+		Driver: DriverSQLite, Path: primaryPath, ReplicaDSNs: []string{replicaPath}, MaxOpenConns: 5,
+	}})
+	require.NoError(t, err)
+	db.Logger = logger.Discard
+	defer Close(db)
+	set := replicasOf(db)
+	require.NotNil(t, set)
+
+	titles := func() []string {
+		var titles []string
+		require.NoError(t, db.Model(&models.Quiz{}).Order("id").Pluck("title", &titles).Error)
+		return titles
+	}
+
+	// Test case 1: Quiz reads go to the replica, writes to the primary
+	assert.Equal(t, []string{"Replica"}, titles())
+	require.NoError(t, db.Create(newUser("primary@example.com")).Error)
+	require.NoError(t, db.Create(newQuiz("Primary")).Error)
+	assert.Equal(t, []string{"Replica"}, titles())
+
+	// Test case 2: Other tables and transactions are served by the primary
+	var emails []string
+	require.NoError(t, db.Model(&models.User{}).Pluck("email", &emails).Error)
+	assert.Equal(t, []string{"primary@example.com"}, emails)
+	require.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		var titles []string
+		require.NoError(t, tx.Model(&models.Quiz{}).Pluck("title", &titles).Error)
+		assert.Equal(t, []string{"Primary"}, titles)
+		return nil
+	}))
+
+	// Test case 3: Pool stats cover both databases
+	stats, err := Stats(db)
+	require.NoError(t, err)
+	require.Len(t, stats, 2)
+	assert.Equal(t, "primary", stats[0].Name)
+	assert.Equal(t, "replica-1", stats[1].Name)
+	assert.True(t, stats[1].Healthy)
+	assert.Equal(t, 1, stats[1].MaxOpenConnections)
+
+	// Test case 4: Reads fall back to the primary while the replica is down
+	set.replicas[0].db.Close()
+	set.check(context.Background())
+	assert.Equal(t, []string{"Primary"}, titles())
+
+	stats, err = Stats(db)
+	require.NoError(t, err)
+	assert.True(t, stats[0].Healthy)
+	assert.False(t, stats[1].Healthy)
+}
+
+func TestDSN(t *testing.T) {
+	cfg := &config.Config{DB: config.DBConfig{
+		Driver: DriverPostgres, Host: "db", Port: 5432, User: "aicg", Password: "secret", Name: "aicg", SSLMode: "verify-full",
+	}}
+	assert.Equal(t, "host=db port=5432 user=aicg password=secret dbname=aicg sslmode=verify-full TimeZone=UTC", DSN(cfg))
+
+	// The CA certificate is only passed on when configured
+	cfg.DB.SSLRootCert = "/etc/ssl/ca.pem"
+	assert.Contains(t, DSN(cfg), " sslrootcert=/etc/ssl/ca.pem")
+}
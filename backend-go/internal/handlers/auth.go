@@ -4,60 +4,94 @@ import (
 	"net/http"
 
 	"aicg/internal/models"
+	"aicg/internal/models/enums"
 	"aicg/internal/services"
 
 	"github.com/gin-gonic/gin"
 )
 
+// errUnsupportedProvider is reported for SSO routes with a provider we don't support
+var errUnsupportedProvider = invalidValue("unsupported_provider", "unsupported SSO provider", "provider")
+
+// RegisterRequest is the body of a registration
+type RegisterRequest struct {
+	Email     string `json:"email" binding:"required,email"`
+	Password  string `json:"password" binding:"required,min=8"`
+	FirstName string `json:"first_name" binding:"required"`
+	LastName  string `json:"last_name" binding:"required"`
+}
+
+// RegisterResponse is the body of a successful registration
+type RegisterResponse struct {
+	Message string       `json:"message"`
+	User    *models.User `json:"user"`
+}
+
+// LoginRequest is the body of an email and password login
+type LoginRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
+
+// SSORequest is the body of an SSO callback, with the user the provider vouched for
+type SSORequest struct {
+	ProviderID string `json:"provider_id" binding:"required"`
+	Email      string `json:"email" binding:"required,email"`
+	FirstName  string `json:"first_name" binding:"required"`
+	LastName   string `json:"last_name" binding:"required"`
+}
+
+// RefreshRequest is the body of a token refresh
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// SSOConfigResponse is the public part of an SSO provider's configuration
+type SSOConfigResponse struct {
+	ClientID    string `json:"client_id"`
+	RedirectURL string `json:"redirect_url"`
+	Scopes      string `json:"scopes"` // Comma-separated
+}
+
 type AuthHandler struct {
-	authService *services.AuthService
+	authService services.IAuthService
 }
 
-func NewAuthHandler(authService *services.AuthService) *AuthHandler {
+func NewAuthHandler(authService services.IAuthService) *AuthHandler {
 	return &AuthHandler{authService: authService}
 }
 
 // Register handles user registration
 func (h *AuthHandler) Register(c *gin.Context) {
-	var req struct {
-		Email     string `json:"email" binding:"required,email"`
-		Password  string `json:"password" binding:"required,min=8"`
-		FirstName string `json:"first_name" binding:"required"`
-		LastName  string `json:"last_name" binding:"required"`
-	}
+	var req RegisterRequest
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
-	user, err := h.authService.Register(req.Email, req.Password, req.FirstName, req.LastName)
+	user, err := h.authService.Register(c.Request.Context(), req.Email, req.Password, req.FirstName, req.LastName)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		_ = c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"message": "User registered successfully",
-		"user":    user,
+	c.JSON(http.StatusCreated, RegisterResponse{
+		Message: "User registered successfully",
+		User:    user,
 	})
 }
 
 // Login handles user login
 func (h *AuthHandler) Login(c *gin.Context) {
-	var req struct {
-		Email    string `json:"email" binding:"required,email"`
-		Password string `json:"password" binding:"required"`
-	}
+	var req LoginRequest
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
-	tokens, err := h.authService.Login(req.Email, req.Password)
+	tokens, err := h.authService.Login(c.Request.Context(), req.Email, req.Password)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		_ = c.Error(err)
 		return
 	}
 
@@ -66,27 +100,21 @@ func (h *AuthHandler) Login(c *gin.Context) {
 
 // HandleSSO handles SSO authentication
 func (h *AuthHandler) HandleSSO(c *gin.Context) {
-	provider := models.AuthProvider(c.Param("provider"))
-	if provider != models.ProviderGoogle && provider != models.ProviderFacebook && provider != models.ProviderInstagram {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported SSO provider"})
+	provider := enums.AuthProvider(c.Param("provider"))
+	if !provider.IsSSO() {
+		_ = c.Error(errUnsupportedProvider)
 		return
 	}
 
-	var req struct {
-		ProviderID string `json:"provider_id" binding:"required"`
-		Email      string `json:"email" binding:"required,email"`
-		FirstName  string `json:"first_name" binding:"required"`
-		LastName   string `json:"last_name" binding:"required"`
-	}
+	var req SSORequest
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
-	tokens, err := h.authService.HandleSSO(provider, req.ProviderID, req.Email, req.FirstName, req.LastName)
+	tokens, err := h.authService.HandleSSO(c.Request.Context(), provider, req.ProviderID, req.Email, req.FirstName, req.LastName)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		_ = c.Error(err)
 		return
 	}
 
@@ -95,18 +123,15 @@ func (h *AuthHandler) HandleSSO(c *gin.Context) {
 
 // RefreshToken handles token refresh
 func (h *AuthHandler) RefreshToken(c *gin.Context) {
-	var req struct {
-		RefreshToken string `json:"refresh_token" binding:"required"`
-	}
+	var req RefreshRequest
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
-	tokens, err := h.authService.RefreshToken(req.RefreshToken)
+	tokens, err := h.authService.RefreshToken(c.Request.Context(), req.RefreshToken)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		_ = c.Error(err)
 		return
 	}
 
@@ -115,22 +140,22 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 
 // GetSSOConfig returns SSO configuration for a provider
 func (h *AuthHandler) GetSSOConfig(c *gin.Context) {
-	provider := models.AuthProvider(c.Param("provider"))
-	if provider != models.ProviderGoogle && provider != models.ProviderFacebook && provider != models.ProviderInstagram {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported SSO provider"})
+	provider := enums.AuthProvider(c.Param("provider"))
+	if !provider.IsSSO() {
+		_ = c.Error(errUnsupportedProvider)
 		return
 	}
 
-	config, err := h.authService.GetSSOConfig(provider)
+	config, err := h.authService.GetSSOConfig(c.Request.Context(), provider)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "SSO configuration not found"})
+		_ = c.Error(err)
 		return
 	}
 
 	// Return only necessary information for client
-	c.JSON(http.StatusOK, gin.H{
-		"client_id":    config.ClientID,
-		"redirect_url": config.RedirectURL,
-		"scopes":       config.Scopes,
+	c.JSON(http.StatusOK, SSOConfigResponse{
+		ClientID:    config.ClientID,
+		RedirectURL: config.RedirectURL,
+		Scopes:      config.Scopes,
 	})
 }
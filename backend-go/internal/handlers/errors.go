@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"aicg/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// Handlers report failures with c.Error and leave writing the response to
+// middleware.Errors, which turns domain errors into the documented error body.
+
+// errNotAuthenticated is reported when a protected handler runs without a logged in user
+var errNotAuthenticated = services.Unauthorized("unauthenticated", "user not authenticated")
+
+func init() {
+	// Name invalid fields the way clients send them
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(jsonFieldName)
+	}
+}
+
+// jsonFieldName returns the JSON name of a struct field, or "" to keep the Go name
+func jsonFieldName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// bindJSON parses the request body into obj, reporting a validation error if
+// it isn't JSON or breaks obj's binding rules
+func bindJSON(c *gin.Context, obj any) bool {
+	if err := c.ShouldBindJSON(obj); err != nil {
+		_ = c.Error(bindError(err))
+		return false
+	}
+	return true
+}
+
+// bindError describes why a request body couldn't be bound, field by field where possible
+func bindError(err error) *services.Error {
+	var validationErrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &validationErrs):
+		fields := make([]services.FieldError, len(validationErrs))
+		for i, fe := range validationErrs {
+			fields[i] = fieldError(fe)
+		}
+		return services.Invalid("validation_failed", "request body has invalid fields", fields...)
+	case errors.As(err, &typeErr):
+		return services.Invalid("validation_failed", "request body has invalid fields", services.FieldError{
+			Field: typeErr.Field, Code: "type", Message: "must be " + jsonType(typeErr.Type),
+		})
+	case errors.Is(err, io.EOF):
+		return services.Invalid("invalid_body", "request body is required")
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return services.Invalid("invalid_body", "request body is not valid JSON")
+	}
+	return services.Invalid("invalid_body", "request body could not be read")
+}
+
+// fieldError describes a failed binding rule
+func fieldError(fe validator.FieldError) services.FieldError {
+	// The namespace starts with the Go name of the bound struct
+	field := fe.Namespace()
+	if i := strings.IndexByte(field, '.'); i >= 0 {
+		field = field[i+1:]
+	}
+
+	message := "is invalid"
+	switch fe.Tag() {
+	case "required":
+		message = "is required"
+	case "email":
+		message = "must be a valid email address"
+	case "oneof":
+		message = "must be one of " + fe.Param()
+	case "min", "gte":
+		message = boundMessage("at least", fe)
+	case "max", "lte":
+		message = boundMessage("at most", fe)
+	}
+	return services.FieldError{Field: field, Code: fe.Tag(), Message: message}
+}
+
+// boundMessage describes a min or max rule for the field's kind
+func boundMessage(bound string, fe validator.FieldError) string {
+	switch fe.Kind() {
+	case reflect.String:
+		return fmt.Sprintf("must be %s %s characters long", bound, fe.Param())
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return fmt.Sprintf("must have %s %s items", bound, fe.Param())
+	}
+	return fmt.Sprintf("must be %s %s", bound, fe.Param())
+}
+
+// jsonType names the JSON type that decodes into t
+func jsonType(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	}
+	return "an object"
+}
+
+// pathID parses the ID in path parameter name, reporting it if it isn't one.
+// label names what it identifies in the error, e.g. "quiz".
+func pathID(c *gin.Context, name, label string) (uint, bool) {
+	return parseID(c, c.Param(name), name, label)
+}
+
+// queryID is pathID for query parameters
+func queryID(c *gin.Context, name, label string) (uint, bool) {
+	return parseID(c, c.Query(name), name, label)
+}
+
+func parseID(c *gin.Context, value, field, label string) (uint, bool) {
+	id, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		_ = c.Error(services.Invalid("invalid_id", "invalid "+label+" ID",
+			services.FieldError{Field: field, Code: "format", Message: "must be a whole number"}))
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// invalidValue returns an error for a parameter outside its set of values
+func invalidValue(code, message, field string) *services.Error {
+	return services.Invalid(code, message, services.FieldError{Field: field, Code: "oneof", Message: "is not a known value"})
+}
+
+// invalidQuery returns an error for a malformed query parameter
+func invalidQuery(name, message string) *services.Error {
+	return services.Invalid("invalid_query", "invalid "+name, services.FieldError{Field: name, Code: "format", Message: message})
+}
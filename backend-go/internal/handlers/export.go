@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"aicg/internal/models"
+	"aicg/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportResponse is an export, with a signed download URL once it's ready
+type ExportResponse struct {
+	Export               *models.DataExport `json:"export"`
+	DownloadURL          string             `json:"download_url,omitempty"`
+	DownloadURLExpiresAt *time.Time         `json:"download_url_expires_at,omitempty"`
+}
+
+// ExportHandler handles GDPR data export requests and downloads
+type ExportHandler struct {
+	exportService *services.ExportService
+}
+
+// NewExportHandler creates a new export handler with the given service
+func NewExportHandler(exportService *services.ExportService) *ExportHandler {
+	return &ExportHandler{exportService: exportService}
+}
+
+// RequestMyExport starts an export of the logged in user's data
+// POST /api/users/me/export
+func (h *ExportHandler) RequestMyExport(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		_ = c.Error(errNotAuthenticated)
+		return
+	}
+
+	h.requestExport(c, userID, userID)
+}
+
+// RequestUserExport starts an export of a user's data on their behalf
+// POST /api/admin/users/:id/export
+func (h *ExportHandler) RequestUserExport(c *gin.Context) {
+	adminID, ok := currentUserID(c)
+	if !ok {
+		_ = c.Error(errNotAuthenticated)
+		return
+	}
+
+	userID, ok := pathID(c, "id", "user")
+	if !ok {
+		return
+	}
+
+	h.requestExport(c, userID, adminID)
+}
+
+func (h *ExportHandler) requestExport(c *gin.Context, userID, requestedBy uint) {
+	export, err := h.exportService.RequestExport(c.Request.Context(), userID, requestedBy)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	// 202: the ZIP is assembled in the background, poll the status URL
+	c.JSON(http.StatusAccepted, h.exportResponse(c, export))
+}
+
+// GetMyExport returns the status of one of the logged in user's exports
+// GET /api/users/me/exports/:id
+func (h *ExportHandler) GetMyExport(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		_ = c.Error(errNotAuthenticated)
+		return
+	}
+
+	h.getExport(c, userID)
+}
+
+// GetExport returns the status of any export
+// GET /api/admin/exports/:id
+func (h *ExportHandler) GetExport(c *gin.Context) {
+	h.getExport(c, 0)
+}
+
+func (h *ExportHandler) getExport(c *gin.Context, ownerID uint) {
+	id, ok := pathID(c, "id", "export")
+	if !ok {
+		return
+	}
+
+	export, err := h.exportService.GetExport(c.Request.Context(), id, ownerID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, h.exportResponse(c, export))
+}
+
+// Download streams the export ZIP. The link is signed and expires, so it needs no login.
+// GET /api/exports/:id/download?expires=&signature=
+func (h *ExportHandler) Download(c *gin.Context) {
+	id, ok := pathID(c, "id", "export")
+	if !ok {
+		return
+	}
+
+	export, body, err := h.exportService.OpenDownload(c.Request.Context(), id, c.Query("expires"), c.Query("signature"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	defer body.Close()
+
+	filename := fmt.Sprintf("aicg-data-export-%d-%s.zip", export.UserID, export.CompletedAt.Format("20060102"))
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header("Cache-Control", "no-store")
+	if export.Size > 0 {
+		c.Header("Content-Length", strconv.FormatInt(export.Size, 10))
+	}
+	c.Status(http.StatusOK)
+	if _, err := io.Copy(c.Writer, body); err != nil {
+		slog.WarnContext(c.Request.Context(), "Data export download interrupted", "export_id", export.ID, "error", err)
+	}
+}
+
+// exportResponse adds a status URL and, once ready, a signed download URL to the export
+func (h *ExportHandler) exportResponse(c *gin.Context, export *models.DataExport) ExportResponse {
+	response := ExportResponse{Export: export}
+
+	if link, expires, err := h.exportService.DownloadLink(export); err == nil {
+		response.DownloadURL = fmt.Sprintf("/api/exports/%d/download?%s", export.ID, link)
+		response.DownloadURLExpiresAt = &expires
+	}
+	return response
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"net/http"
+
+	"aicg/internal/health"
+	"aicg/internal/version"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Liveness is the body of a liveness probe
+type Liveness struct {
+	Status  string       `json:"status"` // Always "ok"
+	Version version.Info `json:"version"`
+}
+
+// HealthHandler answers liveness and readiness probes
+type HealthHandler struct {
+	checker *health.Checker
+}
+
+// NewHealthHandler creates a new health handler with the given readiness checks
+func NewHealthHandler(checker *health.Checker) *HealthHandler {
+	return &HealthHandler{checker: checker}
+}
+
+// Livez reports that the process is up and serving, along with its version
+// GET /livez
+func (h *HealthHandler) Livez(c *gin.Context) {
+	c.JSON(http.StatusOK, Liveness{Status: "ok", Version: version.Get()})
+}
+
+// Readyz reports whether the server can take traffic: it isn't shutting down
+// and every dependency check passes
+// GET /readyz
+func (h *HealthHandler) Readyz(c *gin.Context) {
+	result := h.checker.Ready(c.Request.Context())
+	status := http.StatusOK
+	if !result.Ready {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, result)
+}
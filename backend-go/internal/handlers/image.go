@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"errors"
+	"mime/multipart"
+	"net/http"
+
+	"aicg/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// imageFormField is the multipart form field uploads are read from
+const imageFormField = "image"
+
+// Errors for uploads that never reach the service
+var (
+	errImageRequired = services.Invalid("image_required", `an image file is required in the "image" field`,
+		services.FieldError{Field: imageFormField, Code: "required", Message: "is required"})
+	errUnreadableUpload = services.Invalid("invalid_upload", "failed to read uploaded file")
+)
+
+// ImageHandler handles image uploads
+type ImageHandler struct {
+	imageService *services.ImageService
+}
+
+// NewImageHandler creates a new image handler with the given service
+func NewImageHandler(imageService *services.ImageService) *ImageHandler {
+	return &ImageHandler{imageService: imageService}
+}
+
+// UploadProfileImage replaces the logged in user's profile image
+// POST /api/users/me/profile-image (multipart/form-data, field "image")
+func (h *ImageHandler) UploadProfileImage(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		_ = c.Error(errNotAuthenticated)
+		return
+	}
+
+	file, ok := openUpload(c)
+	if !ok {
+		return
+	}
+	defer file.Close()
+
+	uploaded, err := h.imageService.UploadProfileImage(c.Request.Context(), userID, file)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, uploaded)
+}
+
+// UploadAchievementIcon replaces an achievement's icon
+// POST /api/admin/achievements/:id/icon (multipart/form-data, field "image")
+func (h *ImageHandler) UploadAchievementIcon(c *gin.Context) {
+	id, ok := pathID(c, "id", "achievement")
+	if !ok {
+		return
+	}
+
+	file, ok := openUpload(c)
+	if !ok {
+		return
+	}
+	defer file.Close()
+
+	uploaded, err := h.imageService.UploadAchievementIcon(c.Request.Context(), id, file)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, uploaded)
+}
+
+// openUpload returns the uploaded image file, rejecting oversized requests before they're parsed
+func openUpload(c *gin.Context) (multipart.File, bool) {
+	// Leave some room for the multipart envelope around the file itself
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, services.MaxImageUploadSize+64<<10)
+
+	header, err := c.FormFile(imageFormField)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			_ = c.Error(services.ErrImageTooLarge)
+			return nil, false
+		}
+		_ = c.Error(errImageRequired)
+		return nil, false
+	}
+
+	if header.Size > services.MaxImageUploadSize {
+		_ = c.Error(services.ErrImageTooLarge)
+		return nil, false
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		_ = c.Error(errUnreadableUpload)
+		return nil, false
+	}
+	return file, true
+}
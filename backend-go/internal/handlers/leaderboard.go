@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"net/http"
+
+	"aicg/internal/models/enums"
+	"aicg/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LeaderboardHandler serves the global and per-category leaderboards
+type LeaderboardHandler struct {
+	leaderboardService services.ILeaderboardService
+}
+
+// NewLeaderboardHandler creates a new leaderboard handler with the given service
+func NewLeaderboardHandler(leaderboardService services.ILeaderboardService) *LeaderboardHandler {
+	return &LeaderboardHandler{leaderboardService: leaderboardService}
+}
+
+// GetLeaderboard returns a page of the leaderboard for a period, global or
+// within a category, best ranked first
+// GET /api/leaderboard?period=&category=&limit=&cursor=
+func (h *LeaderboardHandler) GetLeaderboard(c *gin.Context) {
+	filter := services.LeaderboardFilter{Period: enums.RankingPeriod(c.Query("period"))}
+	if filter.Period != "" && !filter.Period.IsValid() {
+		_ = c.Error(invalidValue("invalid_period", "invalid ranking period", "period"))
+		return
+	}
+
+	var ok bool
+	if filter.Category, ok = categoryQuery(c); !ok {
+		return
+	}
+	page, ok := pageQuery(c, services.LeaderboardSorting)
+	if !ok {
+		return
+	}
+
+	leaderboard, err := h.leaderboardService.GetLeaderboard(c.Request.Context(), filter, page)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, leaderboard)
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"aicg/internal/models/enums"
+	"aicg/internal/repository"
+	"aicg/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Listings share their query parameters: limit, sort and cursor pick the page,
+// and the filters below narrow down what is listed. Each helper reports a
+// malformed parameter itself and returns false, so handlers just return.
+
+// dateLayout is the layout of date-only time parameters
+const dateLayout = "2006-01-02"
+
+// pageQuery parses the limit, sort and cursor parameters of a listing ordered by sorting
+func pageQuery[T any](c *gin.Context, sorting repository.Sorting[T]) (repository.Page, bool) {
+	page := repository.Page{Sort: sorting.Default}
+
+	var ok bool
+	if page.Limit, ok = limitQuery(c); !ok {
+		return page, false
+	}
+
+	if value := c.Query("sort"); value != "" {
+		if !sorting.Valid(value) {
+			_ = c.Error(services.Invalid("invalid_sort", "invalid sort", services.FieldError{
+				Field:   "sort",
+				Code:    "oneof",
+				Message: "must be one of " + strings.Join(sorting.Names(), ", ") + ", prefixed with - for descending order",
+			}))
+			return page, false
+		}
+		page.Sort = value
+	}
+
+	if value := c.Query("cursor"); value != "" {
+		after, err := sorting.DecodeCursor(value, page.Sort)
+		if err != nil {
+			_ = c.Error(services.Invalid("invalid_cursor", "invalid cursor", services.FieldError{
+				Field:   "cursor",
+				Code:    "format",
+				Message: "must be the next_cursor of a page with the same sort",
+			}))
+			return page, false
+		}
+		page.After = after
+	}
+	return page, true
+}
+
+// limitQuery parses the limit parameter, the number of records to return
+func limitQuery(c *gin.Context) (int, bool) {
+	value := c.Query("limit")
+	if value == "" {
+		return repository.DefaultLimit, true
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 || limit > repository.MaxLimit {
+		_ = c.Error(invalidQuery("limit", fmt.Sprintf("must be a whole number from 1 to %d", repository.MaxLimit)))
+		return 0, false
+	}
+	return limit, true
+}
+
+// boolQuery parses an optional true/false parameter, returning nil when it's missing
+func boolQuery(c *gin.Context, name string) (*bool, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, true
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		_ = c.Error(invalidQuery(name, "must be true or false"))
+		return nil, false
+	}
+	return &b, true
+}
+
+// timeRangeQuery parses the optional from and to parameters, each a date or an
+// RFC 3339 time, into a half-open range. A date as to includes that whole day.
+func timeRangeQuery(c *gin.Context) (from, to time.Time, ok bool) {
+	if from, ok = timeQuery(c, "from", false); !ok {
+		return
+	}
+	if to, ok = timeQuery(c, "to", true); !ok {
+		return
+	}
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		_ = c.Error(invalidQuery("to", "must be after from"))
+		return from, to, false
+	}
+	return from, to, true
+}
+
+// timeQuery parses an optional date or RFC 3339 time parameter. With endOfDay,
+// a date stands for the end of that day, i.e. the start of the next one.
+func timeQuery(c *gin.Context, name string, endOfDay bool) (time.Time, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return time.Time{}, true
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, true
+	}
+	t, err := time.Parse(dateLayout, value)
+	if err != nil {
+		_ = c.Error(invalidQuery(name, "must be a date (2006-01-02) or an RFC 3339 time"))
+		return time.Time{}, false
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, true
+}
+
+// categoryQuery parses an optional quiz category parameter
+func categoryQuery(c *gin.Context) (enums.QuizCategory, bool) {
+	category := enums.QuizCategory(c.Query("category"))
+	if category != "" && !category.IsValid() {
+		_ = c.Error(errInvalidCategory)
+		return "", false
+	}
+	return category, true
+}
+
+// difficultyQuery parses an optional quiz difficulty parameter
+func difficultyQuery(c *gin.Context) (enums.QuizDifficulty, bool) {
+	difficulty := enums.QuizDifficulty(c.Query("difficulty"))
+	if difficulty != "" && !difficulty.IsValid() {
+		_ = c.Error(errInvalidDifficulty)
+		return "", false
+	}
+	return difficulty, true
+}
+
+// optionalQueryID is queryID for parameters that may be left out, returning 0 then
+func optionalQueryID(c *gin.Context, name, label string) (uint, bool) {
+	if c.Query(name) == "" {
+		return 0, true
+	}
+	return queryID(c, name, label)
+}
@@ -1,62 +1,121 @@
 package handlers
 
 import (
-	"aicg/internal/models"
-	"encoding/json"
 	"net/http"
+
+	"aicg/internal/models"
+	"aicg/internal/models/enums"
+	"aicg/internal/services"
+
+	"github.com/gin-gonic/gin"
 )
 
-// GetQuestions handles GET requests to fetch all questions
-//
-// Parameters:
-//   - w: HTTP response writer
-//   - r: HTTP request
-//
-// Returns:
-//   - None
-func GetQuestions(w http.ResponseWriter, r *http.Request) {
-	// TODO: Implement database query
-	questions := []models.Question{
-		{
-			ID:       1,
-			Text:     "Sample question 1",
-			Category: "General",
-		},
-	}
-
-	response := models.QuestionResponse{
-		Success: true,
-		Data:    &questions[0],
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+// QuestionHandler manages question-related HTTP requests
+type QuestionHandler struct {
+	questionService services.IQuestionService
+}
+
+// NewQuestionHandler creates a new question handler with the given service
+func NewQuestionHandler(questionService services.IQuestionService) *QuestionHandler {
+	return &QuestionHandler{questionService: questionService}
+}
+
+// GetQuestions returns questions, optionally filtered by quiz and type
+// GET /api/admin/questions?quiz_id=&type=
+func (h *QuestionHandler) GetQuestions(c *gin.Context) {
+	var filter services.QuestionFilter
+
+	if quizID := c.Query("quiz_id"); quizID != "" {
+		id, ok := queryID(c, "quiz_id", "quiz")
+		if !ok {
+			return
+		}
+		filter.QuizID = id
+	}
+
+	if questionType := c.Query("type"); questionType != "" {
+		if !enums.QuestionType(questionType).IsValid() {
+			_ = c.Error(invalidValue("invalid_question_type", "invalid question type", "type"))
+			return
+		}
+		filter.Type = enums.QuestionType(questionType)
+	}
+
+	questions, err := h.questionService.GetQuestions(c.Request.Context(), filter)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, questions)
+}
+
+// GetQuestion returns a specific question by its ID
+// GET /api/admin/questions/:id
+func (h *QuestionHandler) GetQuestion(c *gin.Context) {
+	id, ok := pathID(c, "id", "question")
+	if !ok {
+		return
+	}
+
+	question, err := h.questionService.GetQuestionByID(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, question)
+}
+
+// CreateQuestion adds a new question to a quiz
+// POST /api/admin/questions
+func (h *QuestionHandler) CreateQuestion(c *gin.Context) {
+	var question models.Question
+	if !bindJSON(c, &question) {
+		return
+	}
+
+	if err := h.questionService.CreateQuestion(c.Request.Context(), &question); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, question)
 }
 
-// CreateQuestion handles POST requests to create a new question
-//
-// Parameters:
-//   - w: HTTP response writer
-//   - r: HTTP request
-//
-// Returns:
-//   - None
-func CreateQuestion(w http.ResponseWriter, r *http.Request) {
+// UpdateQuestion replaces a question and its answers
+// PUT /api/admin/questions/:id
+func (h *QuestionHandler) UpdateQuestion(c *gin.Context) {
+	id, ok := pathID(c, "id", "question")
+	if !ok {
+		return
+	}
+
 	var question models.Question
-	if err := json.NewDecoder(r.Body).Decode(&question); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if !bindJSON(c, &question) {
 		return
 	}
 
-	// TODO: Implement database insert
-	question.ID = 1
+	if err := h.questionService.UpdateQuestion(c.Request.Context(), id, &question); err != nil {
+		_ = c.Error(err)
+		return
+	}
 
-	response := models.QuestionResponse{
-		Success: true,
-		Message: "Question created successfully",
-		Data:    &question,
+	c.JSON(http.StatusOK, question)
+}
+
+// DeleteQuestion removes a question
+// DELETE /api/admin/questions/:id
+func (h *QuestionHandler) DeleteQuestion(c *gin.Context) {
+	id, ok := pathID(c, "id", "question")
+	if !ok {
+		return
+	}
+
+	if err := h.questionService.DeleteQuestion(c.Request.Context(), id); err != nil {
+		_ = c.Error(err)
+		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	c.Status(http.StatusNoContent)
 }
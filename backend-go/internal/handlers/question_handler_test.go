@@ -1,98 +1,209 @@
 package handlers
 
 import (
-	"aicg/internal/models"
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+
+	"aicg/internal/middleware"
+	"aicg/internal/models"
+	"aicg/internal/models/enums"
+	"aicg/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
-func TestGetQuestions(t *testing.T) {
-	req, err := http.NewRequest("GET", "/api/questions", nil)
-	if err != nil {
-		t.Fatal(err)
-	}
+// MockQuestionService is a mock implementation of IQuestionService
+type MockQuestionService struct {
+	mock.Mock
+}
 
-	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(GetQuestions)
+// Ensure MockQuestionService implements services.IQuestionService
+var _ services.IQuestionService = (*MockQuestionService)(nil)
 
-	handler.ServeHTTP(rr, req)
+func (m *MockQuestionService) GetQuestions(ctx context.Context, filter services.QuestionFilter) ([]models.Question, error) {
+	args := m.Called(filter)
+	return args.Get(0).([]models.Question), args.Error(1)
+}
 
-	if status := rr.Code; status != http.StatusOK {
-		t.Errorf(
-			"handler returned wrong status code: got %v want %v",
-			status,
-			http.StatusOK,
-		)
+func (m *MockQuestionService) GetQuestionByID(ctx context.Context, id uint) (*models.Question, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
 	}
+	return args.Get(0).(*models.Question), args.Error(1)
+}
 
-	var response models.QuestionResponse
-	if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
-		t.Fatal(err)
-	}
+func (m *MockQuestionService) CreateQuestion(ctx context.Context, question *models.Question) error {
+	args := m.Called(question)
+	return args.Error(0)
+}
 
-	if !response.Success {
-		t.Error("Expected success to be true")
-	}
+func (m *MockQuestionService) UpdateQuestion(ctx context.Context, id uint, question *models.Question) error {
+	args := m.Called(id, question)
+	return args.Error(0)
+}
 
-	if response.Data == nil {
-		t.Error("Expected data to be present")
-	}
+func (m *MockQuestionService) DeleteQuestion(ctx context.Context, id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
 }
 
-func TestCreateQuestion(t *testing.T) {
-	question := models.Question{
-		Text:     "Test question",
-		Category: "Test",
-	}
+func setupQuestionTest() (*gin.Engine, *MockQuestionService) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.Use(middleware.Errors())
+	mockService := new(MockQuestionService)
+	handler := NewQuestionHandler(mockService)
+
+	// Setup routes
+	r.GET("/questions", handler.GetQuestions)
+	r.GET("/questions/:id", handler.GetQuestion)
+	r.POST("/questions", handler.CreateQuestion)
+	r.PUT("/questions/:id", handler.UpdateQuestion)
+	r.DELETE("/questions/:id", handler.DeleteQuestion)
+
+	return r, mockService
+}
 
-	jsonData, err := json.Marshal(question)
-	if err != nil {
-		t.Fatal(err)
+func testQuestion() models.Question {
+	return models.Question{
+		QuizID:        1,
+		Text:          "What is 2 + 2?",
+		Type:          enums.QuestionTypeMultipleChoice,
+		CorrectAnswer: "4",
+		Points:        1,
+		Answers: []models.Answer{
+			{Text: "3"},
+			{Text: "4", IsCorrect: true},
+		},
 	}
+}
 
-	req, err := http.NewRequest(
-		"POST",
-		"/api/questions",
-		bytes.NewBuffer(jsonData),
-	)
-	if err != nil {
-		t.Fatal(err)
-	}
+func TestGetQuestions(t *testing.T) {
+	r, mockService := setupQuestionTest()
 
-	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(CreateQuestion)
+	// Test case 1: Filter by quiz
+	questions := []models.Question{testQuestion()}
+	mockService.On("GetQuestions", services.QuestionFilter{QuizID: 1}).Return(questions, nil)
 
-	handler.ServeHTTP(rr, req)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/questions?quiz_id=1", nil)
+	r.ServeHTTP(w, req)
 
-	if status := rr.Code; status != http.StatusOK {
-		t.Errorf(
-			"handler returned wrong status code: got %v want %v",
-			status,
-			http.StatusOK,
-		)
-	}
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response []models.Question
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Len(t, response, 1)
+	assert.Equal(t, "What is 2 + 2?", response[0].Text)
 
-	var response models.QuestionResponse
-	if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
-		t.Fatal(err)
-	}
+	// Test case 2: Invalid type
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/questions?type=riddle", nil)
+	r.ServeHTTP(w, req)
 
-	if !response.Success {
-		t.Error("Expected success to be true")
-	}
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
 
-	if response.Data == nil {
-		t.Error("Expected data to be present")
-	}
+func TestGetQuestion(t *testing.T) {
+	r, mockService := setupQuestionTest()
 
-	if response.Data.Text != question.Text {
-		t.Errorf(
-			"Expected text %v, got %v",
-			question.Text,
-			response.Data.Text,
-		)
-	}
+	// Test case 1: Successful retrieval
+	question := testQuestion()
+	mockService.On("GetQuestionByID", uint(1)).Return(&question, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/questions/1", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Test case 2: Not found
+	mockService.On("GetQuestionByID", uint(2)).Return(nil, services.ErrQuestionNotFound)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/questions/2", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestCreateQuestion(t *testing.T) {
+	r, mockService := setupQuestionTest()
+
+	// Test case 1: Successful creation
+	mockService.On("CreateQuestion", mock.AnythingOfType("*models.Question")).Return(nil).Once()
+
+	body, _ := json.Marshal(testQuestion())
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/questions", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// Test case 2: Quiz doesn't exist
+	mockService.On("CreateQuestion", mock.AnythingOfType("*models.Question")).Return(services.ErrUnknownQuiz).Once()
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/questions", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var response middleware.ErrorResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "unknown_quiz", response.Code)
+	assert.Equal(t, "Quiz not found", response.Error)
+
+	// Test case 3: Missing fields are named as they are in JSON
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/questions", bytes.NewBufferString(`{"text": "?"}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	response = middleware.ErrorResponse{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "validation_failed", response.Code)
+	assert.Contains(t, response.Details, services.FieldError{Field: "quiz_id", Code: "required", Message: "is required"})
+	assert.Contains(t, response.Details, services.FieldError{Field: "answers", Code: "required", Message: "is required"})
+
+	// Test case 4: Fields of the wrong type
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/questions", bytes.NewBufferString(`{"quiz_id": "one"}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	response = middleware.ErrorResponse{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, []services.FieldError{{Field: "quiz_id", Code: "type", Message: "must be a number"}}, response.Details)
+
+	// Test case 5: Malformed JSON
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/questions", bytes.NewBufferString(`{"text": `))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error": "Request body is not valid JSON", "code": "invalid_body"}`, w.Body.String())
+}
+
+func TestDeleteQuestion(t *testing.T) {
+	r, mockService := setupQuestionTest()
+
+	mockService.On("DeleteQuestion", uint(1)).Return(nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/questions/1", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
 }
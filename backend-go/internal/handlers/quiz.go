@@ -1,16 +1,27 @@
 package handlers
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
-	"strconv"
 
-	"aicg/internal/database"
 	"aicg/internal/models"
+	"aicg/internal/models/enums"
+	"aicg/internal/repository"
 	"aicg/internal/services"
+	"aicg/internal/tracing"
 
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Errors for quiz requests the service never sees
+var (
+	errInvalidCategory    = invalidValue("invalid_category", "invalid category", "category")
+	errInvalidDifficulty  = invalidValue("invalid_difficulty", "invalid difficulty", "difficulty")
+	errQuizHasNoQuestions = services.Invalid("quiz_has_no_questions", "quiz has no questions")
+	errDraftsForbidden    = services.Forbidden("drafts_forbidden", "only admins may list unpublished quizzes")
+	errResultsForbidden   = services.Forbidden("results_forbidden", "only admins may list other users' results")
 )
 
 // QuizHandler manages all quiz-related HTTP requests
@@ -24,33 +35,73 @@ func NewQuizHandler(quizService services.IQuizService) *QuizHandler {
 	return &QuizHandler{quizService: quizService}
 }
 
-// GetQuizzes returns a list of all available quizzes
-// GET /api/quizzes
+// GetQuizzes returns a page of quizzes, without their questions, optionally
+// filtered by category, difficulty, publication, author and creation time.
+// Only admins see unpublished quizzes; everyone else gets the published ones.
+// GET /api/quiz/?category=&difficulty=&published=&created_by=&from=&to=&sort=&limit=&cursor=
 func (h *QuizHandler) GetQuizzes(c *gin.Context) {
-	quizzes, err := h.quizService.GetQuizzes()
+	var filter repository.QuizFilter
+	var ok bool
+	if filter.Category, ok = categoryQuery(c); !ok {
+		return
+	}
+	if filter.Difficulty, ok = difficultyQuery(c); !ok {
+		return
+	}
+	if filter.Published, ok = boolQuery(c, "published"); !ok {
+		return
+	}
+	if !isSuperAdmin(c) {
+		if filter.Published != nil && !*filter.Published {
+			_ = c.Error(errDraftsForbidden)
+			return
+		}
+		published := true
+		filter.Published = &published
+	}
+	if filter.CreatedBy, ok = optionalQueryID(c, "created_by", "user"); !ok {
+		return
+	}
+	if filter.From, filter.To, ok = timeRangeQuery(c); !ok {
+		return
+	}
+	page, ok := pageQuery(c, repository.QuizSorting)
+	if !ok {
+		return
+	}
+
+	quizzes, err := h.quizService.ListQuizzes(c.Request.Context(), filter, page)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch quizzes"})
+		_ = c.Error(err)
 		return
 	}
 	c.JSON(http.StatusOK, quizzes)
 }
 
-// GetQuiz returns a specific quiz by its ID
+// GetQuiz returns a specific quiz by its ID. Only admins get the correct
+// answers, and unpublished quizzes; everyone else gets the questions as
+// they're shown when taking it.
 // GET /api/quiz/:id
 func (h *QuizHandler) GetQuiz(c *gin.Context) {
-	// Convert the ID from string to number
-	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid quiz ID"})
+	id, ok := pathID(c, "id", "quiz")
+	if !ok {
 		return
 	}
 
-	quiz, err := h.quizService.GetQuizByID(uint(id))
+	quiz, err := h.quizService.GetQuizByID(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Quiz not found"})
+		_ = c.Error(err)
 		return
 	}
 
+	if !isSuperAdmin(c) {
+		if !quiz.IsPublished {
+			_ = c.Error(services.ErrQuizNotFound)
+			return
+		}
+		c.JSON(http.StatusOK, quiz.ForPlayer())
+		return
+	}
 	c.JSON(http.StatusOK, quiz)
 }
 
@@ -59,13 +110,12 @@ func (h *QuizHandler) GetQuiz(c *gin.Context) {
 func (h *QuizHandler) CreateQuiz(c *gin.Context) {
 	var quiz models.Quiz
 	// Try to parse the request body into a Quiz object
-	if err := c.ShouldBindJSON(&quiz); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &quiz) {
 		return
 	}
 
-	if err := h.quizService.CreateQuiz(&quiz); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.quizService.CreateQuiz(c.Request.Context(), &quiz); err != nil {
+		_ = c.Error(err)
 		return
 	}
 
@@ -77,14 +127,14 @@ func (h *QuizHandler) CreateQuiz(c *gin.Context) {
 func (h *QuizHandler) GetQuizzesByCategory(c *gin.Context) {
 	category := c.Param("category")
 	// Make sure the category is valid
-	if !models.IsValidQuizCategory(category) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category"})
+	if !enums.QuizCategory(category).IsValid() {
+		_ = c.Error(errInvalidCategory)
 		return
 	}
 
-	quizzes, err := h.quizService.GetQuizzesByCategory(models.QuizCategory(category))
+	quizzes, err := h.quizService.GetQuizzesByCategory(c.Request.Context(), enums.QuizCategory(category))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(err)
 		return
 	}
 
@@ -96,61 +146,74 @@ func (h *QuizHandler) GetQuizzesByCategory(c *gin.Context) {
 func (h *QuizHandler) GetQuizzesByDifficulty(c *gin.Context) {
 	difficulty := c.Param("difficulty")
 	// Make sure the difficulty level is valid
-	if !models.IsValidQuizDifficulty(difficulty) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid difficulty"})
+	if !enums.QuizDifficulty(difficulty).IsValid() {
+		_ = c.Error(errInvalidDifficulty)
 		return
 	}
 
-	quizzes, err := h.quizService.GetQuizzesByDifficulty(models.QuizDifficulty(difficulty))
+	quizzes, err := h.quizService.GetQuizzesByDifficulty(c.Request.Context(), enums.QuizDifficulty(difficulty))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusOK, quizzes)
 }
 
+// SubmitQuizRequest is the body of a quiz submission
+type SubmitQuizRequest struct {
+	Answers   []SubmittedAnswer `json:"answers" binding:"required,min=1"`
+	TimeTaken int               `json:"time_taken" binding:"required,min=0"` // Seconds
+}
+
+// SubmittedAnswer is the answer given to one question of a quiz
+type SubmittedAnswer struct {
+	QuestionID uint   `json:"question_id" binding:"required"`
+	Answer     string `json:"answer" binding:"required"`
+}
+
+// SubmitQuizResponse is the grade of a quiz submission
+type SubmitQuizResponse struct {
+	Score          float64 `json:"score"` // Percentage of the questions answered right
+	TotalQuestions int     `json:"total_questions"`
+	CorrectAnswers int     `json:"correct_answers"`
+	TimeTaken      int     `json:"time_taken"`
+	IsPassed       bool    `json:"is_passed"`
+	PassingScore   float64 `json:"passing_score"`
+}
+
 // SubmitQuiz handles a user submitting their quiz answers
 // POST /api/quiz/:id/submit
 func (h *QuizHandler) SubmitQuiz(c *gin.Context) {
 	// Get user ID from the authentication context
-	userID, exists := c.Get("userID")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+	userID, ok := currentUserID(c)
+	if !ok {
+		_ = c.Error(errNotAuthenticated)
 		return
 	}
 
 	// Get quiz ID from URL
-	id := c.Param("id")
-	quizID, err := strconv.ParseUint(id, 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid quiz ID format"})
+	quizID, ok := pathID(c, "id", "quiz")
+	if !ok {
 		return
 	}
 
-	// Structure for the submitted answers
-	var submission struct {
-		Answers []struct {
-			QuestionID uint   `json:"question_id" binding:"required"`
-			Answer     string `json:"answer" binding:"required"`
-		} `json:"answers" binding:"required,min=1"`
-		TimeTaken int `json:"time_taken" binding:"required,min=0"`
-	}
+	var submission SubmitQuizRequest
 
 	// Parse the submission data
-	if err := c.ShouldBindJSON(&submission); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid submission format: " + err.Error()})
+	if !bindJSON(c, &submission) {
 		return
 	}
 
 	// Get the quiz with its questions
-	var quiz models.Quiz
-	if err := database.DB.Preload("Questions").First(&quiz, quizID).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Quiz not found"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch quiz"})
-		}
+	quiz, err := h.quizService.GetQuizByID(c.Request.Context(), quizID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	if len(quiz.Questions) == 0 {
+		_ = c.Error(errQuizHasNoQuestions)
 		return
 	}
 
@@ -159,14 +222,20 @@ func (h *QuizHandler) SubmitQuiz(c *gin.Context) {
 	for _, q := range quiz.Questions {
 		questionIDs[q.ID] = true
 	}
-	for _, a := range submission.Answers {
+	for i, a := range submission.Answers {
 		if !questionIDs[a.QuestionID] {
-			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid question ID: %d", a.QuestionID)})
+			_ = c.Error(services.Invalid("unknown_question", fmt.Sprintf("invalid question ID: %d", a.QuestionID), services.FieldError{
+				Field:   fmt.Sprintf("answers[%d].question_id", i),
+				Code:    "exists",
+				Message: "is not a question of this quiz",
+			}))
 			return
 		}
 	}
 
 	// Calculate the score
+	_, gradeSpan := tracing.Start(c.Request.Context(), "QuizHandler.grade",
+		attribute.Int("quiz.questions", len(quiz.Questions)), attribute.Int("quiz.answers", len(submission.Answers)))
 	correctAnswers := 0
 	for _, q := range quiz.Questions {
 		for _, a := range submission.Answers {
@@ -175,121 +244,174 @@ func (h *QuizHandler) SubmitQuiz(c *gin.Context) {
 			}
 		}
 	}
+	gradeSpan.End()
 
 	// Convert to percentage score
 	score := float64(correctAnswers) / float64(len(quiz.Questions)) * 100
 
-	// Save the result
+	// Keep the submitted answers with the result
+	answers, err := json.Marshal(submission.Answers)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	// Save the result and update the user's progress
 	result := models.Result{
-		QuizID:         uint(quizID),
-		UserID:         userID.(uint),
+		QuizID:         quizID,
+		UserID:         userID,
 		Score:          score,
 		TotalQuestions: len(quiz.Questions),
+		CorrectAnswers: correctAnswers,
 		TimeTaken:      submission.TimeTaken,
+		Answers:        answers,
 		IsPassed:       score >= quiz.PassingScore,
 		PassingScore:   quiz.PassingScore,
 	}
 
-	if err := database.DB.Create(&result).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save quiz result"})
+	if err := h.quizService.SubmitQuizResult(c.Request.Context(), &result); err != nil {
+		_ = c.Error(err)
 		return
 	}
 
 	// Return the results to the user
-	c.JSON(http.StatusOK, gin.H{
-		"score":           score,
-		"total_questions": len(quiz.Questions),
-		"correct_answers": correctAnswers,
-		"time_taken":      submission.TimeTaken,
-		"is_passed":       result.IsPassed,
-		"passing_score":   quiz.PassingScore,
+	c.JSON(http.StatusOK, SubmitQuizResponse{
+		Score:          score,
+		TotalQuestions: len(quiz.Questions),
+		CorrectAnswers: correctAnswers,
+		TimeTaken:      submission.TimeTaken,
+		IsPassed:       result.IsPassed,
+		PassingScore:   quiz.PassingScore,
 	})
 }
 
 // SubmitQuizResult handles POST request to submit quiz result
 func (h *QuizHandler) SubmitQuizResult(c *gin.Context) {
 	var result models.Result
-	if err := c.ShouldBindJSON(&result); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &result) {
 		return
 	}
 
-	if err := h.quizService.SubmitQuizResult(&result); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.quizService.SubmitQuizResult(c.Request.Context(), &result); err != nil {
+		_ = c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusCreated, result)
 }
 
-// GetUserProgress handles GET request to fetch user's quiz progress
-func (h *QuizHandler) GetUserProgress(c *gin.Context) {
-	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+// GetMyProgress returns the logged in user's progress overall and in each category
+// GET /api/users/me/progress
+func (h *QuizHandler) GetMyProgress(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		_ = c.Error(errNotAuthenticated)
 		return
 	}
 
-	progress, err := h.quizService.GetUserProgress(uint(userID))
+	summary, err := h.quizService.GetProgressSummary(c.Request.Context(), userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, progress)
+	c.JSON(http.StatusOK, summary)
 }
 
-// GetUserProgressByCategory handles GET request to fetch user's progress by category
-func (h *QuizHandler) GetUserProgressByCategory(c *gin.Context) {
-	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+// GetMyProgressByCategory sums up the logged in user's progress in one category
+// GET /api/users/me/progress/:category
+func (h *QuizHandler) GetMyProgressByCategory(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		_ = c.Error(errNotAuthenticated)
 		return
 	}
 
-	category := c.Param("category")
-	if !models.IsValidQuizCategory(category) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category"})
+	category := enums.QuizCategory(c.Param("category"))
+	if !category.IsValid() {
+		_ = c.Error(errInvalidCategory)
 		return
 	}
 
-	progress, err := h.quizService.GetUserProgressByCategory(uint(userID), models.QuizCategory(category))
+	progress, err := h.quizService.GetUserProgressByCategory(c.Request.Context(), userID, category)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusOK, progress)
 }
 
-// GetResults handles GET request to fetch user's quiz results
+// GetResults handles GET request to fetch a page of quiz results, without the
+// submitted answers. They are the logged in user's; admins may pick another user with user_id.
+// GET /api/results/?user_id=&quiz_id=&category=&difficulty=&passed=&from=&to=&sort=&limit=&cursor=
 func (h *QuizHandler) GetResults(c *gin.Context) {
-	userID, err := strconv.ParseUint(c.Query("user_id"), 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+	var filter repository.ResultFilter
+	var ok bool
+	if filter.UserID, ok = optionalQueryID(c, "user_id", "user"); !ok {
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		_ = c.Error(errNotAuthenticated)
+		return
+	}
+	switch {
+	case filter.UserID == 0:
+		filter.UserID = userID
+	case filter.UserID != userID && !isSuperAdmin(c):
+		_ = c.Error(errResultsForbidden)
+		return
+	}
+	if filter.QuizID, ok = optionalQueryID(c, "quiz_id", "quiz"); !ok {
+		return
+	}
+	if filter.Category, ok = categoryQuery(c); !ok {
+		return
+	}
+	if filter.Difficulty, ok = difficultyQuery(c); !ok {
+		return
+	}
+	if filter.Passed, ok = boolQuery(c, "passed"); !ok {
+		return
+	}
+	if filter.From, filter.To, ok = timeRangeQuery(c); !ok {
+		return
+	}
+	page, ok := pageQuery(c, repository.ResultSorting)
+	if !ok {
 		return
 	}
 
-	results, err := h.quizService.GetUserResults(uint(userID))
+	results, err := h.quizService.ListResults(c.Request.Context(), filter, page)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusOK, results)
 }
 
-// GetResult handles GET request to fetch a specific quiz result
+// GetResult handles GET request to fetch a specific quiz result. Users only
+// find their own; admins find anyone's.
 func (h *QuizHandler) GetResult(c *gin.Context) {
-	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid result ID"})
+	id, ok := pathID(c, "id", "result")
+	if !ok {
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		_ = c.Error(errNotAuthenticated)
 		return
 	}
 
-	result, err := h.quizService.GetResultByID(uint(id))
+	result, err := h.quizService.GetResultByID(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Result not found"})
+		_ = c.Error(err)
+		return
+	}
+	if result.UserID != userID && !isSuperAdmin(c) {
+		_ = c.Error(services.ErrResultNotFound)
 		return
 	}
 
@@ -2,12 +2,19 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
+	"time"
 
+	"aicg/internal/middleware"
 	"aicg/internal/models"
+	"aicg/internal/models/enums"
+	"aicg/internal/repository"
 	"aicg/internal/services"
 
 	"github.com/gin-gonic/gin"
@@ -23,12 +30,12 @@ type MockQuizService struct {
 // Ensure MockQuizService implements services.IQuizService
 var _ services.IQuizService = (*MockQuizService)(nil)
 
-func (m *MockQuizService) GetQuizzes() ([]models.Quiz, error) {
-	args := m.Called()
-	return args.Get(0).([]models.Quiz), args.Error(1)
+func (m *MockQuizService) ListQuizzes(ctx context.Context, filter repository.QuizFilter, page repository.Page) (repository.List[models.QuizSummary], error) {
+	args := m.Called(filter, page)
+	return args.Get(0).(repository.List[models.QuizSummary]), args.Error(1)
 }
 
-func (m *MockQuizService) GetQuizByID(id uint) (*models.Quiz, error) {
+func (m *MockQuizService) GetQuizByID(ctx context.Context, id uint) (*models.Quiz, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -36,32 +43,32 @@ func (m *MockQuizService) GetQuizByID(id uint) (*models.Quiz, error) {
 	return args.Get(0).(*models.Quiz), args.Error(1)
 }
 
-func (m *MockQuizService) CreateQuiz(quiz *models.Quiz) error {
+func (m *MockQuizService) CreateQuiz(ctx context.Context, quiz *models.Quiz) error {
 	args := m.Called(quiz)
 	return args.Error(0)
 }
 
-func (m *MockQuizService) GetQuizzesByCategory(category models.QuizCategory) ([]models.Quiz, error) {
+func (m *MockQuizService) GetQuizzesByCategory(ctx context.Context, category enums.QuizCategory) ([]models.Quiz, error) {
 	args := m.Called(category)
 	return args.Get(0).([]models.Quiz), args.Error(1)
 }
 
-func (m *MockQuizService) GetQuizzesByDifficulty(difficulty models.QuizDifficulty) ([]models.Quiz, error) {
+func (m *MockQuizService) GetQuizzesByDifficulty(ctx context.Context, difficulty enums.QuizDifficulty) ([]models.Quiz, error) {
 	args := m.Called(difficulty)
 	return args.Get(0).([]models.Quiz), args.Error(1)
 }
 
-func (m *MockQuizService) SubmitQuizResult(result *models.Result) error {
+func (m *MockQuizService) SubmitQuizResult(ctx context.Context, result *models.Result) error {
 	args := m.Called(result)
 	return args.Error(0)
 }
 
-func (m *MockQuizService) GetUserResults(userID uint) ([]models.Result, error) {
-	args := m.Called(userID)
-	return args.Get(0).([]models.Result), args.Error(1)
+func (m *MockQuizService) ListResults(ctx context.Context, filter repository.ResultFilter, page repository.Page) (repository.List[models.ResultSummary], error) {
+	args := m.Called(filter, page)
+	return args.Get(0).(repository.List[models.ResultSummary]), args.Error(1)
 }
 
-func (m *MockQuizService) GetResultByID(id uint) (*models.Result, error) {
+func (m *MockQuizService) GetResultByID(ctx context.Context, id uint) (*models.Result, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -69,22 +76,36 @@ func (m *MockQuizService) GetResultByID(id uint) (*models.Result, error) {
 	return args.Get(0).(*models.Result), args.Error(1)
 }
 
-func (m *MockQuizService) GetUserProgress(userID uint) ([]models.UserProgress, error) {
-	args := m.Called(userID)
-	return args.Get(0).([]models.UserProgress), args.Error(1)
+func (m *MockQuizService) GetUserProgressByCategory(ctx context.Context, userID uint, category enums.QuizCategory) (*models.CategoryProgress, error) {
+	args := m.Called(userID, category)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.CategoryProgress), args.Error(1)
 }
 
-func (m *MockQuizService) GetUserProgressByCategory(userID uint, category models.QuizCategory) (*models.UserProgress, error) {
-	args := m.Called(userID, category)
+func (m *MockQuizService) GetProgressSummary(ctx context.Context, userID uint) (*models.ProgressSummary, error) {
+	args := m.Called(userID)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
-	return args.Get(0).(*models.UserProgress), args.Error(1)
+	return args.Get(0).(*models.ProgressSummary), args.Error(1)
 }
 
+// setupTest serves the quiz handlers, with the logged in user and role taken
+// from the X-User-ID and X-User-Role headers in place of a token
 func setupTest() (*gin.Engine, *MockQuizService) {
 	gin.SetMode(gin.TestMode)
 	r := gin.Default()
+	r.Use(middleware.Errors())
+	r.Use(func(c *gin.Context) {
+		if id, err := strconv.Atoi(c.GetHeader("X-User-ID")); err == nil {
+			c.Set("userID", uint(id))
+		}
+		if role := c.GetHeader("X-User-Role"); role != "" {
+			c.Set("userRole", enums.UserRole(role))
+		}
+	})
 	mockService := new(MockQuizService)
 	handler := NewQuizHandler(mockService)
 
@@ -104,26 +125,96 @@ func setupTest() (*gin.Engine, *MockQuizService) {
 func TestGetQuizzes(t *testing.T) {
 	r, mockService := setupTest()
 
-	// Test case 1: Successful retrieval
-	quizzes := []models.Quiz{{Title: "Test Quiz"}}
-	mockService.On("GetQuizzes").Return(quizzes, nil)
+	// Test case 1: Successful retrieval of the published quizzes, first page by default
+	published := true
+	quizzes := repository.List[models.QuizSummary]{Items: []models.QuizSummary{{Title: "Test Quiz", QuestionCount: 3}}, NextCursor: "next"}
+	mockService.On("ListQuizzes", repository.QuizFilter{Published: &published}, repository.Page{Limit: 20, Sort: "-created_at"}).Return(quizzes, nil).Once()
 
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", "/quizzes", nil)
 	r.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusOK, w.Code)
-	var response []models.Quiz
+	var response repository.List[models.QuizSummary]
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err)
 	assert.Equal(t, quizzes, response)
+
+	// Test case 2: Filters and sorting
+	filter := repository.QuizFilter{
+		Category: enums.CategoryMath, Difficulty: enums.DifficultyHard, Published: &published, CreatedBy: 4,
+		From: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), To: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+	}
+	mockService.On("ListQuizzes", filter, repository.Page{Limit: 5, Sort: "title"}).Return(quizzes, nil).Once()
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/quizzes?category=math&difficulty=hard&published=true&created_by=4&from=2026-01-01&to=2026-01-31&sort=title&limit=5", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Test case 3: Invalid parameters are reported by name
+	for query, field := range map[string]string{
+		"limit=0":                       "limit",
+		"limit=101":                     "limit",
+		"sort=difficulty":               "sort",
+		"cursor=garbage":                "cursor",
+		"published=maybe":               "published",
+		"from=yesterday":                "from",
+		"from=2026-02-01&to=2026-01-01": "to",
+		"category=cooking":              "category",
+	} {
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", "/quizzes?"+query, nil)
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+		var errResponse middleware.ErrorResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &errResponse))
+		if assert.Len(t, errResponse.Details, 1, query) {
+			assert.Equal(t, field, errResponse.Details[0].Field, query)
+		}
+	}
+
+	// Test case 4: A cursor continues where its page ended
+	list := repository.QuizSorting.Slice([]models.QuizSummary{{ID: 1, Title: "A"}, {ID: 2, Title: "B"}}, repository.Page{Limit: 1, Sort: "title"})
+	after, err := repository.QuizSorting.DecodeCursor(list.NextCursor, "title")
+	assert.NoError(t, err)
+	mockService.On("ListQuizzes", repository.QuizFilter{Published: &published}, repository.Page{Limit: 1, Sort: "title", After: after}).Return(quizzes, nil).Once()
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/quizzes?sort=title&limit=1&cursor="+list.NextCursor, nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Test case 5: Only admins list unpublished quizzes
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/quizzes?published=false", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"drafts_forbidden"`)
+
+	drafts := false
+	mockService.On("ListQuizzes", repository.QuizFilter{}, repository.Page{Limit: 20, Sort: "-created_at"}).Return(quizzes, nil).Once()
+	mockService.On("ListQuizzes", repository.QuizFilter{Published: &drafts}, repository.Page{Limit: 20, Sort: "-created_at"}).Return(quizzes, nil).Once()
+	for _, query := range []string{"", "?published=false"} {
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", "/quizzes"+query, nil)
+		req.Header.Set("X-User-Role", string(enums.RoleSuperAdmin))
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code, query)
+	}
+	mockService.AssertExpectations(t)
 }
 
 func TestGetQuiz(t *testing.T) {
 	r, mockService := setupTest()
 
 	// Test case 1: Successful retrieval
-	quiz := &models.Quiz{Title: "Test Quiz"}
+	quiz := &models.Quiz{Title: "Test Quiz", IsPublished: true}
 	mockService.On("GetQuizByID", uint(1)).Return(quiz, nil)
 
 	w := httptest.NewRecorder()
@@ -142,13 +233,66 @@ func TestGetQuiz(t *testing.T) {
 	r.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Test case 3: Missing quiz
+	mockService.On("GetQuizByID", uint(2)).Return(nil, services.ErrQuizNotFound)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/quiz/2", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.JSONEq(t, `{"error": "Quiz not found", "code": "quiz_not_found"}`, w.Body.String())
+
+	// Test case 4: Database failures are server errors and keep their details to themselves
+	mockService.On("GetQuizByID", uint(3)).Return(nil, errors.New("sql: database is closed"))
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/quiz/3", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.JSONEq(t, `{"error": "Internal server error", "code": "internal_error"}`, w.Body.String())
+
+	// Test case 5: Unpublished quizzes are only found by admins
+	mockService.On("GetQuizByID", uint(4)).Return(&models.Quiz{Title: "Draft"}, nil)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/quiz/4", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/quiz/4", nil)
+	req.Header.Set("X-User-Role", string(enums.RoleSuperAdmin))
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
 }
 
 func TestCreateQuiz(t *testing.T) {
 	r, mockService := setupTest()
 
 	// Test case 1: Successful creation
-	quiz := &models.Quiz{Title: "New Quiz"}
+	quiz := &models.Quiz{
+		Title:        "New Quiz",
+		Description:  "Basic arithmetic",
+		Category:     enums.CategoryMath,
+		Difficulty:   enums.DifficultyEasy,
+		TimeLimit:    10,
+		CreatedBy:    1,
+		PassingScore: 60,
+		Questions: []models.Question{
+			{
+				Text:          "What is 2 + 2?",
+				Type:          enums.QuestionTypeMultipleChoice,
+				CorrectAnswer: "4",
+				Points:        1,
+				Answers:       []models.Answer{{Text: "3"}, {Text: "4", IsCorrect: true}},
+			},
+		},
+	}
 	mockService.On("CreateQuiz", mock.AnythingOfType("*models.Quiz")).Return(nil)
 
 	body, _ := json.Marshal(quiz)
@@ -158,6 +302,20 @@ func TestCreateQuiz(t *testing.T) {
 	r.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusCreated, w.Code)
+	var response models.Quiz
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Len(t, response.Questions, 1)
+	assert.Len(t, response.Questions[0].Answers, 2)
+
+	// Test case 2: Missing required fields
+	body, _ = json.Marshal(&models.Quiz{Title: "New Quiz"})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/quiz", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
 
 func TestGetQuizzesByCategory(t *testing.T) {
@@ -165,7 +323,7 @@ func TestGetQuizzesByCategory(t *testing.T) {
 
 	// Test case 1: Successful retrieval
 	quizzes := []models.Quiz{{Title: "Math Quiz"}}
-	mockService.On("GetQuizzesByCategory", models.CategoryMath).Return(quizzes, nil)
+	mockService.On("GetQuizzesByCategory", enums.CategoryMath).Return(quizzes, nil)
 
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", "/quiz/category/math", nil)
@@ -189,15 +347,18 @@ func TestGetResults(t *testing.T) {
 	r, mockService := setupTest()
 
 	// Test case 1: Successful retrieval
-	results := []models.Result{{Score: 85}}
-	mockService.On("GetUserResults", uint(1)).Return(results, nil)
+	results := repository.List[models.ResultSummary]{Items: []models.ResultSummary{{Score: 85, QuizTitle: "Test Quiz"}}}
+	passed := false
+	filter := repository.ResultFilter{UserID: 1, Category: enums.CategoryMath, Passed: &passed}
+	mockService.On("ListResults", filter, repository.Page{Limit: 20, Sort: "-score"}).Return(results, nil)
 
 	w := httptest.NewRecorder()
-	req, _ := http.NewRequest("GET", "/results?user_id=1", nil)
+	req, _ := http.NewRequest("GET", "/results?user_id=1&category=math&passed=false&sort=-score", nil)
+	req.Header.Set("X-User-ID", "1")
 	r.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusOK, w.Code)
-	var response []models.Result
+	var response repository.List[models.ResultSummary]
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err)
 	assert.Equal(t, results, response)
@@ -208,17 +369,44 @@ func TestGetResults(t *testing.T) {
 	r.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Test case 3: Nobody's results without logging in
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/results", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Test case 4: Only admins list other users' results
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/results?user_id=2", nil)
+	req.Header.Set("X-User-ID", "1")
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"results_forbidden"`)
+
+	mockService.On("ListResults", repository.ResultFilter{UserID: 2}, repository.Page{Limit: 20, Sort: "-created_at"}).Return(results, nil)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/results?user_id=2", nil)
+	req.Header.Set("X-User-ID", "1")
+	req.Header.Set("X-User-Role", string(enums.RoleSuperAdmin))
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
 }
 
 func TestGetResult(t *testing.T) {
 	r, mockService := setupTest()
 
 	// Test case 1: Successful retrieval
-	result := &models.Result{Score: 85}
+	result := &models.Result{UserID: 1, Score: 85}
 	mockService.On("GetResultByID", uint(1)).Return(result, nil)
 
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", "/result/1", nil)
+	req.Header.Set("X-User-ID", "1")
 	r.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusOK, w.Code)
@@ -233,4 +421,21 @@ func TestGetResult(t *testing.T) {
 	r.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Test case 3: Other users' results are only found by admins
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/result/1", nil)
+	req.Header.Set("X-User-ID", "2")
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"result_not_found"`)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/result/1", nil)
+	req.Header.Set("X-User-ID", "2")
+	req.Header.Set("X-User-Role", string(enums.RoleSuperAdmin))
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
 }
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"aicg/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errMissingSearchQuery is reported for searches without words to search for
+var errMissingSearchQuery = services.Invalid("invalid_query", "search query is required",
+	services.FieldError{Field: "q", Code: "required", Message: "is required"})
+
+// SearchHandler serves full-text search over the quizzes
+type SearchHandler struct {
+	searchService services.ISearchService
+}
+
+// NewSearchHandler creates a new search handler with the given service
+func NewSearchHandler(searchService services.ISearchService) *SearchHandler {
+	return &SearchHandler{searchService: searchService}
+}
+
+// Search returns the published quizzes and questions matching every word of
+// q, best first, with highlighted snippets. When nothing matches it suggests
+// corrected queries.
+// GET /api/search?q=&category=&difficulty=&limit=
+func (h *SearchHandler) Search(c *gin.Context) {
+	query := services.SearchQuery{Text: strings.TrimSpace(c.Query("q"))}
+	if query.Text == "" {
+		_ = c.Error(errMissingSearchQuery)
+		return
+	}
+
+	var ok bool
+	if query.Category, ok = categoryQuery(c); !ok {
+		return
+	}
+	if query.Difficulty, ok = difficultyQuery(c); !ok {
+		return
+	}
+	if query.Limit, ok = limitQuery(c); !ok {
+		return
+	}
+
+	results, err := h.searchService.Search(c.Request.Context(), query)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
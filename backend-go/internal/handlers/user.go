@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"net/http"
+
+	"aicg/internal/models/enums"
+	"aicg/internal/repository"
+	"aicg/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errOwnAccount is reported when admins try to lock, demote or remove themselves
+var errOwnAccount = services.Invalid("own_account", "you cannot perform this action on your own account")
+
+// ChangeRoleRequest is the body of a role change
+type ChangeRoleRequest struct {
+	Role enums.UserRole `json:"role" binding:"required"`
+}
+
+// UserHandler manages user profile and user administration requests
+type UserHandler struct {
+	userService services.IUserService
+}
+
+// NewUserHandler creates a new user handler with the given service
+func NewUserHandler(userService services.IUserService) *UserHandler {
+	return &UserHandler{userService: userService}
+}
+
+// GetMe returns the profile of the logged in user
+// GET /api/users/me
+func (h *UserHandler) GetMe(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		_ = c.Error(errNotAuthenticated)
+		return
+	}
+
+	user, err := h.userService.GetUserByID(c.Request.Context(), userID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// UpdateMe lets the logged in user change their name, profile image, locale and time zone
+// PATCH /api/users/me
+func (h *UserHandler) UpdateMe(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		_ = c.Error(errNotAuthenticated)
+		return
+	}
+
+	var update services.ProfileUpdate
+	if !bindJSON(c, &update) {
+		return
+	}
+
+	user, err := h.userService.UpdateProfile(c.Request.Context(), userID, update)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// ListUsers returns a page of users, optionally filtered by search text, role, status and sign up time
+// GET /api/admin/users?q=&role=&is_active=&from=&to=&sort=&limit=&cursor=
+func (h *UserHandler) ListUsers(c *gin.Context) {
+	filter := services.UserFilter{Search: c.Query("q")}
+
+	if role := c.Query("role"); role != "" {
+		if !enums.UserRole(role).IsValid() {
+			_ = c.Error(invalidValue("invalid_role", "invalid role", "role"))
+			return
+		}
+		filter.Role = enums.UserRole(role)
+	}
+
+	var ok bool
+	if filter.IsActive, ok = boolQuery(c, "is_active"); !ok {
+		return
+	}
+	if filter.From, filter.To, ok = timeRangeQuery(c); !ok {
+		return
+	}
+	page, ok := pageQuery(c, repository.UserSorting)
+	if !ok {
+		return
+	}
+
+	users, err := h.userService.ListUsers(c.Request.Context(), filter, page)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, users)
+}
+
+// ActivateUser re-enables a deactivated user account
+// POST /api/admin/users/:id/activate
+func (h *UserHandler) ActivateUser(c *gin.Context) {
+	h.setUserActive(c, true)
+}
+
+// DeactivateUser disables a user account so it can no longer log in
+// POST /api/admin/users/:id/deactivate
+func (h *UserHandler) DeactivateUser(c *gin.Context) {
+	h.setUserActive(c, false)
+}
+
+func (h *UserHandler) setUserActive(c *gin.Context, active bool) {
+	id, ok := h.targetUserID(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.userService.SetUserActive(c.Request.Context(), id, active)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// ChangeUserRole gives a user a new role
+// PATCH /api/admin/users/:id/role
+func (h *UserHandler) ChangeUserRole(c *gin.Context) {
+	id, ok := h.targetUserID(c)
+	if !ok {
+		return
+	}
+
+	var req ChangeRoleRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	user, err := h.userService.ChangeUserRole(c.Request.Context(), id, req.Role)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// DeleteUser soft-deletes a user account
+// DELETE /api/admin/users/:id
+func (h *UserHandler) DeleteUser(c *gin.Context) {
+	id, ok := h.targetUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.userService.DeleteUser(c.Request.Context(), id); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// EraseUser anonymizes a user's personal data (GDPR erasure) but keeps their statistics
+// POST /api/admin/users/:id/erase
+func (h *UserHandler) EraseUser(c *gin.Context) {
+	id, ok := h.targetUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.userService.EraseUser(c.Request.Context(), id); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// targetUserID parses the :id path parameter of an admin action.
+// Admins may not use these actions on their own account, so they can't lock themselves out.
+func (h *UserHandler) targetUserID(c *gin.Context) (uint, bool) {
+	id, ok := pathID(c, "id", "user")
+	if !ok {
+		return 0, false
+	}
+
+	if self, ok := currentUserID(c); ok && self == id {
+		_ = c.Error(errOwnAccount)
+		return 0, false
+	}
+
+	return id, true
+}
+
+// isSuperAdmin reports whether the logged in user, as set by the auth middleware, is a super admin
+func isSuperAdmin(c *gin.Context) bool {
+	role, _ := c.Get("userRole")
+	return role == enums.RoleSuperAdmin
+}
+
+// currentUserID returns the ID of the logged in user set by the auth middleware
+func currentUserID(c *gin.Context) (uint, bool) {
+	value, exists := c.Get("userID")
+	if !exists {
+		return 0, false
+	}
+	userID, ok := value.(uint)
+	return userID, ok
+}
@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"aicg/internal/middleware"
+	"aicg/internal/models"
+	"aicg/internal/models/enums"
+	"aicg/internal/repository"
+	"aicg/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockUserService is a mock implementation of IUserService
+type MockUserService struct {
+	mock.Mock
+}
+
+// Ensure MockUserService implements services.IUserService
+var _ services.IUserService = (*MockUserService)(nil)
+
+func (m *MockUserService) GetUserByID(ctx context.Context, id uint) (*models.User, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockUserService) UpdateProfile(ctx context.Context, id uint, update services.ProfileUpdate) (*models.User, error) {
+	args := m.Called(id, update)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockUserService) ListUsers(ctx context.Context, filter services.UserFilter, page repository.Page) (repository.List[models.User], error) {
+	args := m.Called(filter, page)
+	return args.Get(0).(repository.List[models.User]), args.Error(1)
+}
+
+func (m *MockUserService) SetUserActive(ctx context.Context, id uint, active bool) (*models.User, error) {
+	args := m.Called(id, active)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockUserService) ChangeUserRole(ctx context.Context, id uint, role enums.UserRole) (*models.User, error) {
+	args := m.Called(id, role)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockUserService) DeleteUser(ctx context.Context, id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockUserService) EraseUser(ctx context.Context, id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func setupUserTest() (*gin.Engine, *MockUserService) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.Use(middleware.Errors())
+	mockService := new(MockUserService)
+	handler := NewUserHandler(mockService)
+
+	// Pretend user 1 is logged in
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		c.Next()
+	})
+
+	// Setup routes
+	r.GET("/users/me", handler.GetMe)
+	r.PATCH("/users/me", handler.UpdateMe)
+	r.GET("/admin/users", handler.ListUsers)
+	r.POST("/admin/users/:id/deactivate", handler.DeactivateUser)
+	r.PATCH("/admin/users/:id/role", handler.ChangeUserRole)
+	r.DELETE("/admin/users/:id", handler.DeleteUser)
+	r.POST("/admin/users/:id/erase", handler.EraseUser)
+
+	return r, mockService
+}
+
+func TestGetMe(t *testing.T) {
+	r, mockService := setupUserTest()
+
+	user := &models.User{Email: "me@example.com", FirstName: "Ada"}
+	mockService.On("GetUserByID", uint(1)).Return(user, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/users/me", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response models.User
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, user.Email, response.Email)
+}
+
+func TestUpdateMe(t *testing.T) {
+	r, mockService := setupUserTest()
+
+	// Test case 1: Successful update
+	tz := "Europe/Berlin"
+	update := services.ProfileUpdate{TimeZone: &tz}
+	mockService.On("UpdateProfile", uint(1), update).Return(&models.User{TimeZone: tz}, nil)
+
+	body, _ := json.Marshal(update)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PATCH", "/users/me", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Test case 2: Invalid time zone
+	badTZ := "Mars/Olympus"
+	badUpdate := services.ProfileUpdate{TimeZone: &badTZ}
+	mockService.On("UpdateProfile", uint(1), badUpdate).Return(nil, services.ErrInvalidTimeZone)
+
+	body, _ = json.Marshal(badUpdate)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("PATCH", "/users/me", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestListUsers(t *testing.T) {
+	r, mockService := setupUserTest()
+
+	// Test case 1: Filtered retrieval
+	active := true
+	filter := services.UserFilter{Search: "ada", Role: enums.RoleMaveric, IsActive: &active}
+	users := repository.List[models.User]{Items: []models.User{{Email: "ada@example.com"}}, NextCursor: "next"}
+	mockService.On("ListUsers", filter, repository.Page{Limit: 10, Sort: "-email"}).Return(users, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/admin/users?q=ada&role=maveric&is_active=true&sort=-email&limit=10", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response repository.List[models.User]
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Len(t, response.Items, 1)
+	assert.Equal(t, "next", response.NextCursor)
+
+	// Test case 2: Invalid role
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/admin/users?role=wizard", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestDeactivateUser(t *testing.T) {
+	r, mockService := setupUserTest()
+
+	// Test case 1: Successful deactivation
+	mockService.On("SetUserActive", uint(2), false).Return(&models.User{IsActive: false}, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/admin/users/2/deactivate", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Test case 2: Admins can't deactivate themselves
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/admin/users/1/deactivate", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Test case 3: Unknown user
+	mockService.On("SetUserActive", uint(3), false).Return(nil, services.ErrUserNotFound)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/admin/users/3/deactivate", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestChangeUserRole(t *testing.T) {
+	r, mockService := setupUserTest()
+
+	mockService.On("ChangeUserRole", uint(2), enums.RoleSuperAdmin).Return(&models.User{Role: enums.RoleSuperAdmin}, nil)
+
+	body, _ := json.Marshal(map[string]string{"role": "super_admin"})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PATCH", "/admin/users/2/role", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestDeleteAndEraseUser(t *testing.T) {
+	r, mockService := setupUserTest()
+
+	mockService.On("DeleteUser", uint(2)).Return(nil)
+	mockService.On("EraseUser", uint(2)).Return(nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/admin/users/2", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/admin/users/2/erase", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	mockService.AssertExpectations(t)
+}
@@ -0,0 +1,91 @@
+// Package health tracks whether the server is ready to take traffic
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CheckTimeout bounds a single readiness check
+const CheckTimeout = 2 * time.Second
+
+// ErrShuttingDown is reported while the server drains before exiting
+var ErrShuttingDown = errors.New("shutting down")
+
+// Check reports whether one dependency is usable
+type Check func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Checker runs the readiness checks. The zero value has no checks and is ready.
+type Checker struct {
+	mu           sync.RWMutex
+	checks       []namedCheck
+	shuttingDown atomic.Bool
+}
+
+// NewChecker creates a checker with no checks
+func NewChecker() *Checker {
+	return &Checker{}
+}
+
+// Add registers a named check
+func (c *Checker) Add(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+}
+
+// ShutDown makes the server report not ready, so load balancers stop
+// sending it new requests while in-flight ones drain
+func (c *Checker) ShutDown() {
+	c.shuttingDown.Store(true)
+}
+
+// Result is the outcome of the readiness checks, by check name: "ok" or the error
+type Result struct {
+	Ready  bool              `json:"ready"`
+	Checks map[string]string `json:"checks"`
+}
+
+// Ready runs every check concurrently, each bounded by CheckTimeout
+func (c *Checker) Ready(ctx context.Context) Result {
+	c.mu.RLock()
+	checks := c.checks
+	c.mu.RUnlock()
+
+	result := Result{Ready: true, Checks: make(map[string]string, len(checks)+1)}
+	if c.shuttingDown.Load() {
+		result.Ready = false
+		result.Checks["server"] = ErrShuttingDown.Error()
+	}
+
+	errs := make([]error, len(checks))
+	var wg sync.WaitGroup
+	for i, nc := range checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, CheckTimeout)
+			defer cancel()
+			errs[i] = check(checkCtx)
+		}(i, nc.check)
+	}
+	wg.Wait()
+
+	for i, nc := range checks {
+		if errs[i] != nil {
+			result.Ready = false
+			result.Checks[nc.name] = errs[i].Error()
+		} else {
+			result.Checks[nc.name] = "ok"
+		}
+	}
+	return result
+}
@@ -0,0 +1,49 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecker(t *testing.T) {
+	checker := NewChecker()
+
+	// Test case 1: No checks means ready
+	result := checker.Ready(context.Background())
+	assert.True(t, result.Ready)
+	assert.Empty(t, result.Checks)
+
+	// Test case 2: Every check is reported, and one failure is enough to be unready
+	checker.Add("database", func(ctx context.Context) error { return nil })
+	checker.Add("migrations", func(ctx context.Context) error { return errors.New("2 pending migrations") })
+	result = checker.Ready(context.Background())
+	assert.False(t, result.Ready)
+	assert.Equal(t, map[string]string{"database": "ok", "migrations": "2 pending migrations"}, result.Checks)
+
+	// Test case 3: Checks that hang are cut off
+	checker = NewChecker()
+	checker.Add("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result = checker.Ready(ctx)
+	assert.False(t, result.Ready)
+	assert.Equal(t, context.Canceled.Error(), result.Checks["slow"])
+}
+
+func TestCheckerShutDown(t *testing.T) {
+	checker := NewChecker()
+	checker.Add("database", func(ctx context.Context) error { return nil })
+	checker.ShutDown()
+
+	// A server that is shutting down is never ready
+	result := checker.Ready(context.Background())
+	assert.False(t, result.Ready)
+	assert.Equal(t, ErrShuttingDown.Error(), result.Checks["server"])
+	assert.Equal(t, "ok", result.Checks["database"])
+}
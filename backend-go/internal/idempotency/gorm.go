@@ -0,0 +1,125 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"aicg/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GormStore keeps the keys in the database, so that all server instances
+// recognize retries. Expired keys are deleted periodically.
+type GormStore struct {
+	db  *gorm.DB
+	now func() time.Time
+
+	mu    sync.Mutex
+	swept time.Time
+}
+
+// NewGormStore creates a store on the idempotency_keys table
+func NewGormStore(db *gorm.DB) *GormStore {
+	return &GormStore{db: db, now: time.Now}
+}
+
+// Begin implements Store
+func (s *GormStore) Begin(ctx context.Context, key Key, fingerprint string) (*Response, error) {
+	now := s.now()
+	s.sweep(ctx, now)
+	db := s.db.WithContext(ctx)
+
+	// The unique index on the user and key lets one request claim it
+	claim := models.IdempotencyKey{UserID: key.UserID, Key: key.Key, Fingerprint: fingerprint, ExpiresAt: now.Add(lease)}
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&claim)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to claim idempotency key: %w", result.Error)
+	}
+	if result.RowsAffected == 1 {
+		return nil, nil
+	}
+
+	var existing models.IdempotencyKey
+	if err := db.Where("user_id = ? AND idempotency_key = ?", key.UserID, key.Key).First(&existing).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Expired and deleted in the meantime
+			return s.Begin(ctx, key, fingerprint)
+		}
+		return nil, fmt.Errorf("failed to look up idempotency key: %w", err)
+	}
+
+	if !now.Before(existing.ExpiresAt) {
+		// Take the expired key over, unless another request just did
+		result := db.Model(&models.IdempotencyKey{}).
+			Where("id = ? AND expires_at <= ?", existing.ID, now).
+			Updates(map[string]interface{}{
+				"fingerprint":  fingerprint,
+				"status_code":  0,
+				"content_type": "",
+				"body":         nil,
+				"expires_at":   now.Add(lease),
+			})
+		if result.Error != nil {
+			return nil, fmt.Errorf("failed to claim idempotency key: %w", result.Error)
+		}
+		if result.RowsAffected == 1 {
+			return nil, nil
+		}
+		return nil, ErrInProgress
+	}
+
+	var saved *Response
+	if existing.StatusCode != 0 {
+		saved = &Response{StatusCode: existing.StatusCode, ContentType: existing.ContentType, Body: existing.Body}
+	}
+	return replay(existing.Fingerprint, saved, fingerprint)
+}
+
+// Complete implements Store
+func (s *GormStore) Complete(ctx context.Context, key Key, fingerprint string, resp Response) error {
+	err := s.claimed(ctx, key, fingerprint).Updates(map[string]interface{}{
+		"status_code":  resp.StatusCode,
+		"content_type": resp.ContentType,
+		"body":         resp.Body,
+		"expires_at":   s.now().Add(Retention),
+	}).Error
+	if err != nil {
+		return fmt.Errorf("failed to save idempotent response: %w", err)
+	}
+	return nil
+}
+
+// Release implements Store
+func (s *GormStore) Release(ctx context.Context, key Key, fingerprint string) error {
+	if err := s.claimed(ctx, key, fingerprint).Delete(&models.IdempotencyKey{}).Error; err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// claimed scopes a query to the key while the request with fingerprint holds it
+func (s *GormStore) claimed(ctx context.Context, key Key, fingerprint string) *gorm.DB {
+	return s.db.WithContext(ctx).Model(&models.IdempotencyKey{}).
+		Where("user_id = ? AND idempotency_key = ? AND fingerprint = ? AND status_code = 0", key.UserID, key.Key, fingerprint)
+}
+
+// sweep deletes the expired keys, at most once per sweepInterval. Failing
+// to is harmless, since expired keys are taken over when reused.
+func (s *GormStore) sweep(ctx context.Context, now time.Time) {
+	s.mu.Lock()
+	due := now.Sub(s.swept) >= sweepInterval
+	if due {
+		s.swept = now
+	}
+	s.mu.Unlock()
+	if due {
+		s.db.WithContext(ctx).Where("expires_at <= ?", now).Delete(&models.IdempotencyKey{})
+	}
+}
+
+var _ Store = (*GormStore)(nil)
@@ -0,0 +1,152 @@
+// Package idempotency remembers the responses to requests sent with an
+// Idempotency-Key, so that a client retrying a request whose response it
+// never got, e.g. after a network failure, gets the original response
+// instead of having the request run twice.
+//
+// Keys belong to a user. The first request with a key claims it, and its
+// response is saved for Retention. A fingerprint of the request guards
+// against reusing a key for a different request.
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	// MaxKeyLength is the longest key accepted
+	MaxKeyLength = 255
+	// Retention is how long a response is replayed for
+	Retention = 24 * time.Hour
+	// lease is how long a claim lasts without a response. It outlives any
+	// request, so a claim still running at its end was abandoned, e.g. by
+	// a server that crashed, and can be taken over.
+	lease = 5 * time.Minute
+)
+
+var (
+	// ErrMismatch means the key was used for a different request
+	ErrMismatch = errors.New("idempotency key was used for a different request")
+	// ErrInProgress means the first request with the key hasn't finished
+	ErrInProgress = errors.New("a request with the same idempotency key is in progress")
+)
+
+// Key identifies a request by its user and Idempotency-Key
+type Key struct {
+	UserID uint
+	Key    string
+}
+
+// Response is a saved response
+type Response struct {
+	StatusCode  int
+	ContentType string
+	Body        []byte
+}
+
+// Store keeps the keys and their responses
+type Store interface {
+	// Begin claims the key for a request with the given fingerprint. It
+	// returns the response to replay if the request already completed,
+	// ErrMismatch if the key was used for another request, and
+	// ErrInProgress while the request that claimed it is still running.
+	Begin(ctx context.Context, key Key, fingerprint string) (*Response, error)
+	// Complete saves the response of the request that claimed the key
+	Complete(ctx context.Context, key Key, fingerprint string, resp Response) error
+	// Release gives the key up without a response, so the request can be retried
+	Release(ctx context.Context, key Key, fingerprint string) error
+}
+
+// Fingerprint identifies a request by its method, path and body
+func Fingerprint(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// MemoryStore keeps the keys in the process, so retries are only
+// recognized by the server instance that saw the first request. Expired
+// keys are forgotten periodically.
+type MemoryStore struct {
+	mu    sync.Mutex
+	keys  map[Key]*memoryKey
+	swept time.Time
+	now   func() time.Time
+}
+
+type memoryKey struct {
+	fingerprint string
+	response    *Response // Nil while the request is running
+	expires     time.Time
+}
+
+// sweepInterval is how often expired keys are forgotten
+const sweepInterval = time.Minute
+
+// NewMemoryStore creates an empty in-process store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{keys: make(map[Key]*memoryKey), now: time.Now}
+}
+
+// Begin implements Store
+func (s *MemoryStore) Begin(ctx context.Context, key Key, fingerprint string) (*Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.swept) >= sweepInterval {
+		for k, v := range s.keys {
+			if !now.Before(v.expires) {
+				delete(s.keys, k)
+			}
+		}
+		s.swept = now
+	}
+
+	k, ok := s.keys[key]
+	if !ok || !now.Before(k.expires) {
+		s.keys[key] = &memoryKey{fingerprint: fingerprint, expires: now.Add(lease)}
+		return nil, nil
+	}
+	return replay(k.fingerprint, k.response, fingerprint)
+}
+
+// Complete implements Store
+func (s *MemoryStore) Complete(ctx context.Context, key Key, fingerprint string, resp Response) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if k, ok := s.keys[key]; ok && k.fingerprint == fingerprint && k.response == nil {
+		k.response = &resp
+		k.expires = s.now().Add(Retention)
+	}
+	return nil
+}
+
+// Release implements Store
+func (s *MemoryStore) Release(ctx context.Context, key Key, fingerprint string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if k, ok := s.keys[key]; ok && k.fingerprint == fingerprint && k.response == nil {
+		delete(s.keys, key)
+	}
+	return nil
+}
+
+// replay decides what a request with fingerprint gets from a key claimed
+// by a request with claimed, which has the response saved, if any
+func replay(claimed string, saved *Response, fingerprint string) (*Response, error) {
+	switch {
+	case claimed != fingerprint:
+		return nil, ErrMismatch
+	case saved == nil:
+		return nil, ErrInProgress
+	}
+	return saved, nil
+}
+
+var _ Store = (*MemoryStore)(nil)
@@ -0,0 +1,177 @@
+package idempotency
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"aicg/internal/config"
+	"aicg/internal/database"
+	"aicg/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/logger"
+)
+
+// clock is a fake time source the tests move forward by hand
+type clock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func newClock() *clock {
+	return &clock{t: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+func (c *clock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *clock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
+
+// testStore runs the same scenarios against every store
+func testStore(t *testing.T, newStore func(c *clock) Store) {
+	ctx := context.Background()
+	key := Key{UserID: 1, Key: "a"}
+	submit := Fingerprint("POST", "/api/quiz/1/submit", []byte(`{"time_taken":5}`))
+	created := Response{StatusCode: 200, ContentType: "application/json", Body: []byte(`{"score":100}`)}
+
+	t.Run("replay", func(t *testing.T) {
+		c := newClock()
+		s := newStore(c)
+
+		// Test case 1: The first request claims the key
+		saved, err := s.Begin(ctx, key, submit)
+		require.NoError(t, err)
+		assert.Nil(t, saved)
+
+		// Test case 2: Retries wait for it to finish
+		_, err = s.Begin(ctx, key, submit)
+		assert.ErrorIs(t, err, ErrInProgress)
+
+		// Test case 3: Then get its response
+		require.NoError(t, s.Complete(ctx, key, submit, created))
+		saved, err = s.Begin(ctx, key, submit)
+		require.NoError(t, err)
+		assert.Equal(t, &created, saved)
+
+		// Test case 4: Other requests can't use the key, but other users can
+		_, err = s.Begin(ctx, key, Fingerprint("POST", "/api/quiz/2/submit", []byte(`{"time_taken":5}`)))
+		assert.ErrorIs(t, err, ErrMismatch)
+		saved, err = s.Begin(ctx, Key{UserID: 2, Key: "a"}, submit)
+		require.NoError(t, err)
+		assert.Nil(t, saved)
+
+		// Test case 5: The response is forgotten after Retention
+		c.advance(Retention)
+		saved, err = s.Begin(ctx, key, submit)
+		require.NoError(t, err)
+		assert.Nil(t, saved)
+	})
+
+	t.Run("release", func(t *testing.T) {
+		c := newClock()
+		s := newStore(c)
+
+		// Test case 1: A released key can be claimed again
+		_, err := s.Begin(ctx, key, submit)
+		require.NoError(t, err)
+		require.NoError(t, s.Release(ctx, key, submit))
+		saved, err := s.Begin(ctx, key, submit)
+		require.NoError(t, err)
+		assert.Nil(t, saved)
+
+		// Test case 2: A claim never settled is taken over after its lease
+		c.advance(lease)
+		other := Fingerprint("POST", "/api/quiz/2/submit", nil)
+		saved, err = s.Begin(ctx, key, other)
+		require.NoError(t, err)
+		assert.Nil(t, saved)
+
+		// Test case 3: The abandoned request can't settle it anymore
+		require.NoError(t, s.Complete(ctx, key, submit, created))
+		_, err = s.Begin(ctx, key, other)
+		assert.ErrorIs(t, err, ErrInProgress)
+	})
+
+	t.Run("concurrent claims", func(t *testing.T) {
+		s := newStore(newClock())
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		claimed := 0
+		for range 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				saved, err := s.Begin(ctx, key, submit)
+				if err == nil && saved == nil {
+					mu.Lock()
+					claimed++
+					mu.Unlock()
+				} else {
+					assert.ErrorIs(t, err, ErrInProgress)
+				}
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, 1, claimed)
+	})
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, func(c *clock) Store {
+		s := NewMemoryStore()
+		s.now = c.now
+		return s
+	})
+
+	// Expired keys are forgotten after a while
+	c := newClock()
+	s := NewMemoryStore()
+	s.now = c.now
+	_, err := s.Begin(context.Background(), Key{UserID: 1, Key: "a"}, "x")
+	require.NoError(t, err)
+	c.advance(2 * lease)
+	_, err = s.Begin(context.Background(), Key{UserID: 1, Key: "b"}, "x")
+	require.NoError(t, err)
+	assert.Len(t, s.keys, 1)
+}
+
+func TestGormStore(t *testing.T) {
+	var store *GormStore
+	testStore(t, func(c *clock) Store {
+		db, err := database.InitDB(&config.Config{DB: config.DBConfig{Driver: database.DriverSQLite, Path: filepath.Join(t.TempDir(), "test.db")}})
+		require.NoError(t, err)
+		db.Logger = logger.Discard
+		migrator, err := database.NewMigrator(db)
+		require.NoError(t, err)
+		_, err = migrator.Up(context.Background())
+		require.NoError(t, err)
+		for _, email := range []string{"ada@example.com", "grace@example.com"} {
+			require.NoError(t, db.Create(&models.User{Email: email}).Error)
+		}
+
+		store = NewGormStore(db)
+		store.now = c.now
+		return store
+	})
+
+	// Expired keys are deleted after a while
+	var left int64
+	require.NoError(t, store.db.Model(&models.IdempotencyKey{}).Count(&left).Error)
+	assert.Equal(t, int64(1), left)
+	store.now = func() time.Time { return time.Now().Add(Retention) }
+	_, err := store.Begin(context.Background(), Key{UserID: 2, Key: "b"}, "x")
+	require.NoError(t, err)
+	require.NoError(t, store.db.Model(&models.IdempotencyKey{}).Count(&left).Error)
+	assert.Equal(t, int64(1), left)
+}
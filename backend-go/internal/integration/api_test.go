@@ -0,0 +1,829 @@
+// Package integration runs the full API against a real database: a
+// temporary SQLite file, migrated and seeded like a fresh deployment.
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"aicg/internal/config"
+	"aicg/internal/database"
+	"aicg/internal/handlers"
+	"aicg/internal/health"
+	"aicg/internal/middleware"
+	"aicg/internal/models"
+	"aicg/internal/models/enums"
+	"aicg/internal/repository"
+	"aicg/internal/routes"
+	"aicg/internal/seed"
+	"aicg/internal/services"
+	"aicg/internal/storage"
+	"aicg/internal/version"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	adminEmail    = "admin@aicg.local"
+	adminPassword = "admin-password"
+)
+
+// testServer is the API served over HTTP on a migrated and seeded database
+type testServer struct {
+	*httptest.Server
+	db    *gorm.DB
+	blobs storage.BlobStore
+	svc   routes.Services
+}
+
+// newTestServer starts the API, with the default configuration changed by configure
+func newTestServer(t *testing.T, configure ...func(*config.Config)) *testServer {
+	gin.SetMode(gin.TestMode)
+	dir := t.TempDir()
+	cfg := config.Default()
+	cfg.DB.Driver = database.DriverSQLite
+	cfg.DB.Path = filepath.Join(dir, "aicg.db")
+	cfg.JWT.Secret = "integration-secret"
+	cfg.Storage.LocalDir = filepath.Join(dir, "uploads")
+	for _, c := range configure {
+		c(cfg)
+	}
+	require.NoError(t, cfg.Validate())
+
+	db, err := database.InitDB(cfg)
+	require.NoError(t, err)
+	db.Logger = logger.Discard
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	migrator, err := database.NewMigrator(db)
+	require.NoError(t, err)
+	_, err = migrator.Up(context.Background())
+	require.NoError(t, err)
+
+	fixtures, err := seed.LoadFixtures(os.DirFS("../../fixtures"))
+	require.NoError(t, err)
+	_, err = seed.NewSeeder(db).Seed(fixtures, adminPassword)
+	require.NoError(t, err)
+
+	blobs, err := storage.NewFromConfig(cfg)
+	require.NoError(t, err)
+	svc := routes.NewServices(db, blobs, cfg)
+	srv := httptest.NewServer(routes.SetupRouter(cfg, svc))
+	t.Cleanup(srv.Close)
+	return &testServer{Server: srv, db: db, blobs: blobs, svc: svc}
+}
+
+// do sends a JSON request and decodes the JSON response into out, if given
+func (s *testServer) do(t *testing.T, method, path, token string, body, out interface{}) int {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		require.NoError(t, err)
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, s.URL+path, reader)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := s.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	if out != nil {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(out), "%s %s", method, path)
+	}
+	return resp.StatusCode
+}
+
+// upload posts data as the multipart file field "image" and decodes the JSON response into out, if given
+func (s *testServer) upload(t *testing.T, path, token string, data []byte, out interface{}) int {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("image", "image.png")
+	require.NoError(t, err)
+	_, err = part.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, form.Close())
+
+	req, err := http.NewRequest(http.MethodPost, s.URL+path, &body)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := s.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	if out != nil {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(out), "POST %s", path)
+	}
+	return resp.StatusCode
+}
+
+// testPNG encodes a blank square PNG
+func testPNG(t *testing.T) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 100, 100))))
+	return buf.Bytes()
+}
+
+type tokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// login returns an access token for the given credentials
+func (s *testServer) login(t *testing.T, email, password string) tokenPair {
+	var tokens tokenPair
+	status := s.do(t, http.MethodPost, "/api/auth/login", "", map[string]string{"email": email, "password": password}, &tokens)
+	require.Equal(t, http.StatusOK, status)
+	return tokens
+}
+
+// register signs up a new user and logs them in
+func (s *testServer) register(t *testing.T, email string) (models.User, tokenPair) {
+	var resp struct {
+		User models.User `json:"user"`
+	}
+	status := s.do(t, http.MethodPost, "/api/auth/register", "", map[string]string{
+		"email": email, "password": "user-password", "first_name": "Test", "last_name": "User",
+	}, &resp)
+	require.Equal(t, http.StatusCreated, status)
+	return resp.User, s.login(t, email, "user-password")
+}
+
+func TestAuth(t *testing.T) {
+	s := newTestServer(t)
+
+	// Test case 1: Register, log in and fetch the profile
+	user, tokens := s.register(t, "ada@example.com")
+	var me models.User
+	assert.Equal(t, http.StatusOK, s.do(t, http.MethodGet, "/api/users/me", tokens.AccessToken, nil, &me))
+	assert.Equal(t, user.ID, me.ID)
+	assert.Equal(t, enums.RoleMaveric, me.Role)
+
+	// Test case 2: The email can't be registered twice
+	var failure middleware.ErrorResponse
+	assert.Equal(t, http.StatusConflict, s.do(t, http.MethodPost, "/api/auth/register", "", map[string]string{
+		"email": "ada@example.com", "password": "user-password", "first_name": "Ada", "last_name": "Again",
+	}, &failure))
+	assert.Equal(t, "email_taken", failure.Code)
+	assert.NotEmpty(t, failure.RequestID)
+
+	// Test case 3: Wrong password
+	assert.Equal(t, http.StatusUnauthorized, s.do(t, http.MethodPost, "/api/auth/login", "", map[string]string{
+		"email": "ada@example.com", "password": "wrong-password",
+	}, nil))
+
+	// Test case 4: The refresh token gives a new pair
+	var refreshed tokenPair
+	assert.Equal(t, http.StatusOK, s.do(t, http.MethodPost, "/api/auth/refresh", "", map[string]string{"refresh_token": tokens.RefreshToken}, &refreshed))
+	assert.NotEmpty(t, refreshed.AccessToken)
+
+	// Test case 5: Seeded SSO placeholders are disabled
+	assert.Equal(t, http.StatusNotFound, s.do(t, http.MethodGet, "/api/auth/sso/google/config", "", nil, nil))
+
+	// Test case 6: SSO sign-in creates the user once
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, http.StatusOK, s.do(t, http.MethodPost, "/api/auth/sso/google/callback", "", map[string]string{
+			"provider_id": "g-1", "email": "grace@example.com", "first_name": "Grace", "last_name": "Hopper",
+		}, nil))
+	}
+	var count int64
+	require.NoError(t, s.db.Model(&models.User{}).Where("email = ?", "grace@example.com").Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestTakeQuiz(t *testing.T) {
+	s := newTestServer(t)
+	user, tokens := s.register(t, "ada@example.com")
+
+	// Every seeded starter quiz is listed, summarized
+	var quizzes repository.List[models.QuizSummary]
+	require.Equal(t, http.StatusOK, s.do(t, http.MethodGet, "/api/quiz/", tokens.AccessToken, nil, &quizzes))
+	require.Len(t, quizzes.Items, len(enums.ValidCategories()))
+	assert.Empty(t, quizzes.NextCursor)
+
+	var quiz models.Quiz
+	require.Equal(t, http.StatusOK, s.do(t, http.MethodGet, fmt.Sprintf("/api/quiz/%d", quizzes.Items[0].ID), tokens.AccessToken, nil, &quiz))
+	require.NotEmpty(t, quiz.Questions)
+	assert.Equal(t, len(quiz.Questions), quizzes.Items[0].QuestionCount)
+	for _, q := range quiz.Questions {
+		assert.Empty(t, q.CorrectAnswer)
+	}
+
+	// The answers come from the database, since players aren't shown them
+	var key models.Quiz
+	require.NoError(t, s.db.Preload("Questions").First(&key, quiz.ID).Error)
+
+	// Test case 1: All answers right, then all wrong
+	type answer struct {
+		QuestionID uint   `json:"question_id"`
+		Answer     string `json:"answer"`
+	}
+	submit := func(correct bool) map[string]interface{} {
+		var answers []answer
+		for _, q := range key.Questions {
+			a := answer{QuestionID: q.ID, Answer: q.CorrectAnswer}
+			if !correct {
+				a.Answer = "definitely wrong"
+			}
+			answers = append(answers, a)
+		}
+		var resp map[string]interface{}
+		status := s.do(t, http.MethodPost, fmt.Sprintf("/api/quiz/%d/submit", quiz.ID), tokens.AccessToken,
+			map[string]interface{}{"answers": answers, "time_taken": 42}, &resp)
+		require.Equal(t, http.StatusOK, status)
+		return resp
+	}
+	first := submit(true)
+	assert.Equal(t, 100.0, first["score"])
+	assert.Equal(t, true, first["is_passed"])
+	second := submit(false)
+	assert.Equal(t, 0.0, second["score"])
+
+	// Test case 2: Results keep the submitted answers as JSON
+	var results repository.List[models.ResultSummary]
+	require.Equal(t, http.StatusOK, s.do(t, http.MethodGet, "/api/results/?sort=created_at", tokens.AccessToken, nil, &results))
+	require.Len(t, results.Items, 2)
+	assert.Equal(t, user.ID, results.Items[0].UserID)
+	assert.Equal(t, quiz.Title, results.Items[0].QuizTitle)
+	var result models.Result
+	require.Equal(t, http.StatusOK, s.do(t, http.MethodGet, fmt.Sprintf("/api/results/%d", results.Items[0].ID), tokens.AccessToken, nil, &result))
+	assert.Equal(t, 100.0, result.Score)
+	var stored []answer
+	require.NoError(t, json.Unmarshal(result.Answers, &stored))
+	assert.Len(t, stored, len(quiz.Questions))
+
+	// Test case 3: Results are paged and filtered
+	var newest, older repository.List[models.ResultSummary]
+	require.Equal(t, http.StatusOK, s.do(t, http.MethodGet, "/api/results/?limit=1", tokens.AccessToken, nil, &newest))
+	require.Len(t, newest.Items, 1)
+	assert.Equal(t, 0.0, newest.Items[0].Score)
+	require.NotEmpty(t, newest.NextCursor)
+	require.Equal(t, http.StatusOK, s.do(t, http.MethodGet, "/api/results/?limit=1&cursor="+newest.NextCursor, tokens.AccessToken, nil, &older))
+	require.Len(t, older.Items, 1)
+	assert.Equal(t, 100.0, older.Items[0].Score)
+	assert.Empty(t, older.NextCursor)
+
+	var failed, future repository.List[models.ResultSummary]
+	require.Equal(t, http.StatusOK, s.do(t, http.MethodGet, "/api/results/?passed=false", tokens.AccessToken, nil, &failed))
+	require.Len(t, failed.Items, 1)
+	assert.False(t, failed.Items[0].IsPassed)
+	tomorrow := time.Now().AddDate(0, 0, 1).Format("2006-01-02")
+	require.Equal(t, http.StatusOK, s.do(t, http.MethodGet, "/api/results/?from="+tomorrow, tokens.AccessToken, nil, &future))
+	assert.Empty(t, future.Items)
+
+	// Test case 4: Progress sums up both attempts
+	var progress models.UserProgress
+	require.NoError(t, s.db.Where("user_id = ? AND quiz_id = ?", user.ID, quiz.ID).First(&progress).Error)
+	assert.Equal(t, 2, progress.TotalAttempts)
+	assert.Equal(t, 100.0, progress.BestScore)
+	assert.Equal(t, 50.0, progress.AverageScore)
+	assert.Equal(t, 84, progress.TotalTimeSpent)
+
+	var summary models.ProgressSummary
+	require.Equal(t, http.StatusOK, s.do(t, http.MethodGet, "/api/users/me/progress", tokens.AccessToken, nil, &summary))
+	assert.Equal(t, 2, summary.Overall.TotalAttempts)
+	assert.Equal(t, 1, summary.Overall.QuizzesAttempted)
+	assert.Equal(t, len(quizzes.Items), summary.Overall.QuizzesPublished)
+	require.Len(t, summary.Categories, len(enums.ValidCategories()))
+	for _, category := range summary.Categories {
+		if category.Category != quiz.Category {
+			assert.Zero(t, category.TotalAttempts)
+			continue
+		}
+		assert.Equal(t, 100.0, category.Coverage)
+		assert.Equal(t, 50.0, category.AverageScore)
+		assert.Equal(t, 84, category.TotalTimeSpent)
+		assert.Equal(t, 1.0, category.MasteryLevel)
+	}
+	var categoryProgress models.CategoryProgress
+	require.Equal(t, http.StatusOK, s.do(t, http.MethodGet, "/api/users/me/progress/"+string(quiz.Category), tokens.AccessToken, nil, &categoryProgress))
+	assert.Equal(t, 2, categoryProgress.TotalAttempts)
+	assert.Equal(t, 100.0, categoryProgress.Coverage)
+	assert.Equal(t, http.StatusBadRequest, s.do(t, http.MethodGet, "/api/users/me/progress/cooking", tokens.AccessToken, nil, nil))
+
+	// Test case 5: Unknown quiz
+	assert.Equal(t, http.StatusNotFound, s.do(t, http.MethodPost, "/api/quiz/9999/submit", tokens.AccessToken,
+		map[string]interface{}{"answers": []answer{{QuestionID: 1, Answer: "x"}}, "time_taken": 1}, nil))
+}
+
+func TestIdempotentSubmit(t *testing.T) {
+	s := newTestServer(t)
+	user, tokens := s.register(t, "ada@example.com")
+	_, grace := s.register(t, "grace@example.com")
+	var quizzes repository.List[models.QuizSummary]
+	require.Equal(t, http.StatusOK, s.do(t, http.MethodGet, "/api/quiz/", tokens.AccessToken, nil, &quizzes))
+	quizID := quizzes.Items[0].ID
+	var quiz models.Quiz
+	require.Equal(t, http.StatusOK, s.do(t, http.MethodGet, fmt.Sprintf("/api/quiz/%d", quizID), tokens.AccessToken, nil, &quiz))
+
+	submit := func(key, token string, timeTaken int) (*http.Response, map[string]interface{}) {
+		body, err := json.Marshal(map[string]interface{}{"answers": []map[string]interface{}{{"question_id": quiz.Questions[0].ID, "answer": "x"}}, "time_taken": timeTaken})
+		require.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/api/quiz/%d/submit", s.URL, quizID), bytes.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Idempotency-Key", key)
+		resp, err := s.Client().Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var out map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+		return resp, out
+	}
+
+	// Test case 1: A retry gets the first response, without submitting again
+	resp, first := submit("attempt-1", tokens.AccessToken, 30)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get("Idempotent-Replayed"))
+	resp, retried := submit("attempt-1", tokens.AccessToken, 30)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "true", resp.Header.Get("Idempotent-Replayed"))
+	assert.Equal(t, first, retried)
+
+	var results repository.List[models.ResultSummary]
+	require.Equal(t, http.StatusOK, s.do(t, http.MethodGet, "/api/results/", tokens.AccessToken, nil, &results))
+	assert.Len(t, results.Items, 1)
+	var progress models.UserProgress
+	require.NoError(t, s.db.Where("user_id = ? AND quiz_id = ?", user.ID, quizID).First(&progress).Error)
+	assert.Equal(t, 1, progress.TotalAttempts)
+
+	// Test case 2: Reusing the key for a different submission is refused
+	resp, refused := submit("attempt-1", tokens.AccessToken, 31)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	assert.Equal(t, "idempotency_key_reused", refused["code"])
+
+	// Test case 3: A new key submits again
+	resp, _ = submit("attempt-2", tokens.AccessToken, 30)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get("Idempotent-Replayed"))
+	require.Equal(t, http.StatusOK, s.do(t, http.MethodGet, "/api/results/", tokens.AccessToken, nil, &results))
+	assert.Len(t, results.Items, 2)
+
+	// Test case 4: Keys belong to a user, and are limited in length
+	resp, _ = submit("attempt-2", grace.AccessToken, 30)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get("Idempotent-Replayed"))
+	resp, tooLong := submit(strings.Repeat("k", 256), tokens.AccessToken, 30)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, "invalid_idempotency_key", tooLong["code"])
+
+	// Test case 5: Erasing the user forgets their responses
+	admin := s.login(t, adminEmail, adminPassword)
+	require.Equal(t, http.StatusNoContent, s.do(t, http.MethodPost, fmt.Sprintf("/api/admin/users/%d/erase", user.ID), admin.AccessToken, nil, nil))
+	var kept int64
+	require.NoError(t, s.db.Model(&models.IdempotencyKey{}).Where("user_id = ?", user.ID).Count(&kept).Error)
+	assert.Zero(t, kept)
+
+	// Test case 6: Keys are refused before logging in, rather than ignored
+	body, err := json.Marshal(map[string]string{"email": "alan@example.com", "password": "password123"})
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPost, s.URL+"/api/auth/register", bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", "register-1")
+	resp, err = s.Client().Do(req)
+	require.NoError(t, err)
+	var unsupported map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&unsupported))
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, "idempotency_key_unsupported", unsupported["code"])
+}
+
+func TestProfileImage(t *testing.T) {
+	s := newTestServer(t)
+	user, tokens := s.register(t, "ada@example.com")
+	prefix := services.ProfileImagePrefix(user.ID)
+
+	// Test case 1: An upload stores every variant and points the profile at one
+	var first services.UploadedImage
+	require.Equal(t, http.StatusCreated, s.upload(t, "/api/users/me/profile-image", tokens.AccessToken, testPNG(t), &first))
+	keys, err := s.blobs.List(context.Background(), prefix)
+	require.NoError(t, err)
+	assert.Len(t, keys, len(services.ProfileImageVariants))
+	var me models.User
+	require.Equal(t, http.StatusOK, s.do(t, http.MethodGet, "/api/users/me", tokens.AccessToken, nil, &me))
+	assert.Equal(t, first.URL, me.ProfileImage)
+
+	// Test case 2: A new upload replaces the previous variants
+	var second services.UploadedImage
+	require.Equal(t, http.StatusCreated, s.upload(t, "/api/users/me/profile-image", tokens.AccessToken, testPNG(t), &second))
+	assert.NotEqual(t, first.URL, second.URL)
+	keys, err = s.blobs.List(context.Background(), prefix)
+	require.NoError(t, err)
+	assert.Len(t, keys, len(services.ProfileImageVariants))
+	resp, err := s.Client().Get(s.URL + second.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, err = s.Client().Get(s.URL + first.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// Test case 3: Simultaneous uploads leave the profile pointing at stored variants, and only those
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Equal(t, http.StatusCreated, s.upload(t, "/api/users/me/profile-image", tokens.AccessToken, testPNG(t), nil))
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, http.StatusOK, s.do(t, http.MethodGet, "/api/users/me", tokens.AccessToken, nil, &me))
+	resp, err = s.Client().Get(s.URL + me.ProfileImage)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	keys, err = s.blobs.List(context.Background(), prefix)
+	require.NoError(t, err)
+	assert.Len(t, keys, len(services.ProfileImageVariants))
+
+	// Test case 4: The profile only points at uploads of the user's own, or nothing
+	_, grace := s.register(t, "grace@example.com")
+	for _, url := range []string{"https://evil.example.com/a.png", "javascript:alert(1)", me.ProfileImage} {
+		assert.Equal(t, http.StatusBadRequest, s.do(t, http.MethodPatch, "/api/users/me", grace.AccessToken,
+			map[string]string{"profile_image": url}, nil), url)
+	}
+	var uploaded services.UploadedImage
+	require.Equal(t, http.StatusCreated, s.upload(t, "/api/users/me/profile-image", tokens.AccessToken, testPNG(t), &uploaded))
+	require.Equal(t, http.StatusOK, s.do(t, http.MethodPatch, "/api/users/me", tokens.AccessToken,
+		map[string]string{"profile_image": uploaded.Variants["large"]}, &me))
+	assert.Equal(t, uploaded.Variants["large"], me.ProfileImage)
+	require.Equal(t, http.StatusOK, s.do(t, http.MethodPatch, "/api/users/me", tokens.AccessToken,
+		map[string]string{"profile_image": ""}, &me))
+	assert.Empty(t, me.ProfileImage)
+}
+
+func TestDataExport(t *testing.T) {
+	s := newTestServer(t)
+	user, tokens := s.register(t, "ada@example.com")
+	ctx := context.Background()
+
+	// Test case 1: An export is assembled in the background
+	var requested handlers.ExportResponse
+	require.Equal(t, http.StatusAccepted, s.do(t, http.MethodPost, "/api/users/me/export", tokens.AccessToken, nil, &requested))
+	s.svc.Export.Wait()
+	var export models.DataExport
+	require.NoError(t, s.db.First(&export, requested.Export.ID).Error)
+	require.Equal(t, models.DataExportReady, export.Status)
+	keys, err := s.blobs.List(ctx, services.ExportPrefix(user.ID))
+	require.NoError(t, err)
+	assert.Equal(t, []string{export.BlobKey}, keys)
+
+	// Test case 2: The sweeper deletes it once it expires
+	require.NoError(t, s.db.Model(&export).Update("expires_at", time.Now().Add(-time.Minute)).Error)
+	require.NoError(t, s.svc.Export.Sweep(ctx))
+	require.NoError(t, s.db.First(&export, export.ID).Error)
+	assert.Equal(t, models.DataExportExpired, export.Status)
+	keys, err = s.blobs.List(ctx, services.ExportPrefix(user.ID))
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	// Test case 3: An export a restart left pending is started again, once
+	abandoned := models.DataExport{UserID: user.ID, RequestedBy: user.ID, Status: models.DataExportPending}
+	require.NoError(t, s.db.Create(&abandoned).Error)
+	require.NoError(t, s.db.Model(&abandoned).UpdateColumn("updated_at", time.Now().Add(-time.Hour)).Error)
+	require.NoError(t, s.svc.Export.Sweep(ctx))
+	require.NoError(t, s.svc.Export.Sweep(ctx))
+	s.svc.Export.Wait()
+	require.NoError(t, s.db.First(&abandoned, abandoned.ID).Error)
+	assert.Equal(t, models.DataExportReady, abandoned.Status)
+	keys, err = s.blobs.List(ctx, services.ExportPrefix(user.ID))
+	require.NoError(t, err)
+	assert.Equal(t, []string{abandoned.BlobKey}, keys)
+
+	// Test case 4: Recent pending exports are left alone
+	running := models.DataExport{UserID: user.ID, RequestedBy: user.ID, Status: models.DataExportPending}
+	require.NoError(t, s.db.Create(&running).Error)
+	require.NoError(t, s.svc.Export.Sweep(ctx))
+	s.svc.Export.Wait()
+	require.NoError(t, s.db.First(&running, running.ID).Error)
+	assert.Equal(t, models.DataExportPending, running.Status)
+
+	// Test case 5: Erasing the user deletes their exports and images, files included
+	require.Equal(t, http.StatusCreated, s.upload(t, "/api/users/me/profile-image", tokens.AccessToken, testPNG(t), nil))
+	admin := s.login(t, adminEmail, adminPassword)
+	require.Equal(t, http.StatusNoContent, s.do(t, http.MethodPost, fmt.Sprintf("/api/admin/users/%d/erase", user.ID), admin.AccessToken, nil, nil))
+	assert.Equal(t, http.StatusForbidden, s.do(t, http.MethodGet, "/api/users/me", tokens.AccessToken, nil, nil))
+	var kept int64
+	require.NoError(t, s.db.Unscoped().Model(&models.DataExport{}).Where("user_id = ?", user.ID).Count(&kept).Error)
+	assert.Zero(t, kept)
+	for _, prefix := range []string{"data-exports/", "profile-images/"} {
+		keys, err = s.blobs.List(ctx, prefix)
+		require.NoError(t, err)
+		assert.Empty(t, keys, prefix)
+	}
+}
+
+func TestAdmin(t *testing.T) {
+	s := newTestServer(t)
+	user, tokens := s.register(t, "ada@example.com")
+	admin := s.login(t, adminEmail, adminPassword)
+
+	quiz := map[string]interface{}{
+		"title": "Admin Quiz", "description": "Made by the admin", "category": "history", "difficulty": "hard",
+		"time_limit": 5, "created_by": 1, "is_published": true, "passing_score": 50,
+		"questions": []map[string]interface{}{{
+			"text": "Year the Berlin Wall fell?", "type": "multiple_choice", "correct_answer": "1989", "points": 1,
+			"answers": []map[string]interface{}{{"text": "1989", "is_correct": true}, {"text": "1991"}},
+		}},
+	}
+
+	// Test case 1: Only admins create quizzes
+	assert.Equal(t, http.StatusForbidden, s.do(t, http.MethodPost, "/api/admin/quiz", tokens.AccessToken, quiz, nil))
+	var created models.Quiz
+	require.Equal(t, http.StatusCreated, s.do(t, http.MethodPost, "/api/admin/quiz", admin.AccessToken, quiz, &created))
+	require.NotZero(t, created.ID)
+
+	var fetched models.Quiz
+	require.Equal(t, http.StatusOK, s.do(t, http.MethodGet, fmt.Sprintf("/api/quiz/%d", created.ID), tokens.AccessToken, nil, &fetched))
+	require.Len(t, fetched.Questions, 1)
+	assert.Len(t, fetched.Questions[0].Answers, 2)
+
+	// Test case 2: Constraint violations are reported without the SQL behind them
+	quiz["created_by"] = 9999
+	var failure middleware.ErrorResponse
+	assert.Equal(t, http.StatusConflict, s.do(t, http.MethodPost, "/api/admin/quiz", admin.AccessToken, quiz, &failure))
+	assert.Equal(t, "invalid_reference", failure.Code)
+	assert.NotContains(t, strings.ToLower(failure.Error), "foreign key")
+
+	// Test case 3: Deactivated users can't log in any more, nor use the tokens they have
+	require.Equal(t, http.StatusOK, s.do(t, http.MethodGet, "/api/users/me", tokens.AccessToken, nil, nil))
+	require.Equal(t, http.StatusOK, s.do(t, http.MethodPost, fmt.Sprintf("/api/admin/users/%d/deactivate", user.ID), admin.AccessToken, nil, nil))
+	assert.Equal(t, http.StatusForbidden, s.do(t, http.MethodPost, "/api/auth/login", "", map[string]string{
+		"email": "ada@example.com", "password": "user-password",
+	}, nil))
+	assert.Equal(t, http.StatusForbidden, s.do(t, http.MethodGet, "/api/users/me", tokens.AccessToken, nil, nil))
+
+	// Test case 4: Searching users
+	var page repository.List[models.User]
+	require.Equal(t, http.StatusOK, s.do(t, http.MethodGet, "/api/admin/users?q=ADA", admin.AccessToken, nil, &page))
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, "ada@example.com", page.Items[0].Email)
+	require.Equal(t, http.StatusOK, s.do(t, http.MethodGet, "/api/admin/users?is_active=false&sort=-email", admin.AccessToken, nil, &page))
+	assert.Len(t, page.Items, 1)
+
+	// Quizzes can be filtered by author, publication, category and difficulty
+	var drafts repository.List[models.QuizSummary]
+	require.Equal(t, http.StatusOK, s.do(t, http.MethodGet, "/api/quiz/?published=true&created_by=1&category=history&difficulty=hard", admin.AccessToken, nil, &drafts))
+	require.Len(t, drafts.Items, 1)
+	assert.Equal(t, created.ID, drafts.Items[0].ID)
+
+	// Test case 5: Connection pool stats
+	var stats struct {
+		Pools []database.PoolStats `json:"pools"`
+	}
+	require.Equal(t, http.StatusOK, s.do(t, http.MethodGet, "/api/admin/db/stats", admin.AccessToken, nil, &stats))
+	require.Len(t, stats.Pools, 1)
+	assert.Equal(t, "primary", stats.Pools[0].Name)
+	assert.True(t, stats.Pools[0].Healthy)
+}
+
+func TestSeedDemo(t *testing.T) {
+	s := newTestServer(t)
+	seeder := seed.NewSeeder(s.db)
+	opts := seed.DemoOptions{Users: 5, Days: 30, Seed: 7, Now: time.Now()}
+
+	// Test case 1: Demo users, their history and the aggregates are created
+	report, err := seeder.SeedDemo(opts)
+	require.NoError(t, err)
+	assert.Equal(t, 5, report.Created["demo_users"])
+
+	var rankings []models.GlobalRanking
+	require.NoError(t, s.db.Where("ranking_period = ?", enums.RankingPeriodAllTime).Order("rank").Find(&rankings).Error)
+	require.Len(t, rankings, 5)
+	assert.Equal(t, 1, rankings[0].Rank)
+	assert.Equal(t, 100.0, rankings[0].Percentile)
+
+	var benchmarks int64
+	require.NoError(t, s.db.Model(&models.Benchmark{}).Count(&benchmarks).Error)
+	assert.NotZero(t, benchmarks)
+
+	// Test case 2: Running it again adds nothing
+	var results, again int64
+	require.NoError(t, s.db.Model(&models.Result{}).Count(&results).Error)
+	report, err = seeder.SeedDemo(opts)
+	require.NoError(t, err)
+	assert.Equal(t, 5, report.Skipped["demo_users"])
+	require.NoError(t, s.db.Model(&models.Result{}).Count(&again).Error)
+	assert.Equal(t, results, again)
+
+	// Test case 3: Demo users can log in
+	tokens := s.login(t, "demo-user-001@example.com", seed.DemoPassword)
+
+	// Test case 4: The leaderboard pages through the rankings by rank
+	var ranks []int
+	cursor := ""
+	for {
+		var leaderboard repository.List[models.LeaderboardEntry]
+		require.Equal(t, http.StatusOK, s.do(t, http.MethodGet, "/api/leaderboard?period=all_time&limit=2&cursor="+cursor, tokens.AccessToken, nil, &leaderboard))
+		for _, entry := range leaderboard.Items {
+			assert.NotEmpty(t, entry.Username)
+			ranks = append(ranks, entry.Rank)
+		}
+		if cursor = leaderboard.NextCursor; cursor == "" {
+			break
+		}
+	}
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, ranks)
+
+	var leaderboard repository.List[models.LeaderboardEntry]
+	require.Equal(t, http.StatusOK, s.do(t, http.MethodGet, "/api/leaderboard?category=math", tokens.AccessToken, nil, &leaderboard))
+	for _, entry := range leaderboard.Items {
+		assert.Equal(t, "math", entry.Category)
+	}
+	assert.Equal(t, http.StatusBadRequest, s.do(t, http.MethodGet, "/api/leaderboard?period=yearly", tokens.AccessToken, nil, nil))
+}
+
+func TestSearch(t *testing.T) {
+	s := newTestServer(t)
+	_, tokens := s.register(t, "ada@example.com")
+
+	// Test case 1: Quiz text and question text both match, with the words highlighted
+	var results services.SearchResults
+	require.Equal(t, http.StatusOK, s.do(t, http.MethodGet, "/api/search?q=chemistry", tokens.AccessToken, nil, &results))
+	require.NotEmpty(t, results.Hits)
+	assert.Equal(t, "quiz", results.Hits[0].Kind)
+	assert.Equal(t, "Science Basics", results.Hits[0].Title)
+	assert.Contains(t, results.Hits[0].Snippet, "<mark>chemistry</mark>")
+	assert.Empty(t, results.Suggestions)
+
+	var planets services.SearchResults
+	require.Equal(t, http.StatusOK, s.do(t, http.MethodGet, "/api/search?q=planets", tokens.AccessToken, nil, &planets))
+	require.NotEmpty(t, planets.Hits)
+	assert.Equal(t, "question", planets.Hits[0].Kind)
+	assert.NotZero(t, planets.Hits[0].QuestionID)
+	assert.Contains(t, planets.Hits[0].Snippet, "<mark>Planet</mark>")
+
+	// Test case 2: Filters narrow the hits down
+	var filtered services.SearchResults
+	require.Equal(t, http.StatusOK, s.do(t, http.MethodGet, "/api/search?q=planets&category=math", tokens.AccessToken, nil, &filtered))
+	assert.Empty(t, filtered.Hits)
+
+	// Test case 3: A typo gets a suggestion instead of hits
+	var typo services.SearchResults
+	require.Equal(t, http.StatusOK, s.do(t, http.MethodGet, "/api/search?q=chemestry", tokens.AccessToken, nil, &typo))
+	assert.Empty(t, typo.Hits)
+	assert.Contains(t, typo.Suggestions, "chemistry")
+
+	// Test case 4: A new quiz is found without restarting
+	admin := s.login(t, adminEmail, adminPassword)
+	require.Equal(t, http.StatusCreated, s.do(t, http.MethodPost, "/api/admin/quiz", admin.AccessToken, map[string]interface{}{
+		"title": "Volcanoes", "description": "Eruptions and magma", "category": "science", "difficulty": "easy",
+		"time_limit": 5, "created_by": 1, "is_published": true, "passing_score": 50,
+		"questions": []map[string]interface{}{{
+			"text": "What is magma called above ground?", "type": "multiple_choice", "correct_answer": "Lava", "points": 1,
+			"answers": []map[string]interface{}{{"text": "Lava", "is_correct": true}, {"text": "Ash"}},
+		}},
+	}, nil))
+	var volcanoes services.SearchResults
+	require.Equal(t, http.StatusOK, s.do(t, http.MethodGet, "/api/search?q=magma", tokens.AccessToken, nil, &volcanoes))
+	require.Len(t, volcanoes.Hits, 2)
+	assert.Equal(t, "quiz", volcanoes.Hits[0].Kind)
+	assert.Equal(t, "Volcanoes", volcanoes.Hits[0].Title)
+
+	// Test case 5: A query is required
+	assert.Equal(t, http.StatusBadRequest, s.do(t, http.MethodGet, "/api/search?q=+", tokens.AccessToken, nil, nil))
+	assert.Equal(t, http.StatusUnauthorized, s.do(t, http.MethodGet, "/api/search?q=planets", "", nil, nil))
+}
+
+func TestProbes(t *testing.T) {
+	s := newTestServer(t)
+
+	// Test case 1: A migrated database is ready
+	var ready health.Result
+	assert.Equal(t, http.StatusOK, s.do(t, http.MethodGet, "/readyz", "", nil, &ready))
+	assert.Equal(t, map[string]string{"database": "ok", "migrations": "ok"}, ready.Checks)
+
+	var live struct {
+		Status  string       `json:"status"`
+		Version version.Info `json:"version"`
+	}
+	assert.Equal(t, http.StatusOK, s.do(t, http.MethodGet, "/livez", "", nil, &live))
+	assert.Equal(t, "ok", live.Status)
+	assert.NotEmpty(t, live.Version.Version)
+
+	// Test case 2: A pending migration makes it unready, but still live
+	migrator, err := database.NewMigrator(s.db)
+	require.NoError(t, err)
+	_, err = migrator.Down(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, s.do(t, http.MethodGet, "/readyz", "", nil, &ready))
+	assert.False(t, ready.Ready)
+	assert.Equal(t, "ok", ready.Checks["database"])
+	assert.NotEqual(t, "ok", ready.Checks["migrations"])
+	assert.Equal(t, http.StatusOK, s.do(t, http.MethodGet, "/livez", "", nil, nil))
+}
+
+func TestMetrics(t *testing.T) {
+	s := newTestServer(t)
+	s.register(t, "ada@example.com")
+	assert.Equal(t, http.StatusUnauthorized, s.do(t, http.MethodPost, "/api/auth/login", "",
+		map[string]string{"email": "ada@example.com", "password": "wrong-password"}, nil))
+
+	resp, err := s.Client().Get(s.URL + "/metrics")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	// Logins, requests and the connection pool of the real database are all reported
+	assert.Contains(t, string(body), `aicg_logins_total{provider="email",result="success"}`)
+	assert.Contains(t, string(body), `aicg_logins_total{provider="email",result="failure"}`)
+	assert.Contains(t, string(body), `aicg_http_requests_total{method="POST",route="/api/auth/register",status="201"}`)
+	assert.Contains(t, string(body), `aicg_db_pool_healthy{pool="primary"} 1`)
+}
+
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	s := newTestServer(t, func(cfg *config.Config) { cfg.Tracing.Exporter = "stdout" })
+	_, tokens := s.register(t, "ada@example.com")
+	var quizzes repository.List[models.QuizSummary]
+	require.Equal(t, http.StatusOK, s.do(t, http.MethodGet, "/api/quiz/?sort=created_at", tokens.AccessToken, nil, &quizzes))
+
+	// Submit as part of a trace started by the caller
+	body, err := json.Marshal(map[string]interface{}{"answers": []map[string]interface{}{{"question_id": 1, "answer": "x"}}, "time_taken": 5})
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/api/quiz/%d/submit", s.URL, quizzes.Items[0].ID), bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	resp, err := s.Client().Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	var queries int
+	for _, span := range recorder.Ended() {
+		if span.SpanContext().TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			continue
+		}
+		if span.SpanKind() == trace.SpanKindClient {
+			queries++ // named after the statement, e.g. "select quizzes"
+		}
+		spans[span.Name()] = span
+	}
+
+	// Test case 1: The request continues the caller's trace
+	request, ok := spans["/api/quiz/:id/submit"]
+	require.True(t, ok, "no request span in %v", spans)
+	assert.Equal(t, "00f067aa0ba902b7", request.Parent().SpanID().String())
+
+	// Test case 2: Service calls are children of the request, and queries run under them
+	service, ok := spans["QuizService.SubmitQuizResult"]
+	require.True(t, ok, "no service span in %v", spans)
+	assert.Equal(t, request.SpanContext().SpanID(), spans["QuizHandler.grade"].Parent().SpanID())
+	assert.Equal(t, service.SpanContext().SpanID(), spans["QuizService.saveResultAndProgress"].Parent().SpanID())
+	assert.Greater(t, queries, 2)
+
+	// Test case 3: Queries are traced without their values
+	for _, span := range recorder.Ended() {
+		for _, attr := range span.Attributes() {
+			if attr.Key == "db.statement" {
+				assert.NotContains(t, attr.Value.AsString(), "ada@example.com")
+			}
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"aicg/internal/config"
+	"aicg/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// lines decodes the JSON records written to buf
+func lines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(&buf, config.LogConfig{Level: "info", Format: "json"})
+
+	// Test case 1: Records carry the request ID from the context
+	ctx := WithRequestID(context.Background(), "req-1")
+	log.InfoContext(ctx, "Hello", "user_id", 7)
+	records := lines(t, &buf)
+	require.Len(t, records, 1)
+	assert.Equal(t, "Hello", records[0]["msg"])
+	assert.Equal(t, "req-1", records[0]["request_id"])
+	assert.EqualValues(t, 7, records[0]["user_id"])
+	assert.NotContains(t, records[0], "trace_id")
+
+	// Test case 2: Records inside a span carry its trace and span IDs
+	buf.Reset()
+	ctx = trace.ContextWithSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{0x4b, 0xf9},
+		SpanID:  trace.SpanID{0x01},
+	}))
+	log.InfoContext(ctx, "Traced")
+	records = lines(t, &buf)
+	require.Len(t, records, 1)
+	assert.Equal(t, "4bf90000000000000000000000000000", records[0]["trace_id"])
+	assert.Equal(t, "0100000000000000", records[0]["span_id"])
+
+	// Test case 3: Records below the level are dropped
+	buf.Reset()
+	log.Debug("Too chatty")
+	assert.Empty(t, buf.String())
+
+	// Test case 4: Sensitive attributes are redacted, also inside groups
+	buf.Reset()
+	log.Info("Login",
+		"password", "hunter2",
+		"refresh_token", "rt",
+		slog.Group("request", "Authorization", "Bearer abc", "path", "/api/auth/login"),
+	)
+	out := buf.String()
+	assert.NotContains(t, out, "hunter2")
+	assert.NotContains(t, out, `"rt"`)
+	assert.NotContains(t, out, "Bearer abc")
+	record := lines(t, &buf)[0]
+	assert.Equal(t, Redacted, record["password"])
+	assert.Equal(t, "/api/auth/login", record["request"].(map[string]interface{})["path"])
+
+	// Test case 5: Models leave out their secrets, in either format
+	for _, format := range []string{"json", "text"} {
+		buf.Reset()
+		log := New(&buf, config.LogConfig{Level: "info", Format: format})
+		user := models.User{Email: "ada@example.com", PasswordHash: "$2a$10$hash", RefreshToken: "refresh"}
+		log.Info("User", "user", user, "sso", models.SSOConfig{ClientID: "id", ClientSecret: "client-secret"})
+		assert.NotContains(t, buf.String(), "$2a$10$hash", format)
+		assert.NotContains(t, buf.String(), "refresh", format)
+		assert.NotContains(t, buf.String(), "ada@example.com", format)
+		assert.NotContains(t, buf.String(), "client-secret", format)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	// Test case 1: No request ID outside of a request
+	assert.Empty(t, RequestID(context.Background()))
+
+	// Test case 2: The ID round-trips through the context
+	assert.Equal(t, "abc", RequestID(WithRequestID(context.Background(), "abc")))
+}
+
+func TestIsSensitive(t *testing.T) {
+	for _, key := range []string{"password", "PasswordHash", "refresh_token", "client-secret", "Authorization"} {
+		assert.True(t, IsSensitive(key), key)
+	}
+	for _, key := range []string{"email", "user_id", "path"} {
+		assert.False(t, IsSensitive(key), key)
+	}
+}
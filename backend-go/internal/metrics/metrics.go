@@ -0,0 +1,161 @@
+// Package metrics exposes the application's Prometheus metrics. The
+// collectors live in Registry; the rest of the code records into them
+// through the Observe functions.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"aicg/internal/database"
+	"aicg/internal/models/enums"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "aicg"
+
+// UnmatchedRoute labels requests that matched no route, so that scanners
+// probing random paths don't create a series per path
+const UnmatchedRoute = "unmatched"
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests served, by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time taken to serve HTTP requests, by method, route template and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	quizSubmissions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "quiz_submissions_total",
+		Help:      "Quiz results saved, by quiz category and difficulty and whether the quiz was passed.",
+	}, []string{"category", "difficulty", "passed"})
+
+	logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logins_total",
+		Help:      "Login attempts, by auth provider and result (success or failure).",
+	}, []string{"provider", "result"})
+
+	jobDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "job_duration_seconds",
+		Help:      "Time taken by background jobs, by job and result (success or failure).",
+		Buckets:   prometheus.ExponentialBuckets(0.05, 2, 12), // 50ms to ~100s
+	}, []string{"job", "result"})
+)
+
+// Registry holds the application's collectors, plus the Go runtime and process metrics
+var Registry = prometheus.NewRegistry()
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		quizSubmissions,
+		logins,
+		jobDuration,
+	)
+}
+
+// Handler serves the metrics in the Prometheus text format. If stats is set,
+// the database connection pools are reported too, as of each scrape.
+func Handler(stats func() ([]database.PoolStats, error)) http.Handler {
+	gatherers := prometheus.Gatherers{Registry}
+	if stats != nil {
+		pools := prometheus.NewRegistry()
+		pools.MustRegister(poolCollector{stats: stats})
+		gatherers = append(gatherers, pools)
+	}
+	return promhttp.HandlerFor(gatherers, promhttp.HandlerOpts{})
+}
+
+// ObserveHTTPRequest records a served request. route is the route template,
+// such as /api/quiz/:id, or UnmatchedRoute.
+func ObserveHTTPRequest(method, route string, status int, elapsed time.Duration) {
+	code := strconv.Itoa(status)
+	httpRequests.WithLabelValues(method, route, code).Inc()
+	httpDuration.WithLabelValues(method, route, code).Observe(elapsed.Seconds())
+}
+
+// ObserveQuizSubmission records a saved quiz result
+func ObserveQuizSubmission(category enums.QuizCategory, difficulty enums.QuizDifficulty, passed bool) {
+	quizSubmissions.WithLabelValues(string(category), string(difficulty), strconv.FormatBool(passed)).Inc()
+}
+
+// ObserveLogin records a login attempt through provider
+func ObserveLogin(provider enums.AuthProvider, err error) {
+	logins.WithLabelValues(string(provider), result(err)).Inc()
+}
+
+// ObserveJob records a finished background job
+func ObserveJob(job string, elapsed time.Duration, err error) {
+	jobDuration.WithLabelValues(job, result(err)).Observe(elapsed.Seconds())
+}
+
+func result(err error) string {
+	if err != nil {
+		return "failure"
+	}
+	return "success"
+}
+
+// poolCollector reports the connection pools of the primary and the replicas
+type poolCollector struct {
+	stats func() ([]database.PoolStats, error)
+}
+
+var (
+	poolLabels      = []string{"pool"}
+	poolHealthy     = prometheus.NewDesc(namespace+"_db_pool_healthy", "Whether the database answered its last health check (1) or not (0).", poolLabels, nil)
+	poolMaxOpen     = prometheus.NewDesc(namespace+"_db_pool_max_open_connections", "Maximum number of open connections to the database.", poolLabels, nil)
+	poolOpen        = prometheus.NewDesc(namespace+"_db_pool_open_connections", "Established connections, in use or idle.", poolLabels, nil)
+	poolInUse       = prometheus.NewDesc(namespace+"_db_pool_in_use_connections", "Connections currently in use.", poolLabels, nil)
+	poolIdle        = prometheus.NewDesc(namespace+"_db_pool_idle_connections", "Idle connections.", poolLabels, nil)
+	poolWaits       = prometheus.NewDesc(namespace+"_db_pool_wait_count_total", "Times a query waited for a free connection.", poolLabels, nil)
+	poolWaitSeconds = prometheus.NewDesc(namespace+"_db_pool_wait_duration_seconds_total", "Total time spent waiting for a free connection.", poolLabels, nil)
+	poolClosed      = prometheus.NewDesc(namespace+"_db_pool_closed_connections_total", "Connections closed by the pool, by reason.", []string{"pool", "reason"}, nil)
+)
+
+func (c poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{poolHealthy, poolMaxOpen, poolOpen, poolInUse, poolIdle, poolWaits, poolWaitSeconds, poolClosed} {
+		ch <- desc
+	}
+}
+
+func (c poolCollector) Collect(ch chan<- prometheus.Metric) {
+	pools, err := c.stats()
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(poolOpen, err)
+		return
+	}
+	for _, p := range pools {
+		healthy := 0.0
+		if p.Healthy {
+			healthy = 1
+		}
+		ch <- prometheus.MustNewConstMetric(poolHealthy, prometheus.GaugeValue, healthy, p.Name)
+		ch <- prometheus.MustNewConstMetric(poolMaxOpen, prometheus.GaugeValue, float64(p.MaxOpenConnections), p.Name)
+		ch <- prometheus.MustNewConstMetric(poolOpen, prometheus.GaugeValue, float64(p.OpenConnections), p.Name)
+		ch <- prometheus.MustNewConstMetric(poolInUse, prometheus.GaugeValue, float64(p.InUse), p.Name)
+		ch <- prometheus.MustNewConstMetric(poolIdle, prometheus.GaugeValue, float64(p.Idle), p.Name)
+		ch <- prometheus.MustNewConstMetric(poolWaits, prometheus.CounterValue, float64(p.WaitCount), p.Name)
+		ch <- prometheus.MustNewConstMetric(poolWaitSeconds, prometheus.CounterValue, float64(p.WaitDurationMS)/1000, p.Name)
+		ch <- prometheus.MustNewConstMetric(poolClosed, prometheus.CounterValue, float64(p.MaxIdleClosed), p.Name, "max_idle")
+		ch <- prometheus.MustNewConstMetric(poolClosed, prometheus.CounterValue, float64(p.MaxIdleTimeClosed), p.Name, "max_idle_time")
+		ch <- prometheus.MustNewConstMetric(poolClosed, prometheus.CounterValue, float64(p.MaxLifetimeClosed), p.Name, "max_lifetime")
+	}
+}
@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"errors"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"aicg/internal/database"
+	"aicg/internal/models/enums"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scrape returns what Prometheus would see from the handler
+func scrape(t *testing.T, stats func() ([]database.PoolStats, error)) string {
+	rr := httptest.NewRecorder()
+	Handler(stats).ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+	require.Equal(t, 200, rr.Code)
+	body, err := io.ReadAll(rr.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestObserve(t *testing.T) {
+	// Test case 1: Requests are counted and timed by route template and status
+	before := testutil.ToFloat64(httpRequests.WithLabelValues("GET", "/api/quiz/:id", "200"))
+	ObserveHTTPRequest("GET", "/api/quiz/:id", 200, 30*time.Millisecond)
+	assert.Equal(t, before+1, testutil.ToFloat64(httpRequests.WithLabelValues("GET", "/api/quiz/:id", "200")))
+
+	// Test case 2: Quiz submissions are labeled with whether they passed
+	before = testutil.ToFloat64(quizSubmissions.WithLabelValues("math", "hard", "false"))
+	ObserveQuizSubmission(enums.CategoryMath, enums.DifficultyHard, false)
+	assert.Equal(t, before+1, testutil.ToFloat64(quizSubmissions.WithLabelValues("math", "hard", "false")))
+
+	// Test case 3: Logins are split into successes and failures
+	before = testutil.ToFloat64(logins.WithLabelValues("google", "failure"))
+	ObserveLogin(enums.ProviderGoogle, errors.New("account is deactivated"))
+	assert.Equal(t, before+1, testutil.ToFloat64(logins.WithLabelValues("google", "failure")))
+
+	// Test case 4: Everything shows up in the scrape, with the runtime metrics
+	ObserveJob("data_export", 2*time.Second, nil)
+	body := scrape(t, nil)
+	assert.Contains(t, body, `aicg_http_request_duration_seconds_bucket{method="GET",route="/api/quiz/:id",status="200",le="0.05"}`)
+	assert.Contains(t, body, `aicg_job_duration_seconds_count{job="data_export",result="success"}`)
+	assert.Contains(t, body, "go_goroutines")
+	assert.NotContains(t, body, "aicg_db_pool")
+}
+
+func TestPoolMetrics(t *testing.T) {
+	stats := func() ([]database.PoolStats, error) {
+		return []database.PoolStats{
+			{Name: "primary", Healthy: true, MaxOpenConnections: 100, OpenConnections: 7, InUse: 2, Idle: 5, WaitCount: 3, WaitDurationMS: 1500},
+			{Name: "replica-1", Healthy: false},
+		}, nil
+	}
+
+	// Test case 1: Each pool is reported under its name
+	body := scrape(t, stats)
+	assert.Contains(t, body, `aicg_db_pool_open_connections{pool="primary"} 7`)
+	assert.Contains(t, body, `aicg_db_pool_in_use_connections{pool="primary"} 2`)
+	assert.Contains(t, body, `aicg_db_pool_wait_duration_seconds_total{pool="primary"} 1.5`)
+	assert.Contains(t, body, `aicg_db_pool_healthy{pool="replica-1"} 0`)
+	assert.Contains(t, body, `aicg_db_pool_closed_connections_total{pool="primary",reason="max_lifetime"} 0`)
+
+	// Test case 2: Failing to read the pools fails the scrape instead of reporting zeros
+	rr := httptest.NewRecorder()
+	Handler(func() ([]database.PoolStats, error) { return nil, errors.New("database closed") }).
+		ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, 500, rr.Code)
+}
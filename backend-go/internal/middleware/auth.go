@@ -1,71 +1,92 @@
 package middleware
 
 import (
-	"net/http"
+	"context"
 	"strings"
 
 	"aicg/internal/config"
-	"aicg/internal/models"
+	"aicg/internal/models/enums"
+	"aicg/internal/services"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// Errors for requests that fail authentication or authorization
+var (
+	errMissingToken            = services.Unauthorized("missing_token", "authorization header is required")
+	errMalformedAuthHeader     = services.Unauthorized("invalid_authorization_header", "invalid authorization header format")
+	errInvalidToken            = services.Unauthorized("invalid_token", "invalid token")
+	errInvalidClaims           = services.Unauthorized("invalid_token", "invalid token claims")
+	errMissingRole             = services.Unauthorized("missing_role", "user role not found")
+	errInsufficientPermissions = services.Forbidden("insufficient_permissions", "insufficient permissions")
+)
+
+// ActiveUsers tells whether the user a token was issued to may still use it
+type ActiveUsers interface {
+	CheckActive(ctx context.Context, userID uint) error
+}
+
 // AuthMiddleware checks if users are logged in and have permission
 // to access different parts of the application
 type AuthMiddleware struct {
 	config *config.Config
+	users  ActiveUsers
 }
 
 // NewAuthMiddleware creates a new auth middleware with the app config
-func NewAuthMiddleware(cfg *config.Config) *AuthMiddleware {
-	return &AuthMiddleware{config: cfg}
+func NewAuthMiddleware(cfg *config.Config, users ActiveUsers) *AuthMiddleware {
+	return &AuthMiddleware{config: cfg, users: users}
 }
 
 // AuthRequired makes sure the user is logged in before accessing a route
-// It checks for a valid JWT token in the Authorization header
+// It checks for a valid JWT token in the Authorization header, issued to a
+// user who is still active, so deactivated, deleted and erased users are
+// locked out right away rather than when their token expires
 func (m *AuthMiddleware) AuthRequired() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Look for the Authorization header
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
-			c.Abort()
+			abortWithError(c, errMissingToken)
 			return
 		}
 
 		// Make sure it's a Bearer token
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header format"})
-			c.Abort()
+			abortWithError(c, errMalformedAuthHeader)
 			return
 		}
 
 		// Check if the token is valid
 		tokenString := parts[1]
 		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			return []byte(m.config.JWTSecret), nil
+			return []byte(m.config.JWT.Secret), nil
 		})
 
 		if err != nil || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-			c.Abort()
+			abortWithError(c, errInvalidToken)
 			return
 		}
 
 		// Get the user information from the token
 		claims, ok := token.Claims.(jwt.MapClaims)
 		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
-			c.Abort()
+			abortWithError(c, errInvalidClaims)
+			return
+		}
+
+		userID := uint(claims["sub"].(float64))
+		if err := m.users.CheckActive(c.Request.Context(), userID); err != nil {
+			abortWithError(c, err)
 			return
 		}
 
 		// Save user info so other parts of the app can use it
-		c.Set("userID", uint(claims["sub"].(float64)))
+		c.Set("userID", userID)
 		c.Set("userEmail", claims["email"].(string))
-		c.Set("userRole", models.UserRole(claims["role"].(string)))
+		c.Set("userRole", enums.UserRole(claims["role"].(string)))
 
 		c.Next()
 	}
@@ -73,18 +94,17 @@ func (m *AuthMiddleware) AuthRequired() gin.HandlerFunc {
 
 // RequireRole checks if the user has one of the required roles
 // If not, they can't access the route
-func (m *AuthMiddleware) RequireRole(roles ...models.UserRole) gin.HandlerFunc {
+func (m *AuthMiddleware) RequireRole(roles ...enums.UserRole) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get the user's role from the context
 		userRole, exists := c.Get("userRole")
 		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "User role not found"})
-			c.Abort()
+			abortWithError(c, errMissingRole)
 			return
 		}
 
 		// Check if the user's role matches any of the required roles
-		role := userRole.(models.UserRole)
+		role := userRole.(enums.UserRole)
 		for _, requiredRole := range roles {
 			if role == requiredRole {
 				c.Next()
@@ -93,13 +113,12 @@ func (m *AuthMiddleware) RequireRole(roles ...models.UserRole) gin.HandlerFunc {
 		}
 
 		// If we get here, the user doesn't have permission
-		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
-		c.Abort()
+		abortWithError(c, errInsufficientPermissions)
 	}
 }
 
 // RequireSuperAdmin is a shortcut to check if user is a super admin
 // Only super admins can access routes with this middleware
 func (m *AuthMiddleware) RequireSuperAdmin() gin.HandlerFunc {
-	return m.RequireRole(models.RoleSuperAdmin)
+	return m.RequireRole(enums.RoleSuperAdmin)
 }
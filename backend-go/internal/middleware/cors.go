@@ -2,44 +2,193 @@ package middleware
 
 import (
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"aicg/internal/config"
+
+	"github.com/gin-gonic/gin"
 )
 
-// CORS middleware to handle cross-origin requests
-func CORS(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Get the origin from the request
-		origin := r.Header.Get("Origin")
+// corsPolicy is a resolved CORS configuration, for the whole API or for the
+// paths of one route override
+type corsPolicy struct {
+	anyOrigin   bool
+	origins     map[string]bool // Exact origins
+	suffixes    []string        // Wildcard origins, as scheme://.domain, e.g. "https://.example.com"
+	methods     map[string]bool
+	headers     map[string]bool // Canonical header names
+	credentials bool
+
+	allowMethods  string
+	allowHeaders  string
+	exposeHeaders string
+	maxAge        string
+}
+
+// corsRoute is a policy applying to the paths under prefix
+type corsRoute struct {
+	prefix string
+	policy *corsPolicy
+}
 
-		// List of allowed origins
-		allowedOrigins := []string{
-			"http://localhost:3000", // Frontend development
-			"http://localhost:8080", // Backend development
+// CORS lets the browser origins in cfg call the API, with the policy of the
+// longest matching route override, if any. Preflight requests are answered
+// here: 204 with the allowed methods and headers, or 403 when the origin,
+// method or a header isn't allowed. Other requests from a disallowed origin
+// are served without CORS headers, so the browser hides the response.
+func CORS(cfg config.CORSConfig) gin.HandlerFunc {
+	base := newCORSPolicy(cfg, cfg.AllowedOrigins, cfg.AllowedMethods, cfg.AllowedHeaders, cfg.AllowCredentials)
+	routes := make([]corsRoute, 0, len(cfg.Routes))
+	for _, route := range cfg.Routes {
+		origins, methods, headers, credentials := cfg.AllowedOrigins, cfg.AllowedMethods, cfg.AllowedHeaders, cfg.AllowCredentials
+		if route.AllowedOrigins != nil {
+			origins = route.AllowedOrigins
 		}
+		if route.AllowedMethods != nil {
+			methods = route.AllowedMethods
+		}
+		if route.AllowedHeaders != nil {
+			headers = route.AllowedHeaders
+		}
+		if route.AllowCredentials != nil {
+			credentials = *route.AllowCredentials
+		}
+		routes = append(routes, corsRoute{
+			prefix: route.Path,
+			policy: newCORSPolicy(cfg, origins, methods, headers, credentials),
+		})
+	}
+	// Longest prefix first, so the most specific override wins
+	sort.SliceStable(routes, func(i, j int) bool { return len(routes[i].prefix) > len(routes[j].prefix) })
 
-		// Check if the origin is allowed
-		isAllowed := false
-		for _, allowed := range allowedOrigins {
-			if origin == allowed {
-				isAllowed = true
+	return func(c *gin.Context) {
+		policy := base
+		for _, route := range routes {
+			if strings.HasPrefix(c.Request.URL.Path, route.prefix) {
+				policy = route.policy
 				break
 			}
 		}
+		policy.handle(c)
+	}
+}
+
+func newCORSPolicy(cfg config.CORSConfig, origins, methods, headers []string, credentials bool) *corsPolicy {
+	p := &corsPolicy{
+		origins:       map[string]bool{},
+		methods:       map[string]bool{},
+		headers:       map[string]bool{},
+		credentials:   credentials,
+		allowMethods:  strings.Join(methods, ", "),
+		allowHeaders:  strings.Join(headers, ", "),
+		exposeHeaders: strings.Join(cfg.ExposedHeaders, ", "),
+	}
+	for _, origin := range origins {
+		switch {
+		case origin == "*":
+			p.anyOrigin = true
+		case strings.Contains(origin, "://*."):
+			p.suffixes = append(p.suffixes, strings.Replace(origin, "://*.", "://.", 1))
+		default:
+			p.origins[strings.ToLower(origin)] = true
+		}
+	}
+	for _, method := range methods {
+		p.methods[strings.ToUpper(method)] = true
+	}
+	for _, header := range headers {
+		p.headers[http.CanonicalHeaderKey(header)] = true
+	}
+	if cfg.MaxAge > 0 {
+		p.maxAge = strconv.Itoa(int(cfg.MaxAge.Seconds()))
+	}
+	return p
+}
+
+// allowsOrigin reports whether origin may call the API. A wildcard matches
+// the subdomains at any depth, but not the domain itself.
+func (p *corsPolicy) allowsOrigin(origin string) bool {
+	origin = strings.ToLower(origin)
+	if p.anyOrigin || p.origins[origin] {
+		return true
+	}
+	for _, suffix := range p.suffixes {
+		scheme, domain, _ := strings.Cut(suffix, "://")
+		host, ok := strings.CutPrefix(origin, scheme+"://")
+		if ok && strings.HasSuffix(host, domain) && len(host) > len(domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsHeaders reports whether every header of an Access-Control-Request-Headers list is allowed
+func (p *corsPolicy) allowsHeaders(list string) bool {
+	for _, header := range strings.Split(list, ",") {
+		header = strings.TrimSpace(header)
+		if header != "" && !p.headers[http.CanonicalHeaderKey(header)] {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *corsPolicy) handle(c *gin.Context) {
+	h := c.Writer.Header()
+	preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+	if !p.anyOrigin {
+		// The answer depends on the origin, so caches must keep one per origin
+		h.Add("Vary", "Origin")
+	}
+	if preflight {
+		h.Add("Vary", "Access-Control-Request-Method")
+		h.Add("Vary", "Access-Control-Request-Headers")
+	}
 
-		// Set CORS headers
-		if isAllowed {
-			w.Header().Set("Access-Control-Allow-Origin", origin)
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-			w.Header().Set("Access-Control-Allow-Credentials", "true")
+	origin := c.GetHeader("Origin")
+	if origin == "" {
+		c.Next()
+		return
+	}
+	if !p.allowsOrigin(origin) {
+		if preflight {
+			c.AbortWithStatus(http.StatusForbidden)
+			return
 		}
+		c.Next()
+		return
+	}
 
-		// Handle preflight requests
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
+	if p.anyOrigin && !p.credentials {
+		h.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		h.Set("Access-Control-Allow-Origin", origin)
+	}
+	if p.credentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+
+	if preflight {
+		if !p.methods[strings.ToUpper(c.GetHeader("Access-Control-Request-Method"))] ||
+			!p.allowsHeaders(c.GetHeader("Access-Control-Request-Headers")) {
+			c.AbortWithStatus(http.StatusForbidden)
 			return
 		}
+		h.Set("Access-Control-Allow-Methods", p.allowMethods)
+		if p.allowHeaders != "" {
+			h.Set("Access-Control-Allow-Headers", p.allowHeaders)
+		}
+		if p.maxAge != "" {
+			h.Set("Access-Control-Max-Age", p.maxAge)
+		}
+		c.AbortWithStatus(http.StatusNoContent)
+		return
+	}
 
-		// Call the next handler
-		next.ServeHTTP(w, r)
-	})
+	if p.exposeHeaders != "" {
+		h.Set("Access-Control-Expose-Headers", p.exposeHeaders)
+	}
+	c.Next()
 }
@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"aicg/internal/repository"
+	"aicg/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ProblemContentType is the media type of RFC 7807 problem details, which
+// clients get instead of the default error body by accepting it
+const ProblemContentType = "application/problem+json"
+
+// ErrorResponse is the body of every error response:
+//
+//	{
+//	  "error": "Quiz not found",
+//	  "code": "quiz_not_found",
+//	  "details": [{"field": "email", "code": "required", "message": "is required"}],
+//	  "request_id": "4bf92f3577b34da6a3ce929d0e0e4736"
+//	}
+//
+// Programs should branch on code, which is stable, not on the message.
+// Details are only set for validation errors.
+type ErrorResponse struct {
+	Error     string                `json:"error"`
+	Code      string                `json:"code"`
+	Details   []services.FieldError `json:"details,omitempty"`
+	RequestID string                `json:"request_id,omitempty"`
+}
+
+// Problem is the RFC 7807 form of ErrorResponse. Type is always
+// "about:blank", so Title is the status text; code tells errors apart.
+type Problem struct {
+	Type      string                `json:"type"`
+	Title     string                `json:"title"`
+	Status    int                   `json:"status"`
+	Detail    string                `json:"detail"`
+	Instance  string                `json:"instance"`
+	Code      string                `json:"code"`
+	Errors    []services.FieldError `json:"errors,omitempty"`
+	RequestID string                `json:"request_id,omitempty"`
+}
+
+// kindStatus is the HTTP status of each kind of domain error
+var kindStatus = map[services.Kind]int{
+	services.KindValidation:       http.StatusBadRequest,
+	services.KindUnauthorized:     http.StatusUnauthorized,
+	services.KindForbidden:        http.StatusForbidden,
+	services.KindNotFound:         http.StatusNotFound,
+	services.KindConflict:         http.StatusConflict,
+	services.KindGone:             http.StatusGone,
+	services.KindTooLarge:         http.StatusRequestEntityTooLarge,
+	services.KindUnsupportedMedia: http.StatusUnsupportedMediaType,
+	services.KindRateLimited:      http.StatusTooManyRequests,
+}
+
+var (
+	// errInternal replaces errors that aren't domain errors, whose messages may reveal internals
+	errInternal = &services.Error{Kind: services.KindInternal, Code: "internal_error", Message: "internal server error"}
+	// errRouteNotFound is reported for paths no route matches
+	errRouteNotFound = services.NotFound("route_not_found", "no such endpoint")
+)
+
+// Errors writes the response for the last error a handler or middleware
+// reported with c.Error, unless a response was already written. Domain
+// errors get the status of their kind and their code and message; anything
+// else is a 500 whose details only go to the logs.
+func Errors() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		writeError(c, c.Errors.Last().Err)
+	}
+}
+
+// NoRoute reports requests for paths without a route in the error format
+func NoRoute() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		_ = c.Error(errRouteNotFound)
+	}
+}
+
+// abortWithError stops the chain and leaves err for Errors to report
+func abortWithError(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+}
+
+// classify returns the domain error to report for err. Missing records and
+// constraint violations that no service translated still get a fitting status.
+func classify(err error) *services.Error {
+	if e, ok := services.AsError(err); ok {
+		return e
+	}
+	switch {
+	case errors.Is(err, repository.ErrNotFound), errors.Is(err, gorm.ErrRecordNotFound):
+		return services.NotFound("not_found", "record not found")
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return services.Conflict("duplicate", "a record with the same unique values already exists")
+	case errors.Is(err, gorm.ErrForeignKeyViolated):
+		return services.Conflict("invalid_reference", "refers to a record that doesn't exist or is still in use")
+	}
+	return errInternal
+}
+
+// writeError writes the error response for err, as problem details if the client asked for them
+func writeError(c *gin.Context, err error) {
+	e := classify(err)
+	status, ok := kindStatus[e.Kind]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	message := capitalize(e.Message)
+
+	if strings.Contains(c.GetHeader("Accept"), ProblemContentType) {
+		c.Header("Content-Type", ProblemContentType)
+		c.AbortWithStatusJSON(status, Problem{
+			Type:      "about:blank",
+			Title:     http.StatusText(status),
+			Status:    status,
+			Detail:    message,
+			Instance:  c.Request.URL.Path,
+			Code:      e.Code,
+			Errors:    e.Fields,
+			RequestID: c.GetString("requestID"),
+		})
+		return
+	}
+	c.AbortWithStatusJSON(status, ErrorResponse{
+		Error:     message,
+		Code:      e.Code,
+		Details:   e.Fields,
+		RequestID: c.GetString("requestID"),
+	})
+}
+
+// capitalize turns an error message into a sentence
+func capitalize(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[size:]
+}
@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"aicg/internal/idempotency"
+	"aicg/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// IdempotencyKeyHeader carries the key that makes a POST safe to retry
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader marks the responses replayed for retried requests
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	// maxIdempotentBody bounds the requests read into memory to fingerprint them,
+	// leaving room for the largest upload
+	maxIdempotentBody = 8 << 20
+)
+
+var (
+	// errInvalidIdempotencyKey is reported for keys that are too long
+	errInvalidIdempotencyKey = services.Invalid("invalid_idempotency_key", "idempotency key is too long",
+		services.FieldError{Field: IdempotencyKeyHeader, Code: "max", Message: "must be at most 255 characters"})
+	// errIdempotencyKeyUnsupported is reported for keys sent without logging in
+	errIdempotencyKeyUnsupported = services.Invalid("idempotency_key_unsupported", "idempotency keys need a logged in user",
+		services.FieldError{Field: IdempotencyKeyHeader, Code: "unsupported", Message: "is only accepted on authenticated requests"})
+	// errIdempotencyKeyReused is reported for a key used again with a different request
+	errIdempotencyKeyReused = services.Conflict("idempotency_key_reused", "idempotency key was already used for a different request")
+	// errIdempotencyKeyInUse is reported for a retry while the first request is still running
+	errIdempotencyKeyInUse = services.Conflict("idempotency_key_in_use", "a request with this idempotency key is still in progress")
+	// errIdempotentBodyTooLarge is reported for bodies too large to fingerprint
+	errIdempotentBodyTooLarge = &services.Error{Kind: services.KindTooLarge, Code: "request_too_large", Message: "request body is too large"}
+)
+
+// Idempotency makes POST requests with an Idempotency-Key header safe to
+// retry. Keys belong to a user, so it runs after AuthRequired; requests
+// without a logged in user, whose responses carry tokens that mustn't be
+// saved, have their keys refused with a 400 rather than silently ignored.
+// The response to the first request with a key is saved and replayed, with
+// an Idempotent-Replayed header, for retries of the same request. Reusing
+// the key for a different request gets a 409 idempotency_key_reused, and
+// retrying while the first is still running a 409 idempotency_key_in_use.
+// Server errors and 429s aren't saved, so those requests can be retried.
+func Idempotency(store idempotency.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value := c.GetHeader(IdempotencyKeyHeader)
+		if c.Request.Method != http.MethodPost || value == "" {
+			c.Next()
+			return
+		}
+		if len(value) > idempotency.MaxKeyLength {
+			abortWithError(c, errInvalidIdempotencyKey)
+			return
+		}
+		userID := c.GetUint("userID")
+		if userID == 0 {
+			abortWithError(c, errIdempotencyKeyUnsupported)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxIdempotentBody))
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				err = errIdempotentBodyTooLarge
+			}
+			abortWithError(c, err)
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := c.Request.Context()
+		key := idempotency.Key{UserID: userID, Key: value}
+		fingerprint := idempotency.Fingerprint(c.Request.Method, c.Request.URL.RequestURI(), body)
+		saved, err := store.Begin(ctx, key, fingerprint)
+		switch {
+		case errors.Is(err, idempotency.ErrMismatch):
+			abortWithError(c, errIdempotencyKeyReused)
+			return
+		case errors.Is(err, idempotency.ErrInProgress):
+			abortWithError(c, errIdempotencyKeyInUse)
+			return
+		case err != nil:
+			// Running the request without the key could run it twice
+			abortWithError(c, err)
+			return
+		case saved != nil:
+			c.Header(IdempotentReplayedHeader, "true")
+			c.Data(saved.StatusCode, saved.ContentType, saved.Body)
+			c.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+		if !c.Writer.Written() && len(c.Errors) > 0 {
+			// Write the error now rather than in Errors, to save it
+			writeError(c, c.Errors.Last().Err)
+		}
+
+		// The client may be gone, but the key still has to be settled
+		ctx = context.WithoutCancel(ctx)
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError || status == http.StatusTooManyRequests {
+			err = store.Release(ctx, key, fingerprint)
+		} else {
+			err = store.Complete(ctx, key, fingerprint, idempotency.Response{
+				StatusCode:  status,
+				ContentType: recorder.Header().Get("Content-Type"),
+				Body:        recorder.body.Bytes(),
+			})
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to settle idempotency key", "status", status, "error", err)
+		}
+	}
+}
+
+// responseRecorder keeps a copy of the response body as it's written
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
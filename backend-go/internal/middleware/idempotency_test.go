@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"aicg/internal/idempotency"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newIdempotencyRouter serves handler at POST /submit behind Idempotency,
+// with the user taken from the X-User-ID header in place of a token
+func newIdempotencyRouter(store idempotency.Store, handler gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Errors())
+	r.Use(func(c *gin.Context) {
+		if id, err := strconv.Atoi(c.GetHeader("X-User-ID")); err == nil {
+			c.Set("userID", uint(id))
+		}
+	})
+	r.Use(Idempotency(store))
+	r.POST("/submit", handler)
+	return r
+}
+
+func sendIdempotent(r http.Handler, userID, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/submit", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	if userID != "" {
+		req.Header.Set("X-User-ID", userID)
+	}
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	return rr
+}
+
+func errorCode(t *testing.T, rr *httptest.ResponseRecorder) string {
+	var body ErrorResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body), rr.Body.String())
+	return body.Code
+}
+
+func TestIdempotency(t *testing.T) {
+	var calls atomic.Int32
+	r := newIdempotencyRouter(idempotency.NewMemoryStore(), func(c *gin.Context) {
+		n := calls.Add(1)
+		c.JSON(http.StatusCreated, gin.H{"call": n})
+	})
+
+	// Test case 1: A retry gets the first response, without running the handler again
+	first := sendIdempotent(r, "1", "key-1", `{"a": 1}`)
+	assert.Equal(t, http.StatusCreated, first.Code)
+	assert.Empty(t, first.Header().Get(IdempotentReplayedHeader))
+	retried := sendIdempotent(r, "1", "key-1", `{"a": 1}`)
+	assert.Equal(t, http.StatusCreated, retried.Code)
+	assert.Equal(t, "true", retried.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, first.Body.String(), retried.Body.String())
+	assert.Equal(t, int32(1), calls.Load())
+
+	// Test case 2: The same key with a different body is refused
+	rr := sendIdempotent(r, "1", "key-1", `{"a": 2}`)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Equal(t, "idempotency_key_reused", errorCode(t, rr))
+	assert.Equal(t, int32(1), calls.Load())
+
+	// Test case 3: Keys belong to a user
+	rr = sendIdempotent(r, "2", "key-1", `{"a": 1}`)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Empty(t, rr.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, int32(2), calls.Load())
+
+	// Test case 4: Requests without a key or a user aren't remembered
+	assert.Equal(t, http.StatusCreated, sendIdempotent(r, "1", "", `{"a": 1}`).Code)
+	assert.Equal(t, int32(3), calls.Load())
+	rr = sendIdempotent(r, "", "key-1", `{"a": 1}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, "idempotency_key_unsupported", errorCode(t, rr))
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestIdempotencyInFlight(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	r := newIdempotencyRouter(idempotency.NewMemoryStore(), func(c *gin.Context) {
+		close(started)
+		<-release
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- sendIdempotent(r, "1", "key-1", `{}`) }()
+	<-started
+
+	// Test case 1: A retry while the first request is running is refused
+	rr := sendIdempotent(r, "1", "key-1", `{}`)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Equal(t, "idempotency_key_in_use", errorCode(t, rr))
+
+	// Test case 2: Once it finishes, retries get its response
+	close(release)
+	assert.Equal(t, http.StatusOK, (<-done).Code)
+	rr = sendIdempotent(r, "1", "key-1", `{}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "true", rr.Header().Get(IdempotentReplayedHeader))
+}
+
+func TestIdempotencyServerErrors(t *testing.T) {
+	var calls atomic.Int32
+	r := newIdempotencyRouter(idempotency.NewMemoryStore(), func(c *gin.Context) {
+		if calls.Add(1) == 1 {
+			_ = c.Error(assert.AnError)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+
+	// Test case 1: A failure isn't saved, so the retry runs the request
+	assert.Equal(t, http.StatusInternalServerError, sendIdempotent(r, "1", "key-1", `{}`).Code)
+	rr := sendIdempotent(r, "1", "key-1", `{}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Empty(t, rr.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, int32(2), calls.Load())
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"time"
+
+	"aicg/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics counts and times every request by route template, such as
+// /api/quiz/:id, rather than by path, which would create a series per ID
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = metrics.UnmatchedRoute
+		}
+		metrics.ObserveHTTPRequest(c.Request.Method, route, c.Writer.Status(), time.Since(start))
+	}
+}
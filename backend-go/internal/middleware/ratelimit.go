@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"time"
+
+	"aicg/internal/ratelimit"
+	"aicg/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rateLimitKey stores the result of the tightest limit the request went through
+const rateLimitKey = "rateLimit"
+
+// errRateLimited is reported for requests refused by a limit
+var errRateLimited = &services.Error{Kind: services.KindRateLimited, Code: "rate_limited", Message: "too many requests, please try again later"}
+
+// RateLimit takes a token from the caller's bucket under the named policy:
+// the user's once AuthRequired has run, the client IP's before. Refused
+// requests get 429 and a Retry-After header. Routes can go through several
+// limits; the RateLimit-* headers describe the one with the fewest requests
+// left. If the store fails, requests are let through rather than refused.
+func RateLimit(store ratelimit.Store, policy string, limit ratelimit.Limit) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := policy + ":ip:" + c.ClientIP()
+		if userID, ok := c.Get("userID"); ok {
+			key = fmt.Sprintf("%s:user:%v", policy, userID)
+		}
+
+		result, err := store.Take(c.Request.Context(), key, limit)
+		if err != nil {
+			slog.WarnContext(c.Request.Context(), "Rate limit unavailable, allowing request", "policy", policy, "error", err)
+			c.Next()
+			return
+		}
+
+		previous, seen := c.Get(rateLimitKey)
+		if !seen || !result.Allowed || result.Remaining < previous.(ratelimit.Result).Remaining {
+			c.Set(rateLimitKey, result)
+			h := c.Writer.Header()
+			h.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			h.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			h.Set("RateLimit-Reset", ceilSeconds(result.Reset))
+			h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=60;burst=%d", int(math.Round(limit.PerSec*60)), limit.Burst))
+		}
+
+		if !result.Allowed {
+			c.Header("Retry-After", ceilSeconds(result.RetryAfter))
+			abortWithError(c, errRateLimited)
+			return
+		}
+		c.Next()
+	}
+}
+
+// ceilSeconds formats d as whole seconds, rounded up
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
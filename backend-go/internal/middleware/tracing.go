@@ -0,0 +1,17 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+)
+
+// Tracing starts a span for every request, named after its route template,
+// continuing the trace of the caller when it sent a traceparent header.
+// Probes and scrapes of metricsPath are left out.
+func Tracing(service, metricsPath string) gin.HandlerFunc {
+	return otelgin.Middleware(service, otelgin.WithFilter(func(r *http.Request) bool {
+		return !probePaths[r.URL.Path] && r.URL.Path != metricsPath
+	}))
+}
@@ -3,19 +3,28 @@ package models
 import (
 	"time"
 
+	"aicg/internal/models/enums"
+
 	"gorm.io/gorm"
 )
 
+// Achievement represents an achievement that users can earn
 type Achievement struct {
 	gorm.Model
-	Name             string            `json:"name" gorm:"size:100;not null"`
-	Description      string            `json:"description" gorm:"type:text;not null"`
-	Category         QuizCategory      `json:"category" gorm:"size:50"`
-	Criteria         []byte            `json:"criteria" gorm:"type:jsonb;not null"` // Store achievement criteria
-	IconURL          string            `json:"icon_url" gorm:"size:255"`
-	UserAchievements []UserAchievement `json:"user_achievements" gorm:"foreignKey:AchievementID"`
+	Name             string             `json:"name" gorm:"size:100;not null"`
+	Description      string             `json:"description" gorm:"type:text;not null"`
+	Category         enums.QuizCategory `json:"category" gorm:"size:50"`
+	Criteria         JSON               `json:"criteria" gorm:"not null"` // Store achievement criteria
+	IconURL          string             `json:"icon_url" gorm:"size:255"`
+	UserAchievements []UserAchievement  `json:"user_achievements" gorm:"foreignKey:AchievementID"`
+}
+
+// TableName specifies the table name for the Achievement model
+func (Achievement) TableName() string {
+	return "achievements"
 }
 
+// UserAchievement represents an achievement earned by a user
 type UserAchievement struct {
 	gorm.Model
 	UserID        uint        `json:"user_id" gorm:"not null;uniqueIndex:idx_user_achievement"`
@@ -23,5 +32,10 @@ type UserAchievement struct {
 	User          User        `json:"user" gorm:"foreignKey:UserID"`
 	Achievement   Achievement `json:"achievement" gorm:"foreignKey:AchievementID"`
 	EarnedAt      time.Time   `json:"earned_at" gorm:"default:CURRENT_TIMESTAMP"`
-	Progress      []byte      `json:"progress" gorm:"type:jsonb"` // Store progress towards achievement
+	Progress      JSON        `json:"progress"` // Store progress towards achievement
+}
+
+// TableName specifies the table name for the UserAchievement model
+func (UserAchievement) TableName() string {
+	return "user_achievements"
 }
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DataExportStatus tells us where a data export is in its lifecycle
+type DataExportStatus string
+
+const (
+	DataExportPending DataExportStatus = "pending"
+	DataExportReady   DataExportStatus = "ready"
+	DataExportFailed  DataExportStatus = "failed"
+	DataExportExpired DataExportStatus = "expired"
+)
+
+// DataExport is a GDPR data subject access request: a ZIP with
+// everything we store about a user, assembled in the background
+type DataExport struct {
+	gorm.Model
+	UserID      uint             `json:"user_id" gorm:"not null;index"` // Whose data is exported
+	RequestedBy uint             `json:"requested_by" gorm:"not null"`  // The user or admin who asked for it
+	Status      DataExportStatus `json:"status" gorm:"size:20;not null;default:pending"`
+	BlobKey     string           `json:"-" gorm:"size:255"`                // Where the ZIP is stored
+	Size        int64            `json:"size"`                             // ZIP size in bytes
+	Error       string           `json:"error,omitempty" gorm:"type:text"` // Why the export failed
+	CompletedAt *time.Time       `json:"completed_at,omitempty"`           // When the ZIP was ready
+	ExpiresAt   *time.Time       `json:"expires_at,omitempty"`             // When the ZIP gets deleted
+}
+
+// TableName specifies the table name for the DataExport model
+func (DataExport) TableName() string {
+	return "data_exports"
+}
@@ -0,0 +1,41 @@
+package enums
+
+// AuthProvider represents how a user signs in
+type AuthProvider string
+
+const (
+	ProviderEmail     AuthProvider = "email"
+	ProviderGoogle    AuthProvider = "google"
+	ProviderFacebook  AuthProvider = "facebook"
+	ProviderInstagram AuthProvider = "instagram"
+)
+
+// ValidAuthProviders returns all valid auth providers
+func ValidAuthProviders() []AuthProvider {
+	return []AuthProvider{
+		ProviderEmail,
+		ProviderGoogle,
+		ProviderFacebook,
+		ProviderInstagram,
+	}
+}
+
+// IsValid checks if an auth provider is valid
+func (p AuthProvider) IsValid() bool {
+	for _, validProvider := range ValidAuthProviders() {
+		if p == validProvider {
+			return true
+		}
+	}
+	return false
+}
+
+// IsSSO checks if the provider is an external single sign-on provider
+func (p AuthProvider) IsSSO() bool {
+	return p.IsValid() && p != ProviderEmail
+}
+
+// String returns the string representation of the auth provider
+func (p AuthProvider) String() string {
+	return string(p)
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// IdempotencyKey remembers a request a user sent with an Idempotency-Key
+// header, and its response once there is one, so that a retry of the
+// request gets the same response instead of running it again
+type IdempotencyKey struct {
+	ID          uint      `gorm:"primarykey"`
+	UserID      uint      `gorm:"not null;uniqueIndex:idx_idempotency_keys_user_key"`
+	Key         string    `gorm:"column:idempotency_key;size:255;not null;uniqueIndex:idx_idempotency_keys_user_key"`
+	Fingerprint string    `gorm:"size:64;not null"`   // SHA-256 of the method, path and body
+	StatusCode  int       `gorm:"not null;default:0"` // 0 while the request is running
+	ContentType string    `gorm:"size:255"`
+	Body        []byte    // Of the response
+	ExpiresAt   time.Time `gorm:"not null;index"` // When the key can be forgotten, or taken over if the request never finished
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// TableName specifies the table name for the IdempotencyKey model
+func (IdempotencyKey) TableName() string {
+	return "idempotency_keys"
+}
@@ -1,29 +1,41 @@
 package models
 
-// Question represents a quiz question
-type Question struct {
-	ID       int    `json:"id"`
-	Text     string `json:"text"`
-	Category string `json:"category"`
-}
-
-// QuestionResponse represents the response for question-related operations
-type QuestionResponse struct {
-	Success bool      `json:"success"`
-	Message string    `json:"message,omitempty"`
-	Data    *Question `json:"data,omitempty"`
-}
+import "gorm.io/gorm/schema"
 
-// Quiz represents a collection of questions
-type Quiz struct {
-	ID        int        `json:"id"`
-	Title     string     `json:"title"`
-	Questions []Question `json:"questions"`
+// All returns every persisted model, in an order that satisfies foreign keys
+func All() []interface{} {
+	return []interface{}{
+		&User{},
+		&SSOConfig{},
+		&Quiz{},
+		&Question{},
+		&Answer{},
+		&Result{},
+		&UserProgress{},
+		&Achievement{},
+		&UserAchievement{},
+		&GlobalRanking{},
+		&CategoryRanking{},
+		&Benchmark{},
+		&DataExport{},
+		&IdempotencyKey{},
+	}
 }
 
-// QuizResponse represents the response for quiz-related operations
-type QuizResponse struct {
-	Success bool   `json:"success"`
-	Message string `json:"message,omitempty"`
-	Data    *Quiz  `json:"data,omitempty"`
-}
+// Ensure every model names its table explicitly
+var (
+	_ schema.Tabler = User{}
+	_ schema.Tabler = SSOConfig{}
+	_ schema.Tabler = Quiz{}
+	_ schema.Tabler = Question{}
+	_ schema.Tabler = Answer{}
+	_ schema.Tabler = Result{}
+	_ schema.Tabler = UserProgress{}
+	_ schema.Tabler = Achievement{}
+	_ schema.Tabler = UserAchievement{}
+	_ schema.Tabler = GlobalRanking{}
+	_ schema.Tabler = CategoryRanking{}
+	_ schema.Tabler = Benchmark{}
+	_ schema.Tabler = DataExport{}
+	_ schema.Tabler = IdempotencyKey{}
+)
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/schema"
+)
+
+func parseModel(t *testing.T, model interface{}) *schema.Schema {
+	s, err := schema.Parse(model, &sync.Map{}, schema.NamingStrategy{})
+	require.NoError(t, err)
+	return s
+}
+
+func TestModelsParse(t *testing.T) {
+	for _, model := range All() {
+		parseModel(t, model)
+	}
+}
+
+func TestModelRelationships(t *testing.T) {
+	// Test case 1: has-many relations resolve to the expected foreign keys
+	tests := []struct {
+		model      interface{}
+		relation   string
+		table      string
+		foreignKey string
+	}{
+		{&Quiz{}, "Questions", "questions", "quiz_id"},
+		{&Question{}, "Answers", "answers", "question_id"},
+		{&User{}, "Quizzes", "quizzes", "created_by"},
+		{&User{}, "Results", "results", "user_id"},
+		{&User{}, "UserProgress", "user_progress", "user_id"},
+		{&Achievement{}, "UserAchievements", "user_achievements", "achievement_id"},
+	}
+
+	for _, tt := range tests {
+		s := parseModel(t, tt.model)
+		rel, ok := s.Relationships.Relations[tt.relation]
+		require.True(t, ok, "%s.%s", s.Name, tt.relation)
+		assert.Equal(t, schema.HasMany, rel.Type, "%s.%s", s.Name, tt.relation)
+		assert.Equal(t, tt.table, rel.FieldSchema.Table)
+		require.Len(t, rel.References, 1)
+		assert.Equal(t, tt.foreignKey, rel.References[0].ForeignKey.DBName)
+	}
+
+	// Test case 2: belongs-to relations
+	rel := parseModel(t, &Result{}).Relationships.Relations["Quiz"]
+	require.NotNil(t, rel)
+	assert.Equal(t, schema.BelongsTo, rel.Type)
+
+	rel = parseModel(t, &UserAchievement{}).Relationships.Relations["Achievement"]
+	require.NotNil(t, rel)
+	assert.Equal(t, schema.BelongsTo, rel.Type)
+}
@@ -3,193 +3,223 @@ package models
 import (
 	"time"
 
+	"aicg/internal/models/enums"
+
 	"gorm.io/gorm"
 )
 
-// QuizCategory tells us what subject the quiz is about
-// For example: math, science, history, etc.
-type QuizCategory string
-
-// These are all the different quiz categories we support
-const (
-	CategoryGeneral    QuizCategory = "general"
-	CategoryScience    QuizCategory = "science"
-	CategoryHistory    QuizCategory = "history"
-	CategoryTechnology QuizCategory = "technology"
-	CategoryMath       QuizCategory = "math"
-	CategoryLanguage   QuizCategory = "language"
-)
+// Quiz represents a complete quiz that users can take
+// It includes all the quiz information and its questions
+type Quiz struct {
+	gorm.Model
+	Title        string               `json:"title" gorm:"size:255;not null" binding:"required"`                                  // Name of the quiz
+	Description  string               `json:"description" gorm:"type:text;not null" binding:"required"`                           // What the quiz is about
+	Category     enums.QuizCategory   `json:"category" gorm:"size:50;not null" binding:"required"`                                // Subject area (math, science, etc.)
+	Difficulty   enums.QuizDifficulty `json:"difficulty" gorm:"size:20;not null" binding:"required"`                              // How hard it is (easy, medium, hard)
+	TimeLimit    int                  `json:"time_limit" gorm:"not null" binding:"required,min=1"`                                // How many minutes users have
+	Questions    []Question           `json:"questions" gorm:"foreignKey:QuizID" binding:"required,min=1"`                        // The actual quiz questions
+	CreatedBy    uint                 `json:"created_by" gorm:"not null" binding:"required"`                                      // Who made the quiz
+	IsPublished  bool                 `json:"is_published" gorm:"default:false"`                                                  // Whether it's ready for users
+	CreatedAt    time.Time            `json:"created_at"`                                                                         // When it was made
+	UpdatedAt    time.Time            `json:"updated_at"`                                                                         // When it was last changed
+	PassingScore float64              `json:"passing_score" gorm:"precision:5;scale:2;not null" binding:"required,min=0,max=100"` // Score needed to pass
+}
 
-// QuizDifficulty tells us how hard the quiz is
-type QuizDifficulty string
+// TableName specifies the table name for the Quiz model
+func (Quiz) TableName() string {
+	return "quizzes"
+}
 
-// These are the three difficulty levels
-const (
-	DifficultyEasy   QuizDifficulty = "easy"
-	DifficultyMedium QuizDifficulty = "medium"
-	DifficultyHard   QuizDifficulty = "hard"
-)
+// QuizSummary is a quiz as listed, with the number of its questions instead of the questions themselves
+type QuizSummary struct {
+	ID            uint                 `json:"id"`
+	Title         string               `json:"title"`
+	Description   string               `json:"description"`
+	Category      enums.QuizCategory   `json:"category"`
+	Difficulty    enums.QuizDifficulty `json:"difficulty"`
+	TimeLimit     int                  `json:"time_limit"`
+	QuestionCount int                  `json:"question_count"`
+	CreatedBy     uint                 `json:"created_by"`
+	IsPublished   bool                 `json:"is_published"`
+	PassingScore  float64              `json:"passing_score"`
+	CreatedAt     time.Time            `json:"created_at"`
+	UpdatedAt     time.Time            `json:"updated_at"`
+}
 
-// QuestionType defines the type of question
-type QuestionType string
+// PlayerQuiz is a quiz as shown to the users taking it, without the correct answers
+type PlayerQuiz struct {
+	ID           uint                 `json:"id"`
+	Title        string               `json:"title"`
+	Description  string               `json:"description"`
+	Category     enums.QuizCategory   `json:"category"`
+	Difficulty   enums.QuizDifficulty `json:"difficulty"`
+	TimeLimit    int                  `json:"time_limit"`
+	Questions    []PlayerQuestion     `json:"questions"`
+	CreatedBy    uint                 `json:"created_by"`
+	IsPublished  bool                 `json:"is_published"`
+	PassingScore float64              `json:"passing_score"`
+	CreatedAt    time.Time            `json:"created_at"`
+	UpdatedAt    time.Time            `json:"updated_at"`
+}
 
-const (
-	QuestionTypeMultipleChoice QuestionType = "multiple_choice"
-	QuestionTypeTrueFalse      QuestionType = "true_false"
-	QuestionTypeShortAnswer    QuestionType = "short_answer"
-	QuestionTypeEssay          QuestionType = "essay"
-)
+// PlayerQuestion is a question as shown to the users answering it, without the correct answer or its explanation
+type PlayerQuestion struct {
+	ID           uint               `json:"id"`
+	QuizID       uint               `json:"quiz_id"`
+	Text         string             `json:"text"`
+	Type         enums.QuestionType `json:"type"`
+	Answers      []PlayerAnswer     `json:"answers"`
+	Points       int                `json:"points"`
+	TimeToAnswer int                `json:"time_to_answer"`
+}
 
-// Quiz represents a complete quiz that users can take
-// It includes all the quiz information and its questions
-type Quiz struct {
-	gorm.Model
-	Title        string         `json:"title" binding:"required"`                       // Name of the quiz
-	Description  string         `json:"description" binding:"required"`                 // What the quiz is about
-	Category     QuizCategory   `json:"category" binding:"required"`                    // Subject area (math, science, etc.)
-	Difficulty   QuizDifficulty `json:"difficulty" binding:"required"`                  // How hard it is (easy, medium, hard)
-	TimeLimit    int            `json:"time_limit" binding:"required,min=1"`            // How many minutes users have
-	Questions    []Question     `json:"questions" binding:"required,min=1"`             // The actual quiz questions
-	CreatedBy    uint           `json:"created_by" binding:"required"`                  // Who made the quiz
-	IsPublished  bool           `json:"is_published" gorm:"default:false"`              // Whether it's ready for users
-	CreatedAt    time.Time      `json:"created_at"`                                     // When it was made
-	UpdatedAt    time.Time      `json:"updated_at"`                                     // When it was last changed
-	PassingScore float64        `json:"passing_score" binding:"required,min=0,max=100"` // Score needed to pass
+// PlayerAnswer is a possible answer as shown to the users, without whether it's correct
+type PlayerAnswer struct {
+	ID    uint   `json:"id"`
+	Text  string `json:"text"`
+	Order int    `json:"order"`
+}
+
+// ForPlayer returns the quiz as shown to the users taking it
+func (q *Quiz) ForPlayer() *PlayerQuiz {
+	quiz := &PlayerQuiz{
+		ID:           q.ID,
+		Title:        q.Title,
+		Description:  q.Description,
+		Category:     q.Category,
+		Difficulty:   q.Difficulty,
+		TimeLimit:    q.TimeLimit,
+		Questions:    make([]PlayerQuestion, 0, len(q.Questions)),
+		CreatedBy:    q.CreatedBy,
+		IsPublished:  q.IsPublished,
+		PassingScore: q.PassingScore,
+		CreatedAt:    q.CreatedAt,
+		UpdatedAt:    q.UpdatedAt,
+	}
+	for _, question := range q.Questions {
+		answers := make([]PlayerAnswer, 0, len(question.Answers))
+		for _, answer := range question.Answers {
+			answers = append(answers, PlayerAnswer{ID: answer.ID, Text: answer.Text, Order: answer.Order})
+		}
+		quiz.Questions = append(quiz.Questions, PlayerQuestion{
+			ID:           question.ID,
+			QuizID:       question.QuizID,
+			Text:         question.Text,
+			Type:         question.Type,
+			Answers:      answers,
+			Points:       question.Points,
+			TimeToAnswer: question.TimeToAnswer,
+		})
+	}
+	return quiz
 }
 
 // Question represents a single question in a quiz
 type Question struct {
 	gorm.Model
-	QuizID        uint         `json:"quiz_id" binding:"required"`              // Which quiz this belongs to
-	Text          string       `json:"text" binding:"required"`                 // The actual question
-	Type          QuestionType `json:"type" binding:"required"`                 // What kind of question (multiple choice, etc.)
-	Answers       []Answer     `json:"answers" binding:"required,min=2"`        // Possible answers
-	CorrectAnswer string       `json:"correct_answer" binding:"required"`       // The right answer
-	Points        int          `json:"points" gorm:"default:1" binding:"min=1"` // How many points it's worth
-	Explanation   string       `json:"explanation"`                             // Why the answer is correct
-	TimeToAnswer  int          `json:"time_to_answer" binding:"min=0"`          // Seconds allowed for this question
+	QuizID        uint               `json:"quiz_id" gorm:"not null;index" binding:"required"`              // Which quiz this belongs to
+	Text          string             `json:"text" gorm:"type:text;not null" binding:"required"`             // The actual question
+	Type          enums.QuestionType `json:"type" gorm:"size:20;not null" binding:"required"`               // What kind of question (multiple choice, etc.)
+	Answers       []Answer           `json:"answers" gorm:"foreignKey:QuestionID" binding:"required,min=2"` // Possible answers
+	CorrectAnswer string             `json:"correct_answer" gorm:"type:text;not null" binding:"required"`   // The right answer
+	Points        int                `json:"points" gorm:"default:1" binding:"min=1"`                       // How many points it's worth
+	Explanation   string             `json:"explanation" gorm:"type:text"`                                  // Why the answer is correct
+	TimeToAnswer  int                `json:"time_to_answer" gorm:"default:30" binding:"min=0"`              // Seconds allowed for this question
+}
+
+// TableName specifies the table name for the Question model
+func (Question) TableName() string {
+	return "questions"
 }
 
 // Answer represents one possible answer to a question
 type Answer struct {
 	gorm.Model
-	QuestionID uint   `json:"question_id" binding:"required"` // Which question this answers
-	Text       string `json:"text" binding:"required"`        // The answer text
-	IsCorrect  bool   `json:"is_correct"`                     // Whether this is the right answer
-	Order      int    `json:"order" binding:"min=0"`          // Display order of the answer
+	QuestionID uint   `json:"question_id" gorm:"not null;index"`                 // Which question this answers
+	Text       string `json:"text" gorm:"type:text;not null" binding:"required"` // The answer text
+	IsCorrect  bool   `json:"is_correct" gorm:"default:false"`                   // Whether this is the right answer
+	Order      int    `json:"order" gorm:"default:0" binding:"min=0"`            // Display order of the answer
+}
+
+// TableName specifies the table name for the Answer model
+func (Answer) TableName() string {
+	return "answers"
 }
 
 // Result stores how a user did on a quiz
 type Result struct {
 	gorm.Model
-	QuizID         uint    `json:"quiz_id" binding:"required"`                     // Which quiz they took
-	UserID         uint    `json:"user_id" binding:"required"`                     // Who took the quiz
-	Score          float64 `json:"score" binding:"required,min=0,max=100"`         // Their score (0-100)
-	TotalQuestions int     `json:"total_questions" binding:"required,min=1"`       // How many questions
-	CorrectAnswers int     `json:"correct_answers" binding:"required,min=0"`       // How many they got right
-	TimeTaken      int     `json:"time_taken" binding:"required,min=0"`            // How long it took (seconds)
-	Answers        []byte  `json:"answers" binding:"required"`                     // Their answers (stored as JSON)
-	Feedback       string  `json:"feedback"`                                       // Any feedback for the user
-	IsPassed       bool    `json:"is_passed"`                                      // Whether they passed
-	PassingScore   float64 `json:"passing_score" binding:"required,min=0,max=100"` // Score needed to pass
+	QuizID         uint    `json:"quiz_id" gorm:"not null;index" binding:"required"`                           // Which quiz they took
+	Quiz           *Quiz   `json:"quiz,omitempty" gorm:"foreignKey:QuizID"`                                    // The quiz, when preloaded
+	UserID         uint    `json:"user_id" gorm:"not null;index" binding:"required"`                           // Who took the quiz
+	Score          float64 `json:"score" gorm:"precision:5;scale:2;not null" binding:"required,min=0,max=100"` // Their score (0-100)
+	TotalQuestions int     `json:"total_questions" gorm:"not null" binding:"required,min=1"`                   // How many questions
+	CorrectAnswers int     `json:"correct_answers" gorm:"not null" binding:"required,min=0"`                   // How many they got right
+	TimeTaken      int     `json:"time_taken" gorm:"not null" binding:"required,min=0"`                        // How long it took (seconds)
+	Answers        JSON    `json:"answers" binding:"required"`                                                 // Their answers (stored as JSON)
+	Feedback       string  `json:"feedback" gorm:"type:text"`                                                  // Any feedback for the user
+	IsPassed       bool    `json:"is_passed"`                                                                  // Whether they passed
+	PassingScore   float64 `json:"passing_score" gorm:"precision:5;scale:2" binding:"required,min=0,max=100"`  // Score needed to pass
 }
 
-// UserProgress tracks how well a user is doing in a subject
-type UserProgress struct {
-	gorm.Model
-	UserID          uint         `json:"user_id" binding:"required"`            // Who this progress is for
-	QuizID          uint         `json:"quiz_id" binding:"required"`            // Which quiz they took
-	Category        QuizCategory `json:"category" binding:"required"`           // In what subject
-	TotalAttempts   int          `json:"total_attempts" binding:"min=0"`        // How many times they tried
-	BestScore       float64      `json:"best_score" binding:"min=0,max=100"`    // Their highest score
-	AverageScore    float64      `json:"average_score" binding:"min=0,max=100"` // Their average score
-	TotalTimeSpent  int          `json:"total_time_spent" binding:"min=0"`      // Total time spent (seconds)
-	LastAttemptedAt time.Time    `json:"last_attempted_at"`                     // When they last tried
-	MasteryLevel    int          `json:"mastery_level" binding:"min=1,max=5"`   // How well they know it (1-5)
-}
-
-// IsValidQuizCategory checks if a category name is one we support
-func IsValidQuizCategory(category string) bool {
-	validCategories := []QuizCategory{
-		CategoryGeneral,
-		CategoryScience,
-		CategoryHistory,
-		CategoryTechnology,
-		CategoryMath,
-		CategoryLanguage,
-	}
-
-	for _, c := range validCategories {
-		if string(c) == category {
-			return true
-		}
-	}
-	return false
+// TableName specifies the table name for the Result model
+func (Result) TableName() string {
+	return "results"
 }
 
-// IsValidQuizDifficulty checks if a difficulty level is valid
-func IsValidQuizDifficulty(difficulty string) bool {
-	validDifficulties := []QuizDifficulty{
-		DifficultyEasy,
-		DifficultyMedium,
-		DifficultyHard,
-	}
-
-	for _, d := range validDifficulties {
-		if string(d) == difficulty {
-			return true
-		}
-	}
-	return false
+// ResultSummary is a result as listed: without the submitted answers, but with the quiz it's for
+type ResultSummary struct {
+	ID             uint                 `json:"id"`
+	QuizID         uint                 `json:"quiz_id"`
+	QuizTitle      string               `json:"quiz_title"`
+	Category       enums.QuizCategory   `json:"category"`
+	Difficulty     enums.QuizDifficulty `json:"difficulty"`
+	UserID         uint                 `json:"user_id"`
+	Score          float64              `json:"score"`
+	TotalQuestions int                  `json:"total_questions"`
+	CorrectAnswers int                  `json:"correct_answers"`
+	TimeTaken      int                  `json:"time_taken"`
+	IsPassed       bool                 `json:"is_passed"`
+	PassingScore   float64              `json:"passing_score"`
+	CreatedAt      time.Time            `json:"created_at"`
 }
 
-// IsValidQuestionType checks if a question type is valid
-func IsValidQuestionType(questionType string) bool {
-	validTypes := []QuestionType{
-		QuestionTypeMultipleChoice,
-		QuestionTypeTrueFalse,
-		QuestionTypeShortAnswer,
-		QuestionTypeEssay,
-	}
+// UserProgress tracks how well a user is doing in a subject
+type UserProgress struct {
+	gorm.Model
+	UserID          uint               `json:"user_id" gorm:"not null;index;uniqueIndex:idx_user_progress_user_quiz" binding:"required"` // Who this progress is for
+	QuizID          uint               `json:"quiz_id" gorm:"not null;index;uniqueIndex:idx_user_progress_user_quiz" binding:"required"` // Which quiz they took
+	Category        enums.QuizCategory `json:"category" gorm:"size:50;not null" binding:"required"`                                      // In what subject
+	TotalAttempts   int                `json:"total_attempts" gorm:"default:0" binding:"min=0"`                                          // How many times they tried
+	BestScore       float64            `json:"best_score" gorm:"precision:5;scale:2" binding:"min=0,max=100"`                            // Their highest score
+	AverageScore    float64            `json:"average_score" gorm:"precision:5;scale:2" binding:"min=0,max=100"`                         // Their average score
+	TotalTimeSpent  int                `json:"total_time_spent" gorm:"default:0" binding:"min=0"`                                        // Total time spent (seconds)
+	LastAttemptedAt time.Time          `json:"last_attempted_at"`                                                                        // When they last tried
+	MasteryLevel    int                `json:"mastery_level" gorm:"default:1" binding:"min=1,max=5"`                                     // How well they know it (1-5)
+	Version         int                `json:"-" gorm:"not null;default:0"`                                                              // Bumped by every update, which only applies to the version it read
+}
 
-	for _, t := range validTypes {
-		if string(t) == questionType {
-			return true
-		}
-	}
-	return false
+// TableName specifies the table name for the UserProgress model
+func (UserProgress) TableName() string {
+	return "user_progress"
 }
 
-// Question represents a quiz question
-type Question struct {
-	ID       int64     `json:"id"`
-	Text     string    `json:"text"`
-	Category string    `json:"category"`
-	Created  time.Time `json:"created_at"`
-	Updated  time.Time `json:"updated_at"`
+// CategoryProgress sums up a user's progress on the quizzes of a category, or of all categories
+type CategoryProgress struct {
+	Category         enums.QuizCategory `json:"category,omitempty"`          // Left out for the overall progress
+	QuizzesAttempted int                `json:"quizzes_attempted"`           // How many quizzes they tried
+	QuizzesPublished int                `json:"quizzes_published"`           // How many quizzes there are to try
+	Coverage         float64            `json:"coverage"`                    // Percentage of the published quizzes they tried
+	TotalAttempts    int                `json:"total_attempts"`              // How many times they tried
+	BestScore        float64            `json:"best_score"`                  // Their highest score
+	AverageScore     float64            `json:"average_score"`               // Their average score over all attempts
+	TotalTimeSpent   int                `json:"total_time_spent"`            // Total time spent (seconds)
+	LastAttemptedAt  *time.Time         `json:"last_attempted_at,omitempty"` // When they last tried, if they did
+	MasteryLevel     float64            `json:"mastery_level"`               // The quizzes' mastery levels weighted by attempts, 0 if none
 }
 
-// Quiz represents a complete quiz
-type Quiz struct {
-	ID        int64      `json:"id"`
-	Title     string     `json:"title"`
-	Questions []Question `json:"questions"`
-	Created   time.Time  `json:"created_at"`
-	Updated   time.Time  `json:"updated_at"`
-}
-
-// QuizResponse represents the API response for quiz operations
-type QuizResponse struct {
-	Success bool   `json:"success"`
-	Message string `json:"message,omitempty"`
-	Data    *Quiz  `json:"data,omitempty"`
-	Error   string `json:"error,omitempty"`
-}
-
-// QuestionResponse represents the API response for question operations
-type QuestionResponse struct {
-	Success bool      `json:"success"`
-	Message string    `json:"message,omitempty"`
-	Data    *Question `json:"data,omitempty"`
-	Error   string    `json:"error,omitempty"`
+// ProgressSummary is a user's progress overall and in each category they tried or can try
+type ProgressSummary struct {
+	Overall    CategoryProgress   `json:"overall"`
+	Categories []CategoryProgress `json:"categories"`
 }
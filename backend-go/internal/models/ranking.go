@@ -1,56 +1,65 @@
 package models
 
 import (
-	"gorm.io/gorm"
-)
-
-type RankingPeriod string
+	"aicg/internal/models/enums"
 
-const (
-	RankingPeriodWeekly  RankingPeriod = "weekly"
-	RankingPeriodMonthly RankingPeriod = "monthly"
-	RankingPeriodAllTime RankingPeriod = "all_time"
+	"gorm.io/gorm"
 )
 
 // GlobalRanking represents a user's overall performance across all categories
 type GlobalRanking struct {
 	gorm.Model
-	UserID           uint          `json:"user_id" gorm:"not null;uniqueIndex:idx_user_period"`
-	User             User          `json:"user" gorm:"foreignKey:UserID"`
-	TotalScore       float64       `json:"total_score" gorm:"type:decimal(10,2);default:0"`
-	QuizzesCompleted int           `json:"quizzes_completed" gorm:"default:0"`
-	AverageScore     float64       `json:"average_score" gorm:"type:decimal(5,2);default:0"`
-	TotalTimeSpent   int           `json:"total_time_spent" gorm:"default:0"` // in seconds
-	Rank             int           `json:"rank"`
-	Percentile       float64       `json:"percentile" gorm:"type:decimal(5,2)"`
-	RankingPeriod    RankingPeriod `json:"ranking_period" gorm:"size:20;not null;uniqueIndex:idx_user_period"`
+	UserID           uint                `json:"user_id" gorm:"not null;uniqueIndex:idx_user_period"`
+	User             User                `json:"user" gorm:"foreignKey:UserID"`
+	TotalScore       float64             `json:"total_score" gorm:"precision:10;scale:2;default:0"`
+	QuizzesCompleted int                 `json:"quizzes_completed" gorm:"default:0"`
+	AverageScore     float64             `json:"average_score" gorm:"precision:5;scale:2;default:0"`
+	TotalTimeSpent   int                 `json:"total_time_spent" gorm:"default:0"` // in seconds
+	Rank             int                 `json:"rank"`
+	Percentile       float64             `json:"percentile" gorm:"precision:5;scale:2"`
+	RankingPeriod    enums.RankingPeriod `json:"ranking_period" gorm:"size:20;not null;uniqueIndex:idx_user_period"`
+}
+
+// TableName specifies the table name for the GlobalRanking model
+func (GlobalRanking) TableName() string {
+	return "global_rankings"
 }
 
 // CategoryRanking represents a user's performance in a specific category
 type CategoryRanking struct {
 	gorm.Model
-	UserID           uint          `json:"user_id" gorm:"not null;uniqueIndex:idx_user_category_period"`
-	User             User          `json:"user" gorm:"foreignKey:UserID"`
-	Category         QuizCategory  `json:"category" gorm:"size:50;not null;uniqueIndex:idx_user_category_period"`
-	TotalScore       float64       `json:"total_score" gorm:"type:decimal(10,2);default:0"`
-	QuizzesCompleted int           `json:"quizzes_completed" gorm:"default:0"`
-	AverageScore     float64       `json:"average_score" gorm:"type:decimal(5,2);default:0"`
-	Rank             int           `json:"rank"`
-	Percentile       float64       `json:"percentile" gorm:"type:decimal(5,2)"`
-	RankingPeriod    RankingPeriod `json:"ranking_period" gorm:"size:20;not null;uniqueIndex:idx_user_category_period"`
+	UserID           uint                `json:"user_id" gorm:"not null;uniqueIndex:idx_user_category_period"`
+	User             User                `json:"user" gorm:"foreignKey:UserID"`
+	Category         enums.QuizCategory  `json:"category" gorm:"size:50;not null;uniqueIndex:idx_user_category_period"`
+	TotalScore       float64             `json:"total_score" gorm:"precision:10;scale:2;default:0"`
+	QuizzesCompleted int                 `json:"quizzes_completed" gorm:"default:0"`
+	AverageScore     float64             `json:"average_score" gorm:"precision:5;scale:2;default:0"`
+	Rank             int                 `json:"rank"`
+	Percentile       float64             `json:"percentile" gorm:"precision:5;scale:2"`
+	RankingPeriod    enums.RankingPeriod `json:"ranking_period" gorm:"size:20;not null;uniqueIndex:idx_user_category_period"`
+}
+
+// TableName specifies the table name for the CategoryRanking model
+func (CategoryRanking) TableName() string {
+	return "category_rankings"
 }
 
 // Benchmark represents performance metrics for a specific category and difficulty
 type Benchmark struct {
 	gorm.Model
-	Category              QuizCategory   `json:"category" gorm:"size:50;not null;uniqueIndex:idx_category_difficulty"`
-	Difficulty            QuizDifficulty `json:"difficulty" gorm:"size:20;not null;uniqueIndex:idx_category_difficulty"`
-	AverageScore          float64        `json:"average_score" gorm:"type:decimal(5,2);default:0"`
-	MedianScore           float64        `json:"median_score" gorm:"type:decimal(5,2);default:0"`
-	Percentile75          float64        `json:"percentile_75" gorm:"type:decimal(5,2);default:0"`
-	Percentile90          float64        `json:"percentile_90" gorm:"type:decimal(5,2);default:0"`
-	TotalAttempts         int            `json:"total_attempts" gorm:"default:0"`
-	AverageCompletionTime int            `json:"average_completion_time" gorm:"default:0"` // in seconds
+	Category              enums.QuizCategory   `json:"category" gorm:"size:50;not null;uniqueIndex:idx_category_difficulty"`
+	Difficulty            enums.QuizDifficulty `json:"difficulty" gorm:"size:20;not null;uniqueIndex:idx_category_difficulty"`
+	AverageScore          float64              `json:"average_score" gorm:"precision:5;scale:2;default:0"`
+	MedianScore           float64              `json:"median_score" gorm:"precision:5;scale:2;default:0"`
+	Percentile75          float64              `json:"percentile_75" gorm:"precision:5;scale:2;default:0"`
+	Percentile90          float64              `json:"percentile_90" gorm:"precision:5;scale:2;default:0"`
+	TotalAttempts         int                  `json:"total_attempts" gorm:"default:0"`
+	AverageCompletionTime int                  `json:"average_completion_time" gorm:"default:0"` // in seconds
+}
+
+// TableName specifies the table name for the Benchmark model
+func (Benchmark) TableName() string {
+	return "benchmarks"
 }
 
 // LeaderboardEntry represents a user's position in the leaderboard
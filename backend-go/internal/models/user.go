@@ -1,40 +1,31 @@
 package models
 
 import (
+	"log/slog"
 	"time"
 
-	"gorm.io/gorm"
-)
-
-type UserRole string
-
-const (
-	RoleSuperAdmin UserRole = "super_admin"
-	RoleMaveric    UserRole = "maveric"
-)
-
-type AuthProvider string
+	"aicg/internal/models/enums"
 
-const (
-	ProviderEmail     AuthProvider = "email"
-	ProviderGoogle    AuthProvider = "google"
-	ProviderFacebook  AuthProvider = "facebook"
-	ProviderInstagram AuthProvider = "instagram"
+	"gorm.io/gorm"
 )
 
+// User represents a user in the system
 type User struct {
 	gorm.Model
-	Email        string       `json:"email" gorm:"uniqueIndex"`
-	PasswordHash string       `json:"-"` // Only for email/password auth
-	FirstName    string       `json:"first_name"`
-	LastName     string       `json:"last_name"`
-	Role         UserRole     `json:"role" gorm:"default:maveric"`
-	AuthProvider AuthProvider `json:"auth_provider" gorm:"default:email"`
-	ProviderID   string       `json:"provider_id"` // ID from SSO provider
-	LastLoginAt  time.Time    `json:"last_login_at"`
-	IsActive     bool         `json:"is_active" gorm:"default:true"`
-	ProfileImage string       `json:"profile_image"`
-	RefreshToken string       `json:"-"` // For JWT refresh
+	Email        string             `json:"email" gorm:"size:255;uniqueIndex;not null"`
+	PasswordHash string             `json:"-" gorm:"size:255"` // Only for email/password auth
+	FirstName    string             `json:"first_name" gorm:"size:100"`
+	LastName     string             `json:"last_name" gorm:"size:100"`
+	Role         enums.UserRole     `json:"role" gorm:"size:20;not null;default:maveric"`
+	AuthProvider enums.AuthProvider `json:"auth_provider" gorm:"size:20;not null;default:email"`
+	ProviderID   string             `json:"provider_id" gorm:"size:255"` // ID from SSO provider
+	LastLoginAt  time.Time          `json:"last_login_at"`
+	IsActive     bool               `json:"is_active" gorm:"default:true"`
+	ProfileImage string             `json:"profile_image" gorm:"size:255"`
+	Locale       string             `json:"locale" gorm:"size:35"`    // BCP 47 language tag, e.g. "en-US"
+	TimeZone     string             `json:"time_zone" gorm:"size:64"` // IANA time zone, e.g. "Europe/Berlin"
+	RefreshToken string             `json:"-" gorm:"type:text"`       // For JWT refresh
+	AnonymizedAt *time.Time         `json:"anonymized_at,omitempty"`  // Set once personal data has been erased
 
 	// Relationships
 	Quizzes          []Quiz            `json:"quizzes" gorm:"foreignKey:CreatedBy"`
@@ -45,12 +36,41 @@ type User struct {
 	CategoryRankings []CategoryRanking `json:"category_rankings" gorm:"foreignKey:UserID"`
 }
 
+// TableName specifies the table name for the User model
+func (User) TableName() string {
+	return "users"
+}
+
+// LogValue describes the user in logs without the password hash, refresh token or personal data
+func (u User) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Uint64("id", uint64(u.ID)),
+		slog.String("role", string(u.Role)),
+		slog.String("auth_provider", string(u.AuthProvider)),
+	)
+}
+
+// SSOConfig represents the configuration for a Single Sign-On provider
 type SSOConfig struct {
 	gorm.Model
-	Provider     AuthProvider `json:"provider" gorm:"uniqueIndex"`
-	ClientID     string       `json:"client_id"`
-	ClientSecret string       `json:"client_secret"`
-	RedirectURL  string       `json:"redirect_url"`
-	Scopes       string       `json:"scopes"` // Comma-separated scopes
-	IsEnabled    bool         `json:"is_enabled" gorm:"default:true"`
+	Provider     enums.AuthProvider `json:"provider" gorm:"size:20;uniqueIndex"`
+	ClientID     string             `json:"client_id" gorm:"size:255;not null"`
+	ClientSecret string             `json:"client_secret" gorm:"size:255;not null"`
+	RedirectURL  string             `json:"redirect_url" gorm:"size:255;not null"`
+	Scopes       string             `json:"scopes" gorm:"size:255"` // Comma-separated scopes
+	IsEnabled    bool               `json:"is_enabled"`             // No GORM default, which would turn false into true on create
+}
+
+// TableName specifies the table name for the SSOConfig model
+func (SSOConfig) TableName() string {
+	return "sso_configs"
+}
+
+// LogValue describes the configuration in logs without the client secret
+func (c SSOConfig) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("provider", string(c.Provider)),
+		slog.String("client_id", c.ClientID),
+		slog.Bool("is_enabled", c.IsEnabled),
+	)
 }
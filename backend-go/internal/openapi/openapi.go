@@ -0,0 +1,217 @@
+// Package openapi builds OpenAPI 3.1 documents. Schemas are reflected from
+// the Go types the handlers bind and render, following encoding/json's
+// rules for field names and embedding, and the binding tags' constraints, so
+// the document can't drift from the code.
+package openapi
+
+import (
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Version is the OpenAPI version of the documents
+const Version = "3.1.0"
+
+// Document is an OpenAPI document
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Tags       []Tag                `json:"tags,omitempty"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+}
+
+// Info describes the API
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Tag groups operations
+type Tag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem holds the operations of a path by lowercase HTTP method
+type PathItem map[string]*Operation
+
+// Operation is one method of a path
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []*Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+// Parameter is a path, query or header parameter
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"` // "path", "query" or "header"
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody is the body of an operation by media type
+type RequestBody struct {
+	Required bool                  `json:"required,omitempty"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+// MediaType is the schema of a body in one media type
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+// Response is a response of an operation, or a reference to a shared one
+type Response struct {
+	Ref         string                `json:"$ref,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Headers     map[string]*Header    `json:"headers,omitempty"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+// Header is a response header
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// Components holds what operations share by reference
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas,omitempty"`
+	Responses       map[string]*Response       `json:"responses,omitempty"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme is a way of authenticating requests
+type SecurityScheme struct {
+	Type         string `json:"type"` // e.g. "http"
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+// Spec builds a document, reflecting the schemas of the Go types it's given
+type Spec struct {
+	doc     Document
+	schemas *reflector
+}
+
+// New creates an empty document
+func New(info Info) *Spec {
+	return &Spec{
+		doc: Document{
+			OpenAPI: Version,
+			Info:    info,
+			Paths:   make(map[string]*PathItem),
+			Components: Components{
+				Schemas:         make(map[string]*Schema),
+				Responses:       make(map[string]*Response),
+				SecuritySchemes: make(map[string]*SecurityScheme),
+			},
+		},
+		schemas: newReflector(),
+	}
+}
+
+// Document returns the document built so far
+func (s *Spec) Document() *Document {
+	return &s.doc
+}
+
+// Tag adds a tag for grouping operations
+func (s *Spec) Tag(name, description string) {
+	s.doc.Tags = append(s.doc.Tags, Tag{Name: name, Description: description})
+}
+
+// SecurityScheme adds a way of authenticating requests
+func (s *Spec) SecurityScheme(name string, scheme *SecurityScheme) {
+	s.doc.Components.SecuritySchemes[name] = scheme
+}
+
+// Response adds a response operations can share with ResponseRef
+func (s *Spec) Response(name string, response *Response) {
+	s.doc.Components.Responses[name] = response
+}
+
+// Enum declares the values of a string type, which all have to be of the
+// same type. Its schema lists them wherever the type is used, and is added to
+// the components for parameters to refer to.
+func (s *Spec) Enum(values ...any) {
+	s.schemas.enum(values)
+	if len(values) > 0 {
+		s.Schema(values[0])
+	}
+}
+
+// Name sets the component name of v's type, instead of its Go name
+func (s *Spec) Name(v any, name string) {
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	s.schemas.names[t] = name
+}
+
+// Schema returns the schema of v's type, a reference for named structs and
+// enums, which are added to the components
+func (s *Spec) Schema(v any) *Schema {
+	schema := s.schemas.schema(v)
+	for name, named := range s.schemas.named {
+		s.doc.Components.Schemas[name] = named
+	}
+	return schema
+}
+
+// Add adds an operation for a Gin route, whose :param and *param segments
+// become {param}
+func (s *Spec) Add(method, path string, op *Operation) {
+	path = PathTemplate(path)
+	item, ok := s.doc.Paths[path]
+	if !ok {
+		item = &PathItem{}
+		s.doc.Paths[path] = item
+	}
+	(*item)[strings.ToLower(method)] = op
+}
+
+// Operation returns the operation of a Gin route, or nil if there's none
+func (d *Document) Operation(method, path string) *Operation {
+	item, ok := d.Paths[PathTemplate(path)]
+	if !ok {
+		return nil
+	}
+	return (*item)[strings.ToLower(method)]
+}
+
+// ginParam matches the parameter segments of Gin routes
+var ginParam = regexp.MustCompile(`[:*]([^/]+)`)
+
+// PathTemplate turns a Gin route into an OpenAPI path template
+func PathTemplate(path string) string {
+	return ginParam.ReplaceAllString(path, "{$1}")
+}
+
+// ResponseRef refers to a response added with Spec.Response
+func ResponseRef(name string) *Response {
+	return &Response{Ref: "#/components/responses/" + name}
+}
+
+// JSON returns a response with a JSON body of the given schema
+func JSON(description string, schema *Schema) *Response {
+	return &Response{Description: description, Content: map[string]*MediaType{"application/json": {Schema: schema}}}
+}
+
+// Status returns the responses key of an HTTP status
+func Status(code int) string {
+	return strconv.Itoa(code)
+}
@@ -0,0 +1,89 @@
+package openapi
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type color string
+
+type page[T any] struct {
+	Items []T `json:"items"`
+}
+
+type base struct {
+	ID      uint   `json:"id"`
+	Name    string `json:"name"`
+	Shadow  string
+	Private string `json:"-"`
+}
+
+type widget struct {
+	base
+	gorm.Model
+	Name     string     `json:"name" binding:"required,min=3"`
+	Count    int        `json:"count" binding:"min=1,max=10"`
+	Email    string     `json:"email" binding:"required,email"`
+	Tags     []string   `json:"tags" binding:"required,min=1,dive,min=2"`
+	Color    color      `json:"color"`
+	Parent   *widget    `json:"parent,omitempty"`
+	Removed  *time.Time `json:"removed"`
+	Metadata map[string]any
+	hidden   string // Not marshalled
+}
+
+func TestSchema(t *testing.T) {
+	spec := New(Info{Title: "Test", Version: "1"})
+	spec.Enum(color("red"), color("green"))
+
+	ref := spec.Schema(page[widget]{})
+	assert.Equal(t, "#/components/schemas/widgetpage", ref.Ref)
+	schemas := spec.Document().Components.Schemas
+	require.Contains(t, schemas, "widget")
+	w := schemas["widget"]
+
+	// Test case 1: Fields are named and promoted like encoding/json does
+	assert.ElementsMatch(t, []string{"id", "name", "Shadow", "ID", "CreatedAt", "UpdatedAt", "DeletedAt",
+		"count", "email", "tags", "color", "parent", "removed", "Metadata"}, keys(w.Properties))
+	assert.True(t, w.Properties["ID"].ReadOnly)
+	assert.Equal(t, []string{"string", "null"}, w.Properties["DeletedAt"].Type)
+
+	// Test case 2: Binding tags become constraints
+	assert.Equal(t, []string{"name", "email", "tags"}, w.Required)
+	assert.Equal(t, 3, *w.Properties["name"].MinLength)
+	assert.Equal(t, 1.0, *w.Properties["count"].Minimum)
+	assert.Equal(t, 10.0, *w.Properties["count"].Maximum)
+	assert.Equal(t, "email", w.Properties["email"].Format)
+	assert.Equal(t, 1, *w.Properties["tags"].MinItems)
+	assert.Nil(t, w.Properties["tags"].Items.MinLength)
+
+	// Test case 3: References, enums and nullable pointers
+	assert.Equal(t, "#/components/schemas/widget", w.Properties["parent"].Ref)
+	assert.Equal(t, "#/components/schemas/color", w.Properties["color"].Ref)
+	assert.Equal(t, []any{color("red"), color("green")}, schemas["color"].Enum)
+	assert.Equal(t, []string{"string", "null"}, w.Properties["removed"].Type)
+	assert.Equal(t, "date-time", w.Properties["removed"].Format)
+	assert.Equal(t, &Schema{}, w.Properties["Metadata"].AdditionalProperties)
+}
+
+func TestPaths(t *testing.T) {
+	spec := New(Info{Title: "Test", Version: "1"})
+	spec.Add("GET", "/api/users/:id/files/*path", &Operation{OperationID: "getFile"})
+
+	assert.Contains(t, spec.Document().Paths, "/api/users/{id}/files/{path}")
+	assert.Equal(t, "getFile", spec.Document().Operation("GET", "/api/users/:id/files/*path").OperationID)
+	assert.Nil(t, spec.Document().Operation("POST", "/api/users/:id/files/*path"))
+	assert.Nil(t, spec.Document().Operation("GET", "/api/users"))
+}
+
+func keys(m map[string]*Schema) []string {
+	var out []string
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
@@ -0,0 +1,373 @@
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// Schema is a JSON Schema 2020-12 schema, as OpenAPI 3.1 uses them
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 any                `json:"type,omitempty"` // A type name, or a list of them
+	Format               string             `json:"format,omitempty"`
+	ContentMediaType     string             `json:"contentMediaType,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	ReadOnly             bool               `json:"readOnly,omitempty"`
+	Default              any                `json:"default,omitempty"`
+}
+
+// String returns a string schema
+func String(description string) *Schema {
+	return &Schema{Type: "string", Description: description}
+}
+
+// Integer returns an integer schema
+func Integer(description string) *Schema {
+	return &Schema{Type: "integer", Description: description}
+}
+
+// Boolean returns a boolean schema
+func Boolean(description string) *Schema {
+	return &Schema{Type: "boolean", Description: description}
+}
+
+// Binary returns the schema of a file
+func Binary(mediaType string) *Schema {
+	return &Schema{Type: "string", ContentMediaType: mediaType}
+}
+
+// Types with a fixed schema, usually because they marshal themselves
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	deletedAtType = reflect.TypeOf(gorm.DeletedAt{})
+	gormModelType = reflect.TypeOf(gorm.Model{})
+	rawType       = reflect.TypeOf(json.RawMessage{})
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textType      = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// reflector turns Go types into schemas, naming the structs and enums
+type reflector struct {
+	named map[string]*Schema
+	names map[reflect.Type]string
+	enums map[reflect.Type][]any
+}
+
+func newReflector() *reflector {
+	return &reflector{
+		named: make(map[string]*Schema),
+		names: make(map[reflect.Type]string),
+		enums: make(map[reflect.Type][]any),
+	}
+}
+
+func (r *reflector) enum(values []any) {
+	if len(values) == 0 {
+		return
+	}
+	t := reflect.TypeOf(values[0])
+	for _, v := range values {
+		if reflect.TypeOf(v) != t {
+			panic(fmt.Sprintf("openapi: enum values of different types %s and %T", t, v))
+		}
+	}
+	r.enums[t] = values
+}
+
+func (r *reflector) schema(v any) *Schema {
+	if v == nil {
+		return &Schema{}
+	}
+	return r.typeSchema(reflect.TypeOf(v))
+}
+
+func (r *reflector) typeSchema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case deletedAtType:
+		return &Schema{Type: []string{"string", "null"}, Format: "date-time", ReadOnly: true}
+	case rawType:
+		return &Schema{}
+	}
+	if values, ok := r.enums[t]; ok {
+		return r.ref(t, func() *Schema { return &Schema{Type: "string", Enum: values} })
+	}
+	if t.Implements(marshalerType) || reflect.PointerTo(t).Implements(marshalerType) {
+		// Marshals itself into anything
+		return &Schema{}
+	}
+	if t.Implements(textType) || reflect.PointerTo(t).Implements(textType) {
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer"}
+	case reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Minimum: float(0)}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: r.typeSchema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: r.typeSchema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return r.object(t)
+		}
+		return r.ref(t, func() *Schema { return r.object(t) })
+	}
+	// Interfaces and anything else can be any value
+	return &Schema{}
+}
+
+// ref returns a reference to the named schema of t, building it first if needed
+func (r *reflector) ref(t reflect.Type, build func() *Schema) *Schema {
+	name, ok := r.names[t]
+	if !ok {
+		name = r.name(t)
+		r.names[t] = name
+	}
+	if _, built := r.named[name]; !built {
+		// A placeholder first, for types referring to themselves
+		r.named[name] = &Schema{}
+		*r.named[name] = *build()
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// name picks a unique component name for t: its Go name, with the type
+// argument first for generic types (QuizSummaryList for List[QuizSummary]),
+// prefixed with its package if another type has that name
+func (r *reflector) name(t reflect.Type) string {
+	name := t.Name()
+	if base, args, ok := strings.Cut(name, "["); ok {
+		args = strings.TrimSuffix(args, "]")
+		var prefix string
+		for _, arg := range strings.Split(args, ",") {
+			prefix += arg[strings.LastIndex(arg, ".")+1:]
+		}
+		name = prefix + base
+	}
+	if _, taken := r.named[name]; taken {
+		pkg := t.PkgPath()
+		pkg = pkg[strings.LastIndex(pkg, "/")+1:]
+		name = string(unicode.ToUpper(rune(pkg[0]))) + pkg[1:] + name
+	}
+	return name
+}
+
+// field is a JSON property of a struct, found at some depth of embedding
+type field struct {
+	name   string
+	tagged bool
+	depth  int
+	field  reflect.StructField
+	owner  reflect.Type
+}
+
+// object returns the schema of a struct's JSON object
+func (r *reflector) object(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for _, f := range jsonFields(t) {
+		prop, required := r.property(f)
+		s.Properties[f.name] = prop
+		if required {
+			s.Required = append(s.Required, f.name)
+		}
+	}
+	return s
+}
+
+// property returns the schema of a field and whether requests must set it
+func (r *reflector) property(f field) (*Schema, bool) {
+	_, opts, _ := strings.Cut(f.field.Tag.Get("json"), ",")
+	omitEmpty := strings.Contains(","+opts+",", ",omitempty,")
+	prop := r.typeSchema(f.field.Type)
+
+	required := false
+	for _, rule := range strings.Split(f.field.Tag.Get("binding"), ",") {
+		name, arg, _ := strings.Cut(rule, "=")
+		if name == "dive" {
+			break
+		}
+		switch name {
+		case "required":
+			required = true
+		case "email":
+			prop.Format = "email"
+		case "url":
+			prop.Format = "uri"
+		case "oneof":
+			for _, v := range strings.Fields(arg) {
+				prop.Enum = append(prop.Enum, v)
+			}
+		case "min", "max":
+			n, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				continue
+			}
+			limit(prop, name == "min", n)
+		}
+	}
+
+	if f.owner == gormModelType {
+		prop.ReadOnly = true
+	}
+	if f.field.Type.Kind() == reflect.Pointer && !omitEmpty && !required {
+		prop = nullable(prop)
+	}
+	return prop, required
+}
+
+// limit applies a min or max binding to a schema of any kind
+func limit(s *Schema, min bool, n float64) {
+	count := int(n)
+	switch s.Type {
+	case "string":
+		if min {
+			s.MinLength = &count
+		} else {
+			s.MaxLength = &count
+		}
+	case "array":
+		if min {
+			s.MinItems = &count
+		} else {
+			s.MaxItems = &count
+		}
+	case "integer", "number":
+		if min {
+			s.Minimum = &n
+		} else {
+			s.Maximum = &n
+		}
+	}
+}
+
+// nullable allows null besides what s allows
+func nullable(s *Schema) *Schema {
+	if name, ok := s.Type.(string); ok && s.Ref == "" {
+		s.Type = []string{name, "null"}
+		return s
+	}
+	if s.Ref != "" {
+		return &Schema{AnyOf: []*Schema{s, {Type: "null"}}}
+	}
+	return s
+}
+
+// jsonFields returns the fields encoding/json marshals for a struct: those of
+// embedded structs are promoted unless a shallower or tagged field has the
+// same name, and same-name fields at the same depth cancel out
+func jsonFields(t reflect.Type) []field {
+	var all []field
+	var walk func(t reflect.Type, depth int)
+	walk = func(t reflect.Type, depth int) {
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			tag := sf.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name, _, _ := strings.Cut(tag, ",")
+
+			ft := sf.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+				walk(ft, depth+1)
+				continue
+			}
+			if !sf.IsExported() {
+				continue
+			}
+			tagged := name != ""
+			if !tagged {
+				name = sf.Name
+			}
+			all = append(all, field{name: name, tagged: tagged, depth: depth, field: sf, owner: t})
+		}
+	}
+	walk(t, 0)
+
+	byName := make(map[string][]field)
+	var order []string
+	for _, f := range all {
+		if _, seen := byName[f.name]; !seen {
+			order = append(order, f.name)
+		}
+		byName[f.name] = append(byName[f.name], f)
+	}
+
+	var fields []field
+	for _, name := range order {
+		if f, ok := dominant(byName[name]); ok {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// dominant picks the field marshalled among those with the same name
+func dominant(fields []field) (field, bool) {
+	depth := fields[0].depth
+	for _, f := range fields {
+		depth = min(depth, f.depth)
+	}
+	var shallowest, tagged []field
+	for _, f := range fields {
+		if f.depth == depth {
+			shallowest = append(shallowest, f)
+			if f.tagged {
+				tagged = append(tagged, f)
+			}
+		}
+	}
+	switch {
+	case len(shallowest) == 1:
+		return shallowest[0], true
+	case len(tagged) == 1:
+		return tagged[0], true
+	}
+	return field{}, false
+}
+
+func float(n float64) *float64 {
+	return &n
+}
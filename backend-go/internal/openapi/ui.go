@@ -0,0 +1,29 @@
+package openapi
+
+import (
+	"bytes"
+	_ "embed"
+	"html/template"
+	"net/http"
+)
+
+// SwaggerUIVersion is the swagger-ui-dist release the docs page loads from the CDN
+const SwaggerUIVersion = "5.17.14"
+
+//go:embed swagger-ui.html
+var swaggerUIPage string
+
+var swaggerUITemplate = template.Must(template.New("swagger-ui").Parse(swaggerUIPage))
+
+// SwaggerUI serves a Swagger UI page for the document at specURL
+func SwaggerUI(title, specURL string) http.Handler {
+	var page bytes.Buffer
+	err := swaggerUITemplate.Execute(&page, struct{ Title, Version, SpecURL string }{title, SwaggerUIVersion, specURL})
+	if err != nil {
+		panic(err)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write(page.Bytes())
+	})
+}
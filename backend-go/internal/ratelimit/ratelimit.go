@@ -0,0 +1,145 @@
+// Package ratelimit implements token bucket rate limits. Each key, such as
+// a user or client IP under a policy, has a bucket holding up to Burst
+// tokens, refilled at a steady rate; every request takes one token and is
+// refused when the bucket is empty.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"aicg/internal/config"
+)
+
+// Limit is the size and refill rate of a bucket
+type Limit struct {
+	Burst  int     // Tokens in a full bucket
+	PerSec float64 // Tokens added per second
+}
+
+// PerMinute returns the limit of a policy allowing burst requests at once
+// and requests per minute over time
+func PerMinute(requests, burst int) Limit {
+	return Limit{Burst: burst, PerSec: float64(requests) / 60}
+}
+
+// FromPolicy returns the limit of a configured policy
+func FromPolicy(p config.RateLimitPolicy) Limit {
+	return PerMinute(p.RequestsPerMinute, p.Burst)
+}
+
+// Result is the outcome of taking a token
+type Result struct {
+	Allowed    bool
+	Limit      int           // Size of the bucket
+	Remaining  int           // Whole tokens left after this request
+	Reset      time.Duration // Until the bucket is full again
+	RetryAfter time.Duration // Until the next token, when refused
+}
+
+// Store keeps the buckets
+type Store interface {
+	// Take takes a token from the bucket under key, creating a full bucket if there is none
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// NewFromConfig creates the store selected by cfg.Store
+func NewFromConfig(cfg config.RateLimitConfig) (Store, error) {
+	switch cfg.Store {
+	case "", "memory":
+		return NewMemoryStore(), nil
+	case "redis":
+		return NewRedisStoreFromURL(cfg.RedisURL)
+	default:
+		return nil, fmt.Errorf("unknown rate limit store %q", cfg.Store)
+	}
+}
+
+// result describes a bucket left with tokens after a request
+func result(allowed bool, tokens float64, limit Limit) Result {
+	r := Result{
+		Allowed:   allowed,
+		Limit:     limit.Burst,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     seconds((float64(limit.Burst) - tokens) / limit.PerSec),
+	}
+	if !allowed {
+		r.RetryAfter = seconds((1 - tokens) / limit.PerSec)
+	}
+	return r
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Ceil(s * float64(time.Second)))
+}
+
+// bucket is the state of one key
+type bucket struct {
+	tokens float64
+	at     time.Time // When tokens was last updated
+}
+
+// refill adds the tokens earned since the bucket was last updated
+func (b *bucket) refill(now time.Time, limit Limit) {
+	if elapsed := now.Sub(b.at).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(limit.Burst), b.tokens+elapsed*limit.PerSec)
+	}
+	b.at = now
+}
+
+// MemoryStore keeps the buckets in the process, so each server instance
+// limits on its own. Full buckets are forgotten periodically.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*memoryBucket
+	swept   time.Time
+	now     func() time.Time
+}
+
+type memoryBucket struct {
+	bucket
+	full time.Time // When the bucket will be full, and can be forgotten
+}
+
+// sweepInterval is how often MemoryStore forgets full buckets
+const sweepInterval = time.Minute
+
+// NewMemoryStore creates an empty in-process store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]*memoryBucket{}, now: time.Now}
+}
+
+// Take implements Store
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.swept) >= sweepInterval {
+		for k, b := range s.buckets {
+			if !now.Before(b.full) {
+				delete(s.buckets, k)
+			}
+		}
+		s.swept = now
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &memoryBucket{bucket: bucket{tokens: float64(limit.Burst), at: now}}
+		s.buckets[key] = b
+	}
+	b.refill(now, limit)
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	r := result(allowed, b.tokens, limit)
+	b.full = now.Add(r.Reset)
+	return r, nil
+}
+
+var _ Store = (*MemoryStore)(nil)
@@ -0,0 +1,143 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"aicg/internal/config"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clock is a fake time source the tests move forward by hand
+type clock struct {
+	t time.Time
+}
+
+func newClock() *clock {
+	return &clock{t: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+func (c *clock) now() time.Time {
+	return c.t
+}
+
+func (c *clock) advance(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+// testStore runs the same scenarios against every store
+func testStore(t *testing.T, newStore func(c *clock) Store) {
+	ctx := context.Background()
+	limit := PerMinute(60, 3) // A token a second, three at once
+
+	t.Run("burst then refill", func(t *testing.T) {
+		c := newClock()
+		s := newStore(c)
+
+		// Test case 1: A full bucket allows Burst requests at once
+		for want := 2; want >= 0; want-- {
+			r, err := s.Take(ctx, "user:1", limit)
+			require.NoError(t, err)
+			assert.True(t, r.Allowed)
+			assert.Equal(t, 3, r.Limit)
+			assert.Equal(t, want, r.Remaining)
+		}
+
+		// Test case 2: Then refuses, saying when to retry
+		r, err := s.Take(ctx, "user:1", limit)
+		require.NoError(t, err)
+		assert.False(t, r.Allowed)
+		assert.Equal(t, 0, r.Remaining)
+		assert.Equal(t, time.Second, r.RetryAfter)
+		assert.Equal(t, 3*time.Second, r.Reset)
+
+		// Test case 3: Tokens come back at the refill rate
+		c.advance(1500 * time.Millisecond)
+		r, err = s.Take(ctx, "user:1", limit)
+		require.NoError(t, err)
+		assert.True(t, r.Allowed)
+		assert.Equal(t, 0, r.Remaining)
+		assert.Equal(t, 2500*time.Millisecond, r.Reset)
+
+		// Test case 4: But never more than Burst
+		c.advance(time.Hour)
+		r, err = s.Take(ctx, "user:1", limit)
+		require.NoError(t, err)
+		assert.Equal(t, 2, r.Remaining)
+	})
+
+	t.Run("keys are independent", func(t *testing.T) {
+		c := newClock()
+		s := newStore(c)
+		for i := 0; i < 3; i++ {
+			_, err := s.Take(ctx, "ip:192.0.2.1", limit)
+			require.NoError(t, err)
+		}
+		r, err := s.Take(ctx, "ip:192.0.2.1", limit)
+		require.NoError(t, err)
+		assert.False(t, r.Allowed)
+
+		r, err = s.Take(ctx, "ip:192.0.2.2", limit)
+		require.NoError(t, err)
+		assert.True(t, r.Allowed)
+	})
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, func(c *clock) Store {
+		s := NewMemoryStore()
+		s.now = c.now
+		return s
+	})
+
+	// Full buckets are forgotten after a while
+	c := newClock()
+	s := NewMemoryStore()
+	s.now = c.now
+	_, err := s.Take(context.Background(), "user:1", PerMinute(60, 3))
+	require.NoError(t, err)
+	c.advance(2 * sweepInterval)
+	_, err = s.Take(context.Background(), "user:2", PerMinute(60, 3))
+	require.NoError(t, err)
+	assert.Len(t, s.buckets, 1)
+}
+
+func TestRedisStore(t *testing.T) {
+	testStore(t, func(c *clock) Store {
+		s := NewRedisStore(redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()}))
+		s.now = c.now
+		return s
+	})
+
+	// Buckets expire once they would be full again
+	srv := miniredis.RunT(t)
+	s, err := NewRedisStoreFromURL("redis://" + srv.Addr())
+	require.NoError(t, err)
+	_, err = s.Take(context.Background(), "user:1", PerMinute(60, 3))
+	require.NoError(t, err)
+	assert.True(t, srv.Exists(redisPrefix+"user:1"))
+	srv.FastForward(3 * time.Second)
+	assert.False(t, srv.Exists(redisPrefix+"user:1"))
+
+	// An unreachable server is an error, which callers may choose to ignore
+	srv.Close()
+	_, err = s.Take(context.Background(), "user:1", PerMinute(60, 3))
+	assert.Error(t, err)
+}
+
+func TestNewFromConfig(t *testing.T) {
+	cfg := config.Default().RateLimit
+	store, err := NewFromConfig(cfg)
+	require.NoError(t, err)
+	assert.IsType(t, &MemoryStore{}, store)
+
+	cfg.Store = "redis"
+	cfg.RedisURL = "http://localhost:6379"
+	_, err = NewFromConfig(cfg)
+	assert.ErrorContains(t, err, "invalid rate limit redis URL")
+}
@@ -0,0 +1,87 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisPrefix namespaces the bucket keys in a shared Redis
+const redisPrefix = "ratelimit:"
+
+// takeScript refills and takes from a bucket atomically. The bucket is a
+// hash of its tokens and the time in milliseconds they were counted at,
+// which expires once it would be full again. The caller passes the time so
+// that the script stays deterministic.
+var takeScript = redis.NewScript(`
+local burst = tonumber(ARGV[1])
+local per_ms = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local state = redis.call("HMGET", KEYS[1], "tokens", "at")
+local tokens = tonumber(state[1])
+local at = tonumber(state[2])
+if tokens == nil or at == nil then
+	tokens = burst
+	at = now
+end
+if now > at then
+	tokens = math.min(burst, tokens + (now - at) * per_ms)
+	at = now
+end
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "at", at)
+redis.call("PEXPIRE", KEYS[1], math.ceil((burst - tokens) / per_ms) + 1000)
+return {allowed, tostring(tokens)}
+`)
+
+// RedisStore keeps the buckets in Redis, or anything speaking its protocol
+// and running Lua scripts, so that all server instances share the limits
+type RedisStore struct {
+	client redis.Scripter
+	now    func() time.Time
+}
+
+// NewRedisStore creates a store on an existing client
+func NewRedisStore(client redis.Scripter) *RedisStore {
+	return &RedisStore{client: client, now: time.Now}
+}
+
+// NewRedisStoreFromURL connects to the Redis at a redis:// or rediss:// URL
+func NewRedisStoreFromURL(url string) (*RedisStore, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rate limit redis URL: %w", err)
+	}
+	return NewRedisStore(redis.NewClient(opts)), nil
+}
+
+// Take implements Store
+func (s *RedisStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	now := s.now().UnixMilli()
+	reply, err := takeScript.Run(ctx, s.client, []string{redisPrefix + key},
+		limit.Burst, strconv.FormatFloat(limit.PerSec/1000, 'g', -1, 64), now).Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to take rate limit token: %w", err)
+	}
+	if len(reply) != 2 {
+		return Result{}, fmt.Errorf("unexpected rate limit script reply %v", reply)
+	}
+	allowed, _ := reply[0].(int64)
+	raw, _ := reply[1].(string)
+	tokens, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return Result{}, fmt.Errorf("unexpected rate limit token count %q", raw)
+	}
+	return result(allowed == 1, tokens, limit), nil
+}
+
+var _ Store = (*RedisStore)(nil)
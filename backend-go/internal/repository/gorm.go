@@ -0,0 +1,386 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"time"
+
+	"aicg/internal/models"
+	"aicg/internal/models/enums"
+
+	"gorm.io/gorm"
+)
+
+// GormStore is the Store backed by a GORM database
+type GormStore struct {
+	db *gorm.DB
+}
+
+func NewGormStore(db *gorm.DB) *GormStore {
+	return &GormStore{db: db}
+}
+
+func (s *GormStore) Quizzes() QuizRepository         { return gormQuizzes{s.db} }
+func (s *GormStore) Results() ResultRepository       { return gormResults{s.db} }
+func (s *GormStore) Progress() ProgressRepository    { return gormProgress{s.db} }
+func (s *GormStore) Users() UserRepository           { return gormUsers{s.db} }
+func (s *GormStore) SSOConfigs() SSOConfigRepository { return gormSSOConfigs{s.db} }
+
+// Transaction runs fn inside a database transaction
+func (s *GormStore) Transaction(fn func(tx Store) error) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return fn(NewGormStore(tx))
+	})
+}
+
+// WithContext returns a store whose queries run with ctx
+func (s *GormStore) WithContext(ctx context.Context) Store {
+	return NewGormStore(s.db.WithContext(ctx))
+}
+
+// first loads the first record matching the conditions into dest,
+// translating GORM's not found error into ErrNotFound
+func first(query *gorm.DB, dest interface{}, conds ...interface{}) error {
+	err := query.First(dest, conds...).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+type gormQuizzes struct{ db *gorm.DB }
+
+func (r gormQuizzes) Create(quiz *models.Quiz) error {
+	return r.db.Create(quiz).Error
+}
+
+func (r gormQuizzes) GetByID(id uint) (*models.Quiz, error) {
+	var quiz models.Quiz
+	if err := first(r.db.Preload("Questions.Answers"), &quiz, id); err != nil {
+		return nil, err
+	}
+	return &quiz, nil
+}
+
+// quizSummaryColumns selects a quiz summary, counting the questions in a subquery
+const quizSummaryColumns = "quizzes.id, quizzes.title, quizzes.description, quizzes.category, quizzes.difficulty, " +
+	"quizzes.time_limit, quizzes.created_by, quizzes.is_published, quizzes.passing_score, quizzes.created_at, quizzes.updated_at, " +
+	"(SELECT COUNT(*) FROM questions WHERE questions.quiz_id = quizzes.id AND questions.deleted_at IS NULL) AS question_count"
+
+// where restricts a query on quizzes to the ones matching the filter
+func (f QuizFilter) where(query *gorm.DB) *gorm.DB {
+	if f.Category != "" {
+		query = query.Where("quizzes.category = ?", f.Category)
+	}
+	if f.Difficulty != "" {
+		query = query.Where("quizzes.difficulty = ?", f.Difficulty)
+	}
+	if f.Published != nil {
+		query = query.Where("quizzes.is_published = ?", *f.Published)
+	}
+	if f.CreatedBy != 0 {
+		query = query.Where("quizzes.created_by = ?", f.CreatedBy)
+	}
+	if !f.From.IsZero() {
+		query = query.Where("quizzes.created_at >= ?", f.From)
+	}
+	if !f.To.IsZero() {
+		query = query.Where("quizzes.created_at < ?", f.To)
+	}
+	return query
+}
+
+func (r gormQuizzes) Find(filter QuizFilter, page Page) (List[models.QuizSummary], error) {
+	query := filter.where(r.db.Model(&models.Quiz{}).Select(quizSummaryColumns))
+
+	var quizzes []models.QuizSummary
+	if err := QuizSorting.Paginate(query, page).Scan(&quizzes).Error; err != nil {
+		return List[models.QuizSummary]{}, err
+	}
+	return QuizSorting.List(quizzes, page), nil
+}
+
+func (r gormQuizzes) ListPublished(filter QuizFilter) ([]models.Quiz, error) {
+	published := true
+	filter.Published = &published
+
+	var quizzes []models.Quiz
+	if err := filter.where(r.db).Order("id").Find(&quizzes).Error; err != nil {
+		return nil, err
+	}
+	return quizzes, nil
+}
+
+type gormResults struct{ db *gorm.DB }
+
+func (r gormResults) Create(result *models.Result) error {
+	return r.db.Create(result).Error
+}
+
+func (r gormResults) GetByID(id uint) (*models.Result, error) {
+	var result models.Result
+	if err := first(r.db, &result, id); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// resultSummaryColumns selects a result summary along with the quiz it's for
+const resultSummaryColumns = "results.id, results.quiz_id, quizzes.title AS quiz_title, quizzes.category, quizzes.difficulty, " +
+	"results.user_id, results.score, results.total_questions, results.correct_answers, results.time_taken, " +
+	"results.is_passed, results.passing_score, results.created_at"
+
+// where restricts a query on results joined with their quizzes to the ones matching the filter
+func (f ResultFilter) where(query *gorm.DB) *gorm.DB {
+	if f.UserID != 0 {
+		query = query.Where("results.user_id = ?", f.UserID)
+	}
+	if f.QuizID != 0 {
+		query = query.Where("results.quiz_id = ?", f.QuizID)
+	}
+	if f.Category != "" {
+		query = query.Where("quizzes.category = ?", f.Category)
+	}
+	if f.Difficulty != "" {
+		query = query.Where("quizzes.difficulty = ?", f.Difficulty)
+	}
+	if f.Passed != nil {
+		query = query.Where("results.is_passed = ?", *f.Passed)
+	}
+	if !f.From.IsZero() {
+		query = query.Where("results.created_at >= ?", f.From)
+	}
+	if !f.To.IsZero() {
+		query = query.Where("results.created_at < ?", f.To)
+	}
+	return query
+}
+
+func (r gormResults) Find(filter ResultFilter, page Page) (List[models.ResultSummary], error) {
+	query := filter.where(r.db.Model(&models.Result{}).
+		Select(resultSummaryColumns).
+		Joins("LEFT JOIN quizzes ON quizzes.id = results.quiz_id"))
+
+	var results []models.ResultSummary
+	if err := ResultSorting.Paginate(query, page).Scan(&results).Error; err != nil {
+		return List[models.ResultSummary]{}, err
+	}
+	return ResultSorting.List(results, page), nil
+}
+
+type gormProgress struct{ db *gorm.DB }
+
+func (r gormProgress) Get(userID, quizID uint) (*models.UserProgress, error) {
+	var progress models.UserProgress
+	if err := first(r.db.Where("user_id = ? AND quiz_id = ?", userID, quizID), &progress); err != nil {
+		return nil, err
+	}
+	return &progress, nil
+}
+
+func (r gormProgress) ListByUser(userID uint) ([]models.UserProgress, error) {
+	var progress []models.UserProgress
+	if err := r.db.Where("user_id = ?", userID).Order("id").Find(&progress).Error; err != nil {
+		return nil, err
+	}
+	return progress, nil
+}
+
+func (r gormProgress) TotalsByCategory(userID uint) ([]ProgressTotals, error) {
+	var rows []struct {
+		Category         enums.QuizCategory
+		QuizzesAttempted int
+		QuizzesCovered   int
+		TotalAttempts    int
+		BestScore        float64
+		ScoreSum         float64
+		MasterySum       float64
+		TotalTimeSpent   int
+		LastAttemptedAt  aggregateTime
+	}
+	err := r.db.Table("user_progress AS p").
+		Select("p.category, COUNT(*) AS quizzes_attempted, COUNT(q.id) AS quizzes_covered, "+
+			"SUM(p.total_attempts) AS total_attempts, MAX(p.best_score) AS best_score, "+
+			"SUM(p.average_score * p.total_attempts) AS score_sum, SUM(p.mastery_level * p.total_attempts) AS mastery_sum, "+
+			"SUM(p.total_time_spent) AS total_time_spent, MAX(p.last_attempted_at) AS last_attempted_at").
+		Joins("LEFT JOIN quizzes q ON q.id = p.quiz_id AND q.is_published = ? AND q.deleted_at IS NULL", true).
+		Where("p.user_id = ? AND p.deleted_at IS NULL", userID).
+		Group("p.category").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var published []struct {
+		Category enums.QuizCategory
+		Count    int
+	}
+	if err := r.db.Model(&models.Quiz{}).Select("category, COUNT(*) AS count").
+		Where("is_published = ?", true).Group("category").Scan(&published).Error; err != nil {
+		return nil, err
+	}
+
+	totals := make([]ProgressTotals, 0, len(rows)+len(published))
+	index := make(map[enums.QuizCategory]int, len(rows))
+	for _, row := range rows {
+		index[row.Category] = len(totals)
+		totals = append(totals, ProgressTotals{
+			Category:         row.Category,
+			QuizzesAttempted: row.QuizzesAttempted,
+			QuizzesCovered:   row.QuizzesCovered,
+			TotalAttempts:    row.TotalAttempts,
+			BestScore:        row.BestScore,
+			ScoreSum:         row.ScoreSum,
+			MasterySum:       row.MasterySum,
+			TotalTimeSpent:   row.TotalTimeSpent,
+			LastAttemptedAt:  row.LastAttemptedAt.Time,
+		})
+	}
+	for _, p := range published {
+		if i, ok := index[p.Category]; ok {
+			totals[i].QuizzesPublished = p.Count
+		} else {
+			totals = append(totals, ProgressTotals{Category: p.Category, QuizzesPublished: p.Count})
+		}
+	}
+	return totals, nil
+}
+
+// aggregateTime scans the MAX of a time column. SQLite hands it back as
+// text, since aggregates lose the column's type, where Postgres keeps it.
+type aggregateTime struct {
+	Time *time.Time
+}
+
+// aggregateTimeLayouts are the ways SQLite drivers write times
+var aggregateTimeLayouts = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02T15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+}
+
+// Value lets GORM take it for a column rather than a relation
+func (t aggregateTime) Value() (driver.Value, error) {
+	if t.Time == nil {
+		return nil, nil
+	}
+	return *t.Time, nil
+}
+
+func (t *aggregateTime) Scan(src any) error {
+	var text string
+	switch v := src.(type) {
+	case nil:
+		t.Time = nil
+		return nil
+	case time.Time:
+		t.Time = &v
+		return nil
+	case string:
+		text = v
+	case []byte:
+		text = string(v)
+	default:
+		return fmt.Errorf("can't scan %T into a time", src)
+	}
+	for _, layout := range aggregateTimeLayouts {
+		if parsed, err := time.Parse(layout, text); err == nil {
+			t.Time = &parsed
+			return nil
+		}
+	}
+	return fmt.Errorf("can't parse %q as a time", text)
+}
+
+func (r gormProgress) Save(progress *models.UserProgress) error {
+	if progress.ID == 0 {
+		err := r.db.Create(progress).Error
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return ErrConflict
+		}
+		return err
+	}
+
+	result := r.db.Model(&models.UserProgress{}).
+		Where("id = ? AND version = ?", progress.ID, progress.Version).
+		Updates(map[string]interface{}{
+			"category":          progress.Category,
+			"total_attempts":    progress.TotalAttempts,
+			"best_score":        progress.BestScore,
+			"average_score":     progress.AverageScore,
+			"total_time_spent":  progress.TotalTimeSpent,
+			"last_attempted_at": progress.LastAttemptedAt,
+			"mastery_level":     progress.MasteryLevel,
+			"version":           progress.Version + 1,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrConflict
+	}
+	progress.Version++
+	return nil
+}
+
+type gormUsers struct{ db *gorm.DB }
+
+func (r gormUsers) Create(user *models.User) error {
+	return r.db.Create(user).Error
+}
+
+func (r gormUsers) GetByID(id uint) (*models.User, error) {
+	var user models.User
+	if err := first(r.db, &user, id); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r gormUsers) GetByEmail(email string) (*models.User, error) {
+	var user models.User
+	if err := first(r.db.Where("email = ?", email), &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r gormUsers) GetByProvider(provider enums.AuthProvider, providerID string) (*models.User, error) {
+	var user models.User
+	if err := first(r.db.Where("provider_id = ? AND auth_provider = ?", providerID, provider), &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r gormUsers) GetByRefreshToken(token string) (*models.User, error) {
+	var user models.User
+	if err := first(r.db.Where("refresh_token = ?", token), &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r gormUsers) Save(user *models.User) error {
+	return r.db.Save(user).Error
+}
+
+type gormSSOConfigs struct{ db *gorm.DB }
+
+func (r gormSSOConfigs) Create(config *models.SSOConfig) error {
+	return r.db.Create(config).Error
+}
+
+func (r gormSSOConfigs) GetEnabled(provider enums.AuthProvider) (*models.SSOConfig, error) {
+	var config models.SSOConfig
+	if err := first(r.db.Where("provider = ? AND is_enabled = ?", provider, true), &config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// Ensure GormStore implements Store
+var _ Store = (*GormStore)(nil)
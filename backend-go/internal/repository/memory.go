@@ -0,0 +1,545 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"aicg/internal/models"
+	"aicg/internal/models/enums"
+
+	"gorm.io/gorm"
+)
+
+// MemoryStore is a thread-safe Store that keeps everything in memory.
+// It's meant for tests: records are copied on the way in and out, IDs and
+// timestamps are filled in and unique constraints enforced like the database
+// does, and transactions are all-or-nothing. Transactions run one at a time
+// and block other callers.
+type MemoryStore struct {
+	mu   sync.RWMutex
+	data *memoryData
+}
+
+// memoryData holds the records of every table, keyed by ID
+type memoryData struct {
+	lastID     map[string]uint
+	quizzes    map[uint]models.Quiz
+	results    map[uint]models.Result
+	progress   map[uint]models.UserProgress
+	users      map[uint]models.User
+	ssoConfigs map[uint]models.SSOConfig
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{data: &memoryData{
+		lastID:     map[string]uint{},
+		quizzes:    map[uint]models.Quiz{},
+		results:    map[uint]models.Result{},
+		progress:   map[uint]models.UserProgress{},
+		users:      map[uint]models.User{},
+		ssoConfigs: map[uint]models.SSOConfig{},
+	}}
+}
+
+func (s *MemoryStore) Quizzes() QuizRepository         { return memoryQuizzes{s} }
+func (s *MemoryStore) Results() ResultRepository       { return memoryResults{s} }
+func (s *MemoryStore) Progress() ProgressRepository    { return memoryProgress{s} }
+func (s *MemoryStore) Users() UserRepository           { return memoryUsers{s} }
+func (s *MemoryStore) SSOConfigs() SSOConfigRepository { return memorySSOConfigs{s} }
+
+// Transaction runs fn against a copy of the data that replaces the
+// original only if fn succeeds. fn must use the store it's given; calling
+// the outer store from inside the transaction deadlocks.
+func (s *MemoryStore) Transaction(fn func(tx Store) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx := &MemoryStore{data: s.data.clone()}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	s.data = tx.data
+	return nil
+}
+
+// WithContext returns the store itself; memory operations can't be canceled
+func (s *MemoryStore) WithContext(ctx context.Context) Store {
+	return s
+}
+
+// read runs fn while holding the read lock
+func (s *MemoryStore) read(fn func(d *memoryData) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return fn(s.data)
+}
+
+// write runs fn while holding the write lock
+func (s *MemoryStore) write(fn func(d *memoryData) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return fn(s.data)
+}
+
+func (d *memoryData) clone() *memoryData {
+	c := &memoryData{
+		lastID:     make(map[string]uint, len(d.lastID)),
+		quizzes:    make(map[uint]models.Quiz, len(d.quizzes)),
+		results:    make(map[uint]models.Result, len(d.results)),
+		progress:   make(map[uint]models.UserProgress, len(d.progress)),
+		users:      make(map[uint]models.User, len(d.users)),
+		ssoConfigs: make(map[uint]models.SSOConfig, len(d.ssoConfigs)),
+	}
+	for k, v := range d.lastID {
+		c.lastID[k] = v
+	}
+	// Stored records are never modified in place, so sharing them is safe
+	for k, v := range d.quizzes {
+		c.quizzes[k] = v
+	}
+	for k, v := range d.results {
+		c.results[k] = v
+	}
+	for k, v := range d.progress {
+		c.progress[k] = v
+	}
+	for k, v := range d.users {
+		c.users[k] = v
+	}
+	for k, v := range d.ssoConfigs {
+		c.ssoConfigs[k] = v
+	}
+	return c
+}
+
+// nextID returns the next ID of a table; IDs are never reused, like sequences
+func (d *memoryData) nextID(table string) uint {
+	d.lastID[table]++
+	return d.lastID[table]
+}
+
+// assignID gives a new record an ID (keeping one set by the caller) and its timestamps
+func (d *memoryData) assignID(table string, id *uint, createdAt, updatedAt *time.Time) {
+	if *id == 0 {
+		*id = d.nextID(table)
+	} else if *id > d.lastID[table] {
+		d.lastID[table] = *id
+	}
+	now := time.Now()
+	if createdAt.IsZero() {
+		*createdAt = now
+	}
+	if updatedAt.IsZero() {
+		*updatedAt = now
+	}
+}
+
+// sortedIDs returns the keys of a table in ascending order
+func sortedIDs[T any](table map[uint]T) []uint {
+	ids := make([]uint, 0, len(table))
+	for id := range table {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// copyQuiz copies a quiz deep enough that neither copy shares questions or answers
+func copyQuiz(quiz models.Quiz, withQuestions bool) models.Quiz {
+	if !withQuestions {
+		quiz.Questions = nil
+		return quiz
+	}
+	questions := make([]models.Question, len(quiz.Questions))
+	for i, q := range quiz.Questions {
+		q.Answers = append([]models.Answer(nil), q.Answers...)
+		questions[i] = q
+	}
+	quiz.Questions = questions
+	return quiz
+}
+
+func copyResult(result models.Result) models.Result {
+	result.Quiz = nil
+	result.Answers = append([]byte(nil), result.Answers...)
+	return result
+}
+
+// copyUser drops the relationships, which the store doesn't keep
+func copyUser(user models.User) models.User {
+	user.Quizzes = nil
+	user.Results = nil
+	user.UserProgress = nil
+	user.UserAchievements = nil
+	user.GlobalRankings = nil
+	user.CategoryRankings = nil
+	return user
+}
+
+type memoryQuizzes struct{ s *MemoryStore }
+
+func (r memoryQuizzes) Create(quiz *models.Quiz) error {
+	return r.s.write(func(d *memoryData) error {
+		d.assignID("quizzes", &quiz.ID, &quiz.CreatedAt, &quiz.UpdatedAt)
+		for i := range quiz.Questions {
+			q := &quiz.Questions[i]
+			q.QuizID = quiz.ID
+			d.assignID("questions", &q.ID, &q.CreatedAt, &q.UpdatedAt)
+			for j := range q.Answers {
+				a := &q.Answers[j]
+				a.QuestionID = q.ID
+				d.assignID("answers", &a.ID, &a.CreatedAt, &a.UpdatedAt)
+			}
+		}
+		d.quizzes[quiz.ID] = copyQuiz(*quiz, true)
+		return nil
+	})
+}
+
+func (r memoryQuizzes) GetByID(id uint) (*models.Quiz, error) {
+	var quiz models.Quiz
+	err := r.s.read(func(d *memoryData) error {
+		stored, ok := d.quizzes[id]
+		if !ok {
+			return ErrNotFound
+		}
+		quiz = copyQuiz(stored, true)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &quiz, nil
+}
+
+// matches tells whether a quiz matches the filter
+func (f QuizFilter) matches(quiz models.Quiz) bool {
+	return (f.Category == "" || quiz.Category == f.Category) &&
+		(f.Difficulty == "" || quiz.Difficulty == f.Difficulty) &&
+		(f.Published == nil || quiz.IsPublished == *f.Published) &&
+		(f.CreatedBy == 0 || quiz.CreatedBy == f.CreatedBy) &&
+		inRange(quiz.CreatedAt, f.From, f.To)
+}
+
+// inRange tells whether t is in [from, to), either bound being open when zero
+func inRange(t, from, to time.Time) bool {
+	return (from.IsZero() || !t.Before(from)) && (to.IsZero() || t.Before(to))
+}
+
+// summarizeQuiz returns the listed form of a quiz
+func summarizeQuiz(quiz models.Quiz) models.QuizSummary {
+	return models.QuizSummary{
+		ID: quiz.ID, Title: quiz.Title, Description: quiz.Description, Category: quiz.Category,
+		Difficulty: quiz.Difficulty, TimeLimit: quiz.TimeLimit, QuestionCount: len(quiz.Questions),
+		CreatedBy: quiz.CreatedBy, IsPublished: quiz.IsPublished, PassingScore: quiz.PassingScore,
+		CreatedAt: quiz.CreatedAt, UpdatedAt: quiz.UpdatedAt,
+	}
+}
+
+func (r memoryQuizzes) Find(filter QuizFilter, page Page) (List[models.QuizSummary], error) {
+	var quizzes []models.QuizSummary
+	err := r.s.read(func(d *memoryData) error {
+		for _, quiz := range d.quizzes {
+			if filter.matches(quiz) {
+				quizzes = append(quizzes, summarizeQuiz(quiz))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return List[models.QuizSummary]{}, err
+	}
+	return QuizSorting.Slice(quizzes, page), nil
+}
+
+func (r memoryQuizzes) ListPublished(filter QuizFilter) ([]models.Quiz, error) {
+	published := true
+	filter.Published = &published
+
+	var quizzes []models.Quiz
+	err := r.s.read(func(d *memoryData) error {
+		for _, id := range sortedIDs(d.quizzes) {
+			if quiz := d.quizzes[id]; filter.matches(quiz) {
+				quizzes = append(quizzes, copyQuiz(quiz, false))
+			}
+		}
+		return nil
+	})
+	return quizzes, err
+}
+
+type memoryResults struct{ s *MemoryStore }
+
+func (r memoryResults) Create(result *models.Result) error {
+	return r.s.write(func(d *memoryData) error {
+		d.assignID("results", &result.ID, &result.CreatedAt, &result.UpdatedAt)
+		d.results[result.ID] = copyResult(*result)
+		return nil
+	})
+}
+
+func (r memoryResults) GetByID(id uint) (*models.Result, error) {
+	var result models.Result
+	err := r.s.read(func(d *memoryData) error {
+		stored, ok := d.results[id]
+		if !ok {
+			return ErrNotFound
+		}
+		result = copyResult(stored)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// matches tells whether a result, taken on quiz, matches the filter
+func (f ResultFilter) matches(result models.Result, quiz models.Quiz) bool {
+	return (f.UserID == 0 || result.UserID == f.UserID) &&
+		(f.QuizID == 0 || result.QuizID == f.QuizID) &&
+		(f.Category == "" || quiz.Category == f.Category) &&
+		(f.Difficulty == "" || quiz.Difficulty == f.Difficulty) &&
+		(f.Passed == nil || result.IsPassed == *f.Passed) &&
+		inRange(result.CreatedAt, f.From, f.To)
+}
+
+// summarizeResult returns the listed form of a result taken on quiz
+func summarizeResult(result models.Result, quiz models.Quiz) models.ResultSummary {
+	return models.ResultSummary{
+		ID: result.ID, QuizID: result.QuizID, QuizTitle: quiz.Title, Category: quiz.Category,
+		Difficulty: quiz.Difficulty, UserID: result.UserID, Score: result.Score,
+		TotalQuestions: result.TotalQuestions, CorrectAnswers: result.CorrectAnswers, TimeTaken: result.TimeTaken,
+		IsPassed: result.IsPassed, PassingScore: result.PassingScore, CreatedAt: result.CreatedAt,
+	}
+}
+
+func (r memoryResults) Find(filter ResultFilter, page Page) (List[models.ResultSummary], error) {
+	var results []models.ResultSummary
+	err := r.s.read(func(d *memoryData) error {
+		for _, result := range d.results {
+			// A result of an unknown quiz is listed with an empty one, like a LEFT JOIN
+			quiz := d.quizzes[result.QuizID]
+			if filter.matches(result, quiz) {
+				results = append(results, summarizeResult(result, quiz))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return List[models.ResultSummary]{}, err
+	}
+	return ResultSorting.Slice(results, page), nil
+}
+
+type memoryProgress struct{ s *MemoryStore }
+
+// find returns the first progress of the user matching the predicate
+func (r memoryProgress) find(userID uint, match func(p models.UserProgress) bool) (*models.UserProgress, error) {
+	var progress models.UserProgress
+	err := r.s.read(func(d *memoryData) error {
+		for _, id := range sortedIDs(d.progress) {
+			if p := d.progress[id]; p.UserID == userID && match(p) {
+				progress = p
+				return nil
+			}
+		}
+		return ErrNotFound
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &progress, nil
+}
+
+func (r memoryProgress) Get(userID, quizID uint) (*models.UserProgress, error) {
+	return r.find(userID, func(p models.UserProgress) bool { return p.QuizID == quizID })
+}
+
+func (r memoryProgress) ListByUser(userID uint) ([]models.UserProgress, error) {
+	var progress []models.UserProgress
+	err := r.s.read(func(d *memoryData) error {
+		for _, id := range sortedIDs(d.progress) {
+			if d.progress[id].UserID == userID {
+				progress = append(progress, d.progress[id])
+			}
+		}
+		return nil
+	})
+	return progress, err
+}
+
+func (r memoryProgress) TotalsByCategory(userID uint) ([]ProgressTotals, error) {
+	var totals []ProgressTotals
+	err := r.s.read(func(d *memoryData) error {
+		index := map[enums.QuizCategory]int{}
+		category := func(c enums.QuizCategory) *ProgressTotals {
+			i, ok := index[c]
+			if !ok {
+				i = len(totals)
+				index[c] = i
+				totals = append(totals, ProgressTotals{Category: c})
+			}
+			return &totals[i]
+		}
+
+		for _, id := range sortedIDs(d.progress) {
+			p := d.progress[id]
+			if p.UserID != userID {
+				continue
+			}
+			t := category(p.Category)
+			t.QuizzesAttempted++
+			if quiz, ok := d.quizzes[p.QuizID]; ok && quiz.IsPublished {
+				t.QuizzesCovered++
+			}
+			t.TotalAttempts += p.TotalAttempts
+			t.BestScore = max(t.BestScore, p.BestScore)
+			t.ScoreSum += p.AverageScore * float64(p.TotalAttempts)
+			t.MasterySum += float64(p.MasteryLevel * p.TotalAttempts)
+			t.TotalTimeSpent += p.TotalTimeSpent
+			if t.LastAttemptedAt == nil || p.LastAttemptedAt.After(*t.LastAttemptedAt) {
+				last := p.LastAttemptedAt
+				t.LastAttemptedAt = &last
+			}
+		}
+		for _, id := range sortedIDs(d.quizzes) {
+			if quiz := d.quizzes[id]; quiz.IsPublished {
+				category(quiz.Category).QuizzesPublished++
+			}
+		}
+		return nil
+	})
+	return totals, err
+}
+
+func (r memoryProgress) Save(progress *models.UserProgress) error {
+	return r.s.write(func(d *memoryData) error {
+		if stored, ok := d.progress[progress.ID]; ok {
+			if stored.Version != progress.Version {
+				return ErrConflict
+			}
+			progress.Version++
+			progress.UpdatedAt = time.Now()
+		} else {
+			for _, p := range d.progress {
+				if p.UserID == progress.UserID && p.QuizID == progress.QuizID {
+					return ErrConflict
+				}
+			}
+			d.assignID("user_progress", &progress.ID, &progress.CreatedAt, &progress.UpdatedAt)
+		}
+		d.progress[progress.ID] = *progress
+		return nil
+	})
+}
+
+type memoryUsers struct{ s *MemoryStore }
+
+// find returns the first user matching the predicate
+func (r memoryUsers) find(match func(u models.User) bool) (*models.User, error) {
+	var user models.User
+	err := r.s.read(func(d *memoryData) error {
+		for _, id := range sortedIDs(d.users) {
+			if u := d.users[id]; !u.DeletedAt.Valid && match(u) {
+				user = u
+				return nil
+			}
+		}
+		return ErrNotFound
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// checkEmail enforces the unique index on users.email
+func (d *memoryData) checkEmail(user *models.User) error {
+	for id, u := range d.users {
+		if id != user.ID && u.Email == user.Email {
+			return fmt.Errorf("%w: email %q already exists", gorm.ErrDuplicatedKey, user.Email)
+		}
+	}
+	return nil
+}
+
+func (r memoryUsers) Create(user *models.User) error {
+	return r.s.write(func(d *memoryData) error {
+		if err := d.checkEmail(user); err != nil {
+			return err
+		}
+		d.assignID("users", &user.ID, &user.CreatedAt, &user.UpdatedAt)
+		d.users[user.ID] = copyUser(*user)
+		return nil
+	})
+}
+
+func (r memoryUsers) GetByID(id uint) (*models.User, error) {
+	return r.find(func(u models.User) bool { return u.ID == id })
+}
+
+func (r memoryUsers) GetByEmail(email string) (*models.User, error) {
+	return r.find(func(u models.User) bool { return u.Email == email })
+}
+
+func (r memoryUsers) GetByProvider(provider enums.AuthProvider, providerID string) (*models.User, error) {
+	return r.find(func(u models.User) bool { return u.AuthProvider == provider && u.ProviderID == providerID })
+}
+
+func (r memoryUsers) GetByRefreshToken(token string) (*models.User, error) {
+	return r.find(func(u models.User) bool { return u.RefreshToken == token })
+}
+
+func (r memoryUsers) Save(user *models.User) error {
+	return r.s.write(func(d *memoryData) error {
+		if err := d.checkEmail(user); err != nil {
+			return err
+		}
+		if _, ok := d.users[user.ID]; ok {
+			user.UpdatedAt = time.Now()
+		} else {
+			d.assignID("users", &user.ID, &user.CreatedAt, &user.UpdatedAt)
+		}
+		d.users[user.ID] = copyUser(*user)
+		return nil
+	})
+}
+
+type memorySSOConfigs struct{ s *MemoryStore }
+
+func (r memorySSOConfigs) Create(config *models.SSOConfig) error {
+	return r.s.write(func(d *memoryData) error {
+		for _, c := range d.ssoConfigs {
+			if c.Provider == config.Provider {
+				return fmt.Errorf("%w: provider %q already exists", gorm.ErrDuplicatedKey, config.Provider)
+			}
+		}
+		d.assignID("sso_configs", &config.ID, &config.CreatedAt, &config.UpdatedAt)
+		d.ssoConfigs[config.ID] = *config
+		return nil
+	})
+}
+
+func (r memorySSOConfigs) GetEnabled(provider enums.AuthProvider) (*models.SSOConfig, error) {
+	var config models.SSOConfig
+	err := r.s.read(func(d *memoryData) error {
+		for _, c := range d.ssoConfigs {
+			if c.Provider == provider && c.IsEnabled {
+				config = c
+				return nil
+			}
+		}
+		return ErrNotFound
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// Ensure MemoryStore implements Store
+var _ Store = (*MemoryStore)(nil)
@@ -0,0 +1,227 @@
+package repository
+
+import (
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultLimit is the page size of listings when the caller doesn't pick one
+	DefaultLimit = 20
+	// MaxLimit is the largest page listings return
+	MaxLimit = 100
+)
+
+// ErrInvalidCursor is returned for cursors that weren't issued for the listing and sort order
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Page selects one page of a listing. Listings are paginated by keyset:
+// records come ordered by a sort key with their ID breaking ties, and a
+// page starts right after the last record of the previous one, so pages
+// never skip or repeat records when others are added in between.
+type Page struct {
+	Limit int     // How many records to return, at most
+	Sort  string  // A sort key of the listing, descending when prefixed with "-"
+	After *Cursor // Where the previous page ended; nil for the first page
+}
+
+// Cursor is where a page ended: the sort value and ID of its last record
+type Cursor struct {
+	Sort  string
+	Value any
+	ID    uint
+}
+
+// List is one page of a listing
+type List[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"` // Pass as cursor for the next page; empty on the last page
+}
+
+// SortKey is something a listing can be ordered by
+type SortKey[T any] struct {
+	Column string      // Column to order by in SQL, qualified when the query joins tables
+	Value  func(T) any // The value of the column for a record
+}
+
+// Sorting describes how the records of a listing can be ordered and paged
+type Sorting[T any] struct {
+	Default  string                // Sort used when the caller doesn't pick one
+	IDColumn string                // Column of the ID breaking ties
+	ID       func(T) uint          // The ID of a record
+	Keys     map[string]SortKey[T] // Sort keys by name
+}
+
+// Names returns the sort keys, sorted
+func (s Sorting[T]) Names() []string {
+	names := make([]string, 0, len(s.Keys))
+	for name := range s.Keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// key returns the sort key of a sort, which may be prefixed with "-"
+func (s Sorting[T]) key(sort string) (SortKey[T], bool, bool) {
+	name, desc := strings.CutPrefix(sort, "-")
+	key, ok := s.Keys[name]
+	return key, desc, ok
+}
+
+// Valid tells whether sort names a sort key, in either direction
+func (s Sorting[T]) Valid(sort string) bool {
+	_, _, ok := s.key(sort)
+	return ok
+}
+
+// cursorData is the encoded form of a cursor
+type cursorData struct {
+	Sort  string          `json:"s"`
+	Value json.RawMessage `json:"v"`
+	ID    uint            `json:"id"`
+}
+
+// DecodeCursor parses a cursor handed out for the listing sorted by sort
+func (s Sorting[T]) DecodeCursor(cursor, sort string) (*Cursor, error) {
+	key, _, ok := s.key(sort)
+	if !ok {
+		return nil, fmt.Errorf("unknown sort %q", sort)
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var data cursorData
+	if err := json.Unmarshal(raw, &data); err != nil || data.Sort != sort {
+		return nil, ErrInvalidCursor
+	}
+
+	// Decode the value into the type the sort key has
+	var zero T
+	value := reflect.New(reflect.TypeOf(key.Value(zero)))
+	if err := json.Unmarshal(data.Value, value.Interface()); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &Cursor{Sort: sort, Value: value.Elem().Interface(), ID: data.ID}, nil
+}
+
+// encodeCursor returns the cursor of the page ending with last
+func (s Sorting[T]) encodeCursor(sort string, last T) string {
+	key, _, _ := s.key(sort)
+	value, _ := json.Marshal(key.Value(last))
+	raw, _ := json.Marshal(cursorData{Sort: sort, Value: value, ID: s.ID(last)})
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// normalize fills in the defaults of a page
+func (s Sorting[T]) normalize(page Page) Page {
+	if page.Sort == "" || !s.Valid(page.Sort) {
+		page.Sort = s.Default
+		page.After = nil
+	}
+	if page.Limit < 1 {
+		page.Limit = DefaultLimit
+	}
+	if page.Limit > MaxLimit {
+		page.Limit = MaxLimit
+	}
+	return page
+}
+
+// Paginate orders query by the page's sort and restricts it to the records
+// after its cursor. It fetches one record more than the limit, which tells
+// List whether there is a next page.
+func (s Sorting[T]) Paginate(query *gorm.DB, page Page) *gorm.DB {
+	page = s.normalize(page)
+	key, desc, _ := s.key(page.Sort)
+
+	op, dir := ">", "ASC"
+	if desc {
+		op, dir = "<", "DESC"
+	}
+	if page.After != nil {
+		query = query.Where(
+			fmt.Sprintf("(%s %s ? OR (%s = ? AND %s %s ?))", key.Column, op, key.Column, s.IDColumn, op),
+			page.After.Value, page.After.Value, page.After.ID,
+		)
+	}
+	return query.Order(key.Column + " " + dir).Order(s.IDColumn + " " + dir).Limit(page.Limit + 1)
+}
+
+// List turns the records fetched with Paginate into a page
+func (s Sorting[T]) List(records []T, page Page) List[T] {
+	page = s.normalize(page)
+	list := List[T]{Items: records}
+	if list.Items == nil {
+		list.Items = []T{}
+	}
+	if len(records) > page.Limit {
+		list.Items = records[:page.Limit]
+		list.NextCursor = s.encodeCursor(page.Sort, list.Items[page.Limit-1])
+	}
+	return list
+}
+
+// Slice pages through records held in memory the way Paginate and List do in SQL
+func (s Sorting[T]) Slice(records []T, page Page) List[T] {
+	page = s.normalize(page)
+	key, desc, _ := s.key(page.Sort)
+
+	// compare orders a record against a sort value and ID, ascending
+	compare := func(record T, value any, id uint) int {
+		if c := compareValues(key.Value(record), value); c != 0 {
+			return c
+		}
+		return compareValues(s.ID(record), id)
+	}
+	sorted := append([]T(nil), records...)
+	sort.Slice(sorted, func(i, j int) bool {
+		c := compare(sorted[i], key.Value(sorted[j]), s.ID(sorted[j]))
+		if desc {
+			return c > 0
+		}
+		return c < 0
+	})
+
+	var after []T
+	for _, record := range sorted {
+		if page.After != nil {
+			c := compare(record, page.After.Value, page.After.ID)
+			if (desc && c >= 0) || (!desc && c <= 0) {
+				continue
+			}
+		}
+		after = append(after, record)
+		if len(after) > page.Limit {
+			break
+		}
+	}
+	return s.List(after, page)
+}
+
+// compareValues orders two values of a sort key
+func compareValues(a, b any) int {
+	switch a := a.(type) {
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	case string:
+		return strings.Compare(a, b.(string))
+	case float64:
+		return cmp.Compare(a, b.(float64))
+	case int:
+		return cmp.Compare(a, b.(int))
+	case uint:
+		return cmp.Compare(a, b.(uint))
+	}
+	panic(fmt.Sprintf("can't sort by values of type %T", a))
+}
@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"aicg/internal/models"
+	"aicg/internal/models/enums"
+)
+
+var (
+	// ErrNotFound is returned when no record matches a lookup
+	ErrNotFound = errors.New("record not found")
+	// ErrConflict is returned when a record changed since it was read, or
+	// another one with the same unique values was created meanwhile
+	ErrConflict = errors.New("record was changed concurrently")
+)
+
+// QuizFilter narrows down quiz listings
+type QuizFilter struct {
+	Category   enums.QuizCategory   // Only quizzes in this category, if set
+	Difficulty enums.QuizDifficulty // Only quizzes of this difficulty, if set
+	Published  *bool                // Only published/unpublished quizzes, if set
+	CreatedBy  uint                 // Only quizzes made by this user, if set
+	From       time.Time            // Only quizzes created at or after this time, if set
+	To         time.Time            // Only quizzes created before this time, if set
+}
+
+// QuizSorting lists the orders of quiz listings, newest first by default
+var QuizSorting = Sorting[models.QuizSummary]{
+	Default:  "-created_at",
+	IDColumn: "quizzes.id",
+	ID:       func(q models.QuizSummary) uint { return q.ID },
+	Keys: map[string]SortKey[models.QuizSummary]{
+		"created_at": {Column: "quizzes.created_at", Value: func(q models.QuizSummary) any { return q.CreatedAt }},
+		"title":      {Column: "quizzes.title", Value: func(q models.QuizSummary) any { return q.Title }},
+	},
+}
+
+// ResultFilter narrows down result listings
+type ResultFilter struct {
+	UserID     uint                 // Only results of this user, if set
+	QuizID     uint                 // Only results of this quiz, if set
+	Category   enums.QuizCategory   // Only results of quizzes in this category, if set
+	Difficulty enums.QuizDifficulty // Only results of quizzes of this difficulty, if set
+	Passed     *bool                // Only passed/failed results, if set
+	From       time.Time            // Only results submitted at or after this time, if set
+	To         time.Time            // Only results submitted before this time, if set
+}
+
+// ProgressTotals is a user's progress on the quizzes of a category, added up
+type ProgressTotals struct {
+	Category         enums.QuizCategory
+	QuizzesAttempted int        // How many quizzes they tried
+	QuizzesCovered   int        // How many of those are still published
+	QuizzesPublished int        // How many quizzes there are to try
+	TotalAttempts    int        // How many times they tried
+	BestScore        float64    // Their highest score
+	ScoreSum         float64    // Their average scores, each times its attempts
+	MasterySum       float64    // Their mastery levels, each times its attempts
+	TotalTimeSpent   int        // Total time spent (seconds)
+	LastAttemptedAt  *time.Time // When they last tried, if they did
+}
+
+// ResultSorting lists the orders of result listings, newest first by default
+var ResultSorting = Sorting[models.ResultSummary]{
+	Default:  "-created_at",
+	IDColumn: "results.id",
+	ID:       func(r models.ResultSummary) uint { return r.ID },
+	Keys: map[string]SortKey[models.ResultSummary]{
+		"created_at": {Column: "results.created_at", Value: func(r models.ResultSummary) any { return r.CreatedAt }},
+		"score":      {Column: "results.score", Value: func(r models.ResultSummary) any { return r.Score }},
+		"time_taken": {Column: "results.time_taken", Value: func(r models.ResultSummary) any { return r.TimeTaken }},
+	},
+}
+
+// UserSorting lists the orders of user listings, oldest first by default
+var UserSorting = Sorting[models.User]{
+	Default:  "created_at",
+	IDColumn: "id",
+	ID:       func(u models.User) uint { return u.ID },
+	Keys: map[string]SortKey[models.User]{
+		"created_at": {Column: "created_at", Value: func(u models.User) any { return u.CreatedAt }},
+		"email":      {Column: "email", Value: func(u models.User) any { return u.Email }},
+		"last_name":  {Column: "last_name", Value: func(u models.User) any { return u.LastName }},
+	},
+}
+
+// QuizRepository stores quizzes together with their questions and answers
+type QuizRepository interface {
+	// Create stores a quiz with its questions and answers, filling in their IDs
+	Create(quiz *models.Quiz) error
+
+	// GetByID returns a quiz with its questions and answers
+	GetByID(id uint) (*models.Quiz, error)
+
+	// Find returns a page of the quizzes matching the filter, summarized
+	Find(filter QuizFilter, page Page) (List[models.QuizSummary], error)
+
+	// ListPublished returns the published quizzes matching the filter, without their questions
+	ListPublished(filter QuizFilter) ([]models.Quiz, error)
+}
+
+// ResultRepository stores quiz results
+type ResultRepository interface {
+	// Create stores a result, filling in its ID
+	Create(result *models.Result) error
+
+	// GetByID returns a result
+	GetByID(id uint) (*models.Result, error)
+
+	// Find returns a page of the results matching the filter, summarized
+	Find(filter ResultFilter, page Page) (List[models.ResultSummary], error)
+}
+
+// ProgressRepository stores each user's progress per quiz
+type ProgressRepository interface {
+	// Get returns a user's progress on a quiz
+	Get(userID, quizID uint) (*models.UserProgress, error)
+
+	// ListByUser returns a user's progress across all quizzes
+	ListByUser(userID uint) ([]models.UserProgress, error)
+
+	// TotalsByCategory adds up a user's progress per category, for every
+	// category they tried or that has published quizzes, in no particular order
+	TotalsByCategory(userID uint) ([]ProgressTotals, error)
+
+	// Save creates the progress when it has no ID yet and updates it
+	// otherwise, bumping its version. It returns ErrConflict if the user
+	// already has progress on the quiz, or if it was updated since it was read.
+	Save(progress *models.UserProgress) error
+}
+
+// UserRepository stores users. Lookups never return soft-deleted users.
+type UserRepository interface {
+	// Create stores a user, filling in its ID
+	Create(user *models.User) error
+
+	// GetByID returns a user
+	GetByID(id uint) (*models.User, error)
+
+	// GetByEmail returns the user with the given email address
+	GetByEmail(email string) (*models.User, error)
+
+	// GetByProvider returns the user signed up through an SSO provider with the given provider ID
+	GetByProvider(provider enums.AuthProvider, providerID string) (*models.User, error)
+
+	// GetByRefreshToken returns the user the refresh token was issued to
+	GetByRefreshToken(token string) (*models.User, error)
+
+	// Save updates all fields of an existing user
+	Save(user *models.User) error
+}
+
+// SSOConfigRepository stores SSO provider configurations
+type SSOConfigRepository interface {
+	// Create stores a provider configuration, filling in its ID
+	Create(config *models.SSOConfig) error
+
+	// GetEnabled returns the configuration of a provider if it's enabled
+	GetEnabled(provider enums.AuthProvider) (*models.SSOConfig, error)
+}
+
+// Store gives access to all repositories backed by the same database
+type Store interface {
+	Quizzes() QuizRepository
+	Results() ResultRepository
+	Progress() ProgressRepository
+	Users() UserRepository
+	SSOConfigs() SSOConfigRepository
+
+	// Transaction runs fn with a store whose changes are committed together
+	// when fn returns nil and discarded when it returns an error
+	Transaction(fn func(tx Store) error) error
+
+	// WithContext returns a store whose queries run with ctx, which cancels
+	// them and carries the request ID into the SQL logs
+	WithContext(ctx context.Context) Store
+}
@@ -0,0 +1,388 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"aicg/internal/config"
+	"aicg/internal/database"
+	"aicg/internal/models"
+	"aicg/internal/models/enums"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/logger"
+)
+
+// testStore runs the behavior every Store implementation must share
+func testStore(t *testing.T, newStore func(t *testing.T) Store) {
+	t.Run("Quizzes", func(t *testing.T) {
+		store := newStore(t)
+		quizzes := store.Quizzes()
+
+		quiz := &models.Quiz{
+			Title: "Math", Description: "Sums", Category: enums.CategoryMath, Difficulty: enums.DifficultyEasy,
+			TimeLimit: 5, CreatedBy: 1, IsPublished: true, PassingScore: 60,
+			Questions: []models.Question{{
+				Text: "1 + 1?", Type: enums.QuestionTypeMultipleChoice, CorrectAnswer: "2",
+				Answers: []models.Answer{{Text: "1"}, {Text: "2", IsCorrect: true}},
+			}},
+		}
+		require.NoError(t, quizzes.Create(quiz))
+		require.NotZero(t, quiz.ID)
+		assert.Equal(t, quiz.ID, quiz.Questions[0].QuizID)
+		assert.Equal(t, quiz.Questions[0].ID, quiz.Questions[0].Answers[1].QuestionID)
+		require.NoError(t, quizzes.Create(&models.Quiz{
+			Title: "Draft", Description: "Not yet", Category: enums.CategoryMath, Difficulty: enums.DifficultyHard,
+			TimeLimit: 5, CreatedBy: 1, PassingScore: 60,
+		}))
+
+		// Test case 1: Quizzes come with their questions and answers
+		got, err := quizzes.GetByID(quiz.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Math", got.Title)
+		require.Len(t, got.Questions, 1)
+		assert.Len(t, got.Questions[0].Answers, 2)
+
+		all, err := quizzes.Find(QuizFilter{}, Page{Sort: "title"})
+		require.NoError(t, err)
+		require.Len(t, all.Items, 2)
+		assert.Equal(t, "Draft", all.Items[0].Title)
+		assert.Equal(t, 1, all.Items[1].QuestionCount)
+		assert.Empty(t, all.NextCursor)
+
+		// Test case 2: Unknown quiz
+		_, err = quizzes.GetByID(999)
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		// Test case 3: Only published quizzes are listed by filter
+		published, err := quizzes.ListPublished(QuizFilter{Category: enums.CategoryMath})
+		require.NoError(t, err)
+		require.Len(t, published, 1)
+		assert.Equal(t, quiz.ID, published[0].ID)
+
+		published, err = quizzes.ListPublished(QuizFilter{Difficulty: enums.DifficultyHard})
+		require.NoError(t, err)
+		assert.Empty(t, published)
+
+		// Test case 4: Listings filter on any quiz
+		unpublished := false
+		drafts, err := quizzes.Find(QuizFilter{Published: &unpublished, CreatedBy: 1}, Page{})
+		require.NoError(t, err)
+		require.Len(t, drafts.Items, 1)
+		assert.Equal(t, "Draft", drafts.Items[0].Title)
+
+		none, err := quizzes.Find(QuizFilter{From: time.Now().Add(time.Hour)}, Page{})
+		require.NoError(t, err)
+		assert.Empty(t, none.Items)
+	})
+
+	t.Run("Pagination", func(t *testing.T) {
+		store := newStore(t)
+		for _, title := range []string{"C", "A", "B", "A", "D"} {
+			require.NoError(t, store.Quizzes().Create(&models.Quiz{
+				Title: title, Description: "-", Category: enums.CategoryMath, Difficulty: enums.DifficultyEasy,
+				TimeLimit: 5, CreatedBy: 1, PassingScore: 60,
+			}))
+		}
+
+		// pageThrough collects the titles of every page, following the cursors
+		pageThrough := func(sort string) []string {
+			var titles []string
+			page := Page{Limit: 2, Sort: sort}
+			for {
+				list, err := store.Quizzes().Find(QuizFilter{}, page)
+				require.NoError(t, err)
+				require.LessOrEqual(t, len(list.Items), 2)
+				for _, quiz := range list.Items {
+					titles = append(titles, quiz.Title)
+				}
+				if list.NextCursor == "" {
+					return titles
+				}
+				page.After, err = QuizSorting.DecodeCursor(list.NextCursor, sort)
+				require.NoError(t, err)
+			}
+		}
+
+		// Test case 1: Pages neither skip nor repeat records, ties included
+		assert.Equal(t, []string{"A", "A", "B", "C", "D"}, pageThrough("title"))
+		assert.Equal(t, []string{"D", "C", "B", "A", "A"}, pageThrough("-title"))
+
+		// Test case 2: By creation time, newest first
+		assert.Equal(t, []string{"D", "A", "B", "A", "C"}, pageThrough("-created_at"))
+
+		// Test case 3: Cursors only work with the sort they were issued for
+		list, err := store.Quizzes().Find(QuizFilter{}, Page{Limit: 2, Sort: "title"})
+		require.NoError(t, err)
+		_, err = QuizSorting.DecodeCursor(list.NextCursor, "-title")
+		assert.ErrorIs(t, err, ErrInvalidCursor)
+		_, err = QuizSorting.DecodeCursor("garbage", "title")
+		assert.ErrorIs(t, err, ErrInvalidCursor)
+	})
+
+	t.Run("ResultsAndProgress", func(t *testing.T) {
+		store := newStore(t)
+
+		for _, score := range []float64{40, 90} {
+			require.NoError(t, store.Results().Create(&models.Result{
+				QuizID: 1, UserID: 7, Score: score, TotalQuestions: 1, Answers: []byte(`[]`), PassingScore: 60, IsPassed: score >= 60,
+			}))
+		}
+		require.NoError(t, store.Results().Create(&models.Result{
+			QuizID: 1, UserID: 8, Score: 50, TotalQuestions: 1, Answers: []byte(`[]`), PassingScore: 60,
+		}))
+
+		results, err := store.Results().Find(ResultFilter{UserID: 7}, Page{Sort: "score"})
+		require.NoError(t, err)
+		require.Len(t, results.Items, 2)
+		assert.Equal(t, 40.0, results.Items[0].Score)
+
+		got, err := store.Results().GetByID(results.Items[1].ID)
+		require.NoError(t, err)
+		assert.Equal(t, 90.0, got.Score)
+
+		passed := true
+		results, err = store.Results().Find(ResultFilter{UserID: 7, Passed: &passed}, Page{})
+		require.NoError(t, err)
+		require.Len(t, results.Items, 1)
+		assert.Equal(t, 90.0, results.Items[0].Score)
+
+		_, err = store.Results().GetByID(999)
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		// Progress is created on the first save and updated on later ones
+		progress := &models.UserProgress{UserID: 7, QuizID: 1, Category: enums.CategoryMath, TotalAttempts: 1, MasteryLevel: 1}
+		require.NoError(t, store.Progress().Save(progress))
+		require.NotZero(t, progress.ID)
+
+		progress.TotalAttempts = 2
+		require.NoError(t, store.Progress().Save(progress))
+
+		saved, err := store.Progress().Get(7, 1)
+		require.NoError(t, err)
+		assert.Equal(t, progress.ID, saved.ID)
+		assert.Equal(t, 2, saved.TotalAttempts)
+
+		list, err := store.Progress().ListByUser(7)
+		require.NoError(t, err)
+		assert.Len(t, list, 1)
+
+		_, err = store.Progress().Get(8, 1)
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		// Saving progress read before another save conflicts, as does a second progress on the quiz
+		stale := *saved
+		saved.TotalAttempts = 3
+		require.NoError(t, store.Progress().Save(saved))
+		stale.TotalAttempts = 3
+		assert.ErrorIs(t, store.Progress().Save(&stale), ErrConflict)
+		assert.ErrorIs(t, store.Progress().Save(&models.UserProgress{UserID: 7, QuizID: 1, Category: enums.CategoryMath, MasteryLevel: 1}), ErrConflict)
+
+		saved, err = store.Progress().Get(7, 1)
+		require.NoError(t, err)
+		assert.Equal(t, 3, saved.TotalAttempts)
+		assert.Equal(t, 2, saved.Version)
+	})
+
+	t.Run("ProgressTotals", func(t *testing.T) {
+		store := newStore(t)
+		quiz := func(category enums.QuizCategory, published bool) uint {
+			q := &models.Quiz{Title: "Quiz", Description: "Quiz", Category: category, Difficulty: enums.DifficultyEasy,
+				TimeLimit: 5, CreatedBy: 1, IsPublished: published, PassingScore: 60}
+			require.NoError(t, store.Quizzes().Create(q))
+			return q.ID
+		}
+		algebra, draft, physics := quiz(enums.CategoryMath, true), quiz(enums.CategoryMath, false), quiz(enums.CategoryScience, true)
+		quiz(enums.CategoryScience, true)
+		first := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+		last := first.Add(48 * time.Hour)
+		for _, p := range []models.UserProgress{
+			{UserID: 7, QuizID: algebra, Category: enums.CategoryMath, TotalAttempts: 2, BestScore: 60, AverageScore: 50, TotalTimeSpent: 30, LastAttemptedAt: first, MasteryLevel: 1},
+			{UserID: 7, QuizID: draft, Category: enums.CategoryMath, TotalAttempts: 1, BestScore: 70, AverageScore: 70, TotalTimeSpent: 5, LastAttemptedAt: last, MasteryLevel: 3},
+			{UserID: 8, QuizID: physics, Category: enums.CategoryScience, TotalAttempts: 4, BestScore: 100, AverageScore: 100, LastAttemptedAt: last, MasteryLevel: 5},
+		} {
+			require.NoError(t, store.Progress().Save(&p))
+		}
+
+		totals, err := store.Progress().TotalsByCategory(7)
+		require.NoError(t, err)
+		byCategory := map[enums.QuizCategory]ProgressTotals{}
+		for _, total := range totals {
+			byCategory[total.Category] = total
+		}
+		require.Len(t, byCategory, 2)
+
+		// Test case 1: Tried categories add up the progress, counting the quizzes still published
+		math := byCategory[enums.CategoryMath]
+		require.NotNil(t, math.LastAttemptedAt)
+		assert.True(t, last.Equal(*math.LastAttemptedAt), "last attempt %v", math.LastAttemptedAt)
+		math.LastAttemptedAt = nil
+		assert.Equal(t, ProgressTotals{
+			Category: enums.CategoryMath, QuizzesAttempted: 2, QuizzesCovered: 1, QuizzesPublished: 1,
+			TotalAttempts: 3, BestScore: 70, ScoreSum: 170, MasterySum: 5, TotalTimeSpent: 35,
+		}, math)
+
+		// Test case 2: Categories only others tried just count their published quizzes
+		assert.Equal(t, ProgressTotals{Category: enums.CategoryScience, QuizzesPublished: 2}, byCategory[enums.CategoryScience])
+	})
+
+	t.Run("Users", func(t *testing.T) {
+		store := newStore(t)
+		users := store.Users()
+
+		user := &models.User{
+			Email: "ada@example.com", Role: enums.RoleMaveric, AuthProvider: enums.ProviderGoogle,
+			ProviderID: "g-1", IsActive: true,
+		}
+		require.NoError(t, users.Create(user))
+		require.NotZero(t, user.ID)
+
+		// Test case 1: Lookups by every key
+		got, err := users.GetByID(user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "ada@example.com", got.Email)
+
+		got, err = users.GetByEmail("ada@example.com")
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, got.ID)
+
+		got, err = users.GetByProvider(enums.ProviderGoogle, "g-1")
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, got.ID)
+
+		_, err = users.GetByProvider(enums.ProviderFacebook, "g-1")
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		// Test case 2: Saved changes are visible to later lookups
+		got.RefreshToken = "token"
+		require.NoError(t, users.Save(got))
+		got, err = users.GetByRefreshToken("token")
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, got.ID)
+
+		// Test case 3: Emails are unique
+		assert.Error(t, users.Create(&models.User{Email: "ada@example.com", Role: enums.RoleMaveric, AuthProvider: enums.ProviderEmail}))
+
+		_, err = users.GetByEmail("nobody@example.com")
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("SSOConfigs", func(t *testing.T) {
+		store := newStore(t)
+		require.NoError(t, store.SSOConfigs().Create(&models.SSOConfig{
+			Provider: enums.ProviderGoogle, ClientID: "id", ClientSecret: "secret", RedirectURL: "http://localhost", IsEnabled: true,
+		}))
+		config := &models.SSOConfig{Provider: enums.ProviderFacebook, ClientID: "id", ClientSecret: "secret", RedirectURL: "http://localhost"}
+		require.NoError(t, store.SSOConfigs().Create(config))
+
+		got, err := store.SSOConfigs().GetEnabled(enums.ProviderGoogle)
+		require.NoError(t, err)
+		assert.Equal(t, "id", got.ClientID)
+
+		// Disabled providers are not returned
+		_, err = store.SSOConfigs().GetEnabled(enums.ProviderFacebook)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("Transaction", func(t *testing.T) {
+		store := newStore(t)
+
+		// Test case 1: Changes are committed together
+		err := store.Transaction(func(tx Store) error {
+			if err := tx.Users().Create(&models.User{Email: "a@example.com", Role: enums.RoleMaveric, AuthProvider: enums.ProviderEmail}); err != nil {
+				return err
+			}
+			return tx.Results().Create(&models.Result{QuizID: 1, UserID: 1, TotalQuestions: 1, Answers: []byte(`[]`)})
+		})
+		require.NoError(t, err)
+		_, err = store.Users().GetByEmail("a@example.com")
+		assert.NoError(t, err)
+
+		// Test case 2: Nothing is kept when the function fails
+		failure := errors.New("failure")
+		err = store.Transaction(func(tx Store) error {
+			if err := tx.Users().Create(&models.User{Email: "b@example.com", Role: enums.RoleMaveric, AuthProvider: enums.ProviderEmail}); err != nil {
+				return err
+			}
+			return failure
+		})
+		assert.ErrorIs(t, err, failure)
+		_, err = store.Users().GetByEmail("b@example.com")
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, func(t *testing.T) Store { return NewMemoryStore() })
+}
+
+func TestGormStore(t *testing.T) {
+	testStore(t, func(t *testing.T) Store {
+		db, err := database.InitDB(&config.Config{DB: config.DBConfig{Driver: database.DriverSQLite, Path: filepath.Join(t.TempDir(), "test.db")}})
+		require.NoError(t, err)
+		db.Logger = logger.Discard
+		migrator, err := database.NewMigrator(db)
+		require.NoError(t, err)
+		_, err = migrator.Up(context.Background())
+		require.NoError(t, err)
+
+		// The tests refer to users and quizzes by ID without creating them first
+		require.NoError(t, db.Exec("PRAGMA foreign_keys = OFF").Error)
+		return NewGormStore(db)
+	})
+}
+
+func TestMemoryStoreCopies(t *testing.T) {
+	store := NewMemoryStore()
+	quiz := &models.Quiz{Title: "Original", Questions: []models.Question{{Text: "Q", Answers: []models.Answer{{Text: "A"}}}}}
+	require.NoError(t, store.Quizzes().Create(quiz))
+
+	// Changing what was passed in or handed out doesn't change the stored quiz
+	quiz.Title = "Changed"
+	quiz.Questions[0].Answers[0].Text = "Changed"
+	got, err := store.Quizzes().GetByID(quiz.ID)
+	require.NoError(t, err)
+	got.Questions[0].Text = "Changed"
+
+	got, err = store.Quizzes().GetByID(quiz.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Original", got.Title)
+	assert.Equal(t, "Q", got.Questions[0].Text)
+	assert.Equal(t, "A", got.Questions[0].Answers[0].Text)
+}
+
+func TestMemoryStoreConcurrency(t *testing.T) {
+	store := NewMemoryStore()
+	progress := &models.UserProgress{UserID: 1, QuizID: 1}
+	require.NoError(t, store.Progress().Save(progress))
+
+	// Increments inside transactions are never lost
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := store.Transaction(func(tx Store) error {
+				p, err := tx.Progress().Get(1, 1)
+				if err != nil {
+					return err
+				}
+				p.TotalAttempts++
+				return tx.Progress().Save(p)
+			})
+			assert.NoError(t, err)
+			_, err = store.Results().Find(ResultFilter{UserID: 1}, Page{})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	got, err := store.Progress().Get(1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 50, got.TotalAttempts)
+}
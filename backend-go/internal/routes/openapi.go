@@ -0,0 +1,586 @@
+package routes
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"aicg/internal/config"
+	"aicg/internal/database"
+	"aicg/internal/handlers"
+	"aicg/internal/health"
+	"aicg/internal/idempotency"
+	"aicg/internal/middleware"
+	"aicg/internal/models"
+	"aicg/internal/models/enums"
+	"aicg/internal/openapi"
+	"aicg/internal/repository"
+	"aicg/internal/services"
+	"aicg/internal/storage"
+	"aicg/internal/version"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Paths of the API documentation
+const (
+	openAPIPath = "/openapi.json"
+	docsPath    = "/docs"
+)
+
+// access is who may call a route
+type access int
+
+const (
+	public        access = iota // Anyone
+	authenticated               // Logged in users, with a bearer token
+	superAdmin                  // Logged in super admins
+)
+
+// apiRoute documents a route for the OpenAPI document. Every route the router
+// registers needs one; TestOpenAPI fails for those that don't have it.
+type apiRoute struct {
+	method, path string
+	id           string // operationId
+	tag          string
+	summary      string
+	access       access
+	params       []*openapi.Parameter
+	body         any         // Request body, bound from JSON; a content for other media types
+	responses    map[int]any // Success bodies by status, nil for none; a content for other media types
+	errors       []int       // Failure statuses besides 401, 403, 429 and 500, which access and the path imply
+}
+
+// content is a body in a media type other than JSON
+type content struct {
+	mediaType string
+	schema    *openapi.Schema
+}
+
+// errorStatuses are the failures operations can refer to, with the names of their shared responses
+var errorStatuses = map[int]string{
+	http.StatusBadRequest:            "BadRequest",
+	http.StatusUnauthorized:          "Unauthorized",
+	http.StatusForbidden:             "Forbidden",
+	http.StatusNotFound:              "NotFound",
+	http.StatusConflict:              "Conflict",
+	http.StatusGone:                  "Gone",
+	http.StatusRequestEntityTooLarge: "PayloadTooLarge",
+	http.StatusUnsupportedMediaType:  "UnsupportedMediaType",
+	http.StatusTooManyRequests:       "TooManyRequests",
+	http.StatusInternalServerError:   "InternalServerError",
+}
+
+// apiRoutes documents every route SetupRouter can register for cfg
+func apiRoutes(cfg *config.Config) []apiRoute {
+	var (
+		id        = pathParam("id", "ID of the record")
+		provider  = &openapi.Parameter{Name: "provider", In: "path", Required: true, Schema: &openapi.Schema{Type: "string", Enum: ssoProviders()}}
+		filepath  = &openapi.Parameter{Name: "filepath", In: "path", Required: true, Description: "Path of the file, which may contain slashes", Schema: openapi.String("")}
+		category  = query("category", &openapi.Schema{Ref: "#/components/schemas/QuizCategory"}, "Only quizzes in this category")
+		level     = query("difficulty", &openapi.Schema{Ref: "#/components/schemas/QuizDifficulty"}, "Only quizzes of this difficulty")
+		timeRange = []*openapi.Parameter{
+			query("from", openapi.String("A date (2006-01-02) or an RFC 3339 time"), "Only those created at or after this time"),
+			query("to", openapi.String("A date (2006-01-02), which includes that day, or an RFC 3339 time"), "Only those created before this time"),
+		}
+		upload = content{"multipart/form-data", &openapi.Schema{
+			Type:       "object",
+			Properties: map[string]*openapi.Schema{"image": openapi.Binary("image/*")},
+			Required:   []string{"image"},
+		}}
+		image = content{"image/*", openapi.Binary("image/*")}
+	)
+
+	routes := []apiRoute{
+		{
+			method: http.MethodGet, path: "/health", id: "health", tag: "health",
+			summary:   "Report that the server answers",
+			responses: map[int]any{http.StatusOK: content{"text/plain", openapi.String(`Always "OK"`)}},
+		},
+		{
+			method: http.MethodGet, path: "/livez", id: "livez", tag: "health",
+			summary:   "Report that the process is up, with its version",
+			responses: map[int]any{http.StatusOK: handlers.Liveness{}},
+		},
+		{
+			method: http.MethodGet, path: "/readyz", id: "readyz", tag: "health",
+			summary:   "Report whether the server can take traffic",
+			responses: map[int]any{http.StatusOK: health.Result{}, http.StatusServiceUnavailable: health.Result{}},
+		},
+		{
+			method: http.MethodGet, path: cfg.Metrics.Path, id: "metrics", tag: "health",
+			summary:   "Prometheus metrics",
+			responses: map[int]any{http.StatusOK: content{"text/plain", openapi.String("Prometheus text exposition format")}},
+		},
+		{
+			method: http.MethodGet, path: openAPIPath, id: "openapi", tag: "docs",
+			summary:   "This OpenAPI document",
+			responses: map[int]any{http.StatusOK: content{"application/json", &openapi.Schema{Type: "object"}}},
+		},
+		{
+			method: http.MethodGet, path: docsPath, id: "docs", tag: "docs",
+			summary:   "Swagger UI for this document",
+			responses: map[int]any{http.StatusOK: content{"text/html", openapi.String("")}},
+		},
+
+		// Authentication
+		{
+			method: http.MethodPost, path: "/api/auth/register", id: "register", tag: "auth",
+			summary: "Sign up with an email address and password",
+			body:    handlers.RegisterRequest{}, responses: map[int]any{http.StatusCreated: handlers.RegisterResponse{}},
+			errors: []int{http.StatusBadRequest, http.StatusConflict},
+		},
+		{
+			method: http.MethodPost, path: "/api/auth/login", id: "login", tag: "auth",
+			summary: "Log in with an email address and password",
+			body:    handlers.LoginRequest{}, responses: map[int]any{http.StatusOK: services.TokenPair{}},
+			errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden},
+		},
+		{
+			method: http.MethodPost, path: "/api/auth/refresh", id: "refreshToken", tag: "auth",
+			summary: "Trade a refresh token for new tokens",
+			body:    handlers.RefreshRequest{}, responses: map[int]any{http.StatusOK: services.TokenPair{}},
+			errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden},
+		},
+		{
+			method: http.MethodGet, path: "/api/auth/sso/:provider/config", id: "getSSOConfig", tag: "auth",
+			summary: "Get the public configuration of an SSO provider",
+			params:  []*openapi.Parameter{provider}, responses: map[int]any{http.StatusOK: handlers.SSOConfigResponse{}},
+			errors: []int{http.StatusBadRequest, http.StatusNotFound},
+		},
+		{
+			method: http.MethodPost, path: "/api/auth/sso/:provider/callback", id: "handleSSO", tag: "auth",
+			summary: "Log in, or sign up, with a user an SSO provider vouched for",
+			params:  []*openapi.Parameter{provider}, body: handlers.SSORequest{}, responses: map[int]any{http.StatusOK: services.TokenPair{}},
+			errors: []int{http.StatusBadRequest, http.StatusForbidden},
+		},
+
+		// Quizzes and results
+		{
+			method: http.MethodGet, path: "/api/quiz/", id: "listQuizzes", tag: "quizzes", access: authenticated,
+			summary: "List quizzes; only admins see unpublished ones",
+			params: append(append([]*openapi.Parameter{category, level,
+				query("published", openapi.Boolean(""), "Only published, or unpublished, quizzes; users always get the published ones"),
+				query("created_by", idSchema(), "Only quizzes made by this user"),
+			}, timeRange...), pageParams(repository.QuizSorting.Names())...),
+			responses: map[int]any{http.StatusOK: repository.List[models.QuizSummary]{}},
+			errors:    []int{http.StatusBadRequest},
+		},
+		{
+			method: http.MethodGet, path: "/api/quiz/:id", id: "getQuiz", tag: "quizzes", access: authenticated,
+			summary: "Get a quiz with its questions; only admins also get the correct answers and explanations",
+			params:  []*openapi.Parameter{id}, responses: map[int]any{http.StatusOK: models.PlayerQuiz{}},
+			errors: []int{http.StatusBadRequest, http.StatusNotFound},
+		},
+		{
+			method: http.MethodPost, path: "/api/quiz/:id/submit", id: "submitQuiz", tag: "quizzes", access: authenticated,
+			summary: "Submit answers to a quiz and get them graded",
+			params:  []*openapi.Parameter{id}, body: handlers.SubmitQuizRequest{}, responses: map[int]any{http.StatusOK: handlers.SubmitQuizResponse{}},
+			errors: []int{http.StatusBadRequest, http.StatusNotFound},
+		},
+		{
+			method: http.MethodPost, path: "/api/admin/quiz", id: "createQuiz", tag: "quizzes", access: superAdmin,
+			summary: "Create a quiz with its questions",
+			body:    models.Quiz{}, responses: map[int]any{http.StatusCreated: models.Quiz{}},
+			errors: []int{http.StatusBadRequest, http.StatusConflict},
+		},
+		{
+			method: http.MethodGet, path: "/api/admin/questions", id: "listQuestions", tag: "questions", access: superAdmin,
+			summary: "List questions with their correct answers",
+			params: []*openapi.Parameter{
+				query("quiz_id", idSchema(), "Only questions of this quiz"),
+				query("type", &openapi.Schema{Ref: "#/components/schemas/QuestionType"}, "Only questions of this type"),
+			},
+			responses: map[int]any{http.StatusOK: []models.Question{}},
+			errors:    []int{http.StatusBadRequest},
+		},
+		{
+			method: http.MethodGet, path: "/api/admin/questions/:id", id: "getQuestion", tag: "questions", access: superAdmin,
+			summary: "Get a question with its correct answer",
+			params:  []*openapi.Parameter{id}, responses: map[int]any{http.StatusOK: models.Question{}},
+			errors: []int{http.StatusBadRequest, http.StatusNotFound},
+		},
+		{
+			method: http.MethodPost, path: "/api/admin/questions", id: "createQuestion", tag: "questions", access: superAdmin,
+			summary: "Add a question to a quiz",
+			body:    models.Question{}, responses: map[int]any{http.StatusCreated: models.Question{}},
+			errors: []int{http.StatusBadRequest},
+		},
+		{
+			method: http.MethodPut, path: "/api/admin/questions/:id", id: "updateQuestion", tag: "questions", access: superAdmin,
+			summary: "Replace a question",
+			params:  []*openapi.Parameter{id}, body: models.Question{}, responses: map[int]any{http.StatusOK: models.Question{}},
+			errors: []int{http.StatusBadRequest, http.StatusNotFound},
+		},
+		{
+			method: http.MethodDelete, path: "/api/admin/questions/:id", id: "deleteQuestion", tag: "questions", access: superAdmin,
+			summary: "Delete a question",
+			params:  []*openapi.Parameter{id}, responses: map[int]any{http.StatusNoContent: nil},
+			errors: []int{http.StatusBadRequest, http.StatusNotFound},
+		},
+		{
+			method: http.MethodGet, path: "/api/results/", id: "listResults", tag: "results", access: authenticated,
+			summary: "List quiz results, the current user's by default",
+			params: append(append([]*openapi.Parameter{
+				query("user_id", idSchema(), "Whose results, instead of the current user's; admins only"),
+				query("quiz_id", idSchema(), "Only results of this quiz"),
+				category, level,
+				query("passed", openapi.Boolean(""), "Only passed, or failed, results"),
+			}, timeRange...), pageParams(repository.ResultSorting.Names())...),
+			responses: map[int]any{http.StatusOK: repository.List[models.ResultSummary]{}},
+			errors:    []int{http.StatusBadRequest},
+		},
+		{
+			method: http.MethodGet, path: "/api/results/:id", id: "getResult", tag: "results", access: authenticated,
+			summary: "Get a quiz result with its answers; users only get their own",
+			params:  []*openapi.Parameter{id}, responses: map[int]any{http.StatusOK: models.Result{}},
+			errors: []int{http.StatusBadRequest, http.StatusNotFound},
+		},
+		{
+			method: http.MethodGet, path: "/api/leaderboard", id: "getLeaderboard", tag: "leaderboard", access: authenticated,
+			summary: "List the ranked users, overall or in a category",
+			params: append([]*openapi.Parameter{
+				query("period", &openapi.Schema{Ref: "#/components/schemas/RankingPeriod"}, "Ranking period"),
+				category,
+			}, pageParams(services.LeaderboardSorting.Names())...),
+			responses: map[int]any{http.StatusOK: repository.List[models.LeaderboardEntry]{}},
+			errors:    []int{http.StatusBadRequest},
+		},
+		{
+			method: http.MethodGet, path: "/api/search", id: "search", tag: "quizzes", access: authenticated,
+			summary: "Search published quizzes and their questions",
+			params: []*openapi.Parameter{
+				{Name: "q", In: "query", Required: true, Description: "Words to find, all of them", Schema: openapi.String("")},
+				category, level, limitParam(),
+			},
+			responses: map[int]any{http.StatusOK: services.SearchResults{}},
+			errors:    []int{http.StatusBadRequest},
+		},
+
+		// Users
+		{
+			method: http.MethodGet, path: "/api/users/me", id: "getMe", tag: "users", access: authenticated,
+			summary:   "Get the current user",
+			responses: map[int]any{http.StatusOK: models.User{}},
+			errors:    []int{http.StatusNotFound},
+		},
+		{
+			method: http.MethodPatch, path: "/api/users/me", id: "updateMe", tag: "users", access: authenticated,
+			summary: "Update the current user's profile; fields left out or null are kept",
+			body:    services.ProfileUpdate{}, responses: map[int]any{http.StatusOK: models.User{}},
+			errors: []int{http.StatusBadRequest, http.StatusNotFound},
+		},
+		{
+			method: http.MethodGet, path: "/api/users/me/progress", id: "getMyProgress", tag: "users", access: authenticated,
+			summary:   "Sum up the current user's progress overall and in each category",
+			responses: map[int]any{http.StatusOK: models.ProgressSummary{}},
+		},
+		{
+			method: http.MethodGet, path: "/api/users/me/progress/:category", id: "getMyCategoryProgress", tag: "users", access: authenticated,
+			summary:   "Sum up the current user's progress in a category",
+			params:    []*openapi.Parameter{{Name: "category", In: "path", Required: true, Schema: &openapi.Schema{Ref: "#/components/schemas/QuizCategory"}}},
+			responses: map[int]any{http.StatusOK: models.CategoryProgress{}},
+			errors:    []int{http.StatusBadRequest},
+		},
+		{
+			method: http.MethodPost, path: "/api/users/me/profile-image", id: "uploadProfileImage", tag: "users", access: authenticated,
+			summary: "Upload the current user's profile image",
+			body:    upload, responses: map[int]any{http.StatusCreated: services.UploadedImage{}},
+			errors: []int{http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType},
+		},
+		{
+			method: http.MethodGet, path: "/api/admin/users", id: "listUsers", tag: "users", access: superAdmin,
+			summary: "List users",
+			params: append(append([]*openapi.Parameter{
+				query("q", openapi.String(""), "Only users whose email or name contains this"),
+				query("role", &openapi.Schema{Ref: "#/components/schemas/UserRole"}, "Only users with this role"),
+				query("is_active", openapi.Boolean(""), "Only active, or deactivated, users"),
+			}, timeRange...), pageParams(repository.UserSorting.Names())...),
+			responses: map[int]any{http.StatusOK: repository.List[models.User]{}},
+			errors:    []int{http.StatusBadRequest},
+		},
+		{
+			method: http.MethodPost, path: "/api/admin/users/:id/activate", id: "activateUser", tag: "users", access: superAdmin,
+			summary: "Let a user log in again",
+			params:  []*openapi.Parameter{id}, responses: map[int]any{http.StatusOK: models.User{}},
+			errors: []int{http.StatusBadRequest, http.StatusNotFound},
+		},
+		{
+			method: http.MethodPost, path: "/api/admin/users/:id/deactivate", id: "deactivateUser", tag: "users", access: superAdmin,
+			summary: "Stop a user from logging in",
+			params:  []*openapi.Parameter{id}, responses: map[int]any{http.StatusOK: models.User{}},
+			errors: []int{http.StatusBadRequest, http.StatusNotFound},
+		},
+		{
+			method: http.MethodPatch, path: "/api/admin/users/:id/role", id: "changeUserRole", tag: "users", access: superAdmin,
+			summary: "Change a user's role",
+			params:  []*openapi.Parameter{id}, body: handlers.ChangeRoleRequest{}, responses: map[int]any{http.StatusOK: models.User{}},
+			errors: []int{http.StatusBadRequest, http.StatusNotFound},
+		},
+		{
+			method: http.MethodDelete, path: "/api/admin/users/:id", id: "deleteUser", tag: "users", access: superAdmin,
+			summary: "Delete a user",
+			params:  []*openapi.Parameter{id}, responses: map[int]any{http.StatusNoContent: nil},
+			errors: []int{http.StatusBadRequest, http.StatusNotFound},
+		},
+		{
+			method: http.MethodPost, path: "/api/admin/users/:id/erase", id: "eraseUser", tag: "users", access: superAdmin,
+			summary: "Erase a user's personal data, keeping anonymized results",
+			params:  []*openapi.Parameter{id}, responses: map[int]any{http.StatusNoContent: nil},
+			errors: []int{http.StatusBadRequest, http.StatusNotFound},
+		},
+		{
+			method: http.MethodPost, path: "/api/admin/achievements/:id/icon", id: "uploadAchievementIcon", tag: "achievements", access: superAdmin,
+			summary: "Upload an achievement's icon",
+			params:  []*openapi.Parameter{id}, body: upload, responses: map[int]any{http.StatusCreated: services.UploadedImage{}},
+			errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType},
+		},
+		{
+			method: http.MethodGet, path: "/api/admin/db/stats", id: "getDBStats", tag: "admin", access: superAdmin,
+			summary: "Get the database connection pool statistics",
+			responses: map[int]any{http.StatusOK: struct {
+				Pools []database.PoolStats `json:"pools"`
+			}{}},
+		},
+
+		// Data exports
+		{
+			method: http.MethodPost, path: "/api/users/me/export", id: "requestMyExport", tag: "exports", access: authenticated,
+			summary:   "Start an export of the current user's data",
+			responses: map[int]any{http.StatusAccepted: handlers.ExportResponse{}},
+			errors:    []int{http.StatusNotFound},
+		},
+		{
+			method: http.MethodGet, path: "/api/users/me/exports/:id", id: "getMyExport", tag: "exports", access: authenticated,
+			summary: "Get an export of the current user's data, with its download link once ready",
+			params:  []*openapi.Parameter{id}, responses: map[int]any{http.StatusOK: handlers.ExportResponse{}},
+			errors: []int{http.StatusBadRequest, http.StatusNotFound},
+		},
+		{
+			method: http.MethodPost, path: "/api/admin/users/:id/export", id: "requestUserExport", tag: "exports", access: superAdmin,
+			summary: "Start an export of a user's data on their behalf",
+			params:  []*openapi.Parameter{id}, responses: map[int]any{http.StatusAccepted: handlers.ExportResponse{}},
+			errors: []int{http.StatusBadRequest, http.StatusNotFound},
+		},
+		{
+			method: http.MethodGet, path: "/api/admin/exports/:id", id: "getExport", tag: "exports", access: superAdmin,
+			summary: "Get any export, with its download link once ready",
+			params:  []*openapi.Parameter{id}, responses: map[int]any{http.StatusOK: handlers.ExportResponse{}},
+			errors: []int{http.StatusBadRequest, http.StatusNotFound},
+		},
+		{
+			method: http.MethodGet, path: "/api/exports/:id/download", id: "downloadExport", tag: "exports",
+			summary: "Download an export's ZIP through its signed link",
+			params: []*openapi.Parameter{id,
+				{Name: "expires", In: "query", Required: true, Description: "From the download link", Schema: openapi.String("")},
+				{Name: "signature", In: "query", Required: true, Description: "From the download link", Schema: openapi.String("")},
+			},
+			responses: map[int]any{http.StatusOK: content{"application/zip", openapi.Binary("application/zip")}},
+			errors:    []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusConflict},
+		},
+	}
+
+	// Locally stored uploads are served with HEAD too
+	for dir, name := range map[string]string{"profile-images": "ProfileImage", "achievement-icons": "AchievementIcon"} {
+		for _, method := range []string{http.MethodGet, http.MethodHead} {
+			routes = append(routes, apiRoute{
+				method: method, path: storage.LocalURLPrefix + "/" + dir + "/*filepath",
+				id:  strings.ToLower(method) + name,
+				tag: "uploads", summary: "Get an uploaded image",
+				params: []*openapi.Parameter{filepath}, responses: map[int]any{http.StatusOK: image},
+				errors: []int{http.StatusNotFound},
+			})
+		}
+	}
+	return routes
+}
+
+// apiSpec builds the OpenAPI document of the registered routes
+func apiSpec(cfg *config.Config, registered gin.RoutesInfo) *openapi.Document {
+	spec := openapi.New(openapi.Info{
+		Title:   "AICG API",
+		Version: version.Get().Version,
+		Description: "Failures answer with an ErrorResponse, or with a Problem for clients accepting application/problem+json. " +
+			"Routes under /api are rate limited, and tell the client its quota in RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset.",
+	})
+	spec.SecurityScheme("bearerAuth", &openapi.SecurityScheme{
+		Type: "http", Scheme: "bearer", BearerFormat: "JWT",
+		Description: "The access token from logging in. Admin routes need a super_admin.",
+	})
+
+	spec.Name(health.Result{}, "Readiness")
+	spec.Name(version.Info{}, "VersionInfo")
+	spec.Enum(values(enums.ValidCategories())...)
+	spec.Enum(values(enums.ValidDifficulties())...)
+	spec.Enum(values(enums.ValidQuestionTypes())...)
+	spec.Enum(values(enums.ValidUserRoles())...)
+	spec.Enum(values(enums.ValidAuthProviders())...)
+	spec.Enum(values(enums.ValidRankingPeriods())...)
+	spec.Enum(models.DataExportPending, models.DataExportReady, models.DataExportFailed, models.DataExportExpired)
+
+	for status, name := range errorStatuses {
+		response := &openapi.Response{
+			Description: http.StatusText(status),
+			Content: map[string]*openapi.MediaType{
+				"application/json":            {Schema: spec.Schema(middleware.ErrorResponse{})},
+				middleware.ProblemContentType: {Schema: spec.Schema(middleware.Problem{})},
+			},
+		}
+		if status == http.StatusTooManyRequests {
+			response.Headers = map[string]*openapi.Header{
+				"Retry-After": {Description: "Seconds until a request will be let through", Schema: openapi.Integer("")},
+			}
+		}
+		spec.Response(name, response)
+	}
+
+	documented := make(map[string]apiRoute)
+	for _, route := range apiRoutes(cfg) {
+		documented[route.method+" "+route.path] = route
+	}
+	tags := make(map[string]bool)
+	for _, r := range registered {
+		route, ok := documented[r.Method+" "+r.Path]
+		if !ok {
+			slog.Warn("Route missing from the OpenAPI document", "method", r.Method, "path", r.Path)
+			continue
+		}
+		spec.Add(route.method, route.path, route.operation(spec))
+		if !tags[route.tag] {
+			tags[route.tag] = true
+			spec.Tag(route.tag, "")
+		}
+	}
+	return spec.Document()
+}
+
+// operation returns the OpenAPI operation of a route
+func (route apiRoute) operation(spec *openapi.Spec) *openapi.Operation {
+	op := &openapi.Operation{
+		OperationID: route.id,
+		Summary:     route.summary,
+		Tags:        []string{route.tag},
+		Parameters:  route.params,
+		Responses:   make(map[string]*openapi.Response),
+	}
+	if route.body != nil {
+		op.RequestBody = &openapi.RequestBody{Required: true, Content: body(spec, route.body)}
+	}
+	for status, v := range route.responses {
+		response := &openapi.Response{Description: http.StatusText(status)}
+		if v != nil {
+			response.Content = body(spec, v)
+		}
+		op.Responses[openapi.Status(status)] = response
+	}
+
+	failures := append([]int{http.StatusInternalServerError}, route.errors...)
+	if strings.HasPrefix(route.path, "/api/") {
+		failures = append(failures, http.StatusTooManyRequests)
+	}
+	if route.access != public {
+		// Deactivated users get a 403 on every route
+		op.Security = []map[string][]string{{"bearerAuth": {}}}
+		failures = append(failures, http.StatusUnauthorized, http.StatusForbidden)
+	}
+	if route.access != public && route.method == http.MethodPost {
+		op.Parameters = append(slices.Clip(op.Parameters), idempotencyKeyParam())
+		failures = append(failures, http.StatusConflict)
+	}
+	for _, status := range failures {
+		op.Responses[openapi.Status(status)] = openapi.ResponseRef(errorStatuses[status])
+	}
+	return op
+}
+
+// body returns the content of a request or response body
+func body(spec *openapi.Spec, v any) map[string]*openapi.MediaType {
+	if c, ok := v.(content); ok {
+		return map[string]*openapi.MediaType{c.mediaType: {Schema: c.schema}}
+	}
+	return map[string]*openapi.MediaType{"application/json": {Schema: spec.Schema(v)}}
+}
+
+// registerAPIDocs serves the OpenAPI document of the router's routes and a
+// Swagger UI for it. It has to be registered last, to see every route.
+func registerAPIDocs(r *gin.Engine, cfg *config.Config) {
+	var document []byte
+	r.GET(openAPIPath, func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json", document)
+	})
+	r.GET(docsPath, gin.WrapH(openapi.SwaggerUI("AICG API", openAPIPath)))
+
+	var err error
+	if document, err = json.Marshal(apiSpec(cfg, r.Routes())); err != nil {
+		panic(err)
+	}
+}
+
+// pathParam returns a numeric ID path parameter
+func pathParam(name, description string) *openapi.Parameter {
+	return &openapi.Parameter{Name: name, In: "path", Required: true, Description: description, Schema: idSchema()}
+}
+
+// query returns an optional query parameter
+func query(name string, schema *openapi.Schema, description string) *openapi.Parameter {
+	return &openapi.Parameter{Name: name, In: "query", Description: description, Schema: schema}
+}
+
+// pageParams returns the limit, sort and cursor parameters of a listing sorted by the given keys
+func pageParams(keys []string) []*openapi.Parameter {
+	sorts := make([]any, 0, 2*len(keys))
+	for _, key := range keys {
+		sorts = append(sorts, key, "-"+key)
+	}
+	return []*openapi.Parameter{
+		limitParam(),
+		query("sort", &openapi.Schema{Type: "string", Enum: sorts}, "Order of the listing, descending with a - prefix"),
+		query("cursor", openapi.String(""), "The next_cursor of the previous page, with the same sort"),
+	}
+}
+
+// idempotencyKeyParam returns the Idempotency-Key header of authenticated POSTs
+func idempotencyKeyParam() *openapi.Parameter {
+	maxLength := idempotency.MaxKeyLength
+	return &openapi.Parameter{
+		Name: middleware.IdempotencyKeyHeader, In: "header",
+		Description: "A unique value, such as a UUID, making the request safe to retry: for 24 hours, retries with the same key and body " +
+			"get the first response again, marked with Idempotent-Replayed. Other requests with the key get a 409 idempotency_key_reused, " +
+			"and retries while the first request is still running a 409 idempotency_key_in_use. Only accepted on authenticated requests",
+		Schema: &openapi.Schema{Type: "string", MaxLength: &maxLength},
+	}
+}
+
+// limitParam returns the limit parameter of listings
+func limitParam() *openapi.Parameter {
+	minimum, maximum := float64(1), float64(repository.MaxLimit)
+	return query("limit", &openapi.Schema{Type: "integer", Minimum: &minimum, Maximum: &maximum, Default: repository.DefaultLimit}, "How many records to return")
+}
+
+// idSchema is the schema of record IDs
+func idSchema() *openapi.Schema {
+	minimum := float64(1)
+	return &openapi.Schema{Type: "integer", Minimum: &minimum}
+}
+
+// ssoProviders returns the providers of the SSO routes
+func ssoProviders() []any {
+	var providers []any
+	for _, p := range enums.ValidAuthProviders() {
+		if p.IsSSO() {
+			providers = append(providers, string(p))
+		}
+	}
+	return providers
+}
+
+// values turns enum values into the arguments of Spec.Enum
+func values[T any](vs []T) []any {
+	out := make([]any, len(vs))
+	for i, v := range vs {
+		out[i] = v
+	}
+	return out
+}
@@ -1,83 +1,323 @@
 package routes
 
 import (
+	"context"
+	"log/slog"
 	"net/http"
+	"path/filepath"
 
+	"aicg/internal/config"
+	"aicg/internal/database"
 	"aicg/internal/handlers"
+	"aicg/internal/health"
+	"aicg/internal/idempotency"
+	"aicg/internal/metrics"
+	"aicg/internal/middleware"
+	"aicg/internal/ratelimit"
+	"aicg/internal/repository"
+	"aicg/internal/services"
+	"aicg/internal/storage"
 
-	"github.com/gorilla/mux"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
-// RegisterRoutes sets up all API routes for the application
+// Services holds the services the API is built on.
+// Tests can pass mocks for the interface-typed ones.
+type Services struct {
+	Auth        services.IAuthService
+	Quiz        services.IQuizService
+	Question    services.IQuestionService
+	User        services.IUserService
+	Leaderboard services.ILeaderboardService
+	Search      services.ISearchService
+	Image       *services.ImageService
+	Export      *services.ExportService
+
+	// Health holds the readiness checks behind /readyz (optional)
+	Health *health.Checker
+
+	// DBStats reports the connection pools for monitoring (optional)
+	DBStats func() ([]database.PoolStats, error)
+
+	// RateLimits keeps the rate limit buckets (optional, in memory by default)
+	RateLimits ratelimit.Store
+
+	// Idempotency keeps the responses to POSTs with an Idempotency-Key (optional, in memory by default)
+	Idempotency idempotency.Store
+}
+
+// NewServices creates the production services backed by db and blobs
+func NewServices(db *gorm.DB, blobs storage.BlobStore, cfg *config.Config) Services {
+	store := repository.NewGormStore(db)
+
+	checker := health.NewChecker()
+	checker.Add("database", func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	})
+	checker.Add("migrations", func(ctx context.Context) error {
+		migrator, err := database.NewMigrator(db)
+		if err != nil {
+			return err
+		}
+		return migrator.CheckSchema(ctx)
+	})
+
+	return Services{
+		Auth:        services.NewAuthService(store, cfg),
+		Quiz:        services.NewQuizService(store),
+		Question:    services.NewQuestionService(db),
+		User:        services.NewUserService(db, blobs),
+		Leaderboard: services.NewLeaderboardService(db),
+		Search:      services.NewSearchService(db),
+		Image:       services.NewImageService(db, blobs),
+		Export:      services.NewExportService(db, blobs, cfg),
+		Health:      checker,
+		DBStats:     func() ([]database.PoolStats, error) { return database.Stats(db) },
+		Idempotency: idempotency.NewGormStore(db),
+	}
+}
+
+// SetupRouter creates the Gin engine with all middleware and API routes
 //
 // Parameters:
-//   - router: The Gorilla Mux router instance
+//   - cfg: The application configuration
+//   - svc: The services handlers delegate to
 //
 // Returns:
-//   - None
-func RegisterRoutes(router *mux.Router) {
-	// Create API subrouter with /api prefix
-	api := router.PathPrefix("/api").Subrouter()
-
-	// Register question routes
-	registerQuestionRoutes(api)
-
-	// Register result routes
-	registerResultRoutes(api)
-
-	// Register user routes
-	registerUserRoutes(api)
-
-	// Register auth routes
-	registerAuthRoutes(api)
-
-	// Register health check route
-	registerHealthCheck(router)
-}
-
-// registerQuestionRoutes sets up routes for question management
-func registerQuestionRoutes(api *mux.Router) {
-	api.HandleFunc("/questions",
-		handlers.GetQuestions).Methods("GET")
-	api.HandleFunc("/questions",
-		handlers.CreateQuestion).Methods("POST")
-}
-
-// registerResultRoutes sets up routes for result management
-func registerResultRoutes(api *mux.Router) {
-	api.HandleFunc("/results",
-		handlers.GetResults).Methods("GET")
-	api.HandleFunc("/results",
-		handlers.CreateResult).Methods("POST")
-}
-
-// registerUserRoutes sets up routes for user management
-func registerUserRoutes(api *mux.Router) {
-	api.HandleFunc("/users",
-		handlers.GetUsers).Methods("GET")
-	api.HandleFunc("/users/{id}",
-		handlers.GetUser).Methods("GET")
-	api.HandleFunc("/users",
-		handlers.CreateUser).Methods("POST")
-	api.HandleFunc("/users/{id}",
-		handlers.UpdateUser).Methods("PUT")
-}
-
-// registerAuthRoutes sets up routes for authentication
-func registerAuthRoutes(api *mux.Router) {
-	api.HandleFunc("/auth/login",
-		handlers.Login).Methods("POST")
-	api.HandleFunc("/auth/register",
-		handlers.Register).Methods("POST")
-	api.HandleFunc("/auth/refresh",
-		handlers.RefreshToken).Methods("POST")
-}
-
-// registerHealthCheck sets up the health check endpoint
-func registerHealthCheck(router *mux.Router) {
-	router.HandleFunc("/health",
-		func(w http.ResponseWriter, r *http.Request) {
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte("OK"))
-		}).Methods("GET")
+//   - The configured Gin engine
+func SetupRouter(cfg *config.Config, svc Services) *gin.Engine {
+	r := gin.New()
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		slog.Error("Invalid trusted proxies, trusting none", "error", err)
+		_ = r.SetTrustedProxies(nil)
+	}
+	if cfg.Tracing.Exporter != "none" {
+		r.Use(middleware.Tracing(cfg.Tracing.ServiceName, cfg.Metrics.Path))
+	}
+	r.Use(middleware.RequestID())
+	r.Use(middleware.Logger(slog.Default()))
+	r.Use(middleware.Recovery(slog.Default()))
+	if cfg.Metrics.Enabled {
+		r.Use(middleware.Metrics())
+	}
+	r.Use(middleware.Errors())
+	r.Use(middleware.CORS(cfg.CORS))
+	r.NoRoute(middleware.NoRoute())
+
+	authMiddleware := middleware.NewAuthMiddleware(cfg, svc.Auth)
+	limit := rateLimiter(cfg.RateLimit, svc.RateLimits)
+	if svc.Idempotency == nil {
+		svc.Idempotency = idempotency.NewMemoryStore()
+	}
+
+	authHandler := handlers.NewAuthHandler(svc.Auth)
+	quizHandler := handlers.NewQuizHandler(svc.Quiz)
+	questionHandler := handlers.NewQuestionHandler(svc.Question)
+	userHandler := handlers.NewUserHandler(svc.User)
+	leaderboardHandler := handlers.NewLeaderboardHandler(svc.Leaderboard)
+	searchHandler := handlers.NewSearchHandler(svc.Search)
+	imageHandler := handlers.NewImageHandler(svc.Image)
+	exportHandler := handlers.NewExportHandler(svc.Export)
+	if svc.Health == nil {
+		svc.Health = health.NewChecker()
+	}
+	healthHandler := handlers.NewHealthHandler(svc.Health)
+
+	// Register health check routes
+	registerHealthCheck(r, healthHandler)
+	if cfg.Metrics.Enabled {
+		registerMetrics(r, cfg.Metrics.Path, svc.DBStats)
+	}
+
+	// Register locally stored uploads
+	registerUploads(r, cfg)
+
+	// Public routes
+	public := r.Group("/api")
+	public.Use(limit("api", cfg.RateLimit.Policy()), middleware.Idempotency(svc.Idempotency))
+	registerAuthRoutes(public, authHandler, cfg.Features, limit("auth", cfg.RateLimit.Auth))
+	if cfg.Features.DataExports {
+		registerExportDownloadRoutes(public, exportHandler)
+	}
+
+	// Protected routes
+	protected := r.Group("/api")
+	protected.Use(authMiddleware.AuthRequired(), limit("api", cfg.RateLimit.Policy()), middleware.Idempotency(svc.Idempotency))
+
+	// Admin routes
+	admin := protected.Group("/admin")
+	admin.Use(authMiddleware.RequireSuperAdmin(), limit("admin", cfg.RateLimit.Admin))
+
+	registerQuizRoutes(protected, admin, quizHandler, limit("submit", cfg.RateLimit.Submit))
+	registerQuestionRoutes(admin, questionHandler)
+	registerResultRoutes(protected, quizHandler)
+	registerProgressRoutes(protected, quizHandler)
+	registerLeaderboardRoutes(protected, leaderboardHandler)
+	registerSearchRoutes(protected, searchHandler)
+	registerUserRoutes(protected, admin, userHandler, imageHandler)
+	if cfg.Features.DataExports {
+		registerExportRoutes(protected, admin, exportHandler)
+	}
+	registerAchievementRoutes(admin, imageHandler)
+	registerDBStats(admin, svc.DBStats)
+
+	// Last, so the document covers every route
+	if cfg.Features.APIDocs {
+		registerAPIDocs(r, cfg)
+	}
+
+	return r
+}
+
+// rateLimiter returns a function building the middleware of each rate limit
+// policy, which lets everything through when rate limiting is off
+func rateLimiter(cfg config.RateLimitConfig, store ratelimit.Store) func(name string, policy config.RateLimitPolicy) gin.HandlerFunc {
+	if !cfg.Enabled {
+		return func(string, config.RateLimitPolicy) gin.HandlerFunc {
+			return func(c *gin.Context) { c.Next() }
+		}
+	}
+	if store == nil {
+		store = ratelimit.NewMemoryStore()
+	}
+	return func(name string, policy config.RateLimitPolicy) gin.HandlerFunc {
+		return middleware.RateLimit(store, name, ratelimit.FromPolicy(policy))
+	}
+}
+
+// registerHealthCheck sets up the health check and the liveness and readiness probes
+func registerHealthCheck(r *gin.Engine, h *handlers.HealthHandler) {
+	r.GET("/health", func(c *gin.Context) {
+		c.String(http.StatusOK, "OK")
+	})
+	r.GET("/livez", h.Livez)
+	r.GET("/readyz", h.Readyz)
+}
+
+// registerMetrics serves the Prometheus metrics, including the connection pools when stats is set
+func registerMetrics(r *gin.Engine, path string, stats func() ([]database.PoolStats, error)) {
+	r.GET(path, gin.WrapH(metrics.Handler(stats)))
+}
+
+// registerDBStats exposes the connection pool statistics to admins
+func registerDBStats(admin *gin.RouterGroup, stats func() ([]database.PoolStats, error)) {
+	if stats == nil {
+		return
+	}
+	admin.GET("/db/stats", func(c *gin.Context) {
+		pools, err := stats()
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"pools": pools})
+	})
+}
+
+// registerUploads serves locally stored images. Data exports live in the same
+// directory but are only downloadable through their signed links.
+func registerUploads(r *gin.Engine, cfg *config.Config) {
+	if cfg.Storage.Driver != "local" || cfg.Storage.PublicURL != "" {
+		return
+	}
+	for _, dir := range []string{"profile-images", "achievement-icons"} {
+		r.Static(storage.LocalURLPrefix+"/"+dir, filepath.Join(cfg.Storage.LocalDir, dir))
+	}
+}
+
+// registerAuthRoutes sets up routes for authentication, leaving out the disabled sign-up methods
+func registerAuthRoutes(public *gin.RouterGroup, h *handlers.AuthHandler, features config.FeatureFlags, limit gin.HandlerFunc) {
+	auth := public.Group("/auth", limit)
+	if features.Registration {
+		auth.POST("/register", h.Register)
+	}
+	auth.POST("/login", h.Login)
+	auth.POST("/refresh", h.RefreshToken)
+	if features.SSO {
+		auth.GET("/sso/:provider/config", h.GetSSOConfig)
+		auth.POST("/sso/:provider/callback", h.HandleSSO)
+	}
+}
+
+// registerExportDownloadRoutes sets up data export downloads, which are authorized by their signed link
+func registerExportDownloadRoutes(public *gin.RouterGroup, h *handlers.ExportHandler) {
+	public.GET("/exports/:id/download", h.Download)
+}
+
+// registerQuizRoutes sets up routes for taking and managing quizzes, with
+// submissions going through their own rate limit
+func registerQuizRoutes(protected, admin *gin.RouterGroup, h *handlers.QuizHandler, submitLimit gin.HandlerFunc) {
+	quiz := protected.Group("/quiz")
+	quiz.GET("/", h.GetQuizzes)
+	quiz.GET("/:id", h.GetQuiz)
+	quiz.POST("/:id/submit", submitLimit, h.SubmitQuiz)
+
+	admin.POST("/quiz", h.CreateQuiz)
+}
+
+// registerQuestionRoutes sets up routes for question management. Questions
+// come with their correct answers, so only admins get to see them this way.
+func registerQuestionRoutes(admin *gin.RouterGroup, h *handlers.QuestionHandler) {
+	admin.GET("/questions", h.GetQuestions)
+	admin.GET("/questions/:id", h.GetQuestion)
+	admin.POST("/questions", h.CreateQuestion)
+	admin.PUT("/questions/:id", h.UpdateQuestion)
+	admin.DELETE("/questions/:id", h.DeleteQuestion)
+}
+
+// registerResultRoutes sets up routes for quiz results
+func registerResultRoutes(protected *gin.RouterGroup, h *handlers.QuizHandler) {
+	results := protected.Group("/results")
+	results.GET("/", h.GetResults)
+	results.GET("/:id", h.GetResult)
+}
+
+// registerProgressRoutes sets up the routes summing up the user's progress
+func registerProgressRoutes(protected *gin.RouterGroup, h *handlers.QuizHandler) {
+	protected.GET("/users/me/progress", h.GetMyProgress)
+	protected.GET("/users/me/progress/:category", h.GetMyProgressByCategory)
+}
+
+// registerLeaderboardRoutes sets up the leaderboard route
+func registerLeaderboardRoutes(protected *gin.RouterGroup, h *handlers.LeaderboardHandler) {
+	protected.GET("/leaderboard", h.GetLeaderboard)
+}
+
+// registerSearchRoutes sets up the quiz search route
+func registerSearchRoutes(protected *gin.RouterGroup, h *handlers.SearchHandler) {
+	protected.GET("/search", h.Search)
+}
+
+// registerUserRoutes sets up routes for profiles and user management
+func registerUserRoutes(protected, admin *gin.RouterGroup, h *handlers.UserHandler, images *handlers.ImageHandler) {
+	users := protected.Group("/users")
+	users.GET("/me", h.GetMe)
+	users.PATCH("/me", h.UpdateMe)
+	users.POST("/me/profile-image", images.UploadProfileImage)
+
+	admin.GET("/users", h.ListUsers)
+	admin.POST("/users/:id/activate", h.ActivateUser)
+	admin.POST("/users/:id/deactivate", h.DeactivateUser)
+	admin.PATCH("/users/:id/role", h.ChangeUserRole)
+	admin.DELETE("/users/:id", h.DeleteUser)
+	admin.POST("/users/:id/erase", h.EraseUser)
+}
+
+// registerExportRoutes sets up routes for requesting personal data exports
+func registerExportRoutes(protected, admin *gin.RouterGroup, h *handlers.ExportHandler) {
+	protected.POST("/users/me/export", h.RequestMyExport)
+	protected.GET("/users/me/exports/:id", h.GetMyExport)
+
+	admin.POST("/users/:id/export", h.RequestUserExport)
+	admin.GET("/exports/:id", h.GetExport)
+}
+
+// registerAchievementRoutes sets up routes for achievement management
+func registerAchievementRoutes(admin *gin.RouterGroup, images *handlers.ImageHandler) {
+	admin.POST("/achievements/:id/icon", images.UploadAchievementIcon)
 }
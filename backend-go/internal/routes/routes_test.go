@@ -1,25 +1,91 @@
 package routes
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"regexp"
 	"testing"
+	"time"
 
-	"github.com/gorilla/mux"
+	"aicg/internal/config"
+	"aicg/internal/database"
+	"aicg/internal/logging"
+	"aicg/internal/models"
+	"aicg/internal/models/enums"
+	"aicg/internal/openapi"
+	"aicg/internal/repository"
+	"aicg/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
 )
 
-func TestRegisterRoutes(t *testing.T) {
-	// Create a new router
-	router := mux.NewRouter()
+// setupRouterTest builds the full router. The requests below are all answered
+// by middleware or request validation, so the services never touch a database.
+func setupRouterTest() (*gin.Engine, *config.Config) {
+	cfg := config.Default()
+	cfg.JWT.Secret = "test-secret"
+	return newTestRouter(cfg), cfg
+}
+
+// newTestRouter builds the full router for cfg on in-memory services, changed by
+// configure, with the active user 1 that testToken's tokens are issued to
+func newTestRouter(cfg *config.Config, configure ...func(*Services)) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	store := repository.NewMemoryStore()
+	if err := store.Users().Create(&models.User{Email: "test@example.com", Role: enums.RoleMaveric, IsActive: true}); err != nil {
+		panic(err)
+	}
+	svc := Services{
+		Auth:        services.NewAuthService(store, cfg),
+		Quiz:        services.NewQuizService(store),
+		Question:    services.NewQuestionService(nil),
+		User:        services.NewUserService(nil, nil),
+		Leaderboard: services.NewLeaderboardService(nil),
+		Search:      services.NewSearchService(nil),
+		Image:       services.NewImageService(nil, nil),
+		Export:      services.NewExportService(nil, nil, cfg),
+	}
+	for _, c := range configure {
+		c(&svc)
+	}
+	return SetupRouter(cfg, svc)
+}
+
+// testToken signs an access token the auth middleware accepts
+func testToken(t *testing.T, cfg *config.Config, role enums.UserRole) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":   1,
+		"email": "test@example.com",
+		"role":  string(role),
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	})
+	signed, err := token.SignedString([]byte(cfg.JWT.Secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
 
-	// Register routes
-	RegisterRoutes(router)
+func TestSetupRouter(t *testing.T) {
+	r, cfg := setupRouterTest()
+	adminToken := testToken(t, cfg, enums.RoleSuperAdmin)
+	userToken := testToken(t, cfg, enums.RoleMaveric)
 
 	// Test cases
 	tests := []struct {
 		name       string
 		method     string
 		path       string
+		token      string
+		body       string
 		wantStatus int
 	}{
 		{
@@ -29,83 +95,108 @@ func TestRegisterRoutes(t *testing.T) {
 			wantStatus: http.StatusOK,
 		},
 		{
-			name:       "Get questions",
+			name:       "Liveness probe",
 			method:     "GET",
-			path:       "/api/questions",
+			path:       "/livez",
 			wantStatus: http.StatusOK,
 		},
 		{
-			name:       "Create question",
-			method:     "POST",
-			path:       "/api/questions",
+			name:       "Readiness probe without checks",
+			method:     "GET",
+			path:       "/readyz",
 			wantStatus: http.StatusOK,
 		},
 		{
-			name:       "Get results",
+			name:       "Unknown route",
 			method:     "GET",
-			path:       "/api/results",
-			wantStatus: http.StatusOK,
+			path:       "/api/unknown",
+			wantStatus: http.StatusNotFound,
 		},
 		{
-			name:       "Create result",
-			method:     "POST",
-			path:       "/api/results",
-			wantStatus: http.StatusOK,
+			name:       "Get questions without token",
+			method:     "GET",
+			path:       "/api/admin/questions",
+			wantStatus: http.StatusUnauthorized,
 		},
 		{
-			name:       "Get users",
+			name:       "Get questions as regular user",
 			method:     "GET",
-			path:       "/api/users",
-			wantStatus: http.StatusOK,
+			path:       "/api/admin/questions/1",
+			token:      userToken,
+			wantStatus: http.StatusForbidden,
 		},
 		{
-			name:       "Get user by ID",
+			name:       "Get results without token",
 			method:     "GET",
-			path:       "/api/users/1",
-			wantStatus: http.StatusOK,
+			path:       "/api/results/",
+			wantStatus: http.StatusUnauthorized,
 		},
 		{
-			name:       "Create user",
-			method:     "POST",
-			path:       "/api/users",
-			wantStatus: http.StatusOK,
+			name:       "Get profile with invalid token",
+			method:     "GET",
+			path:       "/api/users/me",
+			token:      "not-a-token",
+			wantStatus: http.StatusUnauthorized,
 		},
 		{
-			name:       "Update user",
-			method:     "PUT",
-			path:       "/api/users/1",
-			wantStatus: http.StatusOK,
+			name:       "Get questions with invalid filter",
+			method:     "GET",
+			path:       "/api/admin/questions?type=riddle",
+			token:      adminToken,
+			wantStatus: http.StatusBadRequest,
 		},
 		{
-			name:       "Login",
+			name:       "Create question as regular user",
 			method:     "POST",
-			path:       "/api/auth/login",
-			wantStatus: http.StatusOK,
+			path:       "/api/admin/questions",
+			token:      userToken,
+			body:       `{}`,
+			wantStatus: http.StatusForbidden,
 		},
 		{
-			name:       "Register",
+			name:       "Create question with invalid body",
 			method:     "POST",
-			path:       "/api/auth/register",
-			wantStatus: http.StatusOK,
+			path:       "/api/admin/questions",
+			token:      adminToken,
+			body:       `{"text": "?"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "List users as regular user",
+			method:     "GET",
+			path:       "/api/admin/users",
+			token:      userToken,
+			wantStatus: http.StatusForbidden,
 		},
 		{
-			name:       "Refresh token",
+			name:       "Login with invalid body",
 			method:     "POST",
-			path:       "/api/auth/refresh",
-			wantStatus: http.StatusOK,
+			path:       "/api/auth/login",
+			body:       `{}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Download export without signature",
+			method:     "GET",
+			path:       "/api/exports/1/download",
+			wantStatus: http.StatusForbidden,
 		},
 	}
 
 	// Run tests
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			req, err := http.NewRequest(tt.method, tt.path, nil)
+			req, err := http.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
 			if err != nil {
 				t.Fatal(err)
 			}
+			req.Header.Set("Content-Type", "application/json")
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
 
 			rr := httptest.NewRecorder()
-			router.ServeHTTP(rr, req)
+			r.ServeHTTP(rr, req)
 
 			if status := rr.Code; status != tt.wantStatus {
 				t.Errorf(
@@ -117,3 +208,416 @@ func TestRegisterRoutes(t *testing.T) {
 		})
 	}
 }
+
+func TestFeatureFlags(t *testing.T) {
+	cfg := config.Default()
+	cfg.Features = config.FeatureFlags{}
+	r := newTestRouter(cfg)
+	userToken := testToken(t, cfg, enums.RoleMaveric)
+
+	// Disabled features have no routes, while the rest of the API stays up
+	for _, tt := range []struct{ method, path string }{
+		{"POST", "/api/auth/register"},
+		{"GET", "/api/auth/sso/google/config"},
+		{"POST", "/api/auth/sso/google/callback"},
+		{"POST", "/api/users/me/export"},
+		{"GET", "/api/exports/1/download"},
+	} {
+		req := httptest.NewRequest(tt.method, tt.path, bytes.NewBufferString(`{}`))
+		req.Header.Set("Authorization", "Bearer "+userToken)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusNotFound, rr.Code, "%s %s", tt.method, tt.path)
+	}
+
+	req := httptest.NewRequest("POST", "/api/auth/login", bytes.NewBufferString(`{}`))
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestRequestID(t *testing.T) {
+	var logs bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(logging.New(&logs, config.LogConfig{Level: "info", Format: "json"}))
+	defer slog.SetDefault(defaultLogger)
+	r, _ := setupRouterTest()
+
+	// Test case 1: Requests without an ID get one, which is echoed and logged
+	req := httptest.NewRequest("GET", "/api/quiz/?token=secret", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	id := rr.Header().Get("X-Request-ID")
+	assert.Regexp(t, `^[0-9a-f]{32}$`, id)
+	assert.Contains(t, logs.String(), `"request_id":"`+id+`"`)
+	assert.Contains(t, logs.String(), `"status":401`)
+	assert.NotContains(t, logs.String(), "secret")
+
+	// Test case 2: An ID set by a proxy is kept
+	req = httptest.NewRequest("GET", "/livez", nil)
+	req.Header.Set("X-Request-ID", "edge-1234")
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, "edge-1234", rr.Header().Get("X-Request-ID"))
+
+	// Test case 3: IDs that could forge log lines are replaced
+	req = httptest.NewRequest("GET", "/livez", nil)
+	req.Header.Set("X-Request-ID", "x\nlevel=ERROR")
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Regexp(t, `^[0-9a-f]{32}$`, rr.Header().Get("X-Request-ID"))
+}
+
+func TestMetrics(t *testing.T) {
+	r, _ := setupRouterTest()
+
+	// Test case 1: Requests are reported by route template, unknown paths under one label
+	for _, path := range []string{"/livez", "/no/such/page"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `aicg_http_requests_total{method="GET",route="/livez",status="200"}`)
+	assert.Contains(t, rr.Body.String(), `aicg_http_requests_total{method="GET",route="unmatched",status="404"}`)
+	assert.NotContains(t, rr.Body.String(), "/no/such/page")
+
+	// Test case 2: The endpoint can be moved and turned off
+	cfg := config.Default()
+	cfg.Metrics.Path = "/internal/metrics"
+	rr = httptest.NewRecorder()
+	newTestRouter(cfg).ServeHTTP(rr, httptest.NewRequest("GET", "/internal/metrics", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	cfg.Metrics.Enabled = false
+	rr = httptest.NewRecorder()
+	newTestRouter(cfg).ServeHTTP(rr, httptest.NewRequest("GET", "/internal/metrics", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestCORS(t *testing.T) {
+	cfg := config.Default()
+	cfg.CORS.AllowedOrigins = []string{"https://app.example.com", "https://*.preview.example.com"}
+	noCredentials := false
+	cfg.CORS.Routes = []config.CORSRoute{
+		{Path: "/api/exports/", AllowedOrigins: []string{"*"}, AllowedMethods: []string{"GET"}, AllowCredentials: &noCredentials},
+	}
+	r := newTestRouter(cfg)
+	preflight := func(path, origin, method, headers string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("OPTIONS", path, nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", method)
+		if headers != "" {
+			req.Header.Set("Access-Control-Request-Headers", headers)
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Test case 1: Preflights from allowed origins get the policy
+	rr := preflight("/api/quiz/1/submit", "https://app.example.com", "POST", "content-type, authorization")
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	assert.Equal(t, "https://app.example.com", rr.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", rr.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Contains(t, rr.Header().Get("Access-Control-Allow-Methods"), "POST")
+	assert.Contains(t, rr.Header().Get("Access-Control-Allow-Headers"), "Authorization")
+	assert.Equal(t, "43200", rr.Header().Get("Access-Control-Max-Age"))
+	assert.Contains(t, rr.Header().Values("Vary"), "Origin")
+
+	// Test case 2: Any subdomain matches a wildcard, but not the domain itself or another scheme
+	assert.Equal(t, http.StatusNoContent, preflight("/api/quiz/", "https://pr-42.preview.example.com", "GET", "").Code)
+	assert.Equal(t, http.StatusForbidden, preflight("/api/quiz/", "https://preview.example.com", "GET", "").Code)
+	assert.Equal(t, http.StatusForbidden, preflight("/api/quiz/", "http://pr-42.preview.example.com", "GET", "").Code)
+	assert.Equal(t, http.StatusForbidden, preflight("/api/quiz/", "https://evilpreview.example.com", "GET", "").Code)
+
+	// Test case 3: Preflights for other origins, methods or headers are rejected
+	rr = preflight("/api/quiz/", "https://evil.example.net", "GET", "")
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.Empty(t, rr.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, http.StatusForbidden, preflight("/api/quiz/", "https://app.example.com", "TRACE", "").Code)
+	assert.Equal(t, http.StatusForbidden, preflight("/api/quiz/", "https://app.example.com", "GET", "X-Debug").Code)
+
+	// Test case 4: Actual requests expose the request ID, and vary by origin
+	req := httptest.NewRequest("GET", "/livez", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "https://app.example.com", rr.Header().Get("Access-Control-Allow-Origin"))
+	assert.Contains(t, rr.Header().Get("Access-Control-Expose-Headers"), "X-Request-ID")
+	assert.Contains(t, rr.Header().Values("Vary"), "Origin")
+
+	// Test case 5: Disallowed origins are served without CORS headers
+	req.Header.Set("Origin", "https://evil.example.net")
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Empty(t, rr.Header().Get("Access-Control-Allow-Origin"))
+
+	// Test case 6: A route override applies to the paths under it
+	rr = preflight("/api/exports/1/download", "https://evil.example.net", "GET", "")
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	assert.Equal(t, "*", rr.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, rr.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, http.StatusForbidden, preflight("/api/exports/1/download", "https://app.example.com", "DELETE", "").Code)
+}
+
+func TestRateLimit(t *testing.T) {
+	cfg := config.Default()
+	cfg.RateLimit.Auth = config.RateLimitPolicy{RequestsPerMinute: 6, Burst: 2}
+	cfg.RateLimit.Submit = config.RateLimitPolicy{RequestsPerMinute: 6, Burst: 3}
+	r := newTestRouter(cfg)
+	send := func(method, path, token, forwardedFor string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString("{}"))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Test case 1: The auth routes have their own, tighter limit per client IP,
+	// described by the RateLimit headers
+	rr := send("POST", "/api/auth/login", "", "")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, "2", rr.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", rr.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "10", rr.Header().Get("RateLimit-Reset"))
+	assert.Equal(t, "6;w=60;burst=2", rr.Header().Get("RateLimit-Policy"))
+	send("POST", "/api/auth/login", "", "")
+	rr = send("POST", "/api/auth/login", "", "")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "10", rr.Header().Get("Retry-After"))
+	assert.Equal(t, "0", rr.Header().Get("RateLimit-Remaining"))
+	assert.Contains(t, rr.Body.String(), `"code":"rate_limited"`)
+
+	// Test case 2: X-Forwarded-For isn't believed unless the proxy is trusted
+	assert.Equal(t, http.StatusTooManyRequests, send("POST", "/api/auth/register", "", "203.0.113.9").Code)
+
+	// Test case 3: Submissions are limited per user, other routes only by the main limit
+	user := testToken(t, cfg, enums.RoleMaveric)
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusBadRequest, send("POST", "/api/quiz/1/submit", user, "").Code)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, send("POST", "/api/quiz/1/submit", user, "").Code)
+	rr = send("GET", "/api/quiz/abc", user, "")
+	assert.NotEqual(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "30", rr.Header().Get("RateLimit-Limit"))
+
+	// Test case 4: Behind a trusted proxy, clients are told apart by their forwarded address
+	cfg.Server.TrustedProxies = []string{"192.0.2.0/24"}
+	r = newTestRouter(cfg)
+	for i := 0; i < 2; i++ {
+		send("POST", "/api/auth/login", "", "203.0.113.1")
+	}
+	assert.Equal(t, http.StatusTooManyRequests, send("POST", "/api/auth/login", "", "203.0.113.1").Code)
+	assert.Equal(t, http.StatusBadRequest, send("POST", "/api/auth/login", "", "203.0.113.2").Code)
+
+	// Test case 5: No limits or headers when rate limiting is off
+	cfg.RateLimit.Enabled = false
+	r = newTestRouter(cfg)
+	for i := 0; i < 5; i++ {
+		rr = send("POST", "/api/auth/login", "", "")
+	}
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Empty(t, rr.Header().Get("RateLimit-Limit"))
+}
+
+func TestErrors(t *testing.T) {
+	r, cfg := setupRouterTest()
+	user := testToken(t, cfg, enums.RoleMaveric)
+	send := func(method, path, token, accept, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Request-ID", "req-1")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Test case 1: Errors have a message, a code and the request ID
+	rr := send("GET", "/api/users/me", "", "", "")
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.JSONEq(t, `{"error": "Authorization header is required", "code": "missing_token", "request_id": "req-1"}`, rr.Body.String())
+
+	// Test case 2: Invalid bodies list every invalid field
+	rr = send("POST", "/api/auth/login", "", "", `{"email": "ada"}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.JSONEq(t, `{
+		"error": "Request body has invalid fields",
+		"code": "validation_failed",
+		"details": [
+			{"field": "email", "code": "email", "message": "must be a valid email address"},
+			{"field": "password", "code": "required", "message": "is required"}
+		],
+		"request_id": "req-1"
+	}`, rr.Body.String())
+
+	// Test case 3: Clients that accept problem details get RFC 7807 documents
+	rr = send("GET", "/api/quiz/42", user, "application/problem+json, application/json", "")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, "application/problem+json", rr.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{
+		"type": "about:blank",
+		"title": "Not Found",
+		"status": 404,
+		"detail": "Quiz not found",
+		"instance": "/api/quiz/42",
+		"code": "quiz_not_found",
+		"request_id": "req-1"
+	}`, rr.Body.String())
+
+	// Test case 4: Unknown paths get the same body
+	rr = send("GET", "/api/nope", "", "", "")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"code":"route_not_found"`)
+
+	// Test case 5: Unexpected failures are a 500 that doesn't reveal their cause
+	r = newTestRouter(cfg, func(svc *Services) {
+		svc.DBStats = func() ([]database.PoolStats, error) { return nil, errors.New("pool stats: connection refused") }
+	})
+	rr = send("GET", "/api/admin/db/stats", testToken(t, cfg, enums.RoleSuperAdmin), "", "")
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"code":"internal_error"`)
+	assert.NotContains(t, rr.Body.String(), "connection refused")
+}
+
+func TestOpenAPI(t *testing.T) {
+	_, cfg := setupRouterTest()
+	cfg.Storage.Driver, cfg.Storage.PublicURL = "local", ""
+	r := newTestRouter(cfg)
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("GET", "/openapi.json", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var doc openapi.Document
+	if err := json.Unmarshal(rr.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "3.1.0", doc.OpenAPI)
+
+	// Test case 1: Every registered route is documented, and nothing else
+	operations := 0
+	for _, route := range r.Routes() {
+		assert.NotNil(t, doc.Operation(route.Method, route.Path), "%s %s is missing from the OpenAPI document", route.Method, route.Path)
+		operations++
+	}
+	documented := 0
+	for _, item := range doc.Paths {
+		documented += len(*item)
+	}
+	assert.Equal(t, operations, documented, "the OpenAPI document has routes the router doesn't")
+
+	// Test case 2: References resolve, and protected routes require a token
+	for _, ref := range regexp.MustCompile(`"\$ref":"#/components/(\w+)/(\w+)"`).FindAllStringSubmatch(rr.Body.String(), -1) {
+		switch ref[1] {
+		case "schemas":
+			assert.Contains(t, doc.Components.Schemas, ref[2])
+		case "responses":
+			assert.Contains(t, doc.Components.Responses, ref[2])
+		}
+	}
+	submit := doc.Operation("POST", "/api/quiz/:id/submit")
+	if assert.NotNil(t, submit) {
+		assert.Equal(t, []map[string][]string{{"bearerAuth": {}}}, submit.Security)
+		assert.Equal(t, "#/components/schemas/SubmitQuizRequest", submit.RequestBody.Content["application/json"].Schema.Ref)
+		assert.Equal(t, "#/components/responses/Unauthorized", submit.Responses["401"].Ref)
+	}
+	assert.Empty(t, doc.Operation("POST", "/api/auth/login").Security)
+
+	// Test case 3: Swagger UI loads the document
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("GET", "/docs", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `url: "/openapi.json"`)
+
+	// Test case 4: Disabled features leave their routes out
+	cfg.Features.Registration = false
+	rr = httptest.NewRecorder()
+	newTestRouter(cfg).ServeHTTP(rr, httptest.NewRequest("GET", "/openapi.json", nil))
+	assert.NotContains(t, rr.Body.String(), `"/api/auth/register"`)
+}
+
+func TestQuizAnswers(t *testing.T) {
+	cfg := config.Default()
+	cfg.JWT.Secret = "test-secret"
+	store := repository.NewMemoryStore()
+	quiz := &models.Quiz{Title: "Sums", Category: enums.CategoryMath, Difficulty: enums.DifficultyEasy, IsPublished: true,
+		Questions: []models.Question{{Text: "1 + 1?", Type: enums.QuestionTypeMultipleChoice, CorrectAnswer: "2", Explanation: "One and one make two",
+			Answers: []models.Answer{{Text: "2", IsCorrect: true}, {Text: "3"}}}}}
+	if err := store.Quizzes().Create(quiz); err != nil {
+		t.Fatal(err)
+	}
+	r := newTestRouter(cfg, func(svc *Services) { svc.Quiz = services.NewQuizService(store) })
+	get := func(token string) string {
+		req := httptest.NewRequest("GET", fmt.Sprintf("/api/quiz/%d", quiz.ID), nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		return rr.Body.String()
+	}
+
+	// Test case 1: Users get the questions without their answers
+	body := get(testToken(t, cfg, enums.RoleMaveric))
+	assert.Contains(t, body, `"text":"1 + 1?"`)
+	assert.NotContains(t, body, "correct_answer")
+	assert.NotContains(t, body, "is_correct")
+	assert.NotContains(t, body, "One and one make two")
+
+	// Test case 2: Admins get the answers
+	body = get(testToken(t, cfg, enums.RoleSuperAdmin))
+	assert.Contains(t, body, `"correct_answer":"2"`)
+	assert.Contains(t, body, `"is_correct":true`)
+}
+
+func TestInactiveUsers(t *testing.T) {
+	cfg := config.Default()
+	cfg.JWT.Secret = "test-secret"
+	store := repository.NewMemoryStore()
+	user := &models.User{Email: "test@example.com", Role: enums.RoleMaveric, IsActive: true}
+	if err := store.Users().Create(user); err != nil {
+		t.Fatal(err)
+	}
+	r := newTestRouter(cfg, func(svc *Services) { svc.Auth = services.NewAuthService(store, cfg) })
+	token := testToken(t, cfg, enums.RoleMaveric)
+	get := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/quiz/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Test case 1: Active users get in
+	assert.Equal(t, http.StatusOK, get().Code)
+
+	// Test case 2: Their tokens stop working once they're deactivated, before they expire
+	user.IsActive = false
+	if err := store.Users().Save(user); err != nil {
+		t.Fatal(err)
+	}
+	rr := get()
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"code":"account_deactivated"`)
+
+	// Test case 3: And once they're deleted
+	user.IsActive = true
+	user.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	if err := store.Users().Save(user); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, http.StatusForbidden, get().Code)
+}
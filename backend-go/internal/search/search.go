@@ -0,0 +1,371 @@
+// Package search is a small in-process full-text index. Documents are split
+// into words, which are lowercased and reduced to a stem, so "Planets" finds
+// "planet". A search finds the documents containing every word of the query,
+// ranked by TF-IDF with per-field weights, and highlights the matches in a
+// snippet. Query words the index has never seen get spelling suggestions from
+// the words it has.
+package search
+
+import (
+	"html"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Field is a piece of a document's text with its weight in the ranking
+type Field struct {
+	Text   string
+	Weight float64
+}
+
+// Hit is a document matching a query
+type Hit[T any] struct {
+	Doc     T
+	Score   float64
+	Snippet string // Text of the best matching field around the matches, HTML-escaped, with the matches in <mark>
+}
+
+// Index finds documents of type T by their text. It isn't safe for
+// concurrent use while documents are being added.
+type Index[T any] struct {
+	docs     []document[T]
+	postings map[string][]posting // Stem to the documents containing it
+	words    map[string]int       // Every word seen, lowercased, with how often
+}
+
+type document[T any] struct {
+	doc    T
+	fields []Field
+}
+
+// posting is the occurrences of a stem in one document
+type posting struct {
+	doc    int
+	weight float64 // Occurrences, each counted with the weight of its field
+}
+
+// NewIndex creates an empty index
+func NewIndex[T any]() *Index[T] {
+	return &Index[T]{postings: make(map[string][]posting), words: make(map[string]int)}
+}
+
+// Len returns the number of documents
+func (ix *Index[T]) Len() int {
+	return len(ix.docs)
+}
+
+// Add indexes a document by the text of its fields
+func (ix *Index[T]) Add(doc T, fields ...Field) {
+	id := len(ix.docs)
+	ix.docs = append(ix.docs, document[T]{doc: doc, fields: fields})
+
+	weights := make(map[string]float64)
+	for _, f := range fields {
+		for _, word := range words(f.Text) {
+			ix.words[word]++
+			if !stopWords[word] {
+				weights[stem(word)] += f.Weight
+			}
+		}
+	}
+	for s, weight := range weights {
+		ix.postings[s] = append(ix.postings[s], posting{doc: id, weight: weight})
+	}
+}
+
+// Search returns up to limit documents containing every word of the query
+// and accepted by keep (nil keeps all), best first
+func (ix *Index[T]) Search(query string, limit int, keep func(T) bool) []Hit[T] {
+	terms := queryTerms(query)
+	if len(terms) == 0 {
+		return nil
+	}
+
+	scores := make(map[int]float64)
+	for i, term := range terms {
+		postings := ix.postings[term]
+		idf := math.Log(1 + float64(len(ix.docs))/float64(len(postings)+1))
+		matched := make(map[int]float64, len(postings))
+		for _, p := range postings {
+			if i == 0 || scores[p.doc] > 0 {
+				matched[p.doc] = scores[p.doc] + p.weight*idf
+			}
+		}
+		// Documents missing this term drop out
+		scores = matched
+	}
+
+	// Best first, earlier added documents first on a tie
+	ids := make([]int, 0, len(scores))
+	for id := range scores {
+		if keep == nil || keep(ix.docs[id].doc) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if a, b := scores[ids[i]], scores[ids[j]]; a != b {
+			return a > b
+		}
+		return ids[i] < ids[j]
+	})
+	if limit > 0 && len(ids) > limit {
+		ids = ids[:limit]
+	}
+
+	hits := make([]Hit[T], len(ids))
+	for i, id := range ids {
+		hits[i] = Hit[T]{Doc: ix.docs[id].doc, Score: scores[id], Snippet: snippet(ix.docs[id].fields, terms)}
+	}
+	return hits
+}
+
+// Suggest returns up to limit rewrites of the query with its unknown words
+// replaced by similar known ones, or nil when it has no unknown words
+func (ix *Index[T]) Suggest(query string, limit int) []string {
+	words := words(query)
+	unknown := -1
+	var fixes [][]string
+	for i, word := range words {
+		if stopWords[word] || len(ix.postings[stem(word)]) > 0 {
+			fixes = append(fixes, []string{word})
+			continue
+		}
+		candidates := ix.similar(word)
+		if len(candidates) == 0 {
+			return nil
+		}
+		if unknown < 0 {
+			unknown = i
+		}
+		fixes = append(fixes, candidates)
+	}
+	if unknown < 0 {
+		return nil
+	}
+
+	// Vary the first unknown word, taking the best fix for the others
+	var suggestions []string
+	for _, candidate := range fixes[unknown] {
+		rewritten := make([]string, len(words))
+		for i := range words {
+			rewritten[i] = fixes[i][0]
+		}
+		rewritten[unknown] = candidate
+		suggestions = append(suggestions, strings.Join(rewritten, " "))
+		if len(suggestions) == limit {
+			break
+		}
+	}
+	return suggestions
+}
+
+// similar returns the known words within a small edit distance of word,
+// closest and most common first, one per stem
+func (ix *Index[T]) similar(word string) []string {
+	maxDistance := 1
+	if len([]rune(word)) > 4 {
+		maxDistance = 2
+	}
+
+	type candidate struct {
+		word     string
+		distance int
+		count    int
+	}
+	var candidates []candidate
+	for known, count := range ix.words {
+		if stopWords[known] {
+			continue
+		}
+		if d := distance(word, known, maxDistance); d <= maxDistance {
+			candidates = append(candidates, candidate{known, d, count})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.distance != b.distance {
+			return a.distance < b.distance
+		}
+		if a.count != b.count {
+			return a.count > b.count
+		}
+		return a.word < b.word
+	})
+
+	// Singular and plural find the same, so only suggest one of them
+	var similar []string
+	seen := make(map[string]bool)
+	for _, c := range candidates {
+		if s := stem(c.word); !seen[s] {
+			seen[s] = true
+			similar = append(similar, c.word)
+		}
+	}
+	return similar
+}
+
+// snippetWords is how many words a snippet shows
+const snippetWords = 20
+
+// snippet highlights the terms in the field of a document matching most of them
+func snippet(fields []Field, terms []string) string {
+	want := make(map[string]bool, len(terms))
+	for _, t := range terms {
+		want[t] = true
+	}
+
+	best, bestScore, bestFirst := -1, 0.0, 0
+	for i, f := range fields {
+		matches, first := 0, -1
+		for j, word := range tokens(f.Text) {
+			if want[stem(strings.ToLower(word.text))] {
+				matches++
+				if first < 0 {
+					first = j
+				}
+			}
+		}
+		if score := float64(matches) * f.Weight; score > bestScore {
+			best, bestScore, bestFirst = i, score, first
+		}
+	}
+	if best < 0 {
+		return ""
+	}
+
+	text := fields[best].Text
+	toks := tokens(text)
+	start := max(0, bestFirst-snippetWords/4)
+	end := min(len(toks), start+snippetWords)
+
+	var b strings.Builder
+	from := 0
+	if start > 0 {
+		b.WriteString("… ")
+		from = toks[start].start
+	}
+	for _, tok := range toks[start:end] {
+		b.WriteString(html.EscapeString(text[from:tok.start]))
+		if want[stem(strings.ToLower(tok.text))] {
+			b.WriteString("<mark>" + html.EscapeString(tok.text) + "</mark>")
+		} else {
+			b.WriteString(html.EscapeString(tok.text))
+		}
+		from = tok.start + len(tok.text)
+	}
+	if end < len(toks) {
+		b.WriteString(" …")
+	} else {
+		b.WriteString(html.EscapeString(text[from:]))
+	}
+	return b.String()
+}
+
+// token is a word in a text and where it starts
+type token struct {
+	text  string
+	start int
+}
+
+// tokens splits text into its words, keeping their positions
+func tokens(text string) []token {
+	var toks []token
+	start := -1
+	for i, r := range text {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		switch {
+		case isWord && start < 0:
+			start = i
+		case !isWord && start >= 0:
+			toks = append(toks, token{text[start:i], start})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		toks = append(toks, token{text[start:], start})
+	}
+	return toks
+}
+
+// words returns the lowercased words of text
+func words(text string) []string {
+	toks := tokens(text)
+	words := make([]string, len(toks))
+	for i, tok := range toks {
+		words[i] = strings.ToLower(tok.text)
+	}
+	return words
+}
+
+// queryTerms returns the stems a query searches for
+func queryTerms(query string) []string {
+	var terms []string
+	seen := make(map[string]bool)
+	for _, word := range words(query) {
+		if stopWords[word] {
+			continue
+		}
+		if s := stem(word); !seen[s] {
+			seen[s] = true
+			terms = append(terms, s)
+		}
+	}
+	return terms
+}
+
+// stem reduces an English word to a common form for its plural and singular
+func stem(word string) string {
+	switch {
+	case len(word) > 4 && strings.HasSuffix(word, "ies"):
+		return word[:len(word)-3] + "y"
+	case len(word) > 4 && (strings.HasSuffix(word, "ches") || strings.HasSuffix(word, "shes") ||
+		strings.HasSuffix(word, "sses") || strings.HasSuffix(word, "xes")):
+		return word[:len(word)-2]
+	case len(word) > 3 && strings.HasSuffix(word, "s") &&
+		!strings.HasSuffix(word, "ss") && !strings.HasSuffix(word, "us") && !strings.HasSuffix(word, "is"):
+		return word[:len(word)-1]
+	}
+	return word
+}
+
+// stopWords are too common to search for
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "by": true,
+	"for": true, "from": true, "how": true, "in": true, "is": true, "it": true, "of": true, "on": true,
+	"or": true, "the": true, "this": true, "to": true, "was": true, "what": true, "which": true,
+	"who": true, "with": true,
+}
+
+// distance returns the Levenshtein distance between a and b, or limit+1 once
+// it's known to be larger than limit
+func distance(a, b string, limit int) int {
+	ra, rb := []rune(a), []rune(b)
+	if d := len(ra) - len(rb); d > limit || -d > limit {
+		return limit + 1
+	}
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			rowMin = min(rowMin, curr[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,79 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testIndex() *Index[string] {
+	ix := NewIndex[string]()
+	ix.Add("planets", Field{Text: "The Planets", Weight: 2}, Field{Text: "Facts about the planets of our solar system", Weight: 1})
+	ix.Add("stars", Field{Text: "Stars", Weight: 2}, Field{Text: "How stars shine, and which planet is closest to one", Weight: 1})
+	ix.Add("chemistry", Field{Text: "Chemistry <basics>", Weight: 2}, Field{Text: "Atoms & molecules", Weight: 1})
+	return ix
+}
+
+func TestSearch(t *testing.T) {
+	ix := testIndex()
+	require.Equal(t, 3, ix.Len())
+
+	// Test case 1: Plurals find singulars, and matches in heavier fields rank first
+	hits := ix.Search("Planet", 0, nil)
+	require.Len(t, hits, 2)
+	assert.Equal(t, "planets", hits[0].Doc)
+	assert.Equal(t, "stars", hits[1].Doc)
+	assert.Greater(t, hits[0].Score, hits[1].Score)
+
+	// Test case 2: Every word must match
+	hits = ix.Search("planet shine", 0, nil)
+	require.Len(t, hits, 1)
+	assert.Equal(t, "stars", hits[0].Doc)
+	assert.Empty(t, ix.Search("planet atoms", 0, nil))
+
+	// Test case 3: Stop words and punctuation are ignored
+	assert.Len(t, ix.Search("the planets!", 0, nil), 2)
+	assert.Empty(t, ix.Search("the", 0, nil))
+
+	// Test case 4: Limit and filter
+	assert.Len(t, ix.Search("planet", 1, nil), 1)
+	hits = ix.Search("planet", 0, func(doc string) bool { return doc != "planets" })
+	require.Len(t, hits, 1)
+	assert.Equal(t, "stars", hits[0].Doc)
+}
+
+func TestSnippet(t *testing.T) {
+	ix := testIndex()
+
+	// Test case 1: Matches are highlighted in the best field
+	hits := ix.Search("planets", 0, nil)
+	assert.Equal(t, "The <mark>Planets</mark>", hits[0].Snippet)
+	assert.Equal(t, "How stars shine, and which <mark>planet</mark> is closest to one", hits[1].Snippet)
+
+	// Test case 2: Text is HTML-escaped
+	hits = ix.Search("basics", 0, nil)
+	require.Len(t, hits, 1)
+	assert.Equal(t, "Chemistry &lt;<mark>basics</mark>&gt;", hits[0].Snippet)
+
+	// Test case 3: Long texts are cut around the first match
+	long := NewIndex[int]()
+	long.Add(1, Field{Text: "one two three four five six seven eight nine ten eleven twelve thirteen fourteen " +
+		"fifteen sixteen seventeen eighteen nineteen twenty twentyone twentytwo twentythree target end", Weight: 1})
+	hits2 := long.Search("target", 0, nil)
+	require.Len(t, hits2, 1)
+	assert.Equal(t, "… nineteen twenty twentyone twentytwo twentythree <mark>target</mark> end", hits2[0].Snippet)
+}
+
+func TestSuggest(t *testing.T) {
+	ix := testIndex()
+
+	// Test case 1: Misspelled words are corrected
+	assert.Equal(t, []string{"planet"}, ix.Suggest("planest", 3))
+	assert.Equal(t, []string{"solar planets"}, ix.Suggest("soler planets", 3))
+	assert.Equal(t, []string{"chemistry"}, ix.Suggest("chemestry", 3))
+
+	// Test case 2: Nothing to suggest for known or hopeless words
+	assert.Nil(t, ix.Suggest("planets", 3))
+	assert.Nil(t, ix.Suggest("xylophone", 3))
+}
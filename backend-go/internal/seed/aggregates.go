@@ -0,0 +1,242 @@
+package seed
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"aicg/internal/models"
+	"aicg/internal/models/enums"
+
+	"gorm.io/gorm"
+)
+
+// resultRow is a result together with the category and difficulty of its quiz
+type resultRow struct {
+	UserID     uint
+	Score      float64
+	TimeTaken  int
+	CreatedAt  time.Time
+	Category   enums.QuizCategory
+	Difficulty enums.QuizDifficulty
+}
+
+// periodStart returns when a ranking period begins; zero means all time
+func periodStart(period enums.RankingPeriod, now time.Time) time.Time {
+	switch period {
+	case enums.RankingPeriodWeekly:
+		return now.AddDate(0, 0, -7)
+	case enums.RankingPeriodMonthly:
+		return now.AddDate(0, -1, 0)
+	default:
+		return time.Time{}
+	}
+}
+
+// RefreshAggregates recomputes benchmarks and the global and per-category
+// rankings of every period from the stored results
+func RefreshAggregates(tx *gorm.DB, now time.Time) error {
+	var rows []resultRow
+	err := tx.Table("results").
+		Select("results.user_id, results.score, results.time_taken, results.created_at, quizzes.category, quizzes.difficulty").
+		Joins("JOIN quizzes ON quizzes.id = results.quiz_id").
+		Where("results.deleted_at IS NULL").
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	// Aggregates are derived data, so they are replaced rather than merged;
+	// this also drops users who have no results in a period any more
+	if err := replace(tx, &models.Benchmark{}, "1 = 1", buildBenchmarks(rows)); err != nil {
+		return err
+	}
+	for _, period := range enums.ValidRankingPeriods() {
+		since := periodStart(period, now)
+		if err := replace(tx, &models.GlobalRanking{}, "ranking_period = ?", buildGlobalRankings(rows, period, since), period); err != nil {
+			return err
+		}
+		if err := replace(tx, &models.CategoryRanking{}, "ranking_period = ?", buildCategoryRankings(rows, period, since), period); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replace deletes the rows of model matching the query and inserts records instead
+func replace[T any](tx *gorm.DB, model interface{}, query string, records []T, args ...interface{}) error {
+	if err := tx.Unscoped().Where(query, args...).Delete(model).Error; err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	return tx.CreateInBatches(records, 100).Error
+}
+
+// buildBenchmarks computes score statistics per category and difficulty
+func buildBenchmarks(rows []resultRow) []models.Benchmark {
+	type key struct {
+		category   enums.QuizCategory
+		difficulty enums.QuizDifficulty
+	}
+	scores := make(map[key][]float64)
+	times := make(map[key]int)
+	for _, row := range rows {
+		k := key{row.Category, row.Difficulty}
+		scores[k] = append(scores[k], row.Score)
+		times[k] += row.TimeTaken
+	}
+
+	benchmarks := make([]models.Benchmark, 0, len(scores))
+	for k, s := range scores {
+		sort.Float64s(s)
+		benchmarks = append(benchmarks, models.Benchmark{
+			Category:              k.category,
+			Difficulty:            k.difficulty,
+			AverageScore:          round2(mean(s)),
+			MedianScore:           round2(percentile(s, 50)),
+			Percentile75:          round2(percentile(s, 75)),
+			Percentile90:          round2(percentile(s, 90)),
+			TotalAttempts:         len(s),
+			AverageCompletionTime: times[k] / len(s),
+		})
+	}
+	sort.Slice(benchmarks, func(i, j int) bool {
+		if benchmarks[i].Category != benchmarks[j].Category {
+			return benchmarks[i].Category < benchmarks[j].Category
+		}
+		return benchmarks[i].Difficulty < benchmarks[j].Difficulty
+	})
+	return benchmarks
+}
+
+// standing is one user's totals within a period, and optionally a category
+type standing struct {
+	userID    uint
+	total     float64
+	count     int
+	timeSpent int
+	rank      int
+	pct       float64
+}
+
+// rank orders standings by total score and assigns ranks (ties share a rank)
+// and the percentage of users ranked below
+func rank(standings []*standing) {
+	sort.Slice(standings, func(i, j int) bool {
+		if standings[i].total != standings[j].total {
+			return standings[i].total > standings[j].total
+		}
+		return standings[i].userID < standings[j].userID
+	})
+	n := len(standings)
+	for i, s := range standings {
+		s.rank = i + 1
+		if i > 0 && s.total == standings[i-1].total {
+			s.rank = standings[i-1].rank
+		}
+		s.pct = 100
+		if n > 1 {
+			s.pct = round2(float64(n-s.rank) / float64(n-1) * 100)
+		}
+	}
+}
+
+// buildGlobalRankings ranks users by their total score since the given time
+func buildGlobalRankings(rows []resultRow, period enums.RankingPeriod, since time.Time) []models.GlobalRanking {
+	byUser := make(map[uint]*standing)
+	var standings []*standing
+	for _, row := range rows {
+		if row.CreatedAt.Before(since) {
+			continue
+		}
+		s, ok := byUser[row.UserID]
+		if !ok {
+			s = &standing{userID: row.UserID}
+			byUser[row.UserID] = s
+			standings = append(standings, s)
+		}
+		s.total += row.Score
+		s.count++
+		s.timeSpent += row.TimeTaken
+	}
+	rank(standings)
+
+	rankings := make([]models.GlobalRanking, 0, len(standings))
+	for _, s := range standings {
+		rankings = append(rankings, models.GlobalRanking{
+			UserID:           s.userID,
+			TotalScore:       round2(s.total),
+			QuizzesCompleted: s.count,
+			AverageScore:     round2(s.total / float64(s.count)),
+			TotalTimeSpent:   s.timeSpent,
+			Rank:             s.rank,
+			Percentile:       s.pct,
+			RankingPeriod:    period,
+		})
+	}
+	return rankings
+}
+
+// buildCategoryRankings ranks users within each category by their total score since the given time
+func buildCategoryRankings(rows []resultRow, period enums.RankingPeriod, since time.Time) []models.CategoryRanking {
+	type key struct {
+		userID   uint
+		category enums.QuizCategory
+	}
+	byKey := make(map[key]*standing)
+	byCategory := make(map[enums.QuizCategory][]*standing)
+	for _, row := range rows {
+		if row.CreatedAt.Before(since) {
+			continue
+		}
+		k := key{row.UserID, row.Category}
+		s, ok := byKey[k]
+		if !ok {
+			s = &standing{userID: row.UserID}
+			byKey[k] = s
+			byCategory[row.Category] = append(byCategory[row.Category], s)
+		}
+		s.total += row.Score
+		s.count++
+	}
+
+	var rankings []models.CategoryRanking
+	for _, category := range enums.ValidCategories() {
+		standings := byCategory[category]
+		rank(standings)
+		for _, s := range standings {
+			rankings = append(rankings, models.CategoryRanking{
+				UserID:           s.userID,
+				Category:         category,
+				TotalScore:       round2(s.total),
+				QuizzesCompleted: s.count,
+				AverageScore:     round2(s.total / float64(s.count)),
+				Rank:             s.rank,
+				Percentile:       s.pct,
+				RankingPeriod:    period,
+			})
+		}
+	}
+	return rankings
+}
+
+func mean(values []float64) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// percentile interpolates the p-th percentile of sorted values
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 1 {
+		return sorted[0]
+	}
+	pos := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(pos))
+	upper := int(math.Ceil(pos))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(pos-float64(lower))
+}
@@ -0,0 +1,267 @@
+package seed
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"aicg/internal/models"
+	"aicg/internal/models/enums"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// DemoPassword is the password of every generated demo user
+const DemoPassword = "demo-password"
+
+// ErrNoQuizzes is returned when demo data is requested before any published quiz exists
+var ErrNoQuizzes = errors.New("no published quizzes to generate results for; load the fixtures first")
+
+// DemoOptions controls how much synthetic data is generated
+type DemoOptions struct {
+	Users int       // Number of demo users
+	Days  int       // How far back their quiz history goes
+	Seed  int64     // Seed for the random generator, so runs are reproducible
+	Now   time.Time // End of the generated history
+}
+
+var (
+	demoFirstNames = []string{"Ada", "Alan", "Grace", "Linus", "Margaret", "Dennis", "Barbara", "Ken", "Frances", "Edsger", "Radia", "Donald", "Hedy", "Tim", "Katherine", "John"}
+	demoLastNames  = []string{"Lovelace", "Turing", "Hopper", "Torvalds", "Hamilton", "Ritchie", "Liskov", "Thompson", "Allen", "Dijkstra", "Perlman", "Knuth", "Lamarr", "Berners-Lee", "Johnson", "McCarthy"}
+)
+
+// demoEmail is the address of the n-th demo user; it doubles as the natural key
+func demoEmail(n int) string {
+	return fmt.Sprintf("demo-user-%03d@example.com", n)
+}
+
+// SeedDemo creates synthetic users with a quiz history, then recomputes
+// progress, rankings and benchmarks. Demo users that already exist keep
+// their history, so running it again doesn't duplicate results.
+func (s *Seeder) SeedDemo(opts DemoOptions) (*Report, error) {
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+	if opts.Days <= 0 {
+		opts.Days = 90
+	}
+	rng := rand.New(rand.NewSource(opts.Seed))
+	report := newReport()
+
+	var quizzes []models.Quiz
+	if err := s.db.Preload("Questions.Answers").Where("is_published = ?", true).Order("id").Find(&quizzes).Error; err != nil {
+		return nil, err
+	}
+	if len(quizzes) == 0 {
+		return nil, ErrNoQuizzes
+	}
+
+	// All demo users share a password, so hash it once
+	hash, err := bcrypt.GenerateFromPassword([]byte(DemoPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		for n := 1; n <= opts.Users; n++ {
+			// Draw from the generator even for existing users so the
+			// history of the others doesn't depend on what already exists
+			skill := clamp(rng.NormFloat64()*15+65, 20, 98)
+			attempts := 3 + rng.Intn(13)
+			history := simulateHistory(rng, quizzes, skill, attempts, opts.Now, opts.Days)
+			user := &models.User{
+				Email:        demoEmail(n),
+				PasswordHash: string(hash),
+				FirstName:    demoFirstNames[rng.Intn(len(demoFirstNames))],
+				LastName:     demoLastNames[rng.Intn(len(demoLastNames))],
+				Role:         enums.RoleMaveric,
+				AuthProvider: enums.ProviderEmail,
+				IsActive:     true,
+				LastLoginAt:  history[len(history)-1].CreatedAt,
+			}
+
+			var count int64
+			if err := tx.Model(&models.User{}).Where("email = ?", user.Email).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				report.count("demo_users", false)
+				continue
+			}
+			if err := tx.Create(user).Error; err != nil {
+				return err
+			}
+			report.count("demo_users", true)
+
+			if err := createHistory(tx, user.ID, quizzes, history); err != nil {
+				return err
+			}
+			report.Created["results"] += len(history)
+		}
+
+		return RefreshAggregates(tx, opts.Now)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// createHistory stores a user's results and the progress they add up to
+func createHistory(tx *gorm.DB, userID uint, quizzes []models.Quiz, history []models.Result) error {
+	categories := make(map[uint]enums.QuizCategory, len(quizzes))
+	for _, quiz := range quizzes {
+		categories[quiz.ID] = quiz.Category
+	}
+
+	progress := make(map[uint]*models.UserProgress)
+	var order []uint
+	for i := range history {
+		history[i].UserID = userID
+		result := &history[i]
+		p, ok := progress[result.QuizID]
+		if !ok {
+			p = &models.UserProgress{UserID: userID, QuizID: result.QuizID, Category: categories[result.QuizID]}
+			progress[result.QuizID] = p
+			order = append(order, result.QuizID)
+		}
+		applyAttempt(p, result)
+	}
+
+	if err := tx.Create(&history).Error; err != nil {
+		return err
+	}
+	for _, quizID := range order {
+		if err := tx.Create(progress[quizID]).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// simulateHistory generates a user's attempts, oldest first, spread over the given number of days
+func simulateHistory(rng *rand.Rand, quizzes []models.Quiz, skill float64, attempts int, now time.Time, days int) []models.Result {
+	start := now.Add(-time.Duration(days) * 24 * time.Hour)
+	times := make([]time.Time, attempts)
+	for i := range times {
+		times[i] = start.Add(time.Duration(rng.Int63n(int64(now.Sub(start)))))
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+
+	history := make([]models.Result, attempts)
+	for i := range history {
+		quiz := &quizzes[rng.Intn(len(quizzes))]
+		// Users get a little better with practice
+		history[i] = simulateAttempt(rng, quiz, clamp(skill+float64(i), 0, 100), times[i])
+	}
+	return history
+}
+
+// simulateAttempt answers every question of the quiz, getting each right with
+// a probability that depends on the user's skill and the quiz difficulty
+func simulateAttempt(rng *rand.Rand, quiz *models.Quiz, skill float64, at time.Time) models.Result {
+	chance := skill / 100
+	switch quiz.Difficulty {
+	case enums.DifficultyEasy:
+		chance += 0.1
+	case enums.DifficultyHard:
+		chance -= 0.15
+	}
+
+	type submittedAnswer struct {
+		QuestionID uint   `json:"question_id"`
+		Answer     string `json:"answer"`
+	}
+	answers := make([]submittedAnswer, 0, len(quiz.Questions))
+	correct, timeTaken := 0, 0
+	for _, q := range quiz.Questions {
+		answer := q.CorrectAnswer
+		if rng.Float64() < chance {
+			correct++
+		} else {
+			answer = wrongAnswer(rng, &q)
+		}
+		answers = append(answers, submittedAnswer{QuestionID: q.ID, Answer: answer})
+
+		limit := q.TimeToAnswer
+		if limit <= 0 {
+			limit = 30
+		}
+		timeTaken += limit/4 + rng.Intn(limit*3/4+1)
+	}
+
+	score := 0.0
+	if len(quiz.Questions) > 0 {
+		score = math.Round(float64(correct)/float64(len(quiz.Questions))*10000) / 100
+	}
+	answersJSON, _ := json.Marshal(answers)
+
+	result := models.Result{
+		QuizID:         quiz.ID,
+		Score:          score,
+		TotalQuestions: len(quiz.Questions),
+		CorrectAnswers: correct,
+		TimeTaken:      timeTaken,
+		Answers:        answersJSON,
+		IsPassed:       score >= quiz.PassingScore,
+		PassingScore:   quiz.PassingScore,
+	}
+	result.CreatedAt = at
+	result.UpdatedAt = at
+	return result
+}
+
+// wrongAnswer picks an incorrect answer for the question
+func wrongAnswer(rng *rand.Rand, q *models.Question) string {
+	var wrong []string
+	for _, a := range q.Answers {
+		if a.Text != q.CorrectAnswer {
+			wrong = append(wrong, a.Text)
+		}
+	}
+	if len(wrong) == 0 {
+		return "I don't know"
+	}
+	return wrong[rng.Intn(len(wrong))]
+}
+
+// applyAttempt folds a result into the user's progress on that quiz
+func applyAttempt(p *models.UserProgress, result *models.Result) {
+	p.TotalAttempts++
+	p.TotalTimeSpent += result.TimeTaken
+	p.AverageScore = round2((p.AverageScore*float64(p.TotalAttempts-1) + result.Score) / float64(p.TotalAttempts))
+	if result.Score > p.BestScore {
+		p.BestScore = result.Score
+	}
+	p.LastAttemptedAt = result.CreatedAt
+	p.MasteryLevel = masteryLevel(p.AverageScore)
+}
+
+// masteryLevel maps an average score to a level from 1 to 5, like quiz submissions do
+func masteryLevel(score float64) int {
+	switch {
+	case score >= 90:
+		return 5
+	case score >= 80:
+		return 4
+	case score >= 70:
+		return 3
+	case score >= 60:
+		return 2
+	default:
+		return 1
+	}
+}
+
+func clamp(v, min, max float64) float64 {
+	return math.Max(min, math.Min(max, v))
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
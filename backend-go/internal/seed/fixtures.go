@@ -0,0 +1,222 @@
+package seed
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"aicg/internal/models/enums"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Fixtures is the starter data loaded by the seed command. Each fixture file
+// may contain any of the sections; files are merged in name order.
+type Fixtures struct {
+	Admin        *AdminFixture        `yaml:"admin" json:"admin"`
+	SSOConfigs   []SSOConfigFixture   `yaml:"sso_configs" json:"sso_configs"`
+	Achievements []AchievementFixture `yaml:"achievements" json:"achievements"`
+	Quizzes      []QuizFixture        `yaml:"quizzes" json:"quizzes"`
+}
+
+// AdminFixture describes the super admin. The password never lives in a
+// fixture file; it comes from the environment or is generated.
+type AdminFixture struct {
+	Email     string `yaml:"email" json:"email"`
+	FirstName string `yaml:"first_name" json:"first_name"`
+	LastName  string `yaml:"last_name" json:"last_name"`
+}
+
+// SSOConfigFixture is a placeholder SSO provider configuration
+type SSOConfigFixture struct {
+	Provider     enums.AuthProvider `yaml:"provider" json:"provider"`
+	ClientID     string             `yaml:"client_id" json:"client_id"`
+	ClientSecret string             `yaml:"client_secret" json:"client_secret"`
+	RedirectURL  string             `yaml:"redirect_url" json:"redirect_url"`
+	Scopes       string             `yaml:"scopes" json:"scopes"`
+	IsEnabled    bool               `yaml:"is_enabled" json:"is_enabled"`
+}
+
+// AchievementFixture is an achievement users can earn
+type AchievementFixture struct {
+	Name        string                 `yaml:"name" json:"name"`
+	Description string                 `yaml:"description" json:"description"`
+	Category    enums.QuizCategory     `yaml:"category" json:"category"` // Empty for achievements across categories
+	Criteria    map[string]interface{} `yaml:"criteria" json:"criteria"`
+	IconURL     string                 `yaml:"icon_url" json:"icon_url"`
+}
+
+// QuizFixture is a starter quiz with its questions
+type QuizFixture struct {
+	Title        string               `yaml:"title" json:"title"`
+	Description  string               `yaml:"description" json:"description"`
+	Category     enums.QuizCategory   `yaml:"category" json:"category"`
+	Difficulty   enums.QuizDifficulty `yaml:"difficulty" json:"difficulty"`
+	TimeLimit    int                  `yaml:"time_limit" json:"time_limit"` // in minutes
+	PassingScore float64              `yaml:"passing_score" json:"passing_score"`
+	IsPublished  bool                 `yaml:"is_published" json:"is_published"`
+	Questions    []QuestionFixture    `yaml:"questions" json:"questions"`
+}
+
+// QuestionFixture is a quiz question. Answers are listed in display order
+// and the one equal to CorrectAnswer is marked correct.
+type QuestionFixture struct {
+	Text          string             `yaml:"text" json:"text"`
+	Type          enums.QuestionType `yaml:"type" json:"type"`
+	Answers       []string           `yaml:"answers" json:"answers"`
+	CorrectAnswer string             `yaml:"correct_answer" json:"correct_answer"`
+	Points        int                `yaml:"points" json:"points"`
+	Explanation   string             `yaml:"explanation" json:"explanation"`
+	TimeToAnswer  int                `yaml:"time_to_answer" json:"time_to_answer"` // in seconds
+}
+
+// LoadFixtures reads every .yaml, .yml and .json file under fsys
+func LoadFixtures(fsys fs.FS) (*Fixtures, error) {
+	var paths []string
+	err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml", ".json":
+			if !d.IsDir() {
+				paths = append(paths, path)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	all := &Fixtures{}
+	for _, path := range paths {
+		data, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return nil, err
+		}
+		f, err := parseFixtures(path, data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if err := f.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if err := all.merge(f); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return all, nil
+}
+
+// parseFixtures decodes a single fixture file, rejecting unknown fields so typos don't go unnoticed
+func parseFixtures(path string, data []byte) (*Fixtures, error) {
+	f := &Fixtures{}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(f); err != nil {
+			return nil, err
+		}
+		return f, nil
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(f); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return f, nil
+}
+
+// merge adds the fixtures of another file
+func (f *Fixtures) merge(other *Fixtures) error {
+	if other.Admin != nil {
+		if f.Admin != nil {
+			return fmt.Errorf("admin is defined more than once")
+		}
+		f.Admin = other.Admin
+	}
+	f.SSOConfigs = append(f.SSOConfigs, other.SSOConfigs...)
+	f.Achievements = append(f.Achievements, other.Achievements...)
+	f.Quizzes = append(f.Quizzes, other.Quizzes...)
+	return nil
+}
+
+// Validate checks the fixtures against the same rules the API enforces
+func (f *Fixtures) Validate() error {
+	if f.Admin != nil && !strings.Contains(f.Admin.Email, "@") {
+		return fmt.Errorf("admin: invalid email %q", f.Admin.Email)
+	}
+
+	for _, sso := range f.SSOConfigs {
+		if !sso.Provider.IsSSO() {
+			return fmt.Errorf("sso_configs: unsupported provider %q", sso.Provider)
+		}
+	}
+
+	for _, a := range f.Achievements {
+		if a.Name == "" || a.Description == "" {
+			return fmt.Errorf("achievements: name and description are required")
+		}
+		if a.Category != "" && !a.Category.IsValid() {
+			return fmt.Errorf("achievement %q: invalid category %q", a.Name, a.Category)
+		}
+		if len(a.Criteria) == 0 {
+			return fmt.Errorf("achievement %q: criteria are required", a.Name)
+		}
+	}
+
+	for _, q := range f.Quizzes {
+		if err := q.validate(); err != nil {
+			return fmt.Errorf("quiz %q: %w", q.Title, err)
+		}
+	}
+	return nil
+}
+
+func (q *QuizFixture) validate() error {
+	switch {
+	case q.Title == "" || q.Description == "":
+		return fmt.Errorf("title and description are required")
+	case !q.Category.IsValid():
+		return fmt.Errorf("invalid category %q", q.Category)
+	case !q.Difficulty.IsValid():
+		return fmt.Errorf("invalid difficulty %q", q.Difficulty)
+	case q.TimeLimit < 1:
+		return fmt.Errorf("time_limit must be at least 1 minute")
+	case q.PassingScore < 0 || q.PassingScore > 100:
+		return fmt.Errorf("passing_score must be between 0 and 100")
+	case len(q.Questions) == 0:
+		return fmt.Errorf("at least one question is required")
+	}
+
+	for i, question := range q.Questions {
+		if question.Text == "" || question.CorrectAnswer == "" {
+			return fmt.Errorf("question %d: text and correct_answer are required", i+1)
+		}
+		if !question.Type.IsValid() {
+			return fmt.Errorf("question %d: invalid type %q", i+1, question.Type)
+		}
+		if question.Type == enums.QuestionTypeMultipleChoice || question.Type == enums.QuestionTypeTrueFalse {
+			if len(question.Answers) < 2 {
+				return fmt.Errorf("question %d: at least two answers are required", i+1)
+			}
+			found := false
+			for _, answer := range question.Answers {
+				found = found || answer == question.CorrectAnswer
+			}
+			if !found {
+				return fmt.Errorf("question %d: correct_answer %q is not one of the answers", i+1, question.CorrectAnswer)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,269 @@
+package seed
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"aicg/internal/models"
+	"aicg/internal/models/enums"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// Report counts what a seed run created and what already existed
+type Report struct {
+	Created map[string]int
+	Skipped map[string]int
+
+	// GeneratedAdminPassword is set when the admin was created without a
+	// configured password; it's the only time the password is shown.
+	GeneratedAdminPassword string
+}
+
+func newReport() *Report {
+	return &Report{Created: map[string]int{}, Skipped: map[string]int{}}
+}
+
+func (r *Report) count(kind string, created bool) {
+	if created {
+		r.Created[kind]++
+	} else {
+		r.Skipped[kind]++
+	}
+}
+
+// String summarizes the report, e.g. "quizzes: 6 created, 0 existing"
+func (r *Report) String() string {
+	kinds := make(map[string]bool)
+	for kind := range r.Created {
+		kinds[kind] = true
+	}
+	for kind := range r.Skipped {
+		kinds[kind] = true
+	}
+	sorted := make([]string, 0, len(kinds))
+	for kind := range kinds {
+		sorted = append(sorted, kind)
+	}
+	sort.Strings(sorted)
+
+	lines := make([]string, 0, len(sorted))
+	for _, kind := range sorted {
+		lines = append(lines, fmt.Sprintf("%s: %d created, %d existing", kind, r.Created[kind], r.Skipped[kind]))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Seeder loads fixtures into the database. Every record is looked up by its
+// natural key first, so running it again only adds what is missing.
+type Seeder struct {
+	db *gorm.DB
+}
+
+func NewSeeder(db *gorm.DB) *Seeder {
+	return &Seeder{db: db}
+}
+
+// Seed loads the fixtures. adminPassword is used if the admin has to be
+// created; when empty a random one is generated and returned in the report.
+func (s *Seeder) Seed(f *Fixtures, adminPassword string) (*Report, error) {
+	report := newReport()
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var adminID uint
+		if f.Admin != nil {
+			id, err := seedAdmin(tx, f.Admin, adminPassword, report)
+			if err != nil {
+				return fmt.Errorf("admin: %w", err)
+			}
+			adminID = id
+		}
+
+		for _, sso := range f.SSOConfigs {
+			if err := seedSSOConfig(tx, sso, report); err != nil {
+				return fmt.Errorf("sso config %s: %w", sso.Provider, err)
+			}
+		}
+
+		for _, achievement := range f.Achievements {
+			if err := seedAchievement(tx, achievement, report); err != nil {
+				return fmt.Errorf("achievement %q: %w", achievement.Name, err)
+			}
+		}
+
+		if len(f.Quizzes) > 0 && adminID == 0 {
+			return errors.New("quizzes need an admin to be created by")
+		}
+		for _, quiz := range f.Quizzes {
+			if err := seedQuiz(tx, quiz, adminID, report); err != nil {
+				return fmt.Errorf("quiz %q: %w", quiz.Title, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// seedAdmin creates the super admin unless a user with that email exists
+func seedAdmin(tx *gorm.DB, f *AdminFixture, password string, report *Report) (uint, error) {
+	var existing models.User
+	err := tx.Where("email = ?", f.Email).First(&existing).Error
+	if err == nil {
+		report.count("admin", false)
+		return existing.ID, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, err
+	}
+
+	if password == "" {
+		password, err = randomPassword()
+		if err != nil {
+			return 0, err
+		}
+		report.GeneratedAdminPassword = password
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return 0, err
+	}
+
+	admin := &models.User{
+		Email:        f.Email,
+		PasswordHash: string(hash),
+		FirstName:    f.FirstName,
+		LastName:     f.LastName,
+		Role:         enums.RoleSuperAdmin,
+		AuthProvider: enums.ProviderEmail,
+		IsActive:     true,
+	}
+	if err := tx.Create(admin).Error; err != nil {
+		return 0, err
+	}
+	report.count("admin", true)
+	return admin.ID, nil
+}
+
+// seedSSOConfig creates the provider configuration unless one exists
+func seedSSOConfig(tx *gorm.DB, f SSOConfigFixture, report *Report) error {
+	created, err := createIfMissing(tx, &models.SSOConfig{}, "provider = ?", f.Provider, func() interface{} {
+		return &models.SSOConfig{
+			Provider:     f.Provider,
+			ClientID:     f.ClientID,
+			ClientSecret: f.ClientSecret,
+			RedirectURL:  f.RedirectURL,
+			Scopes:       f.Scopes,
+			IsEnabled:    f.IsEnabled,
+		}
+	})
+	if err != nil {
+		return err
+	}
+	report.count("sso_configs", created)
+	return nil
+}
+
+// seedAchievement creates the achievement unless one with that name exists
+func seedAchievement(tx *gorm.DB, f AchievementFixture, report *Report) error {
+	criteria, err := json.Marshal(f.Criteria)
+	if err != nil {
+		return err
+	}
+	created, err := createIfMissing(tx, &models.Achievement{}, "name = ?", f.Name, func() interface{} {
+		return &models.Achievement{
+			Name:        f.Name,
+			Description: f.Description,
+			Category:    f.Category,
+			Criteria:    criteria,
+			IconURL:     f.IconURL,
+		}
+	})
+	if err != nil {
+		return err
+	}
+	report.count("achievements", created)
+	return nil
+}
+
+// seedQuiz creates the quiz with its questions unless one with that title exists
+func seedQuiz(tx *gorm.DB, f QuizFixture, createdBy uint, report *Report) error {
+	created, err := createIfMissing(tx, &models.Quiz{}, "title = ?", f.Title, func() interface{} {
+		return f.toModel(createdBy)
+	})
+	if err != nil {
+		return err
+	}
+	report.count("quizzes", created)
+	return nil
+}
+
+// createIfMissing creates the record built by build unless a row of model matches the query
+func createIfMissing(tx *gorm.DB, model interface{}, query string, arg interface{}, build func() interface{}) (bool, error) {
+	var count int64
+	if err := tx.Model(model).Where(query, arg).Count(&count).Error; err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return false, nil
+	}
+	if err := tx.Create(build()).Error; err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// toModel converts the fixture into a quiz with questions and answers
+func (f *QuizFixture) toModel(createdBy uint) *models.Quiz {
+	quiz := &models.Quiz{
+		Title:        f.Title,
+		Description:  f.Description,
+		Category:     f.Category,
+		Difficulty:   f.Difficulty,
+		TimeLimit:    f.TimeLimit,
+		CreatedBy:    createdBy,
+		IsPublished:  f.IsPublished,
+		PassingScore: f.PassingScore,
+	}
+	for _, q := range f.Questions {
+		question := models.Question{
+			Text:          q.Text,
+			Type:          q.Type,
+			CorrectAnswer: q.CorrectAnswer,
+			Points:        q.Points,
+			Explanation:   q.Explanation,
+			TimeToAnswer:  q.TimeToAnswer,
+		}
+		if question.Points == 0 {
+			question.Points = 1
+		}
+		if question.TimeToAnswer == 0 {
+			question.TimeToAnswer = 30
+		}
+		for i, text := range q.Answers {
+			question.Answers = append(question.Answers, models.Answer{
+				Text:      text,
+				IsCorrect: text == q.CorrectAnswer,
+				Order:     i,
+			})
+		}
+		quiz.Questions = append(quiz.Questions, question)
+	}
+	return quiz
+}
+
+// randomPassword generates a password for an admin created without one
+func randomPassword() (string, error) {
+	b := make([]byte, 18)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
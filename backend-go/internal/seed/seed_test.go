@@ -0,0 +1,292 @@
+package seed
+
+import (
+	"encoding/json"
+	"math/rand"
+	"os"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"aicg/internal/models"
+	"aicg/internal/models/enums"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+const quizYAML = `
+quizzes:
+  - title: Capitals
+    description: Capital cities
+    category: general
+    difficulty: easy
+    time_limit: 5
+    passing_score: 50
+    is_published: true
+    questions:
+      - text: Capital of France?
+        type: multiple_choice
+        answers: [Paris, Lyon]
+        correct_answer: Paris
+`
+
+func TestLoadFixtures(t *testing.T) {
+	// Test case 1: YAML and JSON files are merged in name order
+	f, err := LoadFixtures(fstest.MapFS{
+		"b/quizzes.yaml": {Data: []byte(quizYAML)},
+		"a.json":         {Data: []byte(`{"admin": {"email": "admin@example.com", "first_name": "Ada"}}`)},
+		"empty.yml":      {Data: []byte("")},
+		"README.md":      {Data: []byte("not a fixture")},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, f.Admin)
+	assert.Equal(t, "admin@example.com", f.Admin.Email)
+	require.Len(t, f.Quizzes, 1)
+	assert.Equal(t, enums.CategoryGeneral, f.Quizzes[0].Category)
+	assert.Equal(t, []string{"Paris", "Lyon"}, f.Quizzes[0].Questions[0].Answers)
+
+	// Test case 2: Unknown fields are rejected
+	_, err = LoadFixtures(fstest.MapFS{
+		"quizzes.yaml": {Data: []byte("quizzes:\n  - titel: Typo\n")},
+	})
+	assert.Error(t, err)
+	_, err = LoadFixtures(fstest.MapFS{
+		"admin.json": {Data: []byte(`{"admin": {"mail": "admin@example.com"}}`)},
+	})
+	assert.Error(t, err)
+
+	// Test case 3: The admin may only be defined once
+	_, err = LoadFixtures(fstest.MapFS{
+		"a.yaml": {Data: []byte("admin:\n  email: a@example.com\n")},
+		"b.yaml": {Data: []byte("admin:\n  email: b@example.com\n")},
+	})
+	assert.ErrorContains(t, err, "more than once")
+
+	// Test case 4: Invalid fixtures are reported with their file
+	_, err = LoadFixtures(fstest.MapFS{
+		"sso.yaml": {Data: []byte("sso_configs:\n  - provider: email\n")},
+	})
+	assert.ErrorContains(t, err, "sso.yaml")
+}
+
+func TestLoadShippedFixtures(t *testing.T) {
+	f, err := LoadFixtures(os.DirFS("../../fixtures"))
+	require.NoError(t, err)
+
+	require.NotNil(t, f.Admin)
+	assert.Len(t, f.SSOConfigs, 3)
+	assert.NotEmpty(t, f.Achievements)
+
+	// Every category has a published starter quiz
+	categories := make(map[enums.QuizCategory]bool)
+	for _, q := range f.Quizzes {
+		if q.IsPublished {
+			categories[q.Category] = true
+		}
+	}
+	for _, category := range enums.ValidCategories() {
+		assert.True(t, categories[category], "no starter quiz for %s", category)
+	}
+}
+
+func TestQuizFixtureValidate(t *testing.T) {
+	valid := func() QuizFixture {
+		return QuizFixture{
+			Title:        "Quiz",
+			Description:  "A quiz",
+			Category:     enums.CategoryMath,
+			Difficulty:   enums.DifficultyMedium,
+			TimeLimit:    5,
+			PassingScore: 60,
+			Questions: []QuestionFixture{
+				{Text: "1 + 1?", Type: enums.QuestionTypeMultipleChoice, Answers: []string{"1", "2"}, CorrectAnswer: "2"},
+				{Text: "2 + 2?", Type: enums.QuestionTypeShortAnswer, CorrectAnswer: "4"},
+			},
+		}
+	}
+
+	// Test case 1: Valid quiz
+	q := valid()
+	assert.NoError(t, q.validate())
+
+	// Test case 2: Invalid category
+	q = valid()
+	q.Category = "cooking"
+	assert.ErrorContains(t, q.validate(), "category")
+
+	// Test case 3: Correct answer missing from the choices
+	q = valid()
+	q.Questions[0].CorrectAnswer = "3"
+	assert.ErrorContains(t, q.validate(), "not one of the answers")
+
+	// Test case 4: No questions
+	q = valid()
+	q.Questions = nil
+	assert.ErrorContains(t, q.validate(), "question")
+}
+
+func TestToModel(t *testing.T) {
+	f := QuizFixture{
+		Title:    "Quiz",
+		Category: enums.CategoryScience,
+		Questions: []QuestionFixture{
+			{Text: "Symbol for gold?", Type: enums.QuestionTypeMultipleChoice, Answers: []string{"Ag", "Au"}, CorrectAnswer: "Au"},
+		},
+	}
+	quiz := f.toModel(7)
+
+	assert.Equal(t, uint(7), quiz.CreatedBy)
+	require.Len(t, quiz.Questions, 1)
+	question := quiz.Questions[0]
+	assert.Equal(t, 1, question.Points)
+	assert.Equal(t, 30, question.TimeToAnswer)
+	require.Len(t, question.Answers, 2)
+	assert.False(t, question.Answers[0].IsCorrect)
+	assert.True(t, question.Answers[1].IsCorrect)
+	assert.Equal(t, 1, question.Answers[1].Order)
+}
+
+func demoQuiz() models.Quiz {
+	quiz := models.Quiz{Difficulty: enums.DifficultyMedium, PassingScore: 50}
+	quiz.ID = 1
+	for i := 0; i < 4; i++ {
+		q := models.Question{
+			CorrectAnswer: "right",
+			TimeToAnswer:  20,
+			Answers:       []models.Answer{{Text: "right", IsCorrect: true}, {Text: "wrong"}},
+		}
+		q.ID = uint(i + 1)
+		quiz.Questions = append(quiz.Questions, q)
+	}
+	return quiz
+}
+
+func TestSimulateAttempt(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	quiz := demoQuiz()
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	for i := 0; i < 20; i++ {
+		result := simulateAttempt(rng, &quiz, 60, at)
+
+		// The stored answers agree with the score
+		var answers []struct {
+			QuestionID uint   `json:"question_id"`
+			Answer     string `json:"answer"`
+		}
+		require.NoError(t, json.Unmarshal(result.Answers, &answers))
+		require.Len(t, answers, 4)
+		correct := 0
+		for _, a := range answers {
+			if a.Answer == "right" {
+				correct++
+			}
+		}
+		assert.Equal(t, correct, result.CorrectAnswers)
+		assert.Equal(t, float64(correct)*25, result.Score)
+		assert.Equal(t, result.Score >= 50, result.IsPassed)
+		assert.True(t, result.TimeTaken >= 4*5 && result.TimeTaken <= 4*20, "time taken %d", result.TimeTaken)
+		assert.Equal(t, at, result.CreatedAt)
+	}
+
+	// The same seed gives the same history
+	a := simulateHistory(rand.New(rand.NewSource(42)), []models.Quiz{quiz}, 70, 5, at, 30)
+	b := simulateHistory(rand.New(rand.NewSource(42)), []models.Quiz{quiz}, 70, 5, at, 30)
+	assert.Equal(t, a, b)
+	for i := 1; i < len(a); i++ {
+		assert.False(t, a[i].CreatedAt.Before(a[i-1].CreatedAt), "history is not in order")
+	}
+}
+
+func TestApplyAttempt(t *testing.T) {
+	p := &models.UserProgress{}
+	first := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	applyAttempt(p, &models.Result{Score: 50, TimeTaken: 100, Model: gorm.Model{CreatedAt: first}})
+	applyAttempt(p, &models.Result{Score: 100, TimeTaken: 60, Model: gorm.Model{CreatedAt: first.Add(time.Hour)}})
+
+	assert.Equal(t, 2, p.TotalAttempts)
+	assert.Equal(t, 160, p.TotalTimeSpent)
+	assert.Equal(t, 75.0, p.AverageScore)
+	assert.Equal(t, 100.0, p.BestScore)
+	assert.Equal(t, 3, p.MasteryLevel)
+	assert.Equal(t, first.Add(time.Hour), p.LastAttemptedAt)
+}
+
+func TestRank(t *testing.T) {
+	// Test case 1: Ties share a rank
+	standings := []*standing{
+		{userID: 1, total: 50},
+		{userID: 2, total: 90},
+		{userID: 3, total: 90},
+		{userID: 4, total: 10},
+	}
+	rank(standings)
+
+	ranks := make(map[uint]int)
+	pcts := make(map[uint]float64)
+	for _, s := range standings {
+		ranks[s.userID] = s.rank
+		pcts[s.userID] = s.pct
+	}
+	assert.Equal(t, map[uint]int{2: 1, 3: 1, 1: 3, 4: 4}, ranks)
+	assert.Equal(t, 100.0, pcts[2])
+	assert.Equal(t, 33.33, pcts[1])
+	assert.Equal(t, 0.0, pcts[4])
+
+	// Test case 2: A single user is at the top
+	single := []*standing{{userID: 1, total: 5}}
+	rank(single)
+	assert.Equal(t, 1, single[0].rank)
+	assert.Equal(t, 100.0, single[0].pct)
+}
+
+func TestBuildRankings(t *testing.T) {
+	now := time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC)
+	rows := []resultRow{
+		{UserID: 1, Score: 80, TimeTaken: 60, CreatedAt: now.AddDate(0, 0, -1), Category: enums.CategoryMath, Difficulty: enums.DifficultyEasy},
+		{UserID: 1, Score: 40, TimeTaken: 90, CreatedAt: now.AddDate(0, -2, 0), Category: enums.CategoryMath, Difficulty: enums.DifficultyEasy},
+		{UserID: 2, Score: 100, TimeTaken: 30, CreatedAt: now.AddDate(0, 0, -2), Category: enums.CategoryScience, Difficulty: enums.DifficultyHard},
+	}
+
+	// Test case 1: All-time rankings include every result
+	global := buildGlobalRankings(rows, enums.RankingPeriodAllTime, periodStart(enums.RankingPeriodAllTime, now))
+	require.Len(t, global, 2)
+	assert.Equal(t, uint(1), global[0].UserID)
+	assert.Equal(t, 120.0, global[0].TotalScore)
+	assert.Equal(t, 60.0, global[0].AverageScore)
+	assert.Equal(t, 2, global[0].QuizzesCompleted)
+	assert.Equal(t, 1, global[0].Rank)
+
+	// Test case 2: Weekly rankings leave out older results
+	weekly := buildGlobalRankings(rows, enums.RankingPeriodWeekly, periodStart(enums.RankingPeriodWeekly, now))
+	require.Len(t, weekly, 2)
+	assert.Equal(t, uint(2), weekly[0].UserID)
+	assert.Equal(t, 80.0, weekly[1].TotalScore)
+
+	// Test case 3: Category rankings are ranked per category
+	categories := buildCategoryRankings(rows, enums.RankingPeriodAllTime, time.Time{})
+	require.Len(t, categories, 2)
+	for _, r := range categories {
+		assert.Equal(t, 1, r.Rank)
+		assert.Equal(t, enums.RankingPeriodAllTime, r.RankingPeriod)
+	}
+
+	// Test case 4: Benchmarks per category and difficulty
+	benchmarks := buildBenchmarks(rows)
+	require.Len(t, benchmarks, 2)
+	assert.Equal(t, enums.CategoryMath, benchmarks[0].Category)
+	assert.Equal(t, 60.0, benchmarks[0].AverageScore)
+	assert.Equal(t, 2, benchmarks[0].TotalAttempts)
+	assert.Equal(t, 75, benchmarks[0].AverageCompletionTime)
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := []float64{10, 20, 30, 40, 50}
+	assert.Equal(t, 30.0, percentile(sorted, 50))
+	assert.Equal(t, 40.0, percentile(sorted, 75))
+	assert.InDelta(t, 46.0, percentile(sorted, 90), 1e-9)
+	assert.Equal(t, 7.0, percentile([]float64{7}, 90))
+}
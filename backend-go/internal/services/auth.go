@@ -1,25 +1,30 @@
 package services
 
 import (
+	"context"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
-	"gorm.io/gorm"
 
 	"aicg/internal/config"
+	"aicg/internal/metrics"
 	"aicg/internal/models"
+	"aicg/internal/models/enums"
+	"aicg/internal/repository"
+	"aicg/internal/tracing"
 )
 
 type AuthService struct {
-	db     *gorm.DB
+	store  repository.Store
 	config *config.Config
 }
 
-func NewAuthService(db *gorm.DB, cfg *config.Config) *AuthService {
+func NewAuthService(store repository.Store, cfg *config.Config) *AuthService {
 	return &AuthService{
-		db:     db,
+		store:  store,
 		config: cfg,
 	}
 }
@@ -30,11 +35,17 @@ type TokenPair struct {
 }
 
 // Register a new user
-func (s *AuthService) Register(email, password, firstName, lastName string) (*models.User, error) {
+func (s *AuthService) Register(ctx context.Context, email, password, firstName, lastName string) (_ *models.User, err error) {
+	ctx, span := tracing.Start(ctx, "AuthService.Register")
+	defer tracing.End(span, &err)
+
+	users := s.store.WithContext(ctx).Users()
+
 	// Check if user already exists
-	var existingUser models.User
-	if err := s.db.Where("email = ?", email).First(&existingUser).Error; err == nil {
-		return nil, errors.New("user already exists")
+	if _, err := users.GetByEmail(email); err == nil {
+		return nil, ErrEmailTaken
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return nil, err
 	}
 
 	// Hash password
@@ -48,12 +59,12 @@ func (s *AuthService) Register(email, password, firstName, lastName string) (*mo
 		PasswordHash: string(hashedPassword),
 		FirstName:    firstName,
 		LastName:     lastName,
-		Role:         models.RoleMaveric,
-		AuthProvider: models.ProviderEmail,
+		Role:         enums.RoleMaveric,
+		AuthProvider: enums.ProviderEmail,
 		IsActive:     true,
 	}
 
-	if err := s.db.Create(user).Error; err != nil {
+	if err := users.Create(user); err != nil {
 		return nil, err
 	}
 
@@ -61,44 +72,55 @@ func (s *AuthService) Register(email, password, firstName, lastName string) (*mo
 }
 
 // Login with email/password
-func (s *AuthService) Login(email, password string) (*TokenPair, error) {
-	var user models.User
-	if err := s.db.Where("email = ?", email).First(&user).Error; err != nil {
-		return nil, errors.New("invalid credentials")
+func (s *AuthService) Login(ctx context.Context, email, password string) (tokens *TokenPair, err error) {
+	ctx, span := tracing.Start(ctx, "AuthService.Login")
+	defer tracing.End(span, &err)
+	defer func() { metrics.ObserveLogin(enums.ProviderEmail, err) }()
+
+	users := s.store.WithContext(ctx).Users()
+	user, err := users.GetByEmail(email)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrInvalidCredentials
+	} else if err != nil {
+		return nil, err
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
-		return nil, errors.New("invalid credentials")
+		return nil, ErrInvalidCredentials
 	}
 
 	if !user.IsActive {
-		return nil, errors.New("account is deactivated")
+		return nil, ErrAccountDeactivated
 	}
 
 	// Update last login
 	user.LastLoginAt = time.Now()
-	s.db.Save(&user)
+	users.Save(user)
 
-	return s.generateTokens(&user)
+	return s.generateTokens(users, user)
 }
 
 // Handle SSO login/registration
-func (s *AuthService) HandleSSO(provider models.AuthProvider, providerID, email, firstName, lastName string) (*TokenPair, error) {
-	var user models.User
-	err := s.db.Where("provider_id = ? AND auth_provider = ?", providerID, provider).First(&user).Error
+func (s *AuthService) HandleSSO(ctx context.Context, provider enums.AuthProvider, providerID, email, firstName, lastName string) (tokens *TokenPair, err error) {
+	ctx, span := tracing.Start(ctx, "AuthService.HandleSSO")
+	defer tracing.End(span, &err)
+	defer func() { metrics.ObserveLogin(provider, err) }()
 
-	if err == gorm.ErrRecordNotFound {
+	users := s.store.WithContext(ctx).Users()
+	user, err := users.GetByProvider(provider, providerID)
+
+	if errors.Is(err, repository.ErrNotFound) {
 		// Create new user
-		user = models.User{
+		user = &models.User{
 			Email:        email,
 			FirstName:    firstName,
 			LastName:     lastName,
-			Role:         models.RoleMaveric,
+			Role:         enums.RoleMaveric,
 			AuthProvider: provider,
 			ProviderID:   providerID,
 			IsActive:     true,
 		}
-		if err := s.db.Create(&user).Error; err != nil {
+		if err := users.Create(user); err != nil {
 			return nil, err
 		}
 	} else if err != nil {
@@ -106,27 +128,27 @@ func (s *AuthService) HandleSSO(provider models.AuthProvider, providerID, email,
 	}
 
 	if !user.IsActive {
-		return nil, errors.New("account is deactivated")
+		return nil, ErrAccountDeactivated
 	}
 
 	// Update last login
 	user.LastLoginAt = time.Now()
-	s.db.Save(&user)
+	users.Save(user)
 
-	return s.generateTokens(&user)
+	return s.generateTokens(users, user)
 }
 
 // Generate JWT tokens
-func (s *AuthService) generateTokens(user *models.User) (*TokenPair, error) {
+func (s *AuthService) generateTokens(users repository.UserRepository, user *models.User) (*TokenPair, error) {
 	// Generate access token
 	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"sub":   user.ID,
 		"email": user.Email,
 		"role":  user.Role,
-		"exp":   time.Now().Add(s.config.JWTExpiry).Unix(),
+		"exp":   time.Now().Add(s.config.JWT.Expiry).Unix(),
 	})
 
-	accessTokenString, err := accessToken.SignedString([]byte(s.config.JWTSecret))
+	accessTokenString, err := accessToken.SignedString([]byte(s.config.JWT.Secret))
 	if err != nil {
 		return nil, err
 	}
@@ -134,17 +156,17 @@ func (s *AuthService) generateTokens(user *models.User) (*TokenPair, error) {
 	// Generate refresh token
 	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"sub": user.ID,
-		"exp": time.Now().Add(time.Hour * 24 * 7).Unix(), // 7 days
+		"exp": time.Now().Add(s.config.JWT.RefreshExpiry).Unix(),
 	})
 
-	refreshTokenString, err := refreshToken.SignedString([]byte(s.config.JWTSecret))
+	refreshTokenString, err := refreshToken.SignedString([]byte(s.config.JWT.Secret))
 	if err != nil {
 		return nil, err
 	}
 
 	// Save refresh token
 	user.RefreshToken = refreshTokenString
-	s.db.Save(user)
+	users.Save(user)
 
 	return &TokenPair{
 		AccessToken:  accessTokenString,
@@ -153,20 +175,59 @@ func (s *AuthService) generateTokens(user *models.User) (*TokenPair, error) {
 }
 
 // Refresh access token
-func (s *AuthService) RefreshToken(refreshToken string) (*TokenPair, error) {
-	var user models.User
-	if err := s.db.Where("refresh_token = ?", refreshToken).First(&user).Error; err != nil {
-		return nil, errors.New("invalid refresh token")
+func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string) (_ *TokenPair, err error) {
+	ctx, span := tracing.Start(ctx, "AuthService.RefreshToken")
+	defer tracing.End(span, &err)
+
+	users := s.store.WithContext(ctx).Users()
+	user, err := users.GetByRefreshToken(refreshToken)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrInvalidRefreshToken
+	} else if err != nil {
+		return nil, err
 	}
 
-	return s.generateTokens(&user)
+	return s.generateTokens(users, user)
 }
 
-// Get SSO configuration
-func (s *AuthService) GetSSOConfig(provider models.AuthProvider) (*models.SSOConfig, error) {
-	var config models.SSOConfig
-	if err := s.db.Where("provider = ? AND is_enabled = ?", provider, true).First(&config).Error; err != nil {
-		return nil, err
+// CheckActive returns ErrAccountDeactivated unless the user exists and is
+// active, so their tokens stop working as soon as they're deactivated,
+// deleted or erased rather than when they expire
+func (s *AuthService) CheckActive(ctx context.Context, userID uint) (err error) {
+	ctx, span := tracing.Start(ctx, "AuthService.CheckActive")
+	defer tracing.End(span, &err)
+
+	user, err := s.store.WithContext(ctx).Users().GetByID(userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrAccountDeactivated
+	} else if err != nil {
+		return err
+	}
+
+	if !user.IsActive || user.DeletedAt.Valid {
+		return ErrAccountDeactivated
+	}
+	return nil
+}
+
+// Get SSO configuration. Providers set in the application config take precedence over the database.
+func (s *AuthService) GetSSOConfig(ctx context.Context, provider enums.AuthProvider) (_ *models.SSOConfig, err error) {
+	ctx, span := tracing.Start(ctx, "AuthService.GetSSOConfig")
+	defer tracing.End(span, &err)
+
+	if configured, ok := s.config.SSO.Provider(string(provider)); ok && configured.Configured() {
+		return &models.SSOConfig{
+			Provider:     provider,
+			ClientID:     configured.ClientID,
+			ClientSecret: configured.ClientSecret,
+			RedirectURL:  configured.RedirectURL,
+			Scopes:       strings.Join(configured.Scopes, ","),
+			IsEnabled:    true,
+		}, nil
+	}
+	sso, err := s.store.WithContext(ctx).SSOConfigs().GetEnabled(provider)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrSSONotConfigured
 	}
-	return &config, nil
+	return sso, err
 }
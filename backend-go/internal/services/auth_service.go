@@ -0,0 +1,32 @@
+package services
+
+import (
+	"context"
+
+	"aicg/internal/models"
+	"aicg/internal/models/enums"
+)
+
+// IAuthService defines the interface for authentication operations
+type IAuthService interface {
+	// Register creates a new email/password user
+	Register(ctx context.Context, email, password, firstName, lastName string) (*models.User, error)
+
+	// Login checks email/password credentials and issues tokens
+	Login(ctx context.Context, email, password string) (*TokenPair, error)
+
+	// HandleSSO logs in (or registers) a user coming from an SSO provider
+	HandleSSO(ctx context.Context, provider enums.AuthProvider, providerID, email, firstName, lastName string) (*TokenPair, error)
+
+	// RefreshToken exchanges a refresh token for a new token pair
+	RefreshToken(ctx context.Context, refreshToken string) (*TokenPair, error)
+
+	// GetSSOConfig retrieves the enabled configuration for an SSO provider
+	GetSSOConfig(ctx context.Context, provider enums.AuthProvider) (*models.SSOConfig, error)
+
+	// CheckActive tells whether a user may still use the tokens issued to them
+	CheckActive(ctx context.Context, userID uint) error
+}
+
+// Ensure AuthService implements IAuthService
+var _ IAuthService = (*AuthService)(nil)
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"aicg/internal/config"
+	"aicg/internal/models"
+	"aicg/internal/models/enums"
+	"aicg/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupAuthTest(t *testing.T) (*repository.MemoryStore, *AuthService) {
+	store := repository.NewMemoryStore()
+	cfg := config.Default()
+	cfg.JWT.Secret = "test-secret"
+	return store, NewAuthService(store, cfg)
+}
+
+func TestRegisterAndLogin(t *testing.T) {
+	store, service := setupAuthTest(t)
+
+	// Test case 1: Register a new user
+	user, err := service.Register(context.Background(), "ada@example.com", "secret-password", "Ada", "Lovelace")
+	require.NoError(t, err)
+	assert.NotZero(t, user.ID)
+	assert.Equal(t, enums.RoleMaveric, user.Role)
+	assert.NotEqual(t, "secret-password", user.PasswordHash)
+
+	// Test case 2: The email is taken
+	_, err = service.Register(context.Background(), "ada@example.com", "other-password", "Ada", "Byron")
+	assert.EqualError(t, err, "user already exists")
+
+	// Test case 3: Wrong password
+	_, err = service.Login(context.Background(), "ada@example.com", "wrong-password")
+	assert.EqualError(t, err, "invalid credentials")
+
+	// Test case 4: Unknown email
+	_, err = service.Login(context.Background(), "nobody@example.com", "secret-password")
+	assert.EqualError(t, err, "invalid credentials")
+
+	// Test case 5: Successful login stores the refresh token
+	tokens, err := service.Login(context.Background(), "ada@example.com", "secret-password")
+	require.NoError(t, err)
+	assert.NotEmpty(t, tokens.AccessToken)
+
+	stored, err := store.Users().GetByID(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, tokens.RefreshToken, stored.RefreshToken)
+	assert.False(t, stored.LastLoginAt.IsZero())
+
+	// Test case 6: Deactivated users can't log in
+	stored.IsActive = false
+	require.NoError(t, store.Users().Save(stored))
+	_, err = service.Login(context.Background(), "ada@example.com", "secret-password")
+	assert.EqualError(t, err, "account is deactivated")
+}
+
+func TestHandleSSO(t *testing.T) {
+	store, service := setupAuthTest(t)
+
+	// Test case 1: The first sign-in creates the user
+	_, err := service.HandleSSO(context.Background(), enums.ProviderGoogle, "g-123", "grace@example.com", "Grace", "Hopper")
+	require.NoError(t, err)
+
+	user, err := store.Users().GetByProvider(enums.ProviderGoogle, "g-123")
+	require.NoError(t, err)
+	assert.Equal(t, "grace@example.com", user.Email)
+
+	// Test case 2: Later sign-ins reuse it
+	_, err = service.HandleSSO(context.Background(), enums.ProviderGoogle, "g-123", "grace@example.com", "Grace", "Hopper")
+	require.NoError(t, err)
+
+	again, err := store.Users().GetByProvider(enums.ProviderGoogle, "g-123")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, again.ID)
+}
+
+func TestRefreshToken(t *testing.T) {
+	_, service := setupAuthTest(t)
+	_, err := service.Register(context.Background(), "ada@example.com", "secret-password", "Ada", "Lovelace")
+	require.NoError(t, err)
+	tokens, err := service.Login(context.Background(), "ada@example.com", "secret-password")
+	require.NoError(t, err)
+
+	// Test case 1: A valid refresh token
+	refreshed, err := service.RefreshToken(context.Background(), tokens.RefreshToken)
+	require.NoError(t, err)
+	assert.NotEmpty(t, refreshed.AccessToken)
+
+	// Test case 2: An unknown refresh token
+	_, err = service.RefreshToken(context.Background(), "not-a-token")
+	assert.EqualError(t, err, "invalid refresh token")
+}
+
+func TestGetSSOConfig(t *testing.T) {
+	store, service := setupAuthTest(t)
+	require.NoError(t, store.SSOConfigs().Create(&models.SSOConfig{
+		Provider: enums.ProviderGoogle, ClientID: "client", ClientSecret: "secret", RedirectURL: "http://localhost", IsEnabled: true,
+	}))
+
+	sso, err := service.GetSSOConfig(context.Background(), enums.ProviderGoogle)
+	require.NoError(t, err)
+	assert.Equal(t, "client", sso.ClientID)
+
+	_, err = service.GetSSOConfig(context.Background(), enums.ProviderFacebook)
+	assert.ErrorIs(t, err, ErrSSONotConfigured)
+
+	// Test case 2: Providers in the application config win over the database
+	service.config.SSO.Google = config.SSOProviderConfig{
+		ClientID: "configured", ClientSecret: "secret", RedirectURL: "http://localhost", Scopes: []string{"email", "profile"},
+	}
+	sso, err = service.GetSSOConfig(context.Background(), enums.ProviderGoogle)
+	require.NoError(t, err)
+	assert.Equal(t, "configured", sso.ClientID)
+	assert.Equal(t, "email,profile", sso.Scopes)
+	assert.True(t, sso.IsEnabled)
+}
@@ -0,0 +1,95 @@
+package services
+
+import "errors"
+
+// Kind is the category of a domain error. It decides how the error is
+// reported to API clients, so they can react to a category of failure
+// without knowing every error.
+type Kind int
+
+const (
+	KindInternal         Kind = iota // Not a domain error; reported as a generic failure
+	KindValidation                   // The request is malformed or breaks a rule
+	KindUnauthorized                 // The caller isn't authenticated
+	KindForbidden                    // The caller may not do this
+	KindNotFound                     // The target doesn't exist
+	KindConflict                     // The request clashes with the current state
+	KindGone                         // The target existed but has expired
+	KindTooLarge                     // The upload is too big
+	KindUnsupportedMedia             // The upload is of a type we don't take
+	KindRateLimited                  // The caller sent too many requests
+)
+
+// Error is a domain error. Code is a stable, machine-readable identifier such
+// as "quiz_not_found"; Message is meant for people and may change. Both are
+// safe to show to API clients.
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Fields  []FieldError // Which input fields are invalid, for validation errors
+}
+
+// FieldError is one problem with one input field
+type FieldError struct {
+	Field   string `json:"field"`   // JSON name, e.g. "email" or "answers[0].question_id"
+	Code    string `json:"code"`    // e.g. "required"
+	Message string `json:"message"` // e.g. "is required"
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Is matches errors with the same code, so errors.Is(err, ErrQuizNotFound)
+// holds for copies with field details too
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// NotFound returns an error for a missing record
+func NotFound(code, message string) *Error {
+	return &Error{Kind: KindNotFound, Code: code, Message: message}
+}
+
+// Conflict returns an error for a request that clashes with the current state
+func Conflict(code, message string) *Error {
+	return &Error{Kind: KindConflict, Code: code, Message: message}
+}
+
+// Invalid returns a validation error, optionally naming the offending fields
+func Invalid(code, message string, fields ...FieldError) *Error {
+	return &Error{Kind: KindValidation, Code: code, Message: message, Fields: fields}
+}
+
+// Unauthorized returns an error for a caller that isn't authenticated
+func Unauthorized(code, message string) *Error {
+	return &Error{Kind: KindUnauthorized, Code: code, Message: message}
+}
+
+// Forbidden returns an error for an action the caller may not take
+func Forbidden(code, message string) *Error {
+	return &Error{Kind: KindForbidden, Code: code, Message: message}
+}
+
+// AsError returns the domain error in err's chain, if any
+func AsError(err error) (*Error, bool) {
+	var e *Error
+	ok := errors.As(err, &e)
+	return e, ok
+}
+
+// Errors shared by several services
+var (
+	// ErrInvalidCredentials is returned when logging in with an unknown email or a wrong password
+	ErrInvalidCredentials = Unauthorized("invalid_credentials", "invalid credentials")
+	// ErrInvalidRefreshToken is returned for refresh tokens that were never issued or have been replaced
+	ErrInvalidRefreshToken = Unauthorized("invalid_refresh_token", "invalid refresh token")
+	// ErrAccountDeactivated is returned when a deactivated user tries to log in
+	ErrAccountDeactivated = Forbidden("account_deactivated", "account is deactivated")
+	// ErrEmailTaken is returned when registering with an email that already has an account
+	ErrEmailTaken = Conflict("email_taken", "user already exists")
+	// ErrSSONotConfigured is returned for SSO providers that aren't set up
+	ErrSSONotConfigured = NotFound("sso_not_configured", "SSO configuration not found")
+)
@@ -0,0 +1,747 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"aicg/internal/config"
+	"aicg/internal/metrics"
+	"aicg/internal/models"
+	"aicg/internal/storage"
+	"aicg/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+const (
+	// ExportRetention is how long a finished export can be downloaded before
+	// the sweeper, or the next attempt to open it, deletes it
+	ExportRetention = 7 * 24 * time.Hour
+	// ExportLinkTTL is how long a single download link stays valid
+	ExportLinkTTL = time.Hour
+	// exportStaleAfter is how long an export can stay pending before it's
+	// taken to be abandoned by a restart and started again. Exports take
+	// seconds, so this is only reached when the process running it is gone.
+	exportStaleAfter = 15 * time.Minute
+	// sweepBatch bounds the exports loaded at once by a sweep
+	sweepBatch = 100
+)
+
+var (
+	// ErrExportNotFound is returned when no export matches the given ID (or the caller may not see it)
+	ErrExportNotFound = NotFound("export_not_found", "export not found")
+	// ErrExportNotReady is returned when downloading an export that is still being assembled or failed
+	ErrExportNotReady = Conflict("export_not_ready", "export is not ready")
+	// ErrExportExpired is returned when downloading an export past its retention period
+	ErrExportExpired = &Error{Kind: KindGone, Code: "export_expired", Message: "export has expired"}
+	// ErrInvalidDownloadLink is returned for download links with a bad or expired signature
+	ErrInvalidDownloadLink = Forbidden("invalid_download_link", "invalid or expired download link")
+)
+
+// ExportService assembles GDPR data exports in the background
+type ExportService struct {
+	db       *gorm.DB
+	store    storage.BlobStore
+	secret   []byte
+	interval time.Duration
+	now      func() time.Time
+	wg       sync.WaitGroup // Running exports
+	sweeper  sync.WaitGroup // The sweeper, once started
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+func NewExportService(db *gorm.DB, store storage.BlobStore, cfg *config.Config) *ExportService {
+	return &ExportService{
+		db:       db,
+		store:    store,
+		secret:   []byte(cfg.Exports.SigningKey),
+		interval: cfg.Exports.SweepInterval,
+		now:      time.Now,
+		stop:     make(chan struct{}),
+	}
+}
+
+// Start sweeps the exports right away and then every sweep interval until
+// Shutdown: expired exports are deleted, and those a restart left pending
+// are started again.
+func (s *ExportService) Start() {
+	s.sweeper.Add(1)
+	go func() {
+		defer s.sweeper.Done()
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			if err := s.Sweep(context.Background()); err != nil {
+				slog.Error("Data export sweep failed", "error", err)
+			}
+			select {
+			case <-ticker.C:
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Sweep deletes the exports past their retention period and starts the
+// exports abandoned while pending again
+func (s *ExportService) Sweep(ctx context.Context) (err error) {
+	ctx, span := tracing.Start(ctx, "ExportService.Sweep")
+	defer tracing.End(span, &err)
+
+	db := s.db.WithContext(ctx)
+	for {
+		var expired []models.DataExport
+		if err := db.Where("status = ? AND expires_at <= ?", models.DataExportReady, s.now()).
+			Order("id").Limit(sweepBatch).Find(&expired).Error; err != nil {
+			return err
+		}
+		for i := range expired {
+			if err := s.expire(ctx, &expired[i]); err != nil {
+				return err
+			}
+		}
+		if len(expired) < sweepBatch {
+			break
+		}
+	}
+
+	var stale []models.DataExport
+	if err := db.Where("status = ? AND updated_at <= ?", models.DataExportPending, s.now().Add(-exportStaleAfter)).
+		Order("id").Find(&stale).Error; err != nil {
+		return err
+	}
+	for _, export := range stale {
+		// Claim the export, so only one instance starts it again
+		claim := db.Model(&models.DataExport{}).
+			Where("id = ? AND status = ? AND updated_at = ?", export.ID, models.DataExportPending, export.UpdatedAt).
+			Update("updated_at", s.now())
+		if claim.Error != nil {
+			return claim.Error
+		}
+		if claim.RowsAffected == 0 {
+			continue
+		}
+		slog.WarnContext(ctx, "Restarting abandoned data export", "export_id", export.ID)
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.runExport(context.WithoutCancel(ctx), export.ID)
+		}()
+	}
+	return nil
+}
+
+// RequestExport records a new export of userID's data and starts assembling it in the background.
+// requestedBy is the user themselves or the admin acting on their behalf.
+func (s *ExportService) RequestExport(ctx context.Context, userID, requestedBy uint) (_ *models.DataExport, err error) {
+	ctx, span := tracing.Start(ctx, "ExportService.RequestExport")
+	defer tracing.End(span, &err)
+
+	// Deleted users can still be exported by admins, so look them up unscoped
+	var user models.User
+	if err := s.db.WithContext(ctx).Unscoped().First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	export := &models.DataExport{
+		UserID:      userID,
+		RequestedBy: requestedBy,
+		Status:      models.DataExportPending,
+	}
+	if err := s.db.WithContext(ctx).Create(export).Error; err != nil {
+		return nil, err
+	}
+
+	// The export outlives the request, but keeps its request ID for the logs
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.runExport(context.WithoutCancel(ctx), export.ID)
+	}()
+
+	return export, nil
+}
+
+// Wait blocks until all exports started so far have finished
+func (s *ExportService) Wait() {
+	s.wg.Wait()
+}
+
+// Shutdown stops the sweeper and waits like Wait, but gives up when ctx is
+// done. Exports still running then are left pending, and started again by
+// the next sweep after exportStaleAfter.
+func (s *ExportService) Shutdown(ctx context.Context) (err error) {
+	ctx, span := tracing.Start(ctx, "ExportService.Shutdown")
+	defer tracing.End(span, &err)
+
+	s.stopOnce.Do(func() { close(s.stop) })
+	done := make(chan struct{})
+	go func() {
+		s.sweeper.Wait()
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// GetExport returns an export. If userID is non-zero, the export must belong to that user.
+// Exports past their retention period are marked expired and their ZIP is deleted.
+func (s *ExportService) GetExport(ctx context.Context, id, userID uint) (_ *models.DataExport, err error) {
+	ctx, span := tracing.Start(ctx, "ExportService.GetExport")
+	defer tracing.End(span, &err)
+
+	var export models.DataExport
+	query := s.db.WithContext(ctx)
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+	if err := query.First(&export, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrExportNotFound
+		}
+		return nil, err
+	}
+
+	if export.Status == models.DataExportReady && export.ExpiresAt != nil && s.now().After(*export.ExpiresAt) {
+		if err := s.expire(ctx, &export); err != nil {
+			return nil, err
+		}
+	}
+	return &export, nil
+}
+
+// DownloadLink returns a signed query string ("expires=...&signature=...") for a ready export
+func (s *ExportService) DownloadLink(export *models.DataExport) (string, time.Time, error) {
+	if export.Status != models.DataExportReady {
+		return "", time.Time{}, ErrExportNotReady
+	}
+
+	expires := s.now().Add(ExportLinkTTL)
+	if export.ExpiresAt != nil && export.ExpiresAt.Before(expires) {
+		expires = *export.ExpiresAt
+	}
+
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires.Unix(), 10))
+	query.Set("signature", s.sign(export.ID, expires.Unix()))
+	return query.Encode(), expires, nil
+}
+
+// OpenDownload verifies a download link and opens the export's ZIP. The caller must close it.
+func (s *ExportService) OpenDownload(ctx context.Context, id uint, expires, signature string) (_ *models.DataExport, _ io.ReadCloser, err error) {
+	ctx, span := tracing.Start(ctx, "ExportService.OpenDownload")
+	defer tracing.End(span, &err)
+
+	expiresUnix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || !s.verify(id, expiresUnix, signature, s.now()) {
+		return nil, nil, ErrInvalidDownloadLink
+	}
+
+	export, err := s.GetExport(ctx, id, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch export.Status {
+	case models.DataExportExpired:
+		return nil, nil, ErrExportExpired
+	case models.DataExportReady:
+	default:
+		return nil, nil, ErrExportNotReady
+	}
+
+	body, err := s.store.Get(ctx, export.BlobKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return export, body, nil
+}
+
+func (s *ExportService) sign(id uint, expires int64) string {
+	mac := hmac.New(sha256.New, s.secret)
+	fmt.Fprintf(mac, "data-export:%d:%d", id, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *ExportService) verify(id uint, expires int64, signature string, now time.Time) bool {
+	if now.Unix() > expires {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(s.sign(id, expires)))
+}
+
+func (s *ExportService) expire(ctx context.Context, export *models.DataExport) error {
+	if export.BlobKey != "" {
+		if err := s.store.Delete(ctx, export.BlobKey); err != nil {
+			return err
+		}
+	}
+	export.Status = models.DataExportExpired
+	export.BlobKey = ""
+	return s.db.WithContext(ctx).Model(export).Updates(map[string]interface{}{
+		"status":   export.Status,
+		"blob_key": "",
+	}).Error
+}
+
+// runExport gathers the user's data, stores the ZIP and marks the export ready or failed
+func (s *ExportService) runExport(ctx context.Context, id uint) {
+	start := time.Now()
+	var err error
+	defer func() { metrics.ObserveJob("data_export", time.Since(start), err) }()
+	ctx, span := tracing.Start(ctx, "ExportService.runExport", attribute.Int("export.id", int(id)))
+	defer tracing.End(span, &err)
+
+	db := s.db.WithContext(ctx)
+	var export models.DataExport
+	if err = db.First(&export, id).Error; err != nil {
+		slog.ErrorContext(ctx, "Data export failed to load", "export_id", id, "error", err)
+		return
+	}
+
+	key, size, err := s.buildAndStore(ctx, &export)
+	if err != nil {
+		slog.ErrorContext(ctx, "Data export failed", "export_id", id, "error", err)
+		db.Model(&export).Updates(map[string]interface{}{
+			"status": models.DataExportFailed,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	now := s.now()
+	expiresAt := now.Add(ExportRetention)
+	if err = db.Model(&export).Updates(map[string]interface{}{
+		"status":       models.DataExportReady,
+		"blob_key":     key,
+		"size":         size,
+		"completed_at": now,
+		"expires_at":   expiresAt,
+	}).Error; err != nil {
+		slog.ErrorContext(ctx, "Data export failed to mark ready", "export_id", id, "error", err)
+		return
+	}
+	slog.InfoContext(ctx, "Data export ready", "export_id", id, "size", size)
+}
+
+func (s *ExportService) buildAndStore(ctx context.Context, export *models.DataExport) (string, int64, error) {
+	data, err := s.collect(ctx, export.UserID)
+	if err != nil {
+		return "", 0, err
+	}
+
+	archive, err := BuildExportArchive(data)
+	if err != nil {
+		return "", 0, err
+	}
+
+	// Random component so export locations can't be guessed
+	id, err := randomID()
+	if err != nil {
+		return "", 0, err
+	}
+	key := fmt.Sprintf("%s%d-%s.zip", ExportPrefix(export.UserID), export.ID, id)
+	if err := s.store.Put(ctx, key, bytes.NewReader(archive), int64(len(archive)), "application/zip"); err != nil {
+		return "", 0, err
+	}
+	return key, int64(len(archive)), nil
+}
+
+// ExportPrefix is the blob key prefix all of a user's export ZIPs are stored under
+func ExportPrefix(userID uint) string {
+	return fmt.Sprintf("data-exports/%d/", userID)
+}
+
+// ExportData is everything we store about one user, shaped for export
+type ExportData struct {
+	Profile          []ExportProfile
+	Sessions         []ExportSession
+	Results          []ExportResult
+	ResultAnswers    []ExportResultAnswer
+	Progress         []ExportProgress
+	Achievements     []ExportAchievement
+	GlobalRankings   []ExportGlobalRanking
+	CategoryRankings []ExportCategoryRanking
+}
+
+type ExportProfile struct {
+	ID           uint       `json:"id"`
+	Email        string     `json:"email"`
+	FirstName    string     `json:"first_name"`
+	LastName     string     `json:"last_name"`
+	Role         string     `json:"role"`
+	AuthProvider string     `json:"auth_provider"`
+	ProfileImage string     `json:"profile_image"`
+	Locale       string     `json:"locale"`
+	TimeZone     string     `json:"time_zone"`
+	IsActive     bool       `json:"is_active"`
+	CreatedAt    time.Time  `json:"created_at"`
+	AnonymizedAt *time.Time `json:"anonymized_at"`
+}
+
+// ExportSession describes the user's login session. Tokens themselves are never exported.
+type ExportSession struct {
+	AuthProvider     string    `json:"auth_provider"`
+	LastLoginAt      time.Time `json:"last_login_at"`
+	HasActiveSession bool      `json:"has_active_session"`
+}
+
+type ExportResult struct {
+	ID             uint      `json:"id"`
+	QuizID         uint      `json:"quiz_id"`
+	QuizTitle      string    `json:"quiz_title"`
+	Score          float64   `json:"score"`
+	CorrectAnswers int       `json:"correct_answers"`
+	TotalQuestions int       `json:"total_questions"`
+	TimeTaken      int       `json:"time_taken"`
+	IsPassed       bool      `json:"is_passed"`
+	PassingScore   float64   `json:"passing_score"`
+	Feedback       string    `json:"feedback"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type ExportResultAnswer struct {
+	ResultID      uint   `json:"result_id"`
+	QuestionID    uint   `json:"question_id"`
+	QuestionText  string `json:"question_text"`
+	Answer        string `json:"answer"`
+	CorrectAnswer string `json:"correct_answer"`
+	IsCorrect     bool   `json:"is_correct"`
+}
+
+type ExportProgress struct {
+	QuizID          uint      `json:"quiz_id"`
+	Category        string    `json:"category"`
+	TotalAttempts   int       `json:"total_attempts"`
+	BestScore       float64   `json:"best_score"`
+	AverageScore    float64   `json:"average_score"`
+	TotalTimeSpent  int       `json:"total_time_spent"`
+	MasteryLevel    int       `json:"mastery_level"`
+	LastAttemptedAt time.Time `json:"last_attempted_at"`
+}
+
+type ExportAchievement struct {
+	AchievementID uint      `json:"achievement_id"`
+	Name          string    `json:"name"`
+	Description   string    `json:"description"`
+	EarnedAt      time.Time `json:"earned_at"`
+}
+
+type ExportGlobalRanking struct {
+	RankingPeriod    string  `json:"ranking_period"`
+	TotalScore       float64 `json:"total_score"`
+	QuizzesCompleted int     `json:"quizzes_completed"`
+	AverageScore     float64 `json:"average_score"`
+	TotalTimeSpent   int     `json:"total_time_spent"`
+	Rank             int     `json:"rank"`
+	Percentile       float64 `json:"percentile"`
+}
+
+type ExportCategoryRanking struct {
+	Category         string  `json:"category"`
+	RankingPeriod    string  `json:"ranking_period"`
+	TotalScore       float64 `json:"total_score"`
+	QuizzesCompleted int     `json:"quizzes_completed"`
+	AverageScore     float64 `json:"average_score"`
+	Rank             int     `json:"rank"`
+	Percentile       float64 `json:"percentile"`
+}
+
+// submittedAnswer is the shape answers are stored in Result.Answers
+type submittedAnswer struct {
+	QuestionID uint   `json:"question_id"`
+	Answer     string `json:"answer"`
+}
+
+// collect loads everything stored about the user
+func (s *ExportService) collect(ctx context.Context, userID uint) (*ExportData, error) {
+	db := s.db.WithContext(ctx)
+	var user models.User
+	if err := db.Unscoped().First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+
+	data := &ExportData{
+		Profile: []ExportProfile{{
+			ID:           user.ID,
+			Email:        user.Email,
+			FirstName:    user.FirstName,
+			LastName:     user.LastName,
+			Role:         string(user.Role),
+			AuthProvider: string(user.AuthProvider),
+			ProfileImage: user.ProfileImage,
+			Locale:       user.Locale,
+			TimeZone:     user.TimeZone,
+			IsActive:     user.IsActive,
+			CreatedAt:    user.CreatedAt,
+			AnonymizedAt: user.AnonymizedAt,
+		}},
+		Sessions: []ExportSession{{
+			AuthProvider:     string(user.AuthProvider),
+			LastLoginAt:      user.LastLoginAt,
+			HasActiveSession: user.RefreshToken != "",
+		}},
+	}
+
+	var results []models.Result
+	if err := db.Where("user_id = ?", userID).Order("id").Find(&results).Error; err != nil {
+		return nil, err
+	}
+
+	quizIDs := make([]uint, 0, len(results))
+	for _, r := range results {
+		quizIDs = append(quizIDs, r.QuizID)
+	}
+	quizTitles := map[uint]string{}
+	questions := map[uint]models.Question{}
+	if len(quizIDs) > 0 {
+		var quizzes []models.Quiz
+		if err := db.Unscoped().Where("id IN ?", quizIDs).Find(&quizzes).Error; err != nil {
+			return nil, err
+		}
+		for _, q := range quizzes {
+			quizTitles[q.ID] = q.Title
+		}
+
+		var qs []models.Question
+		if err := db.Unscoped().Where("quiz_id IN ?", quizIDs).Find(&qs).Error; err != nil {
+			return nil, err
+		}
+		for _, q := range qs {
+			questions[q.ID] = q
+		}
+	}
+
+	for _, r := range results {
+		data.Results = append(data.Results, ExportResult{
+			ID:             r.ID,
+			QuizID:         r.QuizID,
+			QuizTitle:      quizTitles[r.QuizID],
+			Score:          r.Score,
+			CorrectAnswers: r.CorrectAnswers,
+			TotalQuestions: r.TotalQuestions,
+			TimeTaken:      r.TimeTaken,
+			IsPassed:       r.IsPassed,
+			PassingScore:   r.PassingScore,
+			Feedback:       r.Feedback,
+			CreatedAt:      r.CreatedAt,
+		})
+
+		var answers []submittedAnswer
+		if len(r.Answers) == 0 || json.Unmarshal(r.Answers, &answers) != nil {
+			continue
+		}
+		for _, a := range answers {
+			q := questions[a.QuestionID]
+			data.ResultAnswers = append(data.ResultAnswers, ExportResultAnswer{
+				ResultID:      r.ID,
+				QuestionID:    a.QuestionID,
+				QuestionText:  q.Text,
+				Answer:        a.Answer,
+				CorrectAnswer: q.CorrectAnswer,
+				IsCorrect:     q.ID != 0 && q.CorrectAnswer == a.Answer,
+			})
+		}
+	}
+
+	var progress []models.UserProgress
+	if err := db.Where("user_id = ?", userID).Order("id").Find(&progress).Error; err != nil {
+		return nil, err
+	}
+	for _, p := range progress {
+		data.Progress = append(data.Progress, ExportProgress{
+			QuizID:          p.QuizID,
+			Category:        string(p.Category),
+			TotalAttempts:   p.TotalAttempts,
+			BestScore:       p.BestScore,
+			AverageScore:    p.AverageScore,
+			TotalTimeSpent:  p.TotalTimeSpent,
+			MasteryLevel:    p.MasteryLevel,
+			LastAttemptedAt: p.LastAttemptedAt,
+		})
+	}
+
+	var achievements []models.UserAchievement
+	if err := db.Preload("Achievement").Where("user_id = ?", userID).Order("id").Find(&achievements).Error; err != nil {
+		return nil, err
+	}
+	for _, a := range achievements {
+		data.Achievements = append(data.Achievements, ExportAchievement{
+			AchievementID: a.AchievementID,
+			Name:          a.Achievement.Name,
+			Description:   a.Achievement.Description,
+			EarnedAt:      a.EarnedAt,
+		})
+	}
+
+	var globalRankings []models.GlobalRanking
+	if err := db.Where("user_id = ?", userID).Order("id").Find(&globalRankings).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range globalRankings {
+		data.GlobalRankings = append(data.GlobalRankings, ExportGlobalRanking{
+			RankingPeriod:    string(r.RankingPeriod),
+			TotalScore:       r.TotalScore,
+			QuizzesCompleted: r.QuizzesCompleted,
+			AverageScore:     r.AverageScore,
+			TotalTimeSpent:   r.TotalTimeSpent,
+			Rank:             r.Rank,
+			Percentile:       r.Percentile,
+		})
+	}
+
+	var categoryRankings []models.CategoryRanking
+	if err := db.Where("user_id = ?", userID).Order("id").Find(&categoryRankings).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range categoryRankings {
+		data.CategoryRankings = append(data.CategoryRankings, ExportCategoryRanking{
+			Category:         string(r.Category),
+			RankingPeriod:    string(r.RankingPeriod),
+			TotalScore:       r.TotalScore,
+			QuizzesCompleted: r.QuizzesCompleted,
+			AverageScore:     r.AverageScore,
+			Rank:             r.Rank,
+			Percentile:       r.Percentile,
+		})
+	}
+
+	return data, nil
+}
+
+// BuildExportArchive writes every dataset as both <name>.json and <name>.csv into a ZIP
+func BuildExportArchive(data *ExportData) ([]byte, error) {
+	datasets := []struct {
+		name    string
+		records interface{}
+	}{
+		{"profile", data.Profile},
+		{"sessions", data.Sessions},
+		{"results", data.Results},
+		{"result_answers", data.ResultAnswers},
+		{"progress", data.Progress},
+		{"achievements", data.Achievements},
+		{"global_rankings", data.GlobalRankings},
+		{"category_rankings", data.CategoryRankings},
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, ds := range datasets {
+		jsonFile, err := zw.Create(ds.name + ".json")
+		if err != nil {
+			return nil, err
+		}
+		enc := json.NewEncoder(jsonFile)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(nonNil(ds.records)); err != nil {
+			return nil, err
+		}
+
+		csvFile, err := zw.Create(ds.name + ".csv")
+		if err != nil {
+			return nil, err
+		}
+		if err := writeCSV(csvFile, ds.records); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// nonNil makes empty datasets encode as [] instead of null
+func nonNil(records interface{}) interface{} {
+	v := reflect.ValueOf(records)
+	if v.Kind() == reflect.Slice && v.IsNil() {
+		return reflect.MakeSlice(v.Type(), 0, 0).Interface()
+	}
+	return records
+}
+
+// writeCSV writes a slice of flat structs as CSV, using the json tags as column names
+func writeCSV(w io.Writer, records interface{}) error {
+	v := reflect.ValueOf(records)
+	elemType := v.Type().Elem()
+
+	header := make([]string, elemType.NumField())
+	for i := range header {
+		header[i] = elemType.Field(i).Tag.Get("json")
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for i := 0; i < v.Len(); i++ {
+		row := make([]string, elemType.NumField())
+		for j := range row {
+			row[j] = csvValue(v.Index(i).Field(j))
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func csvValue(v reflect.Value) string {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	switch value := v.Interface().(type) {
+	case time.Time:
+		if value.IsZero() {
+			return ""
+		}
+		return value.UTC().Format(time.RFC3339)
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case string:
+		return csvText(value)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// csvText keeps spreadsheets from running text as a formula, such as a name
+// a user chose to start with "=", by prefixing it with a quote
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
@@ -0,0 +1,149 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"aicg/internal/config"
+	"aicg/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readZipFile(t *testing.T, zr *zip.Reader, name string) []byte {
+	f, err := zr.Open(name)
+	require.NoError(t, err, name)
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	require.NoError(t, err)
+	return data
+}
+
+func TestBuildExportArchive(t *testing.T) {
+	created := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	data := &ExportData{
+		Profile: []ExportProfile{{ID: 1, Email: "ada@example.com", FirstName: "Ada", LastName: "=HYPERLINK(\"http://evil\")", CreatedAt: created}},
+		Results: []ExportResult{{ID: 10, QuizID: 3, QuizTitle: "Algebra, basics", Score: 87.5, CreatedAt: created}},
+		ResultAnswers: []ExportResultAnswer{
+			{ResultID: 10, QuestionID: 1, QuestionText: "2+2?", Answer: "4", CorrectAnswer: "4", IsCorrect: true},
+		},
+	}
+
+	archive, err := BuildExportArchive(data)
+	require.NoError(t, err)
+
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	require.NoError(t, err)
+
+	// Every dataset is there in both formats
+	for _, name := range []string{"profile", "sessions", "results", "result_answers", "progress", "achievements", "global_rankings", "category_rankings"} {
+		readZipFile(t, zr, name+".json")
+		readZipFile(t, zr, name+".csv")
+	}
+
+	// JSON
+	var profile []ExportProfile
+	require.NoError(t, json.Unmarshal(readZipFile(t, zr, "profile.json"), &profile))
+	assert.Equal(t, "ada@example.com", profile[0].Email)
+
+	var progress []ExportProgress
+	require.NoError(t, json.Unmarshal(readZipFile(t, zr, "progress.json"), &progress))
+	assert.NotNil(t, progress, "empty datasets should be [] rather than null")
+
+	// CSV
+	rows, err := csv.NewReader(bytes.NewReader(readZipFile(t, zr, "results.csv"))).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, "quiz_title", rows[0][2])
+	assert.Equal(t, "Algebra, basics", rows[1][2])
+	assert.Equal(t, "87.5", rows[1][3])
+	assert.Equal(t, "2026-03-01T12:00:00Z", rows[1][10])
+
+	rows, err = csv.NewReader(bytes.NewReader(readZipFile(t, zr, "result_answers.csv"))).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10", "1", "2+2?", "4", "4", "true"}, rows[1])
+
+	// Text that a spreadsheet would run as a formula is quoted, in CSV only
+	rows, err = csv.NewReader(bytes.NewReader(readZipFile(t, zr, "profile.csv"))).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, "last_name", rows[0][3])
+	assert.Equal(t, `'=HYPERLINK("http://evil")`, rows[1][3])
+	assert.Equal(t, `=HYPERLINK("http://evil")`, profile[0].LastName)
+}
+
+func TestCSVText(t *testing.T) {
+	for _, s := range []string{"=1+1", "+1", "-1", "@SUM(A1)", "\tx", "\rx"} {
+		assert.Equal(t, "'"+s, csvText(s), s)
+	}
+	for _, s := range []string{"", "Ada", "1-1", "a=b"} {
+		assert.Equal(t, s, csvText(s), s)
+	}
+}
+
+func TestExportDownloadLink(t *testing.T) {
+	service := NewExportService(nil, nil, &config.Config{Exports: config.ExportsConfig{SigningKey: "test-key", SweepInterval: time.Hour}})
+	export := &models.DataExport{Status: models.DataExportReady}
+	export.ID = 5
+
+	// Test case 1: Only ready exports get a link
+	_, _, err := service.DownloadLink(&models.DataExport{Status: models.DataExportPending})
+	assert.ErrorIs(t, err, ErrExportNotReady)
+
+	// Test case 2: A fresh link verifies
+	link, expires, err := service.DownloadLink(export)
+	require.NoError(t, err)
+	query, err := url.ParseQuery(link)
+	require.NoError(t, err)
+	expiresUnix, _ := strconv.ParseInt(query.Get("expires"), 10, 64)
+	assert.Equal(t, expires.Unix(), expiresUnix)
+	assert.True(t, service.verify(5, expiresUnix, query.Get("signature"), time.Now()))
+
+	// Test case 3: The link is bound to the export and its expiry
+	assert.False(t, service.verify(6, expiresUnix, query.Get("signature"), time.Now()))
+	assert.False(t, service.verify(5, expiresUnix+3600, query.Get("signature"), time.Now()))
+
+	// Test case 4: The link stops working once it expires
+	assert.False(t, service.verify(5, expiresUnix, query.Get("signature"), expires.Add(time.Second)))
+}
+
+func TestExportShutdown(t *testing.T) {
+	service := NewExportService(nil, nil, &config.Config{Exports: config.ExportsConfig{SigningKey: "test-key", SweepInterval: time.Hour}})
+
+	// Test case 1: Nothing running
+	assert.NoError(t, service.Shutdown(context.Background()))
+
+	// Test case 2: Gives up on an export that outlives the deadline
+	service.wg.Add(1)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, service.Shutdown(ctx), context.DeadlineExceeded)
+
+	// Test case 3: Returns once it finishes
+	service.wg.Done()
+	assert.NoError(t, service.Shutdown(context.Background()))
+}
+
+func TestExportSweeperShutdown(t *testing.T) {
+	service := NewExportService(nil, nil, &config.Config{Exports: config.ExportsConfig{SweepInterval: time.Hour}})
+	// Stands in for a sweep that's running
+	service.sweeper.Add(1)
+	go func() {
+		defer service.sweeper.Done()
+		<-service.stop
+	}()
+
+	// Test case 1: Shutdown stops the sweeper and waits for it
+	assert.NoError(t, service.Shutdown(context.Background()))
+
+	// Test case 2: Shutting down again is harmless
+	assert.NoError(t, service.Shutdown(context.Background()))
+}
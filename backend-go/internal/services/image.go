@@ -0,0 +1,366 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log/slog"
+	"net/http"
+	"path"
+
+	"aicg/internal/models"
+	"aicg/internal/storage"
+	"aicg/internal/tracing"
+
+	"golang.org/x/image/draw"
+	"gorm.io/gorm"
+)
+
+// MaxImageUploadSize is the largest image file we accept (5 MB)
+const MaxImageUploadSize = 5 << 20
+
+// maxImagePixels guards against decompression bombs (e.g. a tiny PNG claiming 50000x50000)
+const maxImagePixels = 40_000_000
+
+// maxImageSaveAttempts bounds how often an upload's URL is saved again after another upload replaced the image first
+const maxImageSaveAttempts = 10
+
+var (
+	// ErrImageTooLarge is returned when an upload exceeds MaxImageUploadSize
+	ErrImageTooLarge = &Error{Kind: KindTooLarge, Code: "image_too_large", Message: "image is too large"}
+	// ErrUnsupportedImageType is returned for anything that isn't a JPEG or PNG
+	ErrUnsupportedImageType = &Error{Kind: KindUnsupportedMedia, Code: "unsupported_image_type", Message: "unsupported image type, use JPEG or PNG"}
+	// ErrInvalidImage is returned when the upload can't be decoded as an image
+	ErrInvalidImage = Invalid("invalid_image", "invalid image")
+	// ErrAchievementNotFound is returned when no achievement matches the given ID
+	ErrAchievementNotFound = NotFound("achievement_not_found", "achievement not found")
+	// ErrImageUploadConflict is returned when simultaneous uploads kept replacing the image, so the upload couldn't be saved
+	ErrImageUploadConflict = Conflict("image_upload_conflict", "too many simultaneous uploads of this image, please try again")
+)
+
+// ImageVariant describes one resized copy generated for every upload
+type ImageVariant struct {
+	Name string // Used in the blob key and the response, e.g. "small"
+	Size int    // Width and height in pixels; images are cropped to a square
+}
+
+// ProfileImageVariants are the sizes generated for profile images.
+// The "medium" variant is what User.ProfileImage points to.
+var ProfileImageVariants = []ImageVariant{
+	{Name: "small", Size: 64},
+	{Name: "medium", Size: 256},
+	{Name: "large", Size: 512},
+}
+
+// AchievementIconVariants are the sizes generated for achievement icons.
+// The "medium" variant is what Achievement.IconURL points to.
+var AchievementIconVariants = []ImageVariant{
+	{Name: "small", Size: 48},
+	{Name: "medium", Size: 128},
+}
+
+// UploadedImage is returned after an image and its variants have been stored
+type UploadedImage struct {
+	URL      string            `json:"url"`      // URL of the primary ("medium") variant
+	Variants map[string]string `json:"variants"` // Variant name -> URL
+}
+
+// ImageService validates, sanitizes and stores uploaded images
+type ImageService struct {
+	db    *gorm.DB
+	store storage.BlobStore
+}
+
+func NewImageService(db *gorm.DB, store storage.BlobStore) *ImageService {
+	return &ImageService{db: db, store: store}
+}
+
+// UploadProfileImage stores a new profile image for the user and updates User.ProfileImage
+func (s *ImageService) UploadProfileImage(ctx context.Context, userID uint, r io.Reader) (_ *UploadedImage, err error) {
+	ctx, span := tracing.Start(ctx, "ImageService.UploadProfileImage")
+	defer tracing.End(span, &err)
+
+	var user models.User
+	if err := s.db.WithContext(ctx).First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	prefix := ProfileImagePrefix(userID)
+	uploaded, dir, err := s.storeImage(ctx, prefix, r, ProfileImageVariants)
+	if err != nil {
+		return nil, err
+	}
+
+	replaced, err := s.saveImageURL(ctx, &models.User{}, userID, "profile_image", user.ProfileImage, uploaded.URL, ErrUserNotFound)
+	s.replaceImages(ctx, prefix, dir, replaced, err)
+	if err != nil {
+		return nil, err
+	}
+	return uploaded, nil
+}
+
+// UploadAchievementIcon stores a new icon for the achievement and updates Achievement.IconURL
+func (s *ImageService) UploadAchievementIcon(ctx context.Context, achievementID uint, r io.Reader) (_ *UploadedImage, err error) {
+	ctx, span := tracing.Start(ctx, "ImageService.UploadAchievementIcon")
+	defer tracing.End(span, &err)
+
+	var achievement models.Achievement
+	if err := s.db.WithContext(ctx).First(&achievement, achievementID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAchievementNotFound
+		}
+		return nil, err
+	}
+
+	prefix := fmt.Sprintf("achievement-icons/%d/", achievementID)
+	uploaded, dir, err := s.storeImage(ctx, prefix, r, AchievementIconVariants)
+	if err != nil {
+		return nil, err
+	}
+
+	replaced, err := s.saveImageURL(ctx, &models.Achievement{}, achievementID, "icon_url", achievement.IconURL, uploaded.URL, ErrAchievementNotFound)
+	s.replaceImages(ctx, prefix, dir, replaced, err)
+	if err != nil {
+		return nil, err
+	}
+	return uploaded, nil
+}
+
+// ProfileImagePrefix is the blob key prefix all of a user's profile images are stored under
+func ProfileImagePrefix(userID uint) string {
+	return fmt.Sprintf("profile-images/%d/", userID)
+}
+
+// storeImage processes the upload into every variant and saves them in a new
+// directory under prefix, which it returns. Variants stored before a failure
+// are deleted again.
+func (s *ImageService) storeImage(ctx context.Context, prefix string, r io.Reader, variants []ImageVariant) (_ *UploadedImage, dir string, err error) {
+	processed, err := ProcessImage(r, variants)
+	if err != nil {
+		return nil, "", err
+	}
+
+	id, err := randomID()
+	if err != nil {
+		return nil, "", err
+	}
+	dir = prefix + id + "/"
+	defer func() {
+		if err != nil {
+			_ = deleteBlobs(context.WithoutCancel(ctx), s.store, dir)
+		}
+	}()
+
+	uploaded := &UploadedImage{Variants: make(map[string]string, len(processed))}
+	for _, variant := range variants {
+		img := processed[variant.Name]
+		key := dir + variant.Name + img.Extension
+		if err := s.store.Put(ctx, key, bytes.NewReader(img.Data), int64(len(img.Data)), img.ContentType); err != nil {
+			return nil, "", fmt.Errorf("failed to store image: %w", err)
+		}
+
+		url, err := s.store.URL(ctx, key)
+		if err != nil {
+			return nil, "", err
+		}
+		uploaded.Variants[variant.Name] = url
+	}
+
+	uploaded.URL = uploaded.Variants["medium"]
+	return uploaded, dir, nil
+}
+
+// saveImageURL points column of the record with the given ID at url, in
+// place of the current URL it was read with, and returns the URL it replaced.
+// The update only applies while the column still holds that URL: when another
+// upload saved its own in the meantime, that one is read and replaced instead,
+// so every upload knows exactly which image it replaced.
+func (s *ImageService) saveImageURL(ctx context.Context, model any, id uint, column, current, url string, notFound error) (string, error) {
+	db := s.db.WithContext(ctx)
+	for attempt := 1; ; attempt++ {
+		result := db.Model(model).Where("id = ? AND COALESCE("+column+", '') = ?", id, current).Update(column, url)
+		if result.Error != nil {
+			return "", result.Error
+		}
+		if result.RowsAffected == 1 {
+			return current, nil
+		}
+		if attempt == maxImageSaveAttempts {
+			return "", ErrImageUploadConflict
+		}
+
+		var urls []sql.NullString
+		if err := db.Model(model).Where("id = ?", id).Pluck(column, &urls).Error; err != nil {
+			return "", err
+		}
+		if len(urls) == 0 {
+			return "", notFound
+		}
+		current = urls[0].String
+	}
+}
+
+// replaceImages deletes the images under prefix that the upload stored in
+// dir replaced, which are the directory the replaced URL points to, or the
+// upload itself when saving its URL failed. Leftovers are only logged, since
+// the upload has succeeded or failed either way.
+func (s *ImageService) replaceImages(ctx context.Context, prefix, dir, replaced string, saveErr error) {
+	ctx = context.WithoutCancel(ctx)
+	var err error
+	if saveErr != nil {
+		err = deleteBlobs(ctx, s.store, dir)
+	} else if replaced != "" {
+		var key string
+		if key, err = blobKey(ctx, s.store, prefix, replaced); err == nil && key != "" {
+			err = deleteBlobs(ctx, s.store, path.Dir(key)+"/")
+		}
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to delete replaced images", "prefix", prefix, "error", err)
+	}
+}
+
+// blobKey returns the key of the blob under prefix that url points to, or ""
+// if it doesn't point to any, say because it was set by hand or already deleted
+func blobKey(ctx context.Context, store storage.BlobStore, prefix, url string) (string, error) {
+	keys, err := store.List(ctx, prefix)
+	if err != nil {
+		return "", err
+	}
+	for _, key := range keys {
+		keyURL, err := store.URL(ctx, key)
+		if err != nil {
+			return "", err
+		}
+		if keyURL == url {
+			return key, nil
+		}
+	}
+	return "", nil
+}
+
+// deleteBlobs deletes every blob under prefix
+func deleteBlobs(ctx context.Context, store storage.BlobStore, prefix string) error {
+	keys, err := store.List(ctx, prefix)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := store.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ProcessedImage is one encoded variant of an upload
+type ProcessedImage struct {
+	Data        []byte
+	ContentType string
+	Extension   string
+}
+
+// ProcessImage validates an uploaded image and returns one re-encoded copy per variant.
+// The content type is sniffed from the data rather than trusted from the client.
+// Because every variant is decoded and re-encoded from pixels, metadata such as
+// EXIF (GPS location, camera serial numbers, ...) is never carried over.
+func ProcessImage(r io.Reader, variants []ImageVariant) (map[string]ProcessedImage, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxImageUploadSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxImageUploadSize {
+		return nil, ErrImageTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+	if contentType != "image/jpeg" && contentType != "image/png" {
+		return nil, ErrUnsupportedImageType
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	if cfg.Width == 0 || cfg.Height == 0 || cfg.Width*cfg.Height > maxImagePixels {
+		return nil, ErrInvalidImage
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	src = cropSquare(src)
+
+	result := make(map[string]ProcessedImage, len(variants))
+	for _, variant := range variants {
+		size := variant.Size
+		if b := src.Bounds(); b.Dx() < size {
+			// Never upscale small uploads
+			size = b.Dx()
+		}
+
+		dst := image.NewRGBA(image.Rect(0, 0, size, size))
+		draw.CatmullRom.Scale(dst, dst.Bounds(), src, src.Bounds(), draw.Src, nil)
+
+		var buf bytes.Buffer
+		processed := ProcessedImage{ContentType: contentType}
+		if contentType == "image/png" {
+			// Keep PNGs as PNG so transparency survives
+			err = png.Encode(&buf, dst)
+			processed.Extension = ".png"
+		} else {
+			err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 85})
+			processed.Extension = ".jpg"
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		processed.Data = buf.Bytes()
+		result[variant.Name] = processed
+	}
+	return result, nil
+}
+
+// cropSquare returns the centered square part of img
+func cropSquare(img image.Image) image.Image {
+	b := img.Bounds()
+	side := b.Dx()
+	if b.Dy() < side {
+		side = b.Dy()
+	}
+
+	x := b.Min.X + (b.Dx()-side)/2
+	y := b.Min.Y + (b.Dy()-side)/2
+	square := image.Rect(x, y, x+side, y+side)
+
+	if sub, ok := img.(interface {
+		SubImage(r image.Rectangle) image.Image
+	}); ok {
+		return sub.SubImage(square)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, side, side))
+	draw.Draw(dst, dst.Bounds(), img, square.Min, draw.Src)
+	return dst
+}
+
+func randomID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
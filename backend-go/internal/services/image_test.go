@@ -0,0 +1,85 @@
+package services
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testJPEG(t *testing.T, width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, nil))
+	return buf.Bytes()
+}
+
+// withEXIF inserts an APP1 EXIF segment right after the JPEG SOI marker
+func withEXIF(data []byte) []byte {
+	payload := append([]byte("Exif\x00\x00"), []byte("GPS 52.5200N 13.4050E")...)
+	segment := []byte{0xFF, 0xE1, byte((len(payload) + 2) >> 8), byte(len(payload) + 2)}
+	segment = append(segment, payload...)
+
+	out := append([]byte{}, data[:2]...)
+	out = append(out, segment...)
+	return append(out, data[2:]...)
+}
+
+func TestProcessImage(t *testing.T) {
+	upload := withEXIF(testJPEG(t, 400, 300))
+	require.Contains(t, string(upload), "Exif")
+
+	processed, err := ProcessImage(bytes.NewReader(upload), ProfileImageVariants)
+	require.NoError(t, err)
+	require.Len(t, processed, len(ProfileImageVariants))
+
+	expectedSizes := map[string]int{"small": 64, "medium": 256, "large": 300} // 300 = never upscale
+	for name, size := range expectedSizes {
+		img := processed[name]
+		assert.Equal(t, "image/jpeg", img.ContentType)
+		assert.Equal(t, ".jpg", img.Extension)
+		assert.NotContains(t, string(img.Data), "Exif", "variant %s still has EXIF data", name)
+
+		cfg, err := jpeg.DecodeConfig(bytes.NewReader(img.Data))
+		require.NoError(t, err)
+		assert.Equal(t, size, cfg.Width, name)
+		assert.Equal(t, size, cfg.Height, name)
+	}
+}
+
+func TestProcessImageKeepsPNG(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 100, 100))))
+
+	processed, err := ProcessImage(&buf, AchievementIconVariants)
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", processed["small"].ContentType)
+	assert.Equal(t, ".png", processed["small"].Extension)
+}
+
+func TestProcessImageRejectsInvalidUploads(t *testing.T) {
+	// Test case 1: Not an image
+	_, err := ProcessImage(strings.NewReader("<svg onload=alert(1)>"), ProfileImageVariants)
+	assert.ErrorIs(t, err, ErrUnsupportedImageType)
+
+	// Test case 2: Too large
+	tooLarge := append(testJPEG(t, 10, 10), make([]byte, MaxImageUploadSize)...)
+	_, err = ProcessImage(bytes.NewReader(tooLarge), ProfileImageVariants)
+	assert.ErrorIs(t, err, ErrImageTooLarge)
+
+	// Test case 3: Looks like a JPEG but is corrupt
+	_, err = ProcessImage(bytes.NewReader([]byte("\xFF\xD8\xFF\xE0garbage")), ProfileImageVariants)
+	assert.ErrorIs(t, err, ErrInvalidImage)
+}
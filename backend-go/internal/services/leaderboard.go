@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"math"
+	"strings"
+
+	"aicg/internal/models"
+	"aicg/internal/models/enums"
+	"aicg/internal/repository"
+	"aicg/internal/tracing"
+
+	"gorm.io/gorm"
+)
+
+// LeaderboardFilter selects a leaderboard
+type LeaderboardFilter struct {
+	Period   enums.RankingPeriod // Which rankings to show, all time when empty
+	Category enums.QuizCategory  // Rank within this category, if set; globally otherwise
+}
+
+// LeaderboardSorting pages through a leaderboard by rank. Users share a rank
+// on a tie, so the user ID breaks it.
+var LeaderboardSorting = repository.Sorting[models.LeaderboardEntry]{
+	Default:  "rank",
+	IDColumn: "user_id",
+	ID:       func(e models.LeaderboardEntry) uint { return e.UserID },
+	Keys: map[string]repository.SortKey[models.LeaderboardEntry]{
+		"rank": {Column: "rank", Value: func(e models.LeaderboardEntry) any { return e.Rank }},
+	},
+}
+
+// leaderboardRow is a ranking joined with the ranked user
+type leaderboardRow struct {
+	UserID           uint
+	FirstName        string
+	LastName         string
+	TotalScore       float64
+	Rank             int
+	AchievementCount int
+}
+
+type LeaderboardService struct {
+	db *gorm.DB
+}
+
+func NewLeaderboardService(db *gorm.DB) *LeaderboardService {
+	return &LeaderboardService{db: db}
+}
+
+// GetLeaderboard returns a page of the precomputed rankings, best first
+func (s *LeaderboardService) GetLeaderboard(ctx context.Context, filter LeaderboardFilter, page repository.Page) (_ repository.List[models.LeaderboardEntry], err error) {
+	ctx, span := tracing.Start(ctx, "LeaderboardService.GetLeaderboard")
+	defer tracing.End(span, &err)
+
+	if filter.Period == "" {
+		filter.Period = enums.RankingPeriodAllTime
+	}
+
+	// The ranking tables are read from the replicas, so query them through their models
+	var query *gorm.DB
+	if filter.Category != "" {
+		query = s.db.WithContext(ctx).Model(&models.CategoryRanking{}).
+			Where("category = ?", filter.Category)
+	} else {
+		query = s.db.WithContext(ctx).Model(&models.GlobalRanking{})
+	}
+	query = query.
+		Select("user_id, users.first_name, users.last_name, total_score, rank, "+
+			"(SELECT COUNT(*) FROM user_achievements WHERE user_achievements.user_id = users.id AND user_achievements.deleted_at IS NULL) AS achievement_count").
+		Joins("JOIN users ON users.id = user_id AND users.deleted_at IS NULL").
+		Where("ranking_period = ?", filter.Period)
+
+	var rows []leaderboardRow
+	if err := LeaderboardSorting.Paginate(query, page).Scan(&rows).Error; err != nil {
+		return repository.List[models.LeaderboardEntry]{}, err
+	}
+
+	entries := make([]models.LeaderboardEntry, len(rows))
+	for i, row := range rows {
+		entries[i] = models.LeaderboardEntry{
+			UserID:           row.UserID,
+			Username:         strings.TrimSpace(row.FirstName + " " + row.LastName),
+			Score:            int(math.Round(row.TotalScore)),
+			Rank:             row.Rank,
+			Category:         string(filter.Category),
+			AchievementCount: row.AchievementCount,
+		}
+	}
+	return LeaderboardSorting.List(entries, page), nil
+}
@@ -0,0 +1,17 @@
+package services
+
+import (
+	"context"
+
+	"aicg/internal/models"
+	"aicg/internal/repository"
+)
+
+// ILeaderboardService defines the interface for reading the leaderboards
+type ILeaderboardService interface {
+	// GetLeaderboard retrieves a page of a global or category leaderboard, best first
+	GetLeaderboard(ctx context.Context, filter LeaderboardFilter, page repository.Page) (repository.List[models.LeaderboardEntry], error)
+}
+
+// Ensure LeaderboardService implements ILeaderboardService
+var _ ILeaderboardService = (*LeaderboardService)(nil)
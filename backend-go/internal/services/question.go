@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"aicg/internal/models"
+	"aicg/internal/models/enums"
+	"aicg/internal/tracing"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrQuestionNotFound is returned when no question matches the given ID
+	ErrQuestionNotFound = NotFound("question_not_found", "question not found")
+	// ErrQuizNotFound is returned when no quiz matches the given ID
+	ErrQuizNotFound = NotFound("quiz_not_found", "quiz not found")
+	// ErrUnknownQuiz is returned when a question refers to a quiz that doesn't exist
+	ErrUnknownQuiz = Invalid("unknown_quiz", "quiz not found",
+		FieldError{Field: "quiz_id", Code: "exists", Message: "must be the ID of an existing quiz"})
+	// ErrInvalidQuestionType is returned for question types we don't support
+	ErrInvalidQuestionType = Invalid("invalid_question_type", "invalid question type",
+		FieldError{Field: "type", Code: "oneof", Message: "must be a supported question type"})
+)
+
+// QuestionFilter narrows down the question listing
+type QuestionFilter struct {
+	QuizID uint               // Only questions of this quiz, if set
+	Type   enums.QuestionType // Only questions of this type, if set
+}
+
+type QuestionService struct {
+	db *gorm.DB
+}
+
+func NewQuestionService(db *gorm.DB) *QuestionService {
+	return &QuestionService{db: db}
+}
+
+// GetQuestions returns the questions matching the filter with their answers
+func (s *QuestionService) GetQuestions(ctx context.Context, filter QuestionFilter) (_ []models.Question, err error) {
+	ctx, span := tracing.Start(ctx, "QuestionService.GetQuestions")
+	defer tracing.End(span, &err)
+
+	query := s.db.WithContext(ctx).Preload("Answers")
+	if filter.QuizID != 0 {
+		query = query.Where("quiz_id = ?", filter.QuizID)
+	}
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+
+	var questions []models.Question
+	if err := query.Order("id").Find(&questions).Error; err != nil {
+		return nil, err
+	}
+	return questions, nil
+}
+
+// GetQuestionByID returns a question with its answers
+func (s *QuestionService) GetQuestionByID(ctx context.Context, id uint) (_ *models.Question, err error) {
+	ctx, span := tracing.Start(ctx, "QuestionService.GetQuestionByID")
+	defer tracing.End(span, &err)
+
+	var question models.Question
+	if err := s.db.WithContext(ctx).Preload("Answers").First(&question, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrQuestionNotFound
+		}
+		return nil, err
+	}
+	return &question, nil
+}
+
+// CreateQuestion adds a question (and its answers) to an existing quiz
+func (s *QuestionService) CreateQuestion(ctx context.Context, question *models.Question) (err error) {
+	ctx, span := tracing.Start(ctx, "QuestionService.CreateQuestion")
+	defer tracing.End(span, &err)
+
+	if err := s.validate(ctx, question); err != nil {
+		return err
+	}
+	return s.db.WithContext(ctx).Create(question).Error
+}
+
+// UpdateQuestion replaces a question's fields and answers
+func (s *QuestionService) UpdateQuestion(ctx context.Context, id uint, question *models.Question) (err error) {
+	ctx, span := tracing.Start(ctx, "QuestionService.UpdateQuestion")
+	defer tracing.End(span, &err)
+
+	existing, err := s.GetQuestionByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.validate(ctx, question); err != nil {
+		return err
+	}
+
+	question.ID = existing.ID
+	question.CreatedAt = existing.CreatedAt
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Answers are replaced as a whole
+		if err := tx.Where("question_id = ?", id).Delete(&models.Answer{}).Error; err != nil {
+			return err
+		}
+		for i := range question.Answers {
+			question.Answers[i].ID = 0
+			question.Answers[i].QuestionID = id
+		}
+		return tx.Session(&gorm.Session{FullSaveAssociations: true}).Save(question).Error
+	})
+}
+
+// DeleteQuestion soft-deletes a question and its answers
+func (s *QuestionService) DeleteQuestion(ctx context.Context, id uint) (err error) {
+	ctx, span := tracing.Start(ctx, "QuestionService.DeleteQuestion")
+	defer tracing.End(span, &err)
+
+	if _, err := s.GetQuestionByID(ctx, id); err != nil {
+		return err
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("question_id = ?", id).Delete(&models.Answer{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Question{}, id).Error
+	})
+}
+
+// validate checks the question type and that the quiz it belongs to exists
+func (s *QuestionService) validate(ctx context.Context, question *models.Question) error {
+	if !question.Type.IsValid() {
+		return ErrInvalidQuestionType
+	}
+
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&models.Quiz{}).Where("id = ?", question.QuizID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrUnknownQuiz
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package services
+
+import (
+	"context"
+
+	"aicg/internal/models"
+)
+
+// IQuestionService defines the interface for question management
+type IQuestionService interface {
+	// GetQuestions retrieves questions matching the filter
+	GetQuestions(ctx context.Context, filter QuestionFilter) ([]models.Question, error)
+
+	// GetQuestionByID retrieves a specific question by its ID
+	GetQuestionByID(ctx context.Context, id uint) (*models.Question, error)
+
+	// CreateQuestion adds a question to an existing quiz
+	CreateQuestion(ctx context.Context, question *models.Question) error
+
+	// UpdateQuestion replaces a question and its answers
+	UpdateQuestion(ctx context.Context, id uint, question *models.Question) error
+
+	// DeleteQuestion removes a question
+	DeleteQuestion(ctx context.Context, id uint) error
+}
+
+// Ensure QuestionService implements IQuestionService
+var _ IQuestionService = (*QuestionService)(nil)
@@ -1,125 +1,249 @@
 package services
 
 import (
+	"context"
 	"errors"
+	"math"
+	"math/rand/v2"
 	"time"
 
+	"aicg/internal/metrics"
 	"aicg/internal/models"
+	"aicg/internal/models/enums"
+	"aicg/internal/repository"
+	"aicg/internal/tracing"
+)
+
+var (
+	// ErrResultNotFound is returned when no result matches the given ID
+	ErrResultNotFound = NotFound("result_not_found", "result not found")
+	// ErrSubmissionConflict is returned when concurrent submissions of a quiz
+	// kept changing the progress, so the submission couldn't be saved
+	ErrSubmissionConflict = Conflict("submission_conflict", "too many simultaneous submissions of this quiz, please try again")
+)
 
-	"gorm.io/gorm"
+const (
+	// maxSubmitAttempts bounds how often a submission is saved again after conflicting with another
+	maxSubmitAttempts = 10
+	// minSubmitBackoff and maxSubmitBackoff bound the pause before saving it again
+	minSubmitBackoff = 2 * time.Millisecond
+	maxSubmitBackoff = 50 * time.Millisecond
 )
 
 type QuizService struct {
-	db *gorm.DB
+	store repository.Store
 }
 
-func NewQuizService(db *gorm.DB) *QuizService {
-	return &QuizService{db: db}
+func NewQuizService(store repository.Store) *QuizService {
+	return &QuizService{store: store}
 }
 
-func (s *QuizService) CreateQuiz(quiz *models.Quiz) error {
-	return s.db.Create(quiz).Error
+func (s *QuizService) CreateQuiz(ctx context.Context, quiz *models.Quiz) (err error) {
+	ctx, span := tracing.Start(ctx, "QuizService.CreateQuiz")
+	defer tracing.End(span, &err)
+
+	return s.store.WithContext(ctx).Quizzes().Create(quiz)
 }
 
-func (s *QuizService) GetQuizByID(id uint) (*models.Quiz, error) {
-	var quiz models.Quiz
-	if err := s.db.Preload("Questions.Answers").First(&quiz, id).Error; err != nil {
-		return nil, err
+func (s *QuizService) GetQuizByID(ctx context.Context, id uint) (_ *models.Quiz, err error) {
+	ctx, span := tracing.Start(ctx, "QuizService.GetQuizByID")
+	defer tracing.End(span, &err)
+
+	quiz, err := s.store.WithContext(ctx).Quizzes().GetByID(id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrQuizNotFound
 	}
-	return &quiz, nil
+	return quiz, err
 }
 
-func (s *QuizService) GetQuizzesByCategory(category models.QuizCategory) ([]models.Quiz, error) {
-	var quizzes []models.Quiz
-	if err := s.db.Where("category = ? AND is_published = ?", category, true).Find(&quizzes).Error; err != nil {
-		return nil, err
-	}
-	return quizzes, nil
+func (s *QuizService) GetQuizzesByCategory(ctx context.Context, category enums.QuizCategory) (_ []models.Quiz, err error) {
+	ctx, span := tracing.Start(ctx, "QuizService.GetQuizzesByCategory")
+	defer tracing.End(span, &err)
+
+	return s.store.WithContext(ctx).Quizzes().ListPublished(repository.QuizFilter{Category: category})
 }
 
-func (s *QuizService) GetQuizzesByDifficulty(difficulty models.QuizDifficulty) ([]models.Quiz, error) {
-	var quizzes []models.Quiz
-	if err := s.db.Where("difficulty = ? AND is_published = ?", difficulty, true).Find(&quizzes).Error; err != nil {
-		return nil, err
-	}
-	return quizzes, nil
-}
-
-func (s *QuizService) SubmitQuizResult(result *models.Result) error {
-	// Calculate mastery level based on score
-	masteryLevel := calculateMasteryLevel(result.Score)
-
-	// Update or create user progress
-	progress := &models.UserProgress{
-		UserID:          result.UserID,
-		QuizID:          result.QuizID,
-		TotalAttempts:   1,
-		BestScore:       result.Score,
-		AverageScore:    result.Score,
-		TotalTimeSpent:  result.TimeTaken,
-		LastAttemptedAt: time.Now(),
-		MasteryLevel:    masteryLevel,
-	}
+func (s *QuizService) GetQuizzesByDifficulty(ctx context.Context, difficulty enums.QuizDifficulty) (_ []models.Quiz, err error) {
+	ctx, span := tracing.Start(ctx, "QuizService.GetQuizzesByDifficulty")
+	defer tracing.End(span, &err)
+
+	return s.store.WithContext(ctx).Quizzes().ListPublished(repository.QuizFilter{Difficulty: difficulty})
+}
+
+func (s *QuizService) SubmitQuizResult(ctx context.Context, result *models.Result) (err error) {
+	ctx, span := tracing.Start(ctx, "QuizService.SubmitQuizResult")
+	defer tracing.End(span, &err)
 
 	// Get quiz category
-	var quiz models.Quiz
-	if err := s.db.First(&quiz, result.QuizID).Error; err != nil {
+	quiz, err := s.GetQuizByID(ctx, result.QuizID)
+	if err != nil {
 		return err
 	}
-	progress.Category = quiz.Category
 
-	// Use transaction to ensure data consistency
-	return s.db.Transaction(func(tx *gorm.DB) error {
+	// Use transaction to ensure data consistency. Concurrent submissions of
+	// the quiz conflict on the progress; the loser runs again on the winner's,
+	// after a short pause.
+	txCtx, txSpan := tracing.Start(ctx, "QuizService.saveResultAndProgress")
+	for attempt := 1; ; attempt++ {
+		err = s.saveResultAndProgress(txCtx, quiz, result)
+		if !errors.Is(err, repository.ErrConflict) || attempt == maxSubmitAttempts {
+			break
+		}
+		timer := time.NewTimer(submitBackoff(attempt))
+		select {
+		case <-timer.C:
+			continue
+		case <-txCtx.Done():
+			timer.Stop()
+			err = txCtx.Err()
+		}
+		break
+	}
+	tracing.End(txSpan, &err)
+	if errors.Is(err, repository.ErrConflict) {
+		return ErrSubmissionConflict
+	}
+	if err != nil {
+		return err
+	}
+
+	metrics.ObserveQuizSubmission(quiz.Category, quiz.Difficulty, result.IsPassed)
+	return nil
+}
+
+// submitBackoff returns the pause after a submission's attempt-th conflict.
+// It doubles with every attempt and is jittered, so that the submissions
+// that conflicted don't all try again at the same moment.
+func submitBackoff(attempt int) time.Duration {
+	d := minSubmitBackoff << (attempt - 1)
+	if d > maxSubmitBackoff || d <= 0 {
+		d = maxSubmitBackoff
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// saveResultAndProgress stores a result and adds it to the user's progress on the quiz
+func (s *QuizService) saveResultAndProgress(ctx context.Context, quiz *models.Quiz, result *models.Result) error {
+	// A failed attempt may have assigned an ID that was rolled back
+	result.ID = 0
+	return s.store.WithContext(ctx).Transaction(func(tx repository.Store) error {
 		// Save result
-		if err := tx.Create(result).Error; err != nil {
+		if err := tx.Results().Create(result); err != nil {
 			return err
 		}
 
 		// Update or create progress
-		var existingProgress models.UserProgress
-		if err := tx.Where("user_id = ? AND quiz_id = ?", result.UserID, result.QuizID).First(&existingProgress).Error; err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				return tx.Create(progress).Error
-			}
+		progress, err := tx.Progress().Get(result.UserID, result.QuizID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return tx.Progress().Save(&models.UserProgress{
+				UserID:          result.UserID,
+				QuizID:          result.QuizID,
+				Category:        quiz.Category,
+				TotalAttempts:   1,
+				BestScore:       result.Score,
+				AverageScore:    result.Score,
+				TotalTimeSpent:  result.TimeTaken,
+				LastAttemptedAt: time.Now(),
+				MasteryLevel:    calculateMasteryLevel(result.Score),
+			})
+		}
+		if err != nil {
 			return err
 		}
 
 		// Update existing progress
-		existingProgress.TotalAttempts++
-		existingProgress.TotalTimeSpent += result.TimeTaken
-		existingProgress.AverageScore = (existingProgress.AverageScore*float64(existingProgress.TotalAttempts-1) + result.Score) / float64(existingProgress.TotalAttempts)
-		if result.Score > existingProgress.BestScore {
-			existingProgress.BestScore = result.Score
+		progress.TotalAttempts++
+		progress.TotalTimeSpent += result.TimeTaken
+		progress.AverageScore = (progress.AverageScore*float64(progress.TotalAttempts-1) + result.Score) / float64(progress.TotalAttempts)
+		if result.Score > progress.BestScore {
+			progress.BestScore = result.Score
 		}
-		existingProgress.LastAttemptedAt = time.Now()
-		existingProgress.MasteryLevel = calculateMasteryLevel(existingProgress.AverageScore)
+		progress.LastAttemptedAt = time.Now()
+		progress.MasteryLevel = calculateMasteryLevel(progress.AverageScore)
 
-		return tx.Save(&existingProgress).Error
+		return tx.Progress().Save(progress)
 	})
 }
 
-func (s *QuizService) GetUserProgress(userID uint) ([]models.UserProgress, error) {
-	var progress []models.UserProgress
-	if err := s.db.Where("user_id = ?", userID).Find(&progress).Error; err != nil {
+func (s *QuizService) GetUserProgressByCategory(ctx context.Context, userID uint, category enums.QuizCategory) (_ *models.CategoryProgress, err error) {
+	ctx, span := tracing.Start(ctx, "QuizService.GetUserProgressByCategory")
+	defer tracing.End(span, &err)
+
+	totals, err := s.store.WithContext(ctx).Progress().TotalsByCategory(userID)
+	if err != nil {
 		return nil, err
 	}
-	return progress, nil
+
+	summary := summarizeProgress(category, totals)
+	return &summary, nil
 }
 
-func (s *QuizService) GetUserProgressByCategory(userID uint, category models.QuizCategory) (*models.UserProgress, error) {
-	var progress models.UserProgress
-	if err := s.db.Where("user_id = ? AND category = ?", userID, category).First(&progress).Error; err != nil {
+func (s *QuizService) GetProgressSummary(ctx context.Context, userID uint) (_ *models.ProgressSummary, err error) {
+	ctx, span := tracing.Start(ctx, "QuizService.GetProgressSummary")
+	defer tracing.End(span, &err)
+
+	totals, err := s.store.WithContext(ctx).Progress().TotalsByCategory(userID)
+	if err != nil {
 		return nil, err
 	}
-	return &progress, nil
+
+	summary := &models.ProgressSummary{
+		Overall:    summarizeProgress("", totals),
+		Categories: []models.CategoryProgress{},
+	}
+	for _, category := range enums.ValidCategories() {
+		if c := summarizeProgress(category, totals); c.QuizzesAttempted > 0 || c.QuizzesPublished > 0 {
+			summary.Categories = append(summary.Categories, c)
+		}
+	}
+	return summary, nil
 }
 
-func (s *QuizService) GetQuizzes() ([]models.Quiz, error) {
-	var quizzes []models.Quiz
-	if err := s.db.Preload("Questions.Answers").Find(&quizzes).Error; err != nil {
-		return nil, err
+// summarizeProgress adds up the totals of a category, or of all categories
+// when it's empty. Averages are weighted by attempts, so a quiz taken ten
+// times counts ten times as much as one taken once, and the coverage only
+// counts the quizzes tried that are still published.
+func summarizeProgress(category enums.QuizCategory, totals []repository.ProgressTotals) models.CategoryProgress {
+	summary := models.CategoryProgress{Category: category}
+	var scores, mastery float64
+	var covered int
+	for _, t := range totals {
+		if category != "" && t.Category != category {
+			continue
+		}
+		summary.QuizzesAttempted += t.QuizzesAttempted
+		summary.QuizzesPublished += t.QuizzesPublished
+		covered += t.QuizzesCovered
+		summary.TotalAttempts += t.TotalAttempts
+		summary.TotalTimeSpent += t.TotalTimeSpent
+		summary.BestScore = max(summary.BestScore, t.BestScore)
+		scores += t.ScoreSum
+		mastery += t.MasterySum
+		if t.LastAttemptedAt != nil && (summary.LastAttemptedAt == nil || t.LastAttemptedAt.After(*summary.LastAttemptedAt)) {
+			summary.LastAttemptedAt = t.LastAttemptedAt
+		}
 	}
-	return quizzes, nil
+
+	if summary.TotalAttempts > 0 {
+		summary.AverageScore = round2(scores / float64(summary.TotalAttempts))
+		summary.MasteryLevel = round2(mastery / float64(summary.TotalAttempts))
+	}
+	if summary.QuizzesPublished > 0 {
+		summary.Coverage = round2(float64(covered) * 100 / float64(summary.QuizzesPublished))
+	}
+	return summary
+}
+
+// round2 rounds to two decimals, the precision scores are stored with
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+func (s *QuizService) ListQuizzes(ctx context.Context, filter repository.QuizFilter, page repository.Page) (_ repository.List[models.QuizSummary], err error) {
+	ctx, span := tracing.Start(ctx, "QuizService.ListQuizzes")
+	defer tracing.End(span, &err)
+
+	return s.store.WithContext(ctx).Quizzes().Find(filter, page)
 }
 
 func calculateMasteryLevel(score float64) int {
@@ -137,18 +261,20 @@ func calculateMasteryLevel(score float64) int {
 	}
 }
 
-func (s *QuizService) GetUserResults(userID uint) ([]models.Result, error) {
-	var results []models.Result
-	if err := s.db.Where("user_id = ?", userID).Find(&results).Error; err != nil {
-		return nil, err
-	}
-	return results, nil
+func (s *QuizService) ListResults(ctx context.Context, filter repository.ResultFilter, page repository.Page) (_ repository.List[models.ResultSummary], err error) {
+	ctx, span := tracing.Start(ctx, "QuizService.ListResults")
+	defer tracing.End(span, &err)
+
+	return s.store.WithContext(ctx).Results().Find(filter, page)
 }
 
-func (s *QuizService) GetResultByID(id uint) (*models.Result, error) {
-	var result models.Result
-	if err := s.db.First(&result, id).Error; err != nil {
-		return nil, err
+func (s *QuizService) GetResultByID(ctx context.Context, id uint) (_ *models.Result, err error) {
+	ctx, span := tracing.Start(ctx, "QuizService.GetResultByID")
+	defer tracing.End(span, &err)
+
+	result, err := s.store.WithContext(ctx).Results().GetByID(id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrResultNotFound
 	}
-	return &result, nil
+	return result, err
 }
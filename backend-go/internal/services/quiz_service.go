@@ -1,36 +1,45 @@
 package services
 
-import "aicg/internal/models"
+import (
+	"context"
+
+	"aicg/internal/models"
+	"aicg/internal/models/enums"
+	"aicg/internal/repository"
+)
 
 // IQuizService defines the interface for quiz-related operations
 type IQuizService interface {
-	// GetQuizzes retrieves all quizzes
-	GetQuizzes() ([]models.Quiz, error)
+	// ListQuizzes retrieves a page of the quizzes matching the filter, without their questions
+	ListQuizzes(ctx context.Context, filter repository.QuizFilter, page repository.Page) (repository.List[models.QuizSummary], error)
 
 	// GetQuizByID retrieves a specific quiz by its ID
-	GetQuizByID(id uint) (*models.Quiz, error)
+	GetQuizByID(ctx context.Context, id uint) (*models.Quiz, error)
 
 	// CreateQuiz creates a new quiz
-	CreateQuiz(quiz *models.Quiz) error
+	CreateQuiz(ctx context.Context, quiz *models.Quiz) error
 
 	// GetQuizzesByCategory retrieves quizzes filtered by category
-	GetQuizzesByCategory(category models.QuizCategory) ([]models.Quiz, error)
+	GetQuizzesByCategory(ctx context.Context, category enums.QuizCategory) ([]models.Quiz, error)
 
 	// GetQuizzesByDifficulty retrieves quizzes filtered by difficulty
-	GetQuizzesByDifficulty(difficulty models.QuizDifficulty) ([]models.Quiz, error)
+	GetQuizzesByDifficulty(ctx context.Context, difficulty enums.QuizDifficulty) ([]models.Quiz, error)
 
 	// SubmitQuizResult saves a quiz result
-	SubmitQuizResult(result *models.Result) error
+	SubmitQuizResult(ctx context.Context, result *models.Result) error
 
-	// GetUserResults retrieves all quiz results for a user
-	GetUserResults(userID uint) ([]models.Result, error)
+	// ListResults retrieves a page of the quiz results matching the filter, without their answers
+	ListResults(ctx context.Context, filter repository.ResultFilter, page repository.Page) (repository.List[models.ResultSummary], error)
 
 	// GetResultByID retrieves a specific quiz result by its ID
-	GetResultByID(id uint) (*models.Result, error)
+	GetResultByID(ctx context.Context, id uint) (*models.Result, error)
 
-	// GetUserProgress retrieves a user's progress across all quizzes
-	GetUserProgress(userID uint) ([]models.UserProgress, error)
+	// GetUserProgressByCategory sums up a user's progress on all quizzes of a category
+	GetUserProgressByCategory(ctx context.Context, userID uint, category enums.QuizCategory) (*models.CategoryProgress, error)
 
-	// GetUserProgressByCategory retrieves a user's progress for a specific category
-	GetUserProgressByCategory(userID uint, category models.QuizCategory) (*models.UserProgress, error)
+	// GetProgressSummary sums up a user's progress overall and in each category
+	GetProgressSummary(ctx context.Context, userID uint) (*models.ProgressSummary, error)
 }
+
+// Ensure QuizService implements IQuizService
+var _ IQuizService = (*QuizService)(nil)
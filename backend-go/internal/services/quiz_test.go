@@ -1,207 +1,413 @@
 package services
 
 import (
-	"regexp"
+	"context"
+	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 
+	"aicg/internal/config"
+	"aicg/internal/database"
 	"aicg/internal/models"
+	"aicg/internal/models/enums"
+	"aicg/internal/repository"
 
-	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/assert"
-	"gorm.io/driver/mysql"
-	"gorm.io/gorm"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/logger"
 )
 
-func setupTestDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock, *QuizService) {
-	mockDB, mock, err := sqlmock.New()
-	if err != nil {
-		t.Fatalf("Failed to create mock DB: %v", err)
-	}
-
-	dialector := mysql.New(mysql.Config{
-		Conn:                      mockDB,
-		SkipInitializeWithVersion: true,
-	})
+func setupQuizTest(t *testing.T) (*repository.MemoryStore, *QuizService) {
+	store := repository.NewMemoryStore()
+	return store, NewQuizService(store)
+}
 
-	db, err := gorm.Open(dialector, &gorm.Config{})
-	if err != nil {
-		t.Fatalf("Failed to open GORM DB: %v", err)
+// createTestQuiz stores a published quiz with one question
+func createTestQuiz(t *testing.T, store repository.Store, category enums.QuizCategory, difficulty enums.QuizDifficulty) *models.Quiz {
+	quiz := &models.Quiz{
+		Title:        "Test Quiz",
+		Description:  "Test Description",
+		Category:     category,
+		Difficulty:   difficulty,
+		TimeLimit:    10,
+		CreatedBy:    1,
+		IsPublished:  true,
+		PassingScore: 60,
+		Questions: []models.Question{{
+			Text:          "Test Question",
+			Type:          enums.QuestionTypeMultipleChoice,
+			CorrectAnswer: "A",
+			Answers:       []models.Answer{{Text: "A", IsCorrect: true}, {Text: "B"}},
+		}},
 	}
-
-	return db, mock, NewQuizService(db)
+	require.NoError(t, store.Quizzes().Create(quiz))
+	return quiz
 }
 
-func TestGetQuizzes(t *testing.T) {
-	_, mock, service := setupTestDB(t)
-
-	// Mock quizzes query
-	quizRows := sqlmock.NewRows([]string{"id", "title", "description", "deleted_at"}).
-		AddRow(1, "Test Quiz", "Test Description", nil)
-
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `quizzes` WHERE `quizzes`.`deleted_at` IS NULL")).
-		WillReturnRows(quizRows)
-
-	// Mock questions query
-	questionRows := sqlmock.NewRows([]string{"id", "quiz_id", "text", "correct_answer", "deleted_at"}).
-		AddRow(1, 1, "Test Question", "A", nil)
-
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `questions` WHERE `questions`.`quiz_id` = ? AND `questions`.`deleted_at` IS NULL")).
-		WithArgs(1).
-		WillReturnRows(questionRows)
+func TestListQuizzes(t *testing.T) {
+	store, service := setupQuizTest(t)
+	createTestQuiz(t, store, enums.CategoryMath, enums.DifficultyEasy)
 
-	// Mock answers query
-	answerRows := sqlmock.NewRows([]string{"id", "question_id", "text", "is_correct", "deleted_at"}).
-		AddRow(1, 1, "Answer A", true, nil)
-
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `answers` WHERE `answers`.`question_id` = ? AND `answers`.`deleted_at` IS NULL")).
-		WithArgs(1).
-		WillReturnRows(answerRows)
-
-	quizzes, err := service.GetQuizzes()
+	quizzes, err := service.ListQuizzes(context.Background(), repository.QuizFilter{}, repository.Page{})
 	assert.NoError(t, err)
-	assert.Len(t, quizzes, 1)
-	assert.Equal(t, "Test Quiz", quizzes[0].Title)
+	require.Len(t, quizzes.Items, 1)
+	assert.Equal(t, "Test Quiz", quizzes.Items[0].Title)
+	assert.Equal(t, 1, quizzes.Items[0].QuestionCount)
 }
 
 func TestGetQuizByID(t *testing.T) {
-	_, mock, service := setupTestDB(t)
-
-	// Mock quiz query
-	quizRows := sqlmock.NewRows([]string{"id", "title", "description", "deleted_at"}).
-		AddRow(1, "Test Quiz", "Test Description", nil)
-
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `quizzes` WHERE `quizzes`.`id` = ? AND `quizzes`.`deleted_at` IS NULL ORDER BY `quizzes`.`id` LIMIT ?")).
-		WithArgs(1, 1).
-		WillReturnRows(quizRows)
-
-	// Mock questions query
-	questionRows := sqlmock.NewRows([]string{"id", "quiz_id", "text", "correct_answer", "deleted_at"}).
-		AddRow(1, 1, "Test Question", "A", nil)
+	store, service := setupQuizTest(t)
+	created := createTestQuiz(t, store, enums.CategoryMath, enums.DifficultyEasy)
 
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `questions` WHERE `questions`.`quiz_id` = ? AND `questions`.`deleted_at` IS NULL")).
-		WithArgs(1).
-		WillReturnRows(questionRows)
-
-	// Mock answers query
-	answerRows := sqlmock.NewRows([]string{"id", "question_id", "text", "is_correct", "deleted_at"}).
-		AddRow(1, 1, "Answer A", true, nil)
-
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `answers` WHERE `answers`.`question_id` = ? AND `answers`.`deleted_at` IS NULL")).
-		WithArgs(1).
-		WillReturnRows(answerRows)
-
-	quiz, err := service.GetQuizByID(1)
+	quiz, err := service.GetQuizByID(context.Background(), created.ID)
 	assert.NoError(t, err)
 	assert.NotNil(t, quiz)
 	assert.Equal(t, "Test Quiz", quiz.Title)
+	assert.Len(t, quiz.Questions, 1)
 
 	// Test case 2: Quiz not found
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `quizzes` WHERE `quizzes`.`id` = ? AND `quizzes`.`deleted_at` IS NULL ORDER BY `quizzes`.`id` LIMIT ?")).
-		WithArgs(2, 1).
-		WillReturnError(gorm.ErrRecordNotFound)
-
-	quiz, err = service.GetQuizByID(2)
-	assert.Error(t, err)
+	quiz, err = service.GetQuizByID(context.Background(), created.ID+1)
+	assert.ErrorIs(t, err, ErrQuizNotFound)
 	assert.Nil(t, quiz)
 }
 
 func TestCreateQuiz(t *testing.T) {
-	_, mock, service := setupTestDB(t)
+	store, service := setupQuizTest(t)
 
 	quiz := &models.Quiz{
 		Title:       "New Quiz",
 		Description: "New Description",
-		Category:    models.CategoryMath,
-		Difficulty:  models.DifficultyEasy,
+		Category:    enums.CategoryMath,
+		Difficulty:  enums.DifficultyEasy,
 	}
 
-	mock.ExpectBegin()
-	mock.ExpectExec("^INSERT INTO `quizzes`").
-		WillReturnResult(sqlmock.NewResult(1, 1))
-	mock.ExpectCommit()
+	err := service.CreateQuiz(context.Background(), quiz)
+	assert.NoError(t, err)
+	assert.NotZero(t, quiz.ID)
 
-	err := service.CreateQuiz(quiz)
+	stored, err := store.Quizzes().GetByID(quiz.ID)
 	assert.NoError(t, err)
+	assert.Equal(t, "New Quiz", stored.Title)
 }
 
 func TestGetQuizzesByCategory(t *testing.T) {
-	_, mock, service := setupTestDB(t)
+	store, service := setupQuizTest(t)
+	math := createTestQuiz(t, store, enums.CategoryMath, enums.DifficultyEasy)
+	createTestQuiz(t, store, enums.CategoryScience, enums.DifficultyEasy)
+	require.NoError(t, store.Quizzes().Create(&models.Quiz{Title: "Draft", Category: enums.CategoryMath}))
 
-	rows := sqlmock.NewRows([]string{"id", "title", "category"}).
-		AddRow(1, "Math Quiz", "math")
+	quizzes, err := service.GetQuizzesByCategory(context.Background(), enums.CategoryMath)
+	assert.NoError(t, err)
+	require.Len(t, quizzes, 1)
+	assert.Equal(t, math.ID, quizzes[0].ID)
+}
 
-	mock.ExpectQuery("^SELECT (.+) FROM `quizzes`").
-		WithArgs("math", true).
-		WillReturnRows(rows)
+func TestGetQuizzesByDifficulty(t *testing.T) {
+	store, service := setupQuizTest(t)
+	createTestQuiz(t, store, enums.CategoryMath, enums.DifficultyEasy)
+	hard := createTestQuiz(t, store, enums.CategoryMath, enums.DifficultyHard)
 
-	quizzes, err := service.GetQuizzesByCategory(models.CategoryMath)
+	quizzes, err := service.GetQuizzesByDifficulty(context.Background(), enums.DifficultyHard)
 	assert.NoError(t, err)
-	assert.Len(t, quizzes, 1)
-	assert.Equal(t, "Math Quiz", quizzes[0].Title)
+	require.Len(t, quizzes, 1)
+	assert.Equal(t, hard.ID, quizzes[0].ID)
 }
 
 func TestSubmitQuizResult(t *testing.T) {
-	_, mock, service := setupTestDB(t)
-
-	result := &models.Result{
-		QuizID:         1,
-		UserID:         1,
-		Score:          85,
-		TotalQuestions: 10,
-		TimeTaken:      300,
-	}
+	store, service := setupQuizTest(t)
+	quiz := createTestQuiz(t, store, enums.CategoryMath, enums.DifficultyEasy)
 
-	// Mock quiz retrieval
-	quizRows := sqlmock.NewRows([]string{"id", "title", "category"}).
-		AddRow(1, "Test Quiz", "math")
-
-	mock.ExpectQuery("^SELECT (.+) FROM `quizzes`").
-		WithArgs(1).
-		WillReturnRows(quizRows)
-
-	// Mock transaction
-	mock.ExpectBegin()
-	mock.ExpectExec("^INSERT INTO `results`").
-		WillReturnResult(sqlmock.NewResult(1, 1))
-	mock.ExpectQuery("^SELECT (.+) FROM `user_progress`").
-		WithArgs(1, 1).
-		WillReturnError(gorm.ErrRecordNotFound)
-	mock.ExpectExec("^INSERT INTO `user_progress`").
-		WillReturnResult(sqlmock.NewResult(1, 1))
-	mock.ExpectCommit()
-
-	err := service.SubmitQuizResult(result)
+	// Test case 1: The first attempt creates the progress
+	result := &models.Result{QuizID: quiz.ID, UserID: 1, Score: 60, TotalQuestions: 10, TimeTaken: 300}
+	err := service.SubmitQuizResult(context.Background(), result)
 	assert.NoError(t, err)
+	assert.NotZero(t, result.ID)
+
+	progress, err := service.GetUserProgressByCategory(context.Background(), 1, enums.CategoryMath)
+	require.NoError(t, err)
+	assert.Equal(t, 1, progress.TotalAttempts)
+	assert.Equal(t, 60.0, progress.BestScore)
+	assert.Equal(t, 2.0, progress.MasteryLevel)
+
+	// Test case 2: Later attempts update it
+	err = service.SubmitQuizResult(context.Background(), &models.Result{QuizID: quiz.ID, UserID: 1, Score: 100, TotalQuestions: 10, TimeTaken: 200})
+	assert.NoError(t, err)
+
+	all, err := store.Progress().ListByUser(1)
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+	assert.Equal(t, 2, all[0].TotalAttempts)
+	assert.Equal(t, 500, all[0].TotalTimeSpent)
+	assert.Equal(t, 80.0, all[0].AverageScore)
+	assert.Equal(t, 100.0, all[0].BestScore)
+	assert.Equal(t, 4, all[0].MasteryLevel)
+
+	// Test case 3: Unknown quiz stores nothing
+	err = service.SubmitQuizResult(context.Background(), &models.Result{QuizID: quiz.ID + 1, UserID: 1, Score: 50})
+	assert.ErrorIs(t, err, ErrQuizNotFound)
+
+	results, err := service.ListResults(context.Background(), repository.ResultFilter{UserID: 1}, repository.Page{})
+	require.NoError(t, err)
+	assert.Len(t, results.Items, 2)
 }
 
-func TestGetUserResults(t *testing.T) {
-	_, mock, service := setupTestDB(t)
+// racyStore runs transactions without isolating them, as a database would
+// between reading a row and updating it, and pauses after reading progress
+// so that concurrent submissions all read it before any of them saves it.
+// Like a database, it only keeps the results of transactions that succeed.
+type racyStore struct {
+	*repository.MemoryStore
+	conflicts *int // When set, every progress save conflicts and is counted here
+}
 
-	rows := sqlmock.NewRows([]string{"id", "quiz_id", "user_id", "score"}).
-		AddRow(1, 1, 1, 85)
+func (s racyStore) Progress() repository.ProgressRepository {
+	if s.conflicts != nil {
+		return conflictingProgress{s.MemoryStore.Progress(), s.conflicts}
+	}
+	return slowProgress{s.MemoryStore.Progress()}
+}
 
-	mock.ExpectQuery("^SELECT (.+) FROM `results`").
-		WithArgs(1).
-		WillReturnRows(rows)
+func (s racyStore) Transaction(fn func(tx repository.Store) error) error {
+	tx := &racyTx{racyStore: s}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	for _, result := range tx.results {
+		if err := s.MemoryStore.Results().Create(result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	results, err := service.GetUserResults(1)
-	assert.NoError(t, err)
-	assert.Len(t, results, 1)
-	assert.Equal(t, float64(85), results[0].Score)
+func (s racyStore) WithContext(ctx context.Context) repository.Store {
+	return s
 }
 
-func TestGetResultByID(t *testing.T) {
-	_, mock, service := setupTestDB(t)
+// racyTx holds the results created in a transaction back until it succeeds
+type racyTx struct {
+	racyStore
+	results []*models.Result
+}
 
-	rows := sqlmock.NewRows([]string{"id", "quiz_id", "user_id", "score"}).
-		AddRow(1, 1, 1, 85)
+func (tx *racyTx) Results() repository.ResultRepository {
+	return racyResults{tx.racyStore.Results(), tx}
+}
+
+type racyResults struct {
+	repository.ResultRepository
+	tx *racyTx
+}
+
+func (r racyResults) Create(result *models.Result) error {
+	r.tx.results = append(r.tx.results, result)
+	return nil
+}
+
+type slowProgress struct {
+	repository.ProgressRepository
+}
+
+func (p slowProgress) Get(userID, quizID uint) (*models.UserProgress, error) {
+	progress, err := p.ProgressRepository.Get(userID, quizID)
+	time.Sleep(5 * time.Millisecond)
+	return progress, err
+}
+
+type conflictingProgress struct {
+	repository.ProgressRepository
+	saves *int
+}
+
+func (p conflictingProgress) Save(*models.UserProgress) error {
+	*p.saves++
+	return repository.ErrConflict
+}
+
+func TestGetProgressSummary(t *testing.T) {
+	store, service := setupQuizTest(t)
+	ctx := context.Background()
+	algebra := createTestQuiz(t, store, enums.CategoryMath, enums.DifficultyEasy)
+	geometry := createTestQuiz(t, store, enums.CategoryMath, enums.DifficultyHard)
+	createTestQuiz(t, store, enums.CategoryScience, enums.DifficultyEasy)
+	draft := &models.Quiz{Title: "Draft", Category: enums.CategoryHistory, Difficulty: enums.DifficultyEasy, CreatedBy: 1, PassingScore: 60}
+	require.NoError(t, store.Quizzes().Create(draft))
+
+	for _, result := range []models.Result{
+		{QuizID: algebra.ID, UserID: 1, Score: 60, TimeTaken: 100},
+		{QuizID: algebra.ID, UserID: 1, Score: 100, TimeTaken: 50},
+		{QuizID: geometry.ID, UserID: 1, Score: 90, TimeTaken: 30},
+		{QuizID: draft.ID, UserID: 1, Score: 50, TimeTaken: 20},
+		{QuizID: geometry.ID, UserID: 2, Score: 10, TimeTaken: 1000},
+	} {
+		require.NoError(t, service.SubmitQuizResult(ctx, &result))
+	}
+
+	// Test case 1: Categories add up their quizzes, weighted by attempts
+	summary, err := service.GetProgressSummary(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, summary.Categories, 3)
+	math := summary.Categories[2]
+	assert.Equal(t, enums.CategoryMath, math.Category)
+	assert.Equal(t, 2, math.QuizzesAttempted)
+	assert.Equal(t, 2, math.QuizzesPublished)
+	assert.Equal(t, 100.0, math.Coverage)
+	assert.Equal(t, 3, math.TotalAttempts)
+	assert.Equal(t, 100.0, math.BestScore)
+	assert.Equal(t, 83.33, math.AverageScore)
+	assert.Equal(t, 180, math.TotalTimeSpent)
+	assert.Equal(t, 4.33, math.MasteryLevel)
+	assert.NotNil(t, math.LastAttemptedAt)
+
+	// Test case 2: Categories not tried yet count their published quizzes
+	science := summary.Categories[0]
+	assert.Equal(t, models.CategoryProgress{Category: enums.CategoryScience, QuizzesPublished: 1}, science)
+
+	// Test case 3: Unpublished quizzes count as attempts, but not towards coverage
+	history := summary.Categories[1]
+	assert.Equal(t, enums.CategoryHistory, history.Category)
+	assert.Equal(t, 1, history.QuizzesAttempted)
+	assert.Equal(t, 0, history.QuizzesPublished)
+	assert.Equal(t, 0.0, history.Coverage)
+	assert.Equal(t, 1.0, history.MasteryLevel)
+
+	// Test case 4: The overall progress spans every category
+	overall := summary.Overall
+	assert.Empty(t, overall.Category)
+	assert.Equal(t, 3, overall.QuizzesAttempted)
+	assert.Equal(t, 3, overall.QuizzesPublished)
+	assert.Equal(t, 66.67, overall.Coverage)
+	assert.Equal(t, 4, overall.TotalAttempts)
+	assert.Equal(t, 75.0, overall.AverageScore)
+	assert.Equal(t, 200, overall.TotalTimeSpent)
+	assert.Equal(t, 3.5, overall.MasteryLevel)
+
+	// Test case 5: A single category sums up the same
+	progress, err := service.GetUserProgressByCategory(ctx, 1, enums.CategoryMath)
+	require.NoError(t, err)
+	assert.Equal(t, math, *progress)
+
+	// Test case 6: Users who tried nothing have no attempts
+	summary, err = service.GetProgressSummary(ctx, 3)
+	require.NoError(t, err)
+	assert.Equal(t, 0, summary.Overall.TotalAttempts)
+	assert.Nil(t, summary.Overall.LastAttemptedAt)
+	assert.Equal(t, 0.0, summary.Overall.Coverage)
+	assert.Len(t, summary.Categories, 2)
+}
 
-	mock.ExpectQuery("^SELECT (.+) FROM `results`").
-		WithArgs(1).
-		WillReturnRows(rows)
+func TestSubmitQuizResultConcurrently(t *testing.T) {
+	stores := map[string]func(t *testing.T) repository.Store{
+		"racy": func(t *testing.T) repository.Store {
+			return racyStore{MemoryStore: repository.NewMemoryStore()}
+		},
+		"sqlite": func(t *testing.T) repository.Store {
+			db, err := database.InitDB(&config.Config{DB: config.DBConfig{Driver: database.DriverSQLite, Path: filepath.Join(t.TempDir(), "test.db")}})
+			require.NoError(t, err)
+			db.Logger = logger.Discard
+			migrator, err := database.NewMigrator(db)
+			require.NoError(t, err)
+			_, err = migrator.Up(context.Background())
+			require.NoError(t, err)
+			return repository.NewGormStore(db)
+		},
+	}
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			store := newStore(t)
+			require.NoError(t, store.Users().Create(&models.User{Email: "ada@example.com", Role: enums.RoleMaveric}))
+			service := NewQuizService(store)
+			quiz := createTestQuiz(t, store, enums.CategoryMath, enums.DifficultyEasy)
+
+			// Test case 1: Parallel submissions are each stored and counted once in the progress
+			var wg sync.WaitGroup
+			for i := 1; i <= 8; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					result := &models.Result{QuizID: quiz.ID, UserID: 1, Score: float64(10 * i), TotalQuestions: 10, TimeTaken: i}
+					assert.NoError(t, service.SubmitQuizResult(context.Background(), result))
+				}()
+			}
+			wg.Wait()
+
+			results, err := service.ListResults(context.Background(), repository.ResultFilter{UserID: 1}, repository.Page{})
+			require.NoError(t, err)
+			assert.Len(t, results.Items, 8)
+			progress, err := store.Progress().ListByUser(1)
+			require.NoError(t, err)
+			require.Len(t, progress, 1)
+			assert.Equal(t, 8, progress[0].TotalAttempts)
+			assert.Equal(t, 36, progress[0].TotalTimeSpent)
+			assert.InDelta(t, 45.0, progress[0].AverageScore, 1e-9)
+			assert.Equal(t, 80.0, progress[0].BestScore)
+			assert.Equal(t, 1, progress[0].MasteryLevel)
+		})
+	}
+}
+
+func TestSubmitQuizResultConflicts(t *testing.T) {
+	memory := repository.NewMemoryStore()
+	quiz := createTestQuiz(t, memory, enums.CategoryMath, enums.DifficultyEasy)
+	saves := 0
+	service := NewQuizService(racyStore{MemoryStore: memory, conflicts: &saves})
+
+	// Test case 1: A submission that keeps conflicting gives up, leaving no result behind
+	err := service.SubmitQuizResult(context.Background(), &models.Result{QuizID: quiz.ID, UserID: 1, Score: 50, TotalQuestions: 10})
+	assert.ErrorIs(t, err, ErrSubmissionConflict)
+	assert.Equal(t, maxSubmitAttempts, saves)
+	results, err := service.ListResults(context.Background(), repository.ResultFilter{UserID: 1}, repository.Page{})
+	require.NoError(t, err)
+	assert.Empty(t, results.Items)
+
+	// Test case 2: It stops waiting to try again when the request is canceled
+	saves = 0
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = service.SubmitQuizResult(ctx, &models.Result{QuizID: quiz.ID, UserID: 1, Score: 50, TotalQuestions: 10})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, saves)
+}
+
+func TestSubmitBackoff(t *testing.T) {
+	for attempt := 1; attempt < maxSubmitAttempts; attempt++ {
+		d := submitBackoff(attempt)
+		assert.GreaterOrEqual(t, d, min(minSubmitBackoff<<(attempt-1), maxSubmitBackoff)/2, attempt)
+		assert.LessOrEqual(t, d, min(minSubmitBackoff<<(attempt-1), maxSubmitBackoff), attempt)
+	}
+}
+
+func TestListResults(t *testing.T) {
+	store, service := setupQuizTest(t)
+	quiz := createTestQuiz(t, store, enums.CategoryMath, enums.DifficultyEasy)
+	require.NoError(t, store.Results().Create(&models.Result{QuizID: quiz.ID, UserID: 1, Score: 85}))
+	require.NoError(t, store.Results().Create(&models.Result{QuizID: quiz.ID, UserID: 2, Score: 40}))
 
-	result, err := service.GetResultByID(1)
+	results, err := service.ListResults(context.Background(), repository.ResultFilter{UserID: 1}, repository.Page{})
+	assert.NoError(t, err)
+	require.Len(t, results.Items, 1)
+	assert.Equal(t, float64(85), results.Items[0].Score)
+	assert.Equal(t, "Test Quiz", results.Items[0].QuizTitle)
+
+	// Test case 2: Filtered by the quiz's category
+	results, err = service.ListResults(context.Background(), repository.ResultFilter{Category: enums.CategoryScience}, repository.Page{})
+	assert.NoError(t, err)
+	assert.Empty(t, results.Items)
+}
+
+func TestGetResultByID(t *testing.T) {
+	store, service := setupQuizTest(t)
+	created := &models.Result{QuizID: 1, UserID: 1, Score: 85}
+	require.NoError(t, store.Results().Create(created))
+
+	result, err := service.GetResultByID(context.Background(), created.ID)
 	assert.NoError(t, err)
 	assert.NotNil(t, result)
 	assert.Equal(t, float64(85), result.Score)
+
+	// Test case 2: Result not found
+	result, err = service.GetResultByID(context.Background(), created.ID+1)
+	assert.ErrorIs(t, err, ErrResultNotFound)
+	assert.Nil(t, result)
 }
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"aicg/internal/database"
+	"aicg/internal/models"
+	"aicg/internal/models/enums"
+	"aicg/internal/search"
+	"aicg/internal/tracing"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// maxSuggestions is how many corrected queries a search suggests
+	maxSuggestions = 3
+
+	// Weights of the searched text, in the ranking and the snippets
+	titleWeight       = 1.0
+	descriptionWeight = 0.4
+	questionWeight    = 0.2
+)
+
+// SearchQuery is a full-text search over the published quizzes and their questions
+type SearchQuery struct {
+	Text       string               // Words to find, all of them
+	Category   enums.QuizCategory   // Only quizzes in this category, if set
+	Difficulty enums.QuizDifficulty // Only quizzes of this difficulty, if set
+	Limit      int                  // How many hits to return, at most
+}
+
+// SearchHit is a quiz, or a question of one, matching a search
+type SearchHit struct {
+	Kind       string               `json:"kind"` // "quiz" or "question"
+	QuizID     uint                 `json:"quiz_id"`
+	QuestionID uint                 `json:"question_id,omitempty"`
+	Title      string               `json:"title"` // Of the quiz
+	Category   enums.QuizCategory   `json:"category"`
+	Difficulty enums.QuizDifficulty `json:"difficulty"`
+	Snippet    string               `json:"snippet"` // HTML-escaped matching text, with the matched words in <mark>
+	Score      float64              `json:"score"`
+}
+
+// SearchResults are the hits of a search, best first
+type SearchResults struct {
+	Query       string      `json:"query"`
+	Hits        []SearchHit `json:"hits"`
+	Suggestions []string    `json:"suggestions,omitempty"` // Corrected queries, when nothing matched because of a typo
+}
+
+// SearchService searches quizzes with Postgres full-text search, or with an
+// index kept in process on other databases. The in-process index also
+// supplies the spelling suggestions; it's rebuilt when quizzes or questions
+// have changed since it was built.
+type SearchService struct {
+	db *gorm.DB
+
+	mu          sync.Mutex
+	index       *search.Index[SearchHit]
+	fingerprint searchFingerprint
+}
+
+func NewSearchService(db *gorm.DB) *SearchService {
+	return &SearchService{db: db}
+}
+
+// Search finds the published quizzes and questions containing every word of the query
+func (s *SearchService) Search(ctx context.Context, query SearchQuery) (_ *SearchResults, err error) {
+	ctx, span := tracing.Start(ctx, "SearchService.Search")
+	defer tracing.End(span, &err)
+
+	var hits []SearchHit
+	if s.db.Dialector.Name() == database.DriverPostgres {
+		hits, err = s.searchPostgres(ctx, query)
+	} else {
+		hits, err = s.searchIndex(ctx, query)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	results := &SearchResults{Query: query.Text, Hits: hits}
+	if len(hits) == 0 {
+		index, err := s.currentIndex(ctx)
+		if err != nil {
+			return nil, err
+		}
+		results.Suggestions = index.Suggest(query.Text, maxSuggestions)
+	}
+	if results.Hits == nil {
+		results.Hits = []SearchHit{}
+	}
+	return results, nil
+}
+
+// searchIndex searches the in-process index
+func (s *SearchService) searchIndex(ctx context.Context, query SearchQuery) ([]SearchHit, error) {
+	index, err := s.currentIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	found := index.Search(query.Text, query.Limit, func(hit SearchHit) bool {
+		return (query.Category == "" || hit.Category == query.Category) &&
+			(query.Difficulty == "" || hit.Difficulty == query.Difficulty)
+	})
+	hits := make([]SearchHit, len(found))
+	for i, h := range found {
+		hits[i] = h.Doc
+		hits[i].Snippet = h.Snippet
+		hits[i].Score = h.Score
+	}
+	return hits, nil
+}
+
+// escapeHTML escapes a text column in SQL, so ts_headline's snippets are as safe as the index's
+func escapeHTML(column string) string {
+	return "replace(replace(replace(" + column + ", '&', '&amp;'), '<', '&lt;'), '>', '&gt;')"
+}
+
+// postgresSearch ranks matching quizzes and questions together. The
+// to_tsvector expressions in the WHERE clauses match the GIN indexes.
+var postgresSearch = `
+SELECT * FROM (
+	SELECT 'quiz' AS kind, quizzes.id AS quiz_id, 0 AS question_id, quizzes.title, quizzes.category, quizzes.difficulty,
+		ts_headline('english', ` + escapeHTML("quizzes.title || ' — ' || quizzes.description") + `, q.query, @options) AS snippet,
+		ts_rank(setweight(to_tsvector('english', quizzes.title), 'A') || setweight(to_tsvector('english', quizzes.description), 'B'), q.query) AS score
+	FROM quizzes CROSS JOIN websearch_to_tsquery('english', @text) AS q(query)
+	WHERE to_tsvector('english', quizzes.title || ' ' || quizzes.description) @@ q.query
+		AND quizzes.is_published AND quizzes.deleted_at IS NULL
+		AND (@category = '' OR quizzes.category = @category) AND (@difficulty = '' OR quizzes.difficulty = @difficulty)
+	UNION ALL
+	SELECT 'question', quizzes.id, questions.id, quizzes.title, quizzes.category, quizzes.difficulty,
+		ts_headline('english', ` + escapeHTML("questions.text") + `, q.query, @options),
+		ts_rank(setweight(to_tsvector('english', questions.text), 'C'), q.query)
+	FROM questions JOIN quizzes ON quizzes.id = questions.quiz_id CROSS JOIN websearch_to_tsquery('english', @text) AS q(query)
+	WHERE to_tsvector('english', questions.text) @@ q.query
+		AND questions.deleted_at IS NULL AND quizzes.is_published AND quizzes.deleted_at IS NULL
+		AND (@category = '' OR quizzes.category = @category) AND (@difficulty = '' OR quizzes.difficulty = @difficulty)
+) AS hits
+ORDER BY score DESC, quiz_id, question_id
+LIMIT @limit`
+
+// searchPostgres searches with Postgres full-text search
+func (s *SearchService) searchPostgres(ctx context.Context, query SearchQuery) ([]SearchHit, error) {
+	var hits []SearchHit
+	err := s.db.WithContext(ctx).Raw(postgresSearch, map[string]interface{}{
+		"text":       query.Text,
+		"category":   string(query.Category),
+		"difficulty": string(query.Difficulty),
+		"limit":      query.Limit,
+		"options":    "StartSel=<mark>, StopSel=</mark>, MaxWords=20, MinWords=8, MaxFragments=1",
+	}).Scan(&hits).Error
+	return hits, err
+}
+
+// searchFingerprint changes whenever a published quiz or a question is added, changed or removed
+type searchFingerprint struct {
+	Quizzes          int64
+	QuizzesUpdated   sql.NullString
+	Questions        int64
+	QuestionsUpdated sql.NullString
+}
+
+// currentIndex returns the in-process index, rebuilding it if it's out of date
+func (s *SearchService) currentIndex(ctx context.Context) (*search.Index[SearchHit], error) {
+	var fingerprint searchFingerprint
+	if err := s.db.WithContext(ctx).Raw(`SELECT
+		(SELECT COUNT(*) FROM quizzes WHERE is_published AND deleted_at IS NULL) AS quizzes,
+		(SELECT MAX(updated_at) FROM quizzes) AS quizzes_updated,
+		(SELECT COUNT(*) FROM questions WHERE deleted_at IS NULL) AS questions,
+		(SELECT MAX(updated_at) FROM questions) AS questions_updated`).Scan(&fingerprint).Error; err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.index != nil && s.fingerprint == fingerprint {
+		return s.index, nil
+	}
+
+	var quizzes []models.Quiz
+	if err := s.db.WithContext(ctx).Preload("Questions").Where("is_published = ?", true).Order("id").Find(&quizzes).Error; err != nil {
+		return nil, err
+	}
+	index := search.NewIndex[SearchHit]()
+	for _, quiz := range quizzes {
+		hit := SearchHit{Kind: "quiz", QuizID: quiz.ID, Title: quiz.Title, Category: quiz.Category, Difficulty: quiz.Difficulty}
+		index.Add(hit, search.Field{Text: quiz.Title, Weight: titleWeight}, search.Field{Text: quiz.Description, Weight: descriptionWeight})
+		for _, question := range quiz.Questions {
+			hit.Kind, hit.QuestionID = "question", question.ID
+			index.Add(hit, search.Field{Text: question.Text, Weight: questionWeight})
+		}
+	}
+	s.index, s.fingerprint = index, fingerprint
+	return index, nil
+}
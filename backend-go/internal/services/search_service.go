@@ -0,0 +1,12 @@
+package services
+
+import "context"
+
+// ISearchService defines the interface for searching quizzes
+type ISearchService interface {
+	// Search finds the published quizzes and questions matching a query, with suggestions for typos
+	Search(ctx context.Context, query SearchQuery) (*SearchResults, error)
+}
+
+// Ensure SearchService implements ISearchService
+var _ ISearchService = (*SearchService)(nil)
@@ -0,0 +1,294 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"aicg/internal/models"
+	"aicg/internal/models/enums"
+	"aicg/internal/repository"
+	"aicg/internal/storage"
+	"aicg/internal/tracing"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrUserNotFound is returned when no (non-deleted) user matches the given ID
+	ErrUserNotFound = NotFound("user_not_found", "user not found")
+	// ErrInvalidLocale is returned when a locale is not a well-formed language tag
+	ErrInvalidLocale = Invalid("invalid_locale", "invalid locale",
+		FieldError{Field: "locale", Code: "locale", Message: "must be a language tag such as en or pt-BR"})
+	// ErrInvalidTimeZone is returned when a time zone is not a known IANA zone
+	ErrInvalidTimeZone = Invalid("invalid_time_zone", "invalid time zone",
+		FieldError{Field: "time_zone", Code: "time_zone", Message: "must be an IANA time zone such as Europe/Berlin"})
+	// ErrInvalidProfileImage is returned when a profile image URL isn't one of the user's uploads
+	ErrInvalidProfileImage = Invalid("invalid_profile_image", "invalid profile image",
+		FieldError{Field: "profile_image", Code: "upload", Message: "must be empty or the URL of an uploaded profile image"})
+	// ErrInvalidRole is returned when a role name is not one we support
+	ErrInvalidRole = Invalid("invalid_role", "invalid role",
+		FieldError{Field: "role", Code: "oneof", Message: "must be super_admin or maveric"})
+)
+
+// localePattern accepts BCP 47 style tags such as "en", "en-US" or "zh-Hant-TW"
+var localePattern = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// ProfileUpdate holds the profile fields a user may change about themselves.
+// Nil fields are left untouched.
+type ProfileUpdate struct {
+	FirstName    *string `json:"first_name"`
+	LastName     *string `json:"last_name"`
+	ProfileImage *string `json:"profile_image"` // Empty, or the URL of one of the user's uploaded images
+	Locale       *string `json:"locale"`
+	TimeZone     *string `json:"time_zone"`
+}
+
+// UserFilter narrows down the admin user listing
+type UserFilter struct {
+	Search   string         // Matches email, first or last name (case-insensitive)
+	Role     enums.UserRole // Only users with this role, if set
+	IsActive *bool          // Only active/inactive users, if set
+	From     time.Time      // Only users who signed up at or after this time, if set
+	To       time.Time      // Only users who signed up before this time, if set
+}
+
+type UserService struct {
+	db    *gorm.DB
+	store storage.BlobStore
+}
+
+func NewUserService(db *gorm.DB, store storage.BlobStore) *UserService {
+	return &UserService{db: db, store: store}
+}
+
+// GetUserByID returns a user that has not been deleted
+func (s *UserService) GetUserByID(ctx context.Context, id uint) (_ *models.User, err error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetUserByID")
+	defer tracing.End(span, &err)
+
+	var user models.User
+	if err := s.db.WithContext(ctx).First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// UpdateProfile applies the non-nil fields of update to the user's profile
+func (s *UserService) UpdateProfile(ctx context.Context, id uint, update ProfileUpdate) (_ *models.User, err error) {
+	ctx, span := tracing.Start(ctx, "UserService.UpdateProfile")
+	defer tracing.End(span, &err)
+
+	user, err := s.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]interface{}{}
+	if update.FirstName != nil {
+		changes["first_name"] = strings.TrimSpace(*update.FirstName)
+	}
+	if update.LastName != nil {
+		changes["last_name"] = strings.TrimSpace(*update.LastName)
+	}
+	if update.ProfileImage != nil {
+		// Uploads set the image; only one of theirs, or none, may be picked here
+		image := strings.TrimSpace(*update.ProfileImage)
+		if image != "" {
+			key, err := blobKey(ctx, s.store, ProfileImagePrefix(id), image)
+			if err != nil {
+				return nil, err
+			}
+			if key == "" {
+				return nil, ErrInvalidProfileImage
+			}
+		}
+		changes["profile_image"] = image
+	}
+	if update.Locale != nil {
+		locale := strings.TrimSpace(*update.Locale)
+		if locale != "" && !localePattern.MatchString(locale) {
+			return nil, ErrInvalidLocale
+		}
+		changes["locale"] = locale
+	}
+	if update.TimeZone != nil {
+		tz := strings.TrimSpace(*update.TimeZone)
+		if tz != "" {
+			if _, err := time.LoadLocation(tz); err != nil {
+				return nil, ErrInvalidTimeZone
+			}
+		}
+		changes["time_zone"] = tz
+	}
+
+	if len(changes) == 0 {
+		return user, nil
+	}
+
+	if err := s.db.WithContext(ctx).Model(user).Updates(changes).Error; err != nil {
+		return nil, err
+	}
+	return s.GetUserByID(ctx, id)
+}
+
+// ListUsers returns one page of users matching the filter
+func (s *UserService) ListUsers(ctx context.Context, filter UserFilter, page repository.Page) (_ repository.List[models.User], err error) {
+	ctx, span := tracing.Start(ctx, "UserService.ListUsers")
+	defer tracing.End(span, &err)
+
+	query := s.db.WithContext(ctx).Model(&models.User{})
+	if search := strings.TrimSpace(filter.Search); search != "" {
+		pattern := "%" + strings.ToLower(search) + "%"
+		query = query.Where("LOWER(email) LIKE ? OR LOWER(first_name) LIKE ? OR LOWER(last_name) LIKE ?", pattern, pattern, pattern)
+	}
+	if filter.Role != "" {
+		query = query.Where("role = ?", filter.Role)
+	}
+	if filter.IsActive != nil {
+		query = query.Where("is_active = ?", *filter.IsActive)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("created_at < ?", filter.To)
+	}
+
+	var users []models.User
+	if err := repository.UserSorting.Paginate(query, page).Find(&users).Error; err != nil {
+		return repository.List[models.User]{}, err
+	}
+	return repository.UserSorting.List(users, page), nil
+}
+
+// SetUserActive activates or deactivates a user.
+// Deactivating also revokes the user's refresh token so they can't renew their session,
+// and the auth middleware refuses their access tokens from then on.
+func (s *UserService) SetUserActive(ctx context.Context, id uint, active bool) (_ *models.User, err error) {
+	ctx, span := tracing.Start(ctx, "UserService.SetUserActive")
+	defer tracing.End(span, &err)
+
+	user, err := s.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]interface{}{"is_active": active}
+	if !active {
+		changes["refresh_token"] = ""
+	}
+	if err := s.db.WithContext(ctx).Model(user).Updates(changes).Error; err != nil {
+		return nil, err
+	}
+	return s.GetUserByID(ctx, id)
+}
+
+// ChangeUserRole gives a user a new role
+func (s *UserService) ChangeUserRole(ctx context.Context, id uint, role enums.UserRole) (_ *models.User, err error) {
+	ctx, span := tracing.Start(ctx, "UserService.ChangeUserRole")
+	defer tracing.End(span, &err)
+
+	if !role.IsValid() {
+		return nil, ErrInvalidRole
+	}
+
+	user, err := s.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.db.WithContext(ctx).Model(user).Update("role", role).Error; err != nil {
+		return nil, err
+	}
+	return s.GetUserByID(ctx, id)
+}
+
+// DeleteUser soft-deletes a user. Their data stays in the database and can be restored.
+func (s *UserService) DeleteUser(ctx context.Context, id uint) (err error) {
+	ctx, span := tracing.Start(ctx, "UserService.DeleteUser")
+	defer tracing.End(span, &err)
+
+	user, err := s.GetUserByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Updates(map[string]interface{}{
+			"is_active":     false,
+			"refresh_token": "",
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(user).Error
+	})
+}
+
+// EraseUser permanently removes a user's personal data (GDPR right to erasure).
+// The user row is kept, anonymized and soft-deleted, so results, progress and
+// rankings that reference it still count towards aggregate statistics.
+// Their data exports and profile images are deleted, files included.
+// Erasing an already soft-deleted user is allowed, and finishes deleting
+// files a failed erasure left behind.
+func (s *UserService) EraseUser(ctx context.Context, id uint) (err error) {
+	ctx, span := tracing.Start(ctx, "UserService.EraseUser")
+	defer tracing.End(span, &err)
+
+	var user models.User
+	if err := s.db.WithContext(ctx).Unscoped().First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+
+	now := time.Now()
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&user).Updates(map[string]interface{}{
+			"email":         fmt.Sprintf("erased-user-%d@anonymized.invalid", user.ID),
+			"password_hash": "",
+			"first_name":    "Deleted",
+			"last_name":     "User",
+			"provider_id":   "",
+			"profile_image": "",
+			"locale":        "",
+			"time_zone":     "",
+			"refresh_token": "",
+			"is_active":     false,
+			"anonymized_at": now,
+		}).Error; err != nil {
+			return err
+		}
+		// Saved responses to retried requests can hold personal data
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.IdempotencyKey{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("user_id = ?", user.ID).Delete(&models.DataExport{}).Error; err != nil {
+			return err
+		}
+
+		if user.DeletedAt.Valid {
+			return nil
+		}
+		return tx.Delete(&user).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	// The files go once nothing refers to them anymore
+	if err := deleteBlobs(ctx, s.store, ExportPrefix(user.ID)); err != nil {
+		return fmt.Errorf("failed to delete data exports: %w", err)
+	}
+	if err := deleteBlobs(ctx, s.store, ProfileImagePrefix(user.ID)); err != nil {
+		return fmt.Errorf("failed to delete profile images: %w", err)
+	}
+	return nil
+}
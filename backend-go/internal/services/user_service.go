@@ -0,0 +1,36 @@
+package services
+
+import (
+	"context"
+
+	"aicg/internal/models"
+	"aicg/internal/models/enums"
+	"aicg/internal/repository"
+)
+
+// IUserService defines the interface for user management operations
+type IUserService interface {
+	// GetUserByID retrieves a user that has not been deleted
+	GetUserByID(ctx context.Context, id uint) (*models.User, error)
+
+	// UpdateProfile changes the profile fields a user controls themselves
+	UpdateProfile(ctx context.Context, id uint, update ProfileUpdate) (*models.User, error)
+
+	// ListUsers retrieves one page of users matching the filter
+	ListUsers(ctx context.Context, filter UserFilter, page repository.Page) (repository.List[models.User], error)
+
+	// SetUserActive activates or deactivates a user account
+	SetUserActive(ctx context.Context, id uint, active bool) (*models.User, error)
+
+	// ChangeUserRole gives a user a new role
+	ChangeUserRole(ctx context.Context, id uint, role enums.UserRole) (*models.User, error)
+
+	// DeleteUser soft-deletes a user
+	DeleteUser(ctx context.Context, id uint) error
+
+	// EraseUser anonymizes a user's personal data while keeping their statistics
+	EraseUser(ctx context.Context, id uint) error
+}
+
+// Ensure UserService implements IUserService
+var _ IUserService = (*UserService)(nil)
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"aicg/internal/config"
+)
+
+// LocalURLPrefix is the path the local store's files are served under by default
+const LocalURLPrefix = "/uploads"
+
+// ErrNotFound is returned by Get when no object is stored under the key
+var ErrNotFound = errors.New("blob not found")
+
+// ErrInvalidKey is returned for keys that are empty or try to escape the store
+var ErrInvalidKey = errors.New("invalid blob key")
+
+// BlobStore saves and serves binary objects such as uploaded images.
+// Keys are slash separated relative paths, e.g. "profile-images/42/abc/small.jpg".
+type BlobStore interface {
+	// Put stores the content of r under key, replacing any existing object
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+
+	// Get opens the object stored under key. The caller must close it.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// Delete removes the object stored under key. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+
+	// List returns the keys of the objects stored under the directory-like
+	// prefix, e.g. "profile-images/42/", in no particular order
+	List(ctx context.Context, prefix string) ([]string, error)
+
+	// URL returns a URL clients can use to download the object.
+	// Depending on the store this is a public URL or a signed, expiring one.
+	URL(ctx context.Context, key string) (string, error)
+}
+
+// NewFromConfig creates the blob store selected by cfg.Storage.Driver
+func NewFromConfig(cfg *config.Config) (BlobStore, error) {
+	switch cfg.Storage.Driver {
+	case "", "local":
+		baseURL := cfg.Storage.PublicURL
+		if baseURL == "" {
+			baseURL = LocalURLPrefix
+		}
+		return NewLocalStore(cfg.Storage.LocalDir, baseURL)
+	case "s3":
+		return NewS3Store(S3Config{
+			Endpoint:      cfg.Storage.S3.Endpoint,
+			Region:        cfg.Storage.S3.Region,
+			Bucket:        cfg.Storage.S3.Bucket,
+			AccessKey:     cfg.Storage.S3.AccessKey,
+			SecretKey:     cfg.Storage.S3.SecretKey,
+			UsePathStyle:  cfg.Storage.S3.UsePathStyle,
+			PublicBaseURL: cfg.Storage.PublicURL,
+			URLExpiry:     cfg.Storage.URLExpiry,
+		})
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", cfg.Storage.Driver)
+	}
+}
+
+// validatePrefix makes sure a prefix is a clean relative path ending with a slash
+func validatePrefix(prefix string) error {
+	if !strings.HasSuffix(prefix, "/") {
+		return ErrInvalidKey
+	}
+	return validateKey(strings.TrimSuffix(prefix, "/"))
+}
+
+// validateKey makes sure a key is a clean relative path
+func validateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return ErrInvalidKey
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return ErrInvalidKey
+		}
+	}
+	return nil
+}
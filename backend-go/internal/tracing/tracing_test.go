@@ -0,0 +1,100 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"aicg/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// record sends spans to a recorder for the duration of the test
+func record(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func TestStartEnd(t *testing.T) {
+	recorder := record(t)
+	method := func(ctx context.Context, fail bool) (err error) {
+		ctx, span := Start(ctx, "QuizService.GetQuizByID", attribute.Int("quiz.id", 7))
+		defer End(span, &err)
+		_, child := Start(ctx, "child")
+		child.End()
+		if fail {
+			return errors.New("quiz not found")
+		}
+		return nil
+	}
+
+	// Test case 1: A successful call ends its span unset, with its attributes and children
+	require.NoError(t, method(context.Background(), false))
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	child, parent := spans[0], spans[1]
+	assert.Equal(t, "QuizService.GetQuizByID", parent.Name())
+	assert.Equal(t, codes.Unset, parent.Status().Code)
+	assert.Contains(t, parent.Attributes(), attribute.Int("quiz.id", 7))
+	assert.Equal(t, parent.SpanContext().SpanID(), child.Parent().SpanID())
+
+	// Test case 2: A failed call marks its span as failed and records the error
+	require.Error(t, method(context.Background(), true))
+	failed := recorder.Ended()[3]
+	assert.Equal(t, codes.Error, failed.Status().Code)
+	assert.Equal(t, "quiz not found", failed.Status().Description)
+	require.Len(t, failed.Events(), 1)
+	assert.Equal(t, "exception", failed.Events()[0].Name)
+}
+
+func TestSetup(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	cfg := config.Default().Tracing
+
+	// Test case 1: Without an exporter nothing is installed and shutting down is a no-op
+	shutdown, err := Setup(context.Background(), cfg)
+	require.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+
+	// Test case 2: The stdout exporter samples every trace by default
+	cfg.Exporter = "stdout"
+	shutdown, err = Setup(context.Background(), cfg)
+	require.NoError(t, err)
+	_, span := Start(context.Background(), "test")
+	assert.True(t, span.SpanContext().IsSampled())
+	span.End()
+	assert.NoError(t, shutdown(context.Background()))
+
+	// Test case 3: A zero ratio samples nothing, unless the caller's trace was sampled
+	cfg.SampleRatio = 0
+	shutdown, err = Setup(context.Background(), cfg)
+	require.NoError(t, err)
+	defer shutdown(context.Background())
+	_, span = Start(context.Background(), "test")
+	assert.False(t, span.SpanContext().IsSampled())
+	parent := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{1},
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	}))
+	_, span = Start(parent, "test")
+	assert.True(t, span.SpanContext().IsSampled())
+
+	// Test case 4: Unknown exporters are rejected
+	cfg.Exporter = "zipkin"
+	_, err = Setup(context.Background(), cfg)
+	assert.Error(t, err)
+}
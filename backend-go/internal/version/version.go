@@ -0,0 +1,61 @@
+// Package version describes the running build. Release builds set the
+// variables with the linker:
+//
+//	go build -ldflags "-X aicg/internal/version.Version=v1.2.0 -X aicg/internal/version.Commit=$(git rev-parse HEAD)" ./cmd/api
+//
+// Otherwise the commit and build time come from the VCS information Go embeds.
+package version
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+var (
+	Version = "dev"
+	Commit  = ""
+	Date    = ""
+)
+
+// Info is the version of the running binary
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	Date      string `json:"date,omitempty"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the version of the running binary
+func Get() Info {
+	info := Info{Version: Version, Commit: Commit, Date: Date, GoVersion: runtime.Version()}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = setting.Value
+			case setting.Key == "vcs.time" && info.Date == "":
+				info.Date = setting.Value
+			case setting.Key == "vcs.modified" && setting.Value == "true" && Commit == "":
+				info.Commit += "-dirty"
+			}
+		}
+	}
+	return info
+}
+
+// String formats the version for logs, e.g. "v1.2.0 (commit 1a2b3c4, built 2024-05-01T10:00:00Z, go1.23.0)"
+func (i Info) String() string {
+	commit := i.Commit
+	if len(commit) > 7 {
+		commit = commit[:7]
+	}
+	if commit == "" {
+		commit = "unknown"
+	}
+	date := i.Date
+	if date == "" {
+		date = "unknown"
+	}
+	return fmt.Sprintf("%s (commit %s, built %s, %s)", i.Version, commit, date, i.GoVersion)
+}
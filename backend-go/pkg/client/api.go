@@ -0,0 +1,252 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Register signs up a user with an email address and password. It doesn't log them in.
+func (c *Client) Register(ctx context.Context, req RegisterRequest) (*User, error) {
+	var resp RegisterResponse
+	if err := c.do(ctx, request{method: http.MethodPost, path: "/api/auth/register", body: req, out: &resp}); err != nil {
+		return nil, err
+	}
+	return resp.User, nil
+}
+
+// Login logs in with an email address and password, keeping the tokens for later requests
+func (c *Client) Login(ctx context.Context, email, password string) (*TokenPair, error) {
+	return c.login(ctx, "/api/auth/login", LoginRequest{Email: email, Password: password})
+}
+
+// LoginSSO logs in, or signs up, the user an SSO provider vouched for, keeping the tokens for later requests
+func (c *Client) LoginSSO(ctx context.Context, provider AuthProvider, req SSORequest) (*TokenPair, error) {
+	return c.login(ctx, "/api/auth/sso/"+url.PathEscape(string(provider))+"/callback", req)
+}
+
+func (c *Client) login(ctx context.Context, path string, body any) (*TokenPair, error) {
+	var tokens TokenPair
+	if err := c.do(ctx, request{method: http.MethodPost, path: path, body: body, out: &tokens}); err != nil {
+		return nil, err
+	}
+	c.SetTokens(tokens)
+	return &tokens, nil
+}
+
+// Refresh trades the refresh token for new tokens now. Requests do this by
+// themselves when the access token has expired.
+func (c *Client) Refresh(ctx context.Context) (*TokenPair, error) {
+	if err := c.refresh(ctx, c.Tokens().AccessToken); err != nil {
+		return nil, err
+	}
+	tokens := c.Tokens()
+	return &tokens, nil
+}
+
+// SSOConfig returns the public configuration of an SSO provider, for starting its login flow
+func (c *Client) SSOConfig(ctx context.Context, provider AuthProvider) (*SSOConfig, error) {
+	var config SSOConfig
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/auth/sso/" + url.PathEscape(string(provider)) + "/config", out: &config})
+	return result(&config, err)
+}
+
+// Me returns the logged in user
+func (c *Client) Me(ctx context.Context) (*User, error) {
+	var user User
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/users/me", auth: true, out: &user})
+	return result(&user, err)
+}
+
+// UpdateMe changes the logged in user's profile; nil fields are kept
+func (c *Client) UpdateMe(ctx context.Context, update ProfileUpdate) (*User, error) {
+	var user User
+	err := c.do(ctx, request{method: http.MethodPatch, path: "/api/users/me", body: update, auth: true, out: &user})
+	return result(&user, err)
+}
+
+// MyProgress sums up the logged in user's progress overall and in each category
+func (c *Client) MyProgress(ctx context.Context) (*ProgressSummary, error) {
+	var summary ProgressSummary
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/users/me/progress", auth: true, out: &summary})
+	return result(&summary, err)
+}
+
+// MyCategoryProgress sums up the logged in user's progress in a category
+func (c *Client) MyCategoryProgress(ctx context.Context, category QuizCategory) (*CategoryProgress, error) {
+	var progress CategoryProgress
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/users/me/progress/" + url.PathEscape(string(category)), auth: true, out: &progress})
+	return result(&progress, err)
+}
+
+// PageQuery picks a page of a listing
+type PageQuery struct {
+	Limit  int    // Records per page, 20 if 0
+	Sort   string // e.g. "-created_at" for newest first
+	Cursor string // NextCursor of the previous page
+}
+
+func (q PageQuery) values(v url.Values) url.Values {
+	if q.Limit > 0 {
+		v.Set("limit", strconv.Itoa(q.Limit))
+	}
+	set(v, "sort", q.Sort)
+	set(v, "cursor", q.Cursor)
+	return v
+}
+
+// QuizQuery filters the quiz listing; zero fields don't filter
+type QuizQuery struct {
+	Category   QuizCategory
+	Difficulty QuizDifficulty
+	Published  *bool // Only admins may ask for unpublished quizzes; others always get published ones
+	CreatedBy  uint
+	From, To   time.Time
+	PageQuery
+}
+
+// ListQuizzes returns a page of quizzes, without their questions
+func (c *Client) ListQuizzes(ctx context.Context, q QuizQuery) (*QuizList, error) {
+	v := url.Values{}
+	set(v, "category", string(q.Category))
+	set(v, "difficulty", string(q.Difficulty))
+	setBool(v, "published", q.Published)
+	setID(v, "created_by", q.CreatedBy)
+	setTime(v, "from", q.From)
+	setTime(v, "to", q.To)
+
+	var quizzes QuizList
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/quiz/", query: q.values(v), auth: true, out: &quizzes})
+	return result(&quizzes, err)
+}
+
+// GetQuiz returns a quiz with its questions; only admins get the correct answers
+func (c *Client) GetQuiz(ctx context.Context, id uint) (*Quiz, error) {
+	var quiz Quiz
+	err := c.do(ctx, request{method: http.MethodGet, path: fmt.Sprintf("/api/quiz/%d", id), auth: true, out: &quiz})
+	return result(&quiz, err)
+}
+
+// CreateQuiz creates a quiz with its questions, as a super admin
+func (c *Client) CreateQuiz(ctx context.Context, quiz Quiz) (*Quiz, error) {
+	var created Quiz
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/admin/quiz", body: quiz, auth: true, out: &created})
+	return result(&created, err)
+}
+
+// SubmitQuiz submits answers to a quiz and returns their grade. Retries
+// can't count as another attempt, since they carry the same Idempotency-Key.
+func (c *Client) SubmitQuiz(ctx context.Context, quizID uint, submission SubmitQuizRequest) (*SubmitQuizResponse, error) {
+	var graded SubmitQuizResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: fmt.Sprintf("/api/quiz/%d/submit", quizID), body: submission, auth: true, out: &graded})
+	return result(&graded, err)
+}
+
+// ResultQuery filters the result listing; zero fields don't filter
+type ResultQuery struct {
+	UserID     uint // The logged in user's results if 0; only admins may pick another user
+	QuizID     uint
+	Category   QuizCategory
+	Difficulty QuizDifficulty
+	Passed     *bool
+	From, To   time.Time
+	PageQuery
+}
+
+// ListResults returns a page of quiz results, without their answers
+func (c *Client) ListResults(ctx context.Context, q ResultQuery) (*ResultList, error) {
+	v := url.Values{}
+	setID(v, "user_id", q.UserID)
+	setID(v, "quiz_id", q.QuizID)
+	set(v, "category", string(q.Category))
+	set(v, "difficulty", string(q.Difficulty))
+	setBool(v, "passed", q.Passed)
+	setTime(v, "from", q.From)
+	setTime(v, "to", q.To)
+
+	var results ResultList
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/results/", query: q.values(v), auth: true, out: &results})
+	return result(&results, err)
+}
+
+// GetResult returns a quiz result with its answers
+func (c *Client) GetResult(ctx context.Context, id uint) (*Result, error) {
+	var res Result
+	err := c.do(ctx, request{method: http.MethodGet, path: fmt.Sprintf("/api/results/%d", id), auth: true, out: &res})
+	return result(&res, err)
+}
+
+// LeaderboardQuery picks a leaderboard; zero fields mean all time, overall
+type LeaderboardQuery struct {
+	Period   RankingPeriod
+	Category QuizCategory
+	PageQuery
+}
+
+// Leaderboard returns a page of ranked users, best first
+func (c *Client) Leaderboard(ctx context.Context, q LeaderboardQuery) (*LeaderboardList, error) {
+	v := url.Values{}
+	set(v, "period", string(q.Period))
+	set(v, "category", string(q.Category))
+
+	var leaderboard LeaderboardList
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/leaderboard", query: q.values(v), auth: true, out: &leaderboard})
+	return result(&leaderboard, err)
+}
+
+// SearchQuery is a full-text search over the published quizzes
+type SearchQuery struct {
+	Text       string // Words to find, all of them
+	Category   QuizCategory
+	Difficulty QuizDifficulty
+	Limit      int
+}
+
+// Search finds quizzes and questions, with suggestions when nothing matches
+func (c *Client) Search(ctx context.Context, q SearchQuery) (*SearchResults, error) {
+	v := url.Values{"q": {q.Text}}
+	set(v, "category", string(q.Category))
+	set(v, "difficulty", string(q.Difficulty))
+	if q.Limit > 0 {
+		v.Set("limit", strconv.Itoa(q.Limit))
+	}
+
+	var results SearchResults
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/search", query: v, auth: true, out: &results})
+	return result(&results, err)
+}
+
+// result returns v, or nil on failure
+func result[T any](v *T, err error) (*T, error) {
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func set(v url.Values, name, value string) {
+	if value != "" {
+		v.Set(name, value)
+	}
+}
+
+func setID(v url.Values, name string, id uint) {
+	if id != 0 {
+		v.Set(name, strconv.FormatUint(uint64(id), 10))
+	}
+}
+
+func setBool(v url.Values, name string, b *bool) {
+	if b != nil {
+		v.Set(name, strconv.FormatBool(*b))
+	}
+}
+
+func setTime(v url.Values, name string, t time.Time) {
+	if !t.IsZero() {
+		v.Set(name, t.Format(time.RFC3339))
+	}
+}
@@ -0,0 +1,324 @@
+// Package client is a typed Go client for the AICG API, for tools and load
+// tests. It keeps the tokens of the logged in user, refreshes the access
+// token when the API rejects it, and retries requests that failed for
+// transient reasons with exponential backoff. Requests are only retried if
+// that's safe: those with idempotent methods, and the logged in user's POSTs,
+// which carry an Idempotency-Key so the API runs them only once.
+//
+//	c := client.New("http://localhost:8080")
+//	if _, err := c.Login(ctx, "ada@example.com", "password"); err != nil {
+//		return err
+//	}
+//	quizzes, err := c.ListQuizzes(ctx, client.QuizQuery{Category: client.CategoryMath})
+//
+// Failed requests return an *Error with the API's error code.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// userAgent identifies the client in the server's logs
+const userAgent = "aicg-go-client"
+
+// Client calls the API. It's safe for concurrent use.
+type Client struct {
+	baseURL  string
+	http     *http.Client
+	retry    RetryPolicy
+	onTokens func(TokenPair)
+
+	mu     sync.RWMutex
+	tokens TokenPair
+
+	// refreshing serializes refreshes, since each one replaces the refresh token
+	refreshing sync.Mutex
+}
+
+// Option configures a client
+type Option func(*Client)
+
+// WithHTTPClient sends requests with hc instead of http.DefaultClient
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.http = hc }
+}
+
+// WithTokens starts the client logged in with previously issued tokens
+func WithTokens(tokens TokenPair) Option {
+	return func(c *Client) { c.tokens = tokens }
+}
+
+// WithTokenCallback calls fn with the new tokens after every login and refresh,
+// e.g. to store them for the next run
+func WithTokenCallback(fn func(TokenPair)) Option {
+	return func(c *Client) { c.onTokens = fn }
+}
+
+// WithRetry replaces DefaultRetry
+func WithRetry(policy RetryPolicy) Option {
+	return func(c *Client) { c.retry = policy }
+}
+
+// New creates a client for the API at baseURL, e.g. "https://api.example.com"
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		http:    http.DefaultClient,
+		retry:   DefaultRetry,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Tokens returns the current tokens, empty before logging in
+func (c *Client) Tokens() TokenPair {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.tokens
+}
+
+// SetTokens replaces the current tokens
+func (c *Client) SetTokens(tokens TokenPair) {
+	c.mu.Lock()
+	c.tokens = tokens
+	c.mu.Unlock()
+	if c.onTokens != nil {
+		c.onTokens(tokens)
+	}
+}
+
+// RetryPolicy says how often and how patiently idempotent requests are retried
+type RetryPolicy struct {
+	MaxAttempts int           // Including the first; 1 turns retries off
+	MinBackoff  time.Duration // Before the first retry, doubling after each one
+	MaxBackoff  time.Duration // At most, unless the server asks for longer with Retry-After
+}
+
+// DefaultRetry makes up to 3 attempts, waiting about 200ms and 400ms in between
+var DefaultRetry = RetryPolicy{MaxAttempts: 3, MinBackoff: 200 * time.Millisecond, MaxBackoff: 5 * time.Second}
+
+// backoff returns how long to wait before the given retry, counting from 1,
+// with jitter so that clients failing together don't retry together
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	d := p.MinBackoff << (retry - 1)
+	if d > p.MaxBackoff || d <= 0 {
+		d = p.MaxBackoff
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// Error is a request the API refused, with the details of its error response
+type Error struct {
+	StatusCode int          `json:"-"`
+	Message    string       `json:"error"`
+	Code       string       `json:"code"` // Stable, for programs to branch on
+	Details    []FieldError `json:"details,omitempty"`
+	RequestID  string       `json:"request_id,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("aicg: %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// ErrorCode returns the API's error code of err, or "" if the API didn't refuse the request
+func ErrorCode(err error) string {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Code
+	}
+	return ""
+}
+
+// request is a call to the API
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	body   any    // Sent as JSON, if set
+	auth   bool   // Send the access token, refreshing it when it's rejected
+	out    any    // Decoded from the JSON response, if set
+	key    string // Idempotency-Key, set by do for the logged in user's POSTs
+}
+
+// do sends a request, refreshing the access token once if the API rejects it
+func (c *Client) do(ctx context.Context, r request) error {
+	var body []byte
+	if r.body != nil {
+		var err error
+		if body, err = json.Marshal(r.body); err != nil {
+			return fmt.Errorf("aicg: encoding request: %w", err)
+		}
+	}
+
+	token := ""
+	if r.auth {
+		token = c.Tokens().AccessToken
+		if r.method == http.MethodPost {
+			r.key = newIdempotencyKey()
+		}
+	}
+	err := c.send(ctx, r, body, token)
+	var apiErr *Error
+	if r.auth && errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized && c.Tokens().RefreshToken != "" {
+		if refreshErr := c.refresh(ctx, token); refreshErr != nil {
+			return refreshErr
+		}
+		err = c.send(ctx, r, body, c.Tokens().AccessToken)
+	}
+	return err
+}
+
+// refresh trades the refresh token for new tokens, unless another request
+// already replaced the stale access token
+func (c *Client) refresh(ctx context.Context, stale string) error {
+	c.refreshing.Lock()
+	defer c.refreshing.Unlock()
+
+	current := c.Tokens()
+	if current.AccessToken != stale {
+		return nil
+	}
+	var tokens TokenPair
+	err := c.send(ctx, request{
+		method: http.MethodPost,
+		path:   "/api/auth/refresh",
+		out:    &tokens,
+	}, mustJSON(RefreshRequest{RefreshToken: current.RefreshToken}), "")
+	if err != nil {
+		return fmt.Errorf("aicg: refreshing access token: %w", err)
+	}
+	c.SetTokens(tokens)
+	return nil
+}
+
+// send sends a request, retrying those safe to repeat that failed for transient reasons
+func (c *Client) send(ctx context.Context, r request, body []byte, token string) error {
+	attempts := 1
+	if idempotent(r.method) || r.key != "" {
+		attempts = max(1, c.retry.MaxAttempts)
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		var wait time.Duration
+		wait, err = c.attempt(ctx, r, body, token)
+		if err == nil || wait < 0 || attempt == attempts {
+			return err
+		}
+
+		wait = max(wait, c.retry.backoff(attempt))
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt sends a request once. On failure it returns how long the server
+// asked to wait before retrying, or -1 if retrying wouldn't help.
+func (c *Client) attempt(ctx context.Context, r request, body []byte, token string) (time.Duration, error) {
+	target := c.baseURL + r.path
+	if len(r.query) > 0 {
+		target += "?" + r.query.Encode()
+	}
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, r.method, target, reader)
+	if err != nil {
+		return -1, fmt.Errorf("aicg: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if r.key != "" {
+		req.Header.Set("Idempotency-Key", r.key)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return -1, ctx.Err()
+		}
+		// The connection failed, so the request may not have arrived
+		return 0, fmt.Errorf("aicg: %s %s: %w", r.method, r.path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		apiErr := &Error{StatusCode: resp.StatusCode}
+		if err := json.NewDecoder(resp.Body).Decode(apiErr); err != nil || apiErr.Code == "" {
+			apiErr.Message = http.StatusText(resp.StatusCode)
+		}
+		switch {
+		case resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode == http.StatusServiceUnavailable,
+			resp.StatusCode == http.StatusBadGateway, resp.StatusCode == http.StatusGatewayTimeout:
+			return retryAfter(resp.Header.Get("Retry-After")), apiErr
+		case apiErr.Code == "idempotency_key_in_use":
+			// An earlier attempt is still running; its response will be replayed
+			return 0, apiErr
+		}
+		return -1, apiErr
+	}
+
+	if r.out != nil && resp.StatusCode != http.StatusNoContent {
+		if err := json.NewDecoder(resp.Body).Decode(r.out); err != nil {
+			return -1, fmt.Errorf("aicg: decoding %s %s response: %w", r.method, r.path, err)
+		}
+	}
+	return 0, nil
+}
+
+// idempotent reports whether requests with method can safely be sent twice
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// newIdempotencyKey returns a random key for a request and its retries
+func newIdempotencyKey() string {
+	return fmt.Sprintf("%016x%016x", rand.Uint64(), rand.Uint64())
+}
+
+// retryAfter parses a Retry-After header in seconds, returning 0 without one
+func retryAfter(header string) time.Duration {
+	seconds, err := strconv.Atoi(header)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func mustJSON(v any) []byte {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
@@ -0,0 +1,309 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"aicg/internal/config"
+	"aicg/internal/database"
+	"aicg/internal/routes"
+	"aicg/internal/seed"
+	"aicg/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/logger"
+)
+
+// newTestServer serves the real router on a migrated and seeded SQLite
+// database, with its handler wrapped by wrap if given
+func newTestServer(t *testing.T, wrap func(http.Handler) http.Handler) *httptest.Server {
+	gin.SetMode(gin.TestMode)
+	dir := t.TempDir()
+	cfg := config.Default()
+	cfg.DB.Driver = database.DriverSQLite
+	cfg.DB.Path = filepath.Join(dir, "aicg.db")
+	cfg.JWT.Secret = "client-secret"
+	cfg.Storage.LocalDir = filepath.Join(dir, "uploads")
+	cfg.RateLimit.Enabled = false
+
+	db, err := database.InitDB(cfg)
+	require.NoError(t, err)
+	db.Logger = logger.Discard
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	migrator, err := database.NewMigrator(db)
+	require.NoError(t, err)
+	_, err = migrator.Up(context.Background())
+	require.NoError(t, err)
+	fixtures, err := seed.LoadFixtures(os.DirFS("../../fixtures"))
+	require.NoError(t, err)
+	_, err = seed.NewSeeder(db).Seed(fixtures, "admin-password")
+	require.NoError(t, err)
+
+	blobs, err := storage.NewFromConfig(cfg)
+	require.NoError(t, err)
+	var handler http.Handler = routes.SetupRouter(cfg, routes.NewServices(db, blobs, cfg))
+	if wrap != nil {
+		handler = wrap(handler)
+	}
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// adminEmail is the super admin from the seed fixtures
+const adminEmail = "admin@aicg.local"
+
+// fastRetry retries without making the tests wait
+var fastRetry = WithRetry(RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond})
+
+func TestAuth(t *testing.T) {
+	srv := newTestServer(t, nil)
+	ctx := context.Background()
+	var saved []TokenPair
+	c := New(srv.URL, WithTokenCallback(func(tokens TokenPair) { saved = append(saved, tokens) }))
+
+	// Test case 1: Sign up, log in, and call protected routes with the token
+	user, err := c.Register(ctx, RegisterRequest{Email: "ada@example.com", Password: "user-password", FirstName: "Ada", LastName: "Lovelace"})
+	require.NoError(t, err)
+	assert.Equal(t, "ada@example.com", user.Email)
+
+	tokens, err := c.Login(ctx, "ada@example.com", "user-password")
+	require.NoError(t, err)
+	assert.Equal(t, *tokens, c.Tokens())
+	assert.Len(t, saved, 1)
+
+	me, err := c.Me(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, me.ID)
+
+	// Test case 2: A rejected access token is refreshed, and the request repeated
+	c.SetTokens(TokenPair{AccessToken: "expired", RefreshToken: tokens.RefreshToken})
+	me, err = c.Me(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, me.ID)
+	assert.NotEqual(t, "expired", c.Tokens().AccessToken)
+	assert.Equal(t, c.Tokens(), saved[len(saved)-1])
+
+	// Test case 3: Without a valid refresh token the request fails
+	c.SetTokens(TokenPair{AccessToken: "expired", RefreshToken: "revoked"})
+	_, err = c.Me(ctx)
+	assert.Equal(t, "invalid_refresh_token", ErrorCode(err))
+
+	// Test case 4: API errors carry their code and details
+	_, err = c.Login(ctx, "ada@example.com", "wrong-password")
+	assert.Equal(t, "invalid_credentials", ErrorCode(err))
+	_, err = c.Register(ctx, RegisterRequest{Email: "not-an-email"})
+	var apiErr *Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	assert.Equal(t, "validation_failed", apiErr.Code)
+	assert.NotEmpty(t, apiErr.Details)
+	assert.NotEmpty(t, apiErr.RequestID)
+
+	// Test case 5: Seeded SSO placeholders aren't configured
+	_, err = c.SSOConfig(ctx, ProviderGoogle)
+	assert.Equal(t, "sso_not_configured", ErrorCode(err))
+}
+
+func TestConcurrentRefresh(t *testing.T) {
+	var refreshes atomic.Int32
+	srv := newTestServer(t, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/api/auth/refresh" {
+				refreshes.Add(1)
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+	ctx := context.Background()
+	c := New(srv.URL)
+	_, err := c.Register(ctx, RegisterRequest{Email: "ada@example.com", Password: "user-password", FirstName: "Ada", LastName: "Lovelace"})
+	require.NoError(t, err)
+	tokens, err := c.Login(ctx, "ada@example.com", "user-password")
+	require.NoError(t, err)
+
+	// Requests rejected together share one refresh, which replaces the refresh token
+	c.SetTokens(TokenPair{AccessToken: "expired", RefreshToken: tokens.RefreshToken})
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.Me(ctx)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), refreshes.Load())
+}
+
+func TestQuizzes(t *testing.T) {
+	srv := newTestServer(t, nil)
+	ctx := context.Background()
+	c := New(srv.URL)
+	_, err := c.Register(ctx, RegisterRequest{Email: "ada@example.com", Password: "user-password", FirstName: "Ada", LastName: "Lovelace"})
+	require.NoError(t, err)
+	_, err = c.Login(ctx, "ada@example.com", "user-password")
+	require.NoError(t, err)
+
+	// Test case 1: List, page and filter the seeded quizzes
+	quizzes, err := c.ListQuizzes(ctx, QuizQuery{})
+	require.NoError(t, err)
+	assert.Len(t, quizzes.Items, 6)
+
+	page, err := c.ListQuizzes(ctx, QuizQuery{PageQuery: PageQuery{Limit: 4, Sort: "title"}})
+	require.NoError(t, err)
+	require.Len(t, page.Items, 4)
+	require.NotEmpty(t, page.NextCursor)
+	rest, err := c.ListQuizzes(ctx, QuizQuery{PageQuery: PageQuery{Limit: 4, Sort: "title", Cursor: page.NextCursor}})
+	require.NoError(t, err)
+	assert.Len(t, rest.Items, 2)
+
+	math, err := c.ListQuizzes(ctx, QuizQuery{Category: CategoryMath})
+	require.NoError(t, err)
+	require.Len(t, math.Items, 1)
+
+	// Test case 2: Take a quiz, with the answers only an admin gets, then find the result
+	quiz, err := c.GetQuiz(ctx, math.Items[0].ID)
+	require.NoError(t, err)
+	require.NotEmpty(t, quiz.Questions)
+	assert.Empty(t, quiz.Questions[0].CorrectAnswer)
+	admin := New(srv.URL)
+	_, err = admin.Login(ctx, adminEmail, "admin-password")
+	require.NoError(t, err)
+	key, err := admin.GetQuiz(ctx, quiz.ID)
+	require.NoError(t, err)
+	submission := SubmitQuizRequest{TimeTaken: 42}
+	for _, q := range key.Questions {
+		submission.Answers = append(submission.Answers, SubmittedAnswer{QuestionID: q.ID, Answer: q.CorrectAnswer})
+	}
+	graded, err := c.SubmitQuiz(ctx, quiz.ID, submission)
+	require.NoError(t, err)
+	assert.Equal(t, 100.0, graded.Score)
+	assert.True(t, graded.IsPassed)
+
+	passed := true
+	results, err := c.ListResults(ctx, ResultQuery{Passed: &passed, Category: CategoryMath})
+	require.NoError(t, err)
+	require.Len(t, results.Items, 1)
+	res, err := c.GetResult(ctx, results.Items[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, quiz.ID, res.QuizID)
+	assert.Equal(t, 42, res.TimeTaken)
+
+	progress, err := c.MyProgress(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, progress.Overall.TotalAttempts)
+	assert.Equal(t, 6, progress.Overall.QuizzesPublished)
+	mathProgress, err := c.MyCategoryProgress(ctx, CategoryMath)
+	require.NoError(t, err)
+	assert.Equal(t, CategoryMath, mathProgress.Category)
+	assert.Equal(t, 1, mathProgress.TotalAttempts)
+
+	// Test case 3: Leaderboards and search
+	_, err = c.Leaderboard(ctx, LeaderboardQuery{Period: RankingPeriodWeekly, Category: CategoryMath})
+	require.NoError(t, err)
+	found, err := c.Search(ctx, SearchQuery{Text: "algebra"})
+	require.NoError(t, err)
+	require.NotEmpty(t, found.Hits)
+	assert.Equal(t, quiz.ID, found.Hits[0].QuizID)
+
+	// Test case 4: Bad parameters are refused
+	_, err = c.ListQuizzes(ctx, QuizQuery{Category: "astrology"})
+	assert.Equal(t, "invalid_category", ErrorCode(err))
+	_, err = c.GetQuiz(ctx, 9999)
+	assert.Equal(t, "quiz_not_found", ErrorCode(err))
+}
+
+func TestRetries(t *testing.T) {
+	// The first two attempts of every request hit an overloaded proxy
+	var mu sync.Mutex
+	attempts := make(map[string]int)
+	keys := make(map[string]bool)
+	srv := newTestServer(t, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Method + " " + r.URL.Path
+			mu.Lock()
+			attempts[key]++
+			n := attempts[key]
+			if k := r.Header.Get("Idempotency-Key"); k != "" {
+				keys[k] = true
+			}
+			mu.Unlock()
+			if r.URL.Path != "/api/auth/login" && n <= 2 {
+				w.Header().Set("Retry-After", "0")
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusServiceUnavailable)
+				_, _ = w.Write([]byte(`{"error":"Overloaded","code":"overloaded"}`))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+	ctx := context.Background()
+	c := New(srv.URL, fastRetry)
+	_, err := c.Login(ctx, adminEmail, "admin-password")
+	require.NoError(t, err)
+
+	// Test case 1: Idempotent requests are retried with backoff
+	quizzes, err := c.ListQuizzes(ctx, QuizQuery{})
+	require.NoError(t, err)
+	assert.NotEmpty(t, quizzes.Items)
+	assert.Equal(t, 3, attempts["GET /api/quiz/"])
+
+	// Test case 2: POSTs are retried with the same Idempotency-Key
+	quiz, err := c.GetQuiz(ctx, quizzes.Items[0].ID)
+	require.NoError(t, err)
+	submission := SubmitQuizRequest{TimeTaken: 10}
+	for _, q := range quiz.Questions {
+		submission.Answers = append(submission.Answers, SubmittedAnswer{QuestionID: q.ID, Answer: q.CorrectAnswer})
+	}
+	graded, err := c.SubmitQuiz(ctx, quiz.ID, submission)
+	require.NoError(t, err)
+	assert.True(t, graded.IsPassed)
+	assert.Equal(t, 3, attempts[fmt.Sprintf("POST /api/quiz/%d/submit", quiz.ID)])
+	assert.Len(t, keys, 1)
+	results, err := c.ListResults(ctx, ResultQuery{QuizID: quiz.ID})
+	require.NoError(t, err)
+	assert.Len(t, results.Items, 1)
+
+	// Test case 3: Those without one aren't, since they may have taken effect
+	_, err = c.Register(ctx, RegisterRequest{Email: "ada@example.com", Password: "user-password", FirstName: "Ada", LastName: "Lovelace"})
+	assert.Equal(t, "overloaded", ErrorCode(err))
+	assert.Equal(t, 1, attempts["POST /api/auth/register"])
+
+	// Test case 4: Retries give up after MaxAttempts
+	once := New(srv.URL, WithTokens(c.Tokens()), WithRetry(RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond}))
+	_, err = once.GetQuiz(ctx, quizzes.Items[1].ID)
+	assert.Equal(t, "overloaded", ErrorCode(err))
+	assert.Equal(t, 2, attempts[fmt.Sprintf("GET /api/quiz/%d", quizzes.Items[1].ID)])
+
+	// Test case 5: Waiting for a retry stops with the context
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = c.Me(canceled)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestBackoff(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 5, MinBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	for retry, want := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 3: 400 * time.Millisecond, 5: time.Second, 40: time.Second} {
+		d := policy.backoff(retry)
+		assert.GreaterOrEqual(t, d, want/2, "retry %d", retry)
+		assert.LessOrEqual(t, d, want, "retry %d", retry)
+	}
+}
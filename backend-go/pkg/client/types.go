@@ -0,0 +1,69 @@
+package client
+
+import (
+	"aicg/internal/handlers"
+	"aicg/internal/models"
+	"aicg/internal/models/enums"
+	"aicg/internal/repository"
+	"aicg/internal/services"
+)
+
+// The API's request and response types, under names importable outside this module
+type (
+	TokenPair          = services.TokenPair
+	FieldError         = services.FieldError
+	RegisterRequest    = handlers.RegisterRequest
+	RegisterResponse   = handlers.RegisterResponse
+	LoginRequest       = handlers.LoginRequest
+	SSORequest         = handlers.SSORequest
+	RefreshRequest     = handlers.RefreshRequest
+	SSOConfig          = handlers.SSOConfigResponse
+	User               = models.User
+	ProfileUpdate      = services.ProfileUpdate
+	ProgressSummary    = models.ProgressSummary
+	CategoryProgress   = models.CategoryProgress
+	Quiz               = models.Quiz
+	Question           = models.Question
+	Answer             = models.Answer
+	QuizSummary        = models.QuizSummary
+	QuizList           = repository.List[models.QuizSummary]
+	SubmitQuizRequest  = handlers.SubmitQuizRequest
+	SubmittedAnswer    = handlers.SubmittedAnswer
+	SubmitQuizResponse = handlers.SubmitQuizResponse
+	Result             = models.Result
+	ResultSummary      = models.ResultSummary
+	ResultList         = repository.List[models.ResultSummary]
+	LeaderboardEntry   = models.LeaderboardEntry
+	LeaderboardList    = repository.List[models.LeaderboardEntry]
+	SearchResults      = services.SearchResults
+	SearchHit          = services.SearchHit
+)
+
+// Enumerations used in queries and bodies
+type (
+	QuizCategory   = enums.QuizCategory
+	QuizDifficulty = enums.QuizDifficulty
+	RankingPeriod  = enums.RankingPeriod
+	AuthProvider   = enums.AuthProvider
+)
+
+const (
+	CategoryGeneral    = enums.CategoryGeneral
+	CategoryScience    = enums.CategoryScience
+	CategoryHistory    = enums.CategoryHistory
+	CategoryTechnology = enums.CategoryTechnology
+	CategoryMath       = enums.CategoryMath
+	CategoryLanguage   = enums.CategoryLanguage
+
+	DifficultyEasy   = enums.DifficultyEasy
+	DifficultyMedium = enums.DifficultyMedium
+	DifficultyHard   = enums.DifficultyHard
+
+	RankingPeriodWeekly  = enums.RankingPeriodWeekly
+	RankingPeriodMonthly = enums.RankingPeriodMonthly
+	RankingPeriodAllTime = enums.RankingPeriodAllTime
+
+	ProviderGoogle    = enums.ProviderGoogle
+	ProviderFacebook  = enums.ProviderFacebook
+	ProviderInstagram = enums.ProviderInstagram
+)
@@ -47,6 +47,18 @@ if %ERRORLEVEL% neq 0 (
     exit /b 1
 )
 
+echo Applying migrations...
+pushd "%~dp0.."
+go run ./cmd/api migrate up
+set MIGRATE_ERRORLEVEL=%ERRORLEVEL%
+popd
+if %MIGRATE_ERRORLEVEL% neq 0 (
+    echo Failed to apply migrations.
+    echo Please check the DB_* settings in your .env file.
+    pause
+    exit /b 1
+)
+
 echo Database setup completed successfully!
 echo You can now start the application.
 pause 
\ No newline at end of file
@@ -34,7 +34,7 @@ export default function ResultsPage() {
         throw new Error('Failed to fetch results')
       }
       const data = await res.json()
-      setResults(data)
+      setResults(data.items)
     } catch (err) {
       setError(err instanceof Error ? err.message : 'Failed to load results')
     } finally {